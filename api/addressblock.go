@@ -0,0 +1,83 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// GetAddressBlockTxs returns the transactions of the address in the block given by height or hash, in the order of the block
+// the transactions are found in the address index limited to the height of the block, the list is empty if the address was not active in the block
+func (w *Worker) GetAddressBlockTxs(address string, bid string) (*AddressBlockTxs, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	bi, err := w.getBlockInfoFromBlockID(bid)
+	if err != nil {
+		return nil, err
+	}
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	// the transactions at the height of the block must be in the block, otherwise the index is not in sync with the backend
+	inBlock := make(map[string]struct{}, len(bi.Txids))
+	for _, txid := range bi.Txids {
+		inBlock[txid] = struct{}{}
+	}
+	var txids []string
+	err = w.db.GetAddrDescTransactionsAscending(addrDesc, bi.Height, bi.Height, func(txid string, height uint32, indexes []int32) error {
+		if _, found := inBlock[txid]; !found {
+			glog.Warning("Address ", address, ": tx ", txid, " not found in block ", bi.Hash)
+			return nil
+		}
+		// the transaction is reported once for all its inputs and outputs
+		if len(txids) == 0 || txids[len(txids)-1] != txid {
+			txids = append(txids, txid)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescTransactionsAscending %v", addrDesc)
+	}
+	dbi := &db.BlockInfo{
+		Hash:   bi.Hash,
+		Height: bi.Height,
+		Time:   bi.Time,
+	}
+	txs := make([]*Tx, 0, len(txids))
+	for _, txid := range txids {
+		if w.chainType == bchain.ChainBitcoinType {
+			ta, err := w.db.GetTxAddresses(txid)
+			if err != nil {
+				return nil, errors.Annotatef(err, "GetTxAddresses %v", txid)
+			}
+			if ta == nil {
+				glog.Warning("DB inconsistency:  tx ", txid, ": not found in txAddresses")
+				continue
+			}
+			txs = append(txs, w.txFromTxAddress(txid, ta, dbi, bestheight))
+		} else {
+			tx, err := w.GetTransaction(txid, false, false)
+			if err != nil {
+				return nil, err
+			}
+			txs = append(txs, tx)
+		}
+	}
+	glog.Info("GetAddressBlockTxs ", address, " ", bid, ", ", len(txs), " txs, finished in ", time.Since(start))
+	return &AddressBlockTxs{
+		Address:      address,
+		Hash:         bi.Hash,
+		Height:       bi.Height,
+		Transactions: txs,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+	"math/big"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// GetAddressFees returns the sum of the fees of the confirmed transactions in which the address is an input;
+// the whole fee of such a transaction is attributed to the address, even if the transaction spends also outputs
+// of other addresses, the transactions in which the address only receives are not counted
+// the fees are computed from the values of the inputs and outputs stored in the index, the transactions are not downloaded
+func (w *Worker) GetAddressFees(address string) (*AddressFees, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	var fees big.Int
+	var txs int
+	err = w.db.GetAddrDescTransactions(addrDesc, 0, maxUint32, func(txid string, height uint32, indexes []int32) error {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return errors.Annotatef(err, "GetTxAddresses %v", txid)
+		}
+		if ta == nil {
+			glog.Warning("DB inconsistency:  tx ", txid, ": not found in txAddresses")
+			return nil
+		}
+		if fee, spent := spendingTxFee(ta, indexes); spent {
+			fees.Add(&fees, fee)
+			txs++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescTransactions %v", addrDesc)
+	}
+	glog.Info("GetAddressFees ", address, ", ", txs, " txs, finished in ", time.Since(start))
+	return &AddressFees{
+		Address: address,
+		FeesSat: (*Amount)(&fees),
+		Txs:     txs,
+	}, nil
+}
+
+// spendingTxFee returns the fee of the transaction and true if the address with the indexes in the transaction is its input
+func spendingTxFee(ta *db.TxAddresses, indexes []int32) (*big.Int, bool) {
+	spent := false
+	for _, index := range indexes {
+		if index < 0 {
+			spent = true
+			break
+		}
+	}
+	if !spent {
+		return nil, false
+	}
+	var fee big.Int
+	for i := range ta.Inputs {
+		fee.Add(&fee, &ta.Inputs[i].ValueSat)
+	}
+	for i := range ta.Outputs {
+		fee.Sub(&fee, &ta.Outputs[i].ValueSat)
+	}
+	if fee.Sign() == -1 {
+		fee.SetUint64(0)
+	}
+	return &fee, true
+}
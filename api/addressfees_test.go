@@ -0,0 +1,55 @@
+// +build unittest
+
+package api
+
+import (
+	"blockbook/db"
+	"math/big"
+	"testing"
+)
+
+func Test_spendingTxFee(t *testing.T) {
+	newTa := func(inputs []int64, outputs []int64) *db.TxAddresses {
+		ta := &db.TxAddresses{}
+		for _, v := range inputs {
+			ta.Inputs = append(ta.Inputs, db.TxInput{ValueSat: *big.NewInt(v)})
+		}
+		for _, v := range outputs {
+			ta.Outputs = append(ta.Outputs, db.TxOutput{ValueSat: *big.NewInt(v)})
+		}
+		return ta
+	}
+	// the history of an address, which receives in the first transaction and spends in the others
+	history := []struct {
+		ta      *db.TxAddresses
+		indexes []int32
+	}{
+		{newTa([]int64{5000}, []int64{3000, 1900}), []int32{0}},
+		{newTa([]int64{3000}, []int64{2500}), []int32{^0}},
+		// the address spends two inputs, the fee is counted once
+		{newTa([]int64{1000, 2500, 700}, []int64{4000}), []int32{^0, ^1, 0}},
+		// the whole fee is attributed to the address even if it is only one of the inputs
+		{newTa([]int64{1000, 4000}, []int64{4900}), []int32{^1}},
+		// the fee of a transaction with inconsistent values is not negative
+		{newTa([]int64{100}, []int64{200}), []int32{^0}},
+	}
+	wantFees := []int64{0, 500, 200, 100, 0}
+	var fees big.Int
+	txs := 0
+	for i, h := range history {
+		fee, spent := spendingTxFee(h.ta, h.indexes)
+		if spent != (i > 0) {
+			t.Errorf("%d: spendingTxFee() spent = %v", i, spent)
+		}
+		if spent {
+			if fee.Int64() != wantFees[i] {
+				t.Errorf("%d: spendingTxFee() = %v, want %v", i, fee, wantFees[i])
+			}
+			fees.Add(&fees, fee)
+			txs++
+		}
+	}
+	if fees.Int64() != 800 || txs != 4 {
+		t.Errorf("total fees = %v in %d txs, want 800 in 4 txs", fees.String(), txs)
+	}
+}
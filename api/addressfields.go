@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// AddressFields is the projection of the fields of Address returned by the API, nil means all fields
+type AddressFields map[string]struct{}
+
+var addressFieldNames = jsonFieldNames(reflect.TypeOf(Address{}))
+
+// jsonFieldNames returns the json names of the exported fields of the struct t, including the fields of embedded structs
+func jsonFieldNames(t reflect.Type) map[string]struct{} {
+	names := make(map[string]struct{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			for n := range jsonFieldNames(f.Type) {
+				names[n] = struct{}{}
+			}
+			continue
+		}
+		n := strings.Split(f.Tag.Get("json"), ",")[0]
+		if n == "" || n == "-" {
+			continue
+		}
+		names[n] = struct{}{}
+	}
+	return names
+}
+
+// ParseAddressFields parses comma separated list of the json names of Address fields, empty string means all fields
+func ParseAddressFields(s string) (AddressFields, error) {
+	if s == "" {
+		return nil, nil
+	}
+	fields := make(AddressFields)
+	for _, n := range strings.Split(s, ",") {
+		n = strings.TrimSpace(n)
+		if _, found := addressFieldNames[n]; !found {
+			return nil, NewAPIError(fmt.Sprintf("Unknown field '%s'", n), true)
+		}
+		fields[n] = struct{}{}
+	}
+	return fields, nil
+}
+
+// Has returns true if the field with the json name n is in the projection
+func (f AddressFields) Has(n string) bool {
+	if f == nil {
+		return true
+	}
+	_, found := f[n]
+	return found
+}
+
+// hasAny returns true if any of the fields with the json names n is in the projection
+func (f AddressFields) hasAny(n ...string) bool {
+	for _, s := range n {
+		if f.Has(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Project returns the address containing only the fields in the projection
+func (f AddressFields) Project(a *Address) (interface{}, error) {
+	if f == nil {
+		return a, nil
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, errors.Annotatef(err, "Marshal address %v", a.AddrStr)
+	}
+	var all map[string]json.RawMessage
+	if err = json.Unmarshal(b, &all); err != nil {
+		return nil, errors.Annotatef(err, "Unmarshal address %v", a.AddrStr)
+	}
+	r := make(map[string]json.RawMessage, len(f))
+	for n := range f {
+		if v, found := all[n]; found {
+			r[n] = v
+		}
+	}
+	return r, nil
+}
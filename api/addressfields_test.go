@@ -0,0 +1,88 @@
+// +build unittest
+
+package api
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParseAddressFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{name: "all", s: ""},
+		{name: "balance", s: "balance", want: []string{"balance"}},
+		{name: "paging and txids", s: "page, totalPages,txids", want: []string{"page", "totalPages", "txids"}},
+		{name: "unknown", s: "balance,balanceSat", wantErr: true},
+		{name: "json ignored", s: "Filter", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAddressFields(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAddressFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("ParseAddressFields() = %v, want nil", got)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("ParseAddressFields() = %v, want %v", got, tt.want)
+			}
+			for _, n := range tt.want {
+				if !got.Has(n) {
+					t.Errorf("ParseAddressFields() = %v, missing %v", got, n)
+				}
+			}
+		})
+	}
+}
+
+func TestAddressFields_Project(t *testing.T) {
+	a := &Address{
+		Paging:           Paging{Page: 1, TotalPages: 2, ItemsOnPage: 10},
+		AddrStr:          "addr",
+		BalanceSat:       (*Amount)(big.NewInt(100)),
+		TotalReceivedSat: (*Amount)(big.NewInt(200)),
+		Txids:            []string{"txid"},
+	}
+	tests := []struct {
+		name   string
+		fields string
+		want   string
+	}{
+		{name: "all", want: `{"page":1,"totalPages":2,"itemsOnPage":10,"address":"addr","balance":"100","totalReceived":"200","unconfirmedBalance":null,"unconfirmedTxs":0,"txs":0,"txids":["txid"]}`},
+		{name: "balance", fields: "balance", want: `{"balance":"100"}`},
+		{name: "paging", fields: "txids,page", want: `{"page":1,"txids":["txid"]}`},
+		{name: "omitted empty field", fields: "balance,totalSent", want: `{"balance":"100"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseAddressFields(tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := f.Project(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Project() = %v, want %v", string(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// GetAddressBalanceDelta returns the amounts received and sent by the address in the blocks fromHeight..toHeight and their difference,
+// toHeight 0 means up to the tip of the index
+// the amounts are summed from the address index and the stored inputs and outputs of the transactions, the transactions are not downloaded
+func (w *Worker) GetAddressBalanceDelta(address string, fromHeight, toHeight uint32) (*AddressBalanceDelta, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	if toHeight == 0 || toHeight > bestheight {
+		toHeight = bestheight
+	}
+	if fromHeight > toHeight {
+		return nil, NewAPIError("Invalid block range", true)
+	}
+	received, sent, txs, err := w.sumAddressTransfers(addrDesc, fromHeight, toHeight)
+	if err != nil {
+		return nil, err
+	}
+	var delta big.Int
+	delta.Sub(received, sent)
+	glog.Info("GetAddressBalanceDelta ", address, " ", fromHeight, "-", toHeight, ", ", txs, " txs, finished in ", time.Since(start))
+	return &AddressBalanceDelta{
+		Address:     address,
+		FromHeight:  fromHeight,
+		ToHeight:    toHeight,
+		ReceivedSat: (*Amount)(received),
+		SentSat:     (*Amount)(sent),
+		DeltaSat:    (*Amount)(&delta),
+		Txs:         txs,
+	}, nil
+}
+
+// GetAddressBalanceAtHeights returns the balances of the address after the blocks fromHeight and toHeight and their difference,
+// the heights must be ordered and not higher than the tip of the index; the balance at a height is computed
+// from the amounts received and sent by the address from the genesis block up to the height
+func (w *Worker) GetAddressBalanceAtHeights(address string, fromHeight, toHeight uint32) (*AddressBalanceAtHeights, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if fromHeight > toHeight {
+		return nil, NewAPIError("Invalid block range", true)
+	}
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	if toHeight > bestheight {
+		return nil, NewAPIError(fmt.Sprintf("Block height %d is higher than the best block %d", toHeight, bestheight), true)
+	}
+	// the balance at toHeight is the balance at fromHeight changed by the transfers in the blocks after fromHeight
+	received, sent, txs, err := w.sumAddressTransfers(addrDesc, 0, fromHeight)
+	if err != nil {
+		return nil, err
+	}
+	var fromBalance big.Int
+	fromBalance.Sub(received, sent)
+	var delta big.Int
+	if toHeight > fromHeight {
+		received, sent, t, err := w.sumAddressTransfers(addrDesc, fromHeight+1, toHeight)
+		if err != nil {
+			return nil, err
+		}
+		txs += t
+		delta.Sub(received, sent)
+	}
+	var toBalance big.Int
+	toBalance.Add(&fromBalance, &delta)
+	glog.Info("GetAddressBalanceAtHeights ", address, " ", fromHeight, ",", toHeight, ", ", txs, " txs, finished in ", time.Since(start))
+	return &AddressBalanceAtHeights{
+		Address:        address,
+		FromHeight:     fromHeight,
+		ToHeight:       toHeight,
+		FromBalanceSat: (*Amount)(&fromBalance),
+		ToBalanceSat:   (*Amount)(&toBalance),
+		DeltaSat:       (*Amount)(&delta),
+	}, nil
+}
+
+// sumAddressTransfers sums the amounts received and sent by the address in the blocks fromHeight..toHeight,
+// it returns also the number of the transactions of the address in the blocks
+func (w *Worker) sumAddressTransfers(addrDesc bchain.AddressDescriptor, fromHeight, toHeight uint32) (*big.Int, *big.Int, int, error) {
+	var received, sent big.Int
+	var txs int
+	err := w.db.GetAddrDescTransactions(addrDesc, fromHeight, toHeight, func(txid string, height uint32, indexes []int32) error {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return errors.Annotatef(err, "GetTxAddresses %v", txid)
+		}
+		if ta == nil {
+			glog.Warning("DB inconsistency:  tx ", txid, ": not found in txAddresses")
+			return nil
+		}
+		txs++
+		for _, index := range indexes {
+			if index < 0 {
+				index = ^index
+				if int(index) < len(ta.Inputs) {
+					sent.Add(&sent, &ta.Inputs[index].ValueSat)
+				}
+			} else if int(index) < len(ta.Outputs) {
+				received.Add(&received, &ta.Outputs[index].ValueSat)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, 0, errors.Annotatef(err, "GetAddrDescTransactions %v", addrDesc)
+	}
+	return &received, &sent, txs, nil
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultBalancesConcurrency is the default number of addresses of a bulk balances request resolved in parallel
+const DefaultBalancesConcurrency = 8
+
+// MaxBalancesAddresses is the maximum number of addresses in a bulk balances request
+const MaxBalancesAddresses = 1000
+
+// SetBalancesConcurrency sets the number of addresses of a bulk balances request resolved in parallel
+func (w *Worker) SetBalancesConcurrency(n int) {
+	w.balancesConcurrency = n
+}
+
+// GetAddressesBalances returns balances of the addresses in the order of the addresses, the unspent outputs with fewer
+// than minConfirmations confirmations are counted in the unconfirmed balance
+// at most balancesConcurrency addresses are resolved in parallel, the processing stops when ctx is cancelled
+func (w *Worker) GetAddressesBalances(ctx context.Context, addresses []string, minConfirmations int) ([]*Address, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, NewAPIError("Missing addresses", true)
+	}
+	if len(addresses) > MaxBalancesAddresses {
+		return nil, NewAPIError("Too many addresses", true)
+	}
+	concurrency := w.balancesConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBalancesConcurrency
+	}
+	r := make([]*Address, len(addresses))
+	filter := &AddressFilter{Vout: AddressFilterVoutOff, MinConfirmations: minConfirmations}
+	err := resolveConcurrently(ctx, concurrency, len(addresses), func(ctx context.Context, i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		a, err := w.GetAddress(addresses[i], 0, 0, AccountDetailsBasic, filter)
+		if err != nil {
+			return err
+		}
+		r[i] = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// resolveConcurrently calls resolve for indexes 0..count-1 using at most concurrency goroutines
+// it stops on the first error returned by resolve or on cancellation of ctx and returns the error,
+// resolve gets the context which is cancelled in both cases and should stop its work when it is done
+func resolveConcurrently(ctx context.Context, concurrency int, count int, resolve func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if concurrency > count {
+		concurrency = count
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var resolveErr error
+	for j := 0; j < concurrency; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := resolve(ctx, i); err != nil {
+					once.Do(func() {
+						resolveErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+	for i := 0; i < count && ctx.Err() == nil; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	if resolveErr != nil {
+		return resolveErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,94 @@
+// +build unittest
+
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_resolveConcurrently(t *testing.T) {
+	const count = 50
+	for _, concurrency := range []int{1, 3, 8, 100} {
+		var running, maxRunning int32
+		var mux sync.Mutex
+		done := make([]bool, count)
+		results := make([]int, count)
+		err := resolveConcurrently(context.Background(), concurrency, count, func(ctx context.Context, i int) error {
+			r := atomic.AddInt32(&running, 1)
+			mux.Lock()
+			if r > maxRunning {
+				maxRunning = r
+			}
+			mux.Unlock()
+			// finish the lookups in different order than they were started
+			time.Sleep(time.Duration((count-i)%7) * time.Millisecond)
+			results[i] = i * i
+			done[i] = true
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("concurrency %d: resolveConcurrently() error = %v", concurrency, err)
+		}
+		if int(maxRunning) > concurrency {
+			t.Errorf("concurrency %d: %d concurrent lookups", concurrency, maxRunning)
+		}
+		for i := range results {
+			if !done[i] || results[i] != i*i {
+				t.Errorf("concurrency %d: result %d = %d, want %d", concurrency, i, results[i], i*i)
+			}
+		}
+	}
+}
+
+func Test_resolveConcurrently_Error(t *testing.T) {
+	var calls int32
+	wantErr := errors.New("lookup failed")
+	err := resolveConcurrently(context.Background(), 2, 1000, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i == 5 {
+			return wantErr
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	if err != wantErr {
+		t.Errorf("resolveConcurrently() error = %v, want %v", err, wantErr)
+	}
+	if calls >= 1000 {
+		t.Errorf("resolveConcurrently() did not stop after error, %d calls", calls)
+	}
+}
+
+func Test_resolveConcurrently_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	var cancelled int32
+	err := resolveConcurrently(ctx, 4, 1000, func(ctx context.Context, i int) error {
+		if atomic.AddInt32(&calls, 1) == 10 {
+			// client disconnected
+			cancel()
+		}
+		time.Sleep(time.Millisecond)
+		if ctx.Err() != nil {
+			atomic.AddInt32(&cancelled, 1)
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("resolveConcurrently() error = %v, want %v", err, context.Canceled)
+	}
+	// the lookups in flight at the time of cancellation are finished, no new are started
+	if calls > 10+4 {
+		t.Errorf("resolveConcurrently() did not stop after cancellation, %d calls", calls)
+	}
+	// the lookups in flight see the cancellation in their context
+	if cancelled == 0 {
+		t.Errorf("resolveConcurrently() did not pass the cancelled context to resolve")
+	}
+}
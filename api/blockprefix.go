@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// minBlockHashPrefix is the minimal number of hex characters of a truncated block hash resolved from the index,
+// the hashes start with many zeros and shorter prefixes would match too many blocks
+const minBlockHashPrefix = 16
+
+// maxBlockHashPrefixBlocks is the number of the last blocks searched for a truncated block hash, the lookup scans the index
+// and is bounded so that it is cheap
+const maxBlockHashPrefixBlocks = 10000
+
+// findBlockHashByPrefix returns the hash of the only block among the last maxBlockHashPrefixBlocks indexed blocks
+// whose hash starts with the prefix, the prefix matching several blocks is rejected as ambiguous;
+// the older blocks are not searched and the error says so, they must be specified by the full hash or height
+func (w *Worker) findBlockHashByPrefix(prefix string) (string, error) {
+	if len(prefix) < minBlockHashPrefix {
+		return "", NewAPIError(fmt.Sprintf("Block hash prefix must have at least %d characters", minBlockHashPrefix), true)
+	}
+	prefix = strings.ToLower(prefix)
+	heights, err := w.db.FindBlocksByHashPrefix(prefix, maxBlockHashPrefixBlocks, 2)
+	if err != nil {
+		return "", errors.Annotatef(err, "FindBlocksByHashPrefix %v", prefix)
+	}
+	switch len(heights) {
+	case 0:
+		return "", NewAPIError(fmt.Sprintf("Block hash prefix '%v' not found in the last %d blocks, use the full block hash", prefix, maxBlockHashPrefixBlocks), true)
+	case 1:
+		hash, err := w.db.GetBlockHash(heights[0])
+		if err != nil {
+			return "", errors.Annotatef(err, "GetBlockHash %v", heights[0])
+		}
+		return hash, nil
+	default:
+		return "", NewAPIError(fmt.Sprintf("Block hash prefix '%v' is ambiguous", prefix), true)
+	}
+}
+
+// isHex returns true if the string consists only of hex digits
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return len(s) > 0
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/blockchain"
+)
+
+// chainWorkWarningBlocks is the number of blocks of work, by which the chain work of the indexed tip
+// can differ from the chain work of the backend without a warning
+const chainWorkWarningBlocks = 6
+
+func parseChainWork(s string) (*big.Int, error) {
+	w, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		return nil, errors.Errorf("Invalid chainwork '%v'", s)
+	}
+	return w, nil
+}
+
+// compareChainWork returns a warning if the chain work of the indexed tip differs from the chain work of the backend
+// by more than chainWorkWarningBlocks times the work of the indexed tip block given by its bits, otherwise empty string
+func compareChainWork(backendChainWork, tipChainWork, tipBits string) (string, error) {
+	backend, err := parseChainWork(backendChainWork)
+	if err != nil {
+		return "", err
+	}
+	tip, err := parseChainWork(tipChainWork)
+	if err != nil {
+		return "", err
+	}
+	bits, err := strconv.ParseUint(tipBits, 16, 32)
+	if err != nil {
+		return "", errors.Errorf("Invalid bits '%v'", tipBits)
+	}
+	threshold := blockchain.CalcWork(uint32(bits))
+	threshold.Mul(threshold, big.NewInt(chainWorkWarningBlocks))
+	var diff big.Int
+	diff.Sub(backend, tip)
+	if diff.CmpAbs(threshold) <= 0 {
+		return "", nil
+	}
+	if diff.Sign() > 0 {
+		return fmt.Sprintf("Chain work of the indexed tip %x is lower than chain work %x of the backend", tip, backend), nil
+	}
+	return fmt.Sprintf("Chain work of the indexed tip %x is higher than chain work %x of the backend", tip, backend), nil
+}
+
+// getChainWorkWarning compares the chain work of the indexed tip with the chain work reported by the backend,
+// returns empty string if the chain works match or the backend does not report chain work
+func (w *Worker) getChainWorkWarning(ci *bchain.ChainInfo) string {
+	if ci.Chainwork == "" {
+		return ""
+	}
+	_, hash, err := w.db.GetBestBlock()
+	if err != nil || hash == "" {
+		return ""
+	}
+	bi, err := w.chain.GetBlockInfo(hash)
+	if err != nil {
+		glog.Error("GetBlockInfo ", hash, ": ", err)
+		return ""
+	}
+	if bi.Chainwork == "" {
+		return ""
+	}
+	warning, err := compareChainWork(ci.Chainwork, bi.Chainwork, bi.Bits)
+	if err != nil {
+		glog.Error("compareChainWork: ", err)
+		return ""
+	}
+	return warning
+}
@@ -0,0 +1,72 @@
+// +build unittest
+
+package api
+
+import (
+	"testing"
+)
+
+func Test_compareChainWork(t *testing.T) {
+	// the work of the block with bits 1d00ffff is 0x100010001
+	tests := []struct {
+		name        string
+		backend     string
+		tip         string
+		bits        string
+		wantWarning string
+		wantErr     bool
+	}{
+		{
+			name:    "matching",
+			backend: "00000000000000000000000000000000000000000000000000000a0a0a0a0a0a",
+			tip:     "00000000000000000000000000000000000000000000000000000a0a0a0a0a0a",
+			bits:    "1d00ffff",
+		},
+		{
+			name:    "backend ahead within threshold",
+			backend: "00000000000000000000000000000000000000000000000000000a0f0a0f0a0f",
+			tip:     "00000000000000000000000000000000000000000000000000000a0a0a0a0a0a",
+			bits:    "1d00ffff",
+		},
+		{
+			name:        "backend ahead beyond threshold",
+			backend:     "00000000000000000000000000000000000000000000000000000a110a110a11",
+			tip:         "00000000000000000000000000000000000000000000000000000a0a0a0a0a0a",
+			bits:        "1d00ffff",
+			wantWarning: "Chain work of the indexed tip a0a0a0a0a0a is lower than chain work a110a110a11 of the backend",
+		},
+		{
+			name:        "indexed tip ahead beyond threshold",
+			backend:     "00000000000000000000000000000000000000000000000000000a0a0a0a0a0a",
+			tip:         "00000000000000000000000000000000000000000000000000000a110a110a11",
+			bits:        "1d00ffff",
+			wantWarning: "Chain work of the indexed tip a110a110a11 is higher than chain work a0a0a0a0a0a of the backend",
+		},
+		{
+			name:    "invalid chainwork",
+			backend: "xyz",
+			tip:     "00000000000000000000000000000000000000000000000000000a0a0a0a0a0a",
+			bits:    "1d00ffff",
+			wantErr: true,
+		},
+		{
+			name:    "invalid bits",
+			backend: "00000000000000000000000000000000000000000000000000000a0a0a0a0a0a",
+			tip:     "00000000000000000000000000000000000000000000000000000a0a0a0a0a0a",
+			bits:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := compareChainWork(tt.backend, tt.tip, tt.bits)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compareChainWork() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.wantWarning {
+				t.Errorf("compareChainWork() = %v, want %v", got, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// DefaultRequiredConfirmations is the number of confirmations suggested if the value of an output cannot be converted to USD
+const DefaultRequiredConfirmations = 6
+
+// FiatRateProvider returns the current rate of the coin in USD, it is implemented by a rates module
+type FiatRateProvider interface {
+	GetUSDRate() (float64, error)
+}
+
+// ConfirmationStep is the number of confirmations required for the outputs with the value of at least MinValue USD
+type ConfirmationStep struct {
+	MinValue      float64
+	Confirmations uint32
+}
+
+// ConfirmationPolicy maps the USD value of an output to the number of confirmations after which it should be credited,
+// the steps are ordered by MinValue, Default is used if the USD value is not known
+type ConfirmationPolicy struct {
+	Steps   []ConfirmationStep
+	Default uint32
+}
+
+// ParseConfirmationPolicy parses the risk curve in the format "<min USD value>:<confirmations>,...", for example
+// "0:1,1000:3,10000:6"; the outputs with the value below the lowest step require the confirmations of the lowest step
+func ParseConfirmationPolicy(curve string, defaultConfirmations uint32) (*ConfirmationPolicy, error) {
+	p := &ConfirmationPolicy{Default: defaultConfirmations}
+	if curve == "" {
+		return p, nil
+	}
+	for _, s := range strings.Split(curve, ",") {
+		kv := strings.Split(s, ":")
+		if len(kv) != 2 {
+			return nil, errors.Errorf("Invalid confirmation policy step '%s'", s)
+		}
+		v, err := strconv.ParseFloat(kv[0], 64)
+		if err != nil || v < 0 {
+			return nil, errors.Errorf("Invalid value of confirmation policy step '%s'", s)
+		}
+		c, err := strconv.ParseUint(kv[1], 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("Invalid confirmations of confirmation policy step '%s'", s)
+		}
+		p.Steps = append(p.Steps, ConfirmationStep{MinValue: v, Confirmations: uint32(c)})
+	}
+	sort.SliceStable(p.Steps, func(i, j int) bool { return p.Steps[i].MinValue < p.Steps[j].MinValue })
+	return p, nil
+}
+
+// confirmations returns the confirmations of the highest step not exceeding the USD value
+func (p *ConfirmationPolicy) confirmations(usdValue float64) uint32 {
+	if len(p.Steps) == 0 {
+		return p.Default
+	}
+	c := p.Steps[0].Confirmations
+	for i := range p.Steps {
+		if p.Steps[i].MinValue > usdValue {
+			break
+		}
+		c = p.Steps[i].Confirmations
+	}
+	return c
+}
+
+// SetConfirmationPolicy sets the policy of the confirmations required for the value of an output
+func (w *Worker) SetConfirmationPolicy(p *ConfirmationPolicy) {
+	w.confirmationPolicy = p
+}
+
+// SetFiatRateProvider sets the source of the USD rate of the coin, without it the default confirmations are suggested
+func (w *Worker) SetFiatRateProvider(p FiatRateProvider) {
+	w.fiatRates = p
+}
+
+// GetRequiredConfirmations suggests the number of confirmations after which an output with the value in satoshis
+// should be credited, the value is converted to USD by the current rate and looked up in the confirmation policy;
+// the default confirmations of the policy are returned if the rate is not available
+func (w *Worker) GetRequiredConfirmations(value string) (*RequiredConfirmations, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	v, ok := new(big.Int).SetString(value, 10)
+	if !ok || v.Sign() < 0 {
+		return nil, NewAPIError(fmt.Sprintf("Invalid value '%s'", value), true)
+	}
+	p := w.confirmationPolicy
+	if p == nil {
+		p = &ConfirmationPolicy{Default: DefaultRequiredConfirmations}
+	}
+	return requiredConfirmations(p, w.fiatRates, v, w.chainParser.AmountDecimals()), nil
+}
+
+// requiredConfirmations returns the confirmations for the value with given number of decimals, rates can be nil
+func requiredConfirmations(p *ConfirmationPolicy, rates FiatRateProvider, value *big.Int, decimals int) *RequiredConfirmations {
+	r := &RequiredConfirmations{Value: (*Amount)(value), Confirmations: p.Default}
+	if rates == nil {
+		r.DefaultUsed = true
+		return r
+	}
+	rate, err := rates.GetUSDRate()
+	if err != nil || rate <= 0 {
+		glog.Warning("GetUSDRate ", rate, ": ", err)
+		r.DefaultUsed = true
+		return r
+	}
+	coins := new(big.Float).Quo(new(big.Float).SetInt(value), new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+	usd, _ := new(big.Float).Mul(coins, big.NewFloat(rate)).Float64()
+	r.Rate = &rate
+	r.USDValue = &usd
+	r.Confirmations = p.confirmations(usd)
+	return r
+}
@@ -0,0 +1,81 @@
+// +build unittest
+
+package api
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+type testRates struct {
+	rate float64
+	err  error
+}
+
+func (r *testRates) GetUSDRate() (float64, error) {
+	return r.rate, r.err
+}
+
+func TestParseConfirmationPolicy(t *testing.T) {
+	p, err := ParseConfirmationPolicy("10000:6,0:1,1000:3", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ConfirmationStep{{0, 1}, {1000, 3}, {10000, 6}}
+	if p.Default != 4 || len(p.Steps) != len(want) {
+		t.Fatalf("ParseConfirmationPolicy() = %+v", p)
+	}
+	for i := range want {
+		if p.Steps[i] != want[i] {
+			t.Errorf("ParseConfirmationPolicy() step %d = %+v, want %+v", i, p.Steps[i], want[i])
+		}
+	}
+	for _, curve := range []string{"1000", "x:1", "1000:-1", "-1:2", "0:1,"} {
+		if _, err := ParseConfirmationPolicy(curve, 4); err == nil {
+			t.Errorf("ParseConfirmationPolicy(%q) returned no error", curve)
+		}
+	}
+}
+
+func Test_requiredConfirmations(t *testing.T) {
+	p, err := ParseConfirmationPolicy("100:1,1000:3,10000:6", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		rates       FiatRateProvider
+		value       int64
+		want        uint32
+		wantUSD     float64
+		wantDefault bool
+	}{
+		// 2 USD per coin
+		{name: "below lowest step", rates: &testRates{rate: 2}, value: 1000000000, want: 1, wantUSD: 20},
+		{name: "lowest step", rates: &testRates{rate: 2}, value: 5000000000, want: 1, wantUSD: 100},
+		{name: "middle step", rates: &testRates{rate: 2}, value: 99999999999, want: 3, wantUSD: 1999.99999998},
+		{name: "highest step", rates: &testRates{rate: 2}, value: 500000000000, want: 6, wantUSD: 10000},
+		{name: "rates not configured", value: 500000000000, want: 4, wantDefault: true},
+		{name: "rate not available", rates: &testRates{err: errors.New("rates not available")}, value: 500000000000, want: 4, wantDefault: true},
+		{name: "invalid rate", rates: &testRates{rate: 0}, value: 500000000000, want: 4, wantDefault: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := requiredConfirmations(p, tt.rates, big.NewInt(tt.value), 8)
+			if got.Confirmations != tt.want || got.DefaultUsed != tt.wantDefault {
+				t.Errorf("requiredConfirmations() = %+v, want %v confirmations, default %v", got, tt.want, tt.wantDefault)
+			}
+			if tt.wantDefault {
+				if got.USDValue != nil || got.Rate != nil {
+					t.Errorf("requiredConfirmations() usdValue %v, rate %v, want nil", got.USDValue, got.Rate)
+				}
+			} else if got.USDValue == nil || *got.USDValue != tt.wantUSD {
+				t.Errorf("requiredConfirmations() usdValue = %v, want %v", got.USDValue, tt.wantUSD)
+			}
+			if (*big.Int)(got.Value).Int64() != tt.value {
+				t.Errorf("requiredConfirmations() value = %v, want %v", got.Value, tt.value)
+			}
+		})
+	}
+}
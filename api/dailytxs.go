@@ -0,0 +1,94 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const (
+	dailyTxsDateLayout = "2006-01-02"
+	secondsPerDay      = 24 * 60 * 60
+	// defaultDailyTxsDays is the number of days returned if the start of the range is not specified
+	defaultDailyTxsDays = 30
+	// maxDailyTxsDays is the maximal number of days returned in one request
+	maxDailyTxsDays = 1000
+)
+
+func parseDailyTxsDate(s string) (uint32, error) {
+	t, err := time.ParseInLocation(dailyTxsDateLayout, s, time.UTC)
+	if err != nil || t.Unix() < 0 {
+		return 0, NewAPIError(fmt.Sprintf("Invalid date '%s', expected format YYYY-MM-DD", s), true)
+	}
+	return uint32(t.Unix() / secondsPerDay), nil
+}
+
+func formatDailyTxsDate(day uint32) string {
+	return time.Unix(int64(day)*secondsPerDay, 0).UTC().Format(dailyTxsDateLayout)
+}
+
+// GetDailyTxs returns the number of transactions per UTC day in the days from..to (both inclusive, in format YYYY-MM-DD),
+// the day of a transaction is given by the time of its block, the days without any transaction are returned with zero count
+// empty to means the day of the last indexed block, empty from means defaultDailyTxsDays days before to
+func (w *Worker) GetDailyTxs(from, to string) (*DailyTxsSeries, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	var fromDay, toDay uint32
+	var err error
+	if to != "" {
+		if toDay, err = parseDailyTxsDate(to); err != nil {
+			return nil, err
+		}
+	} else {
+		bestheight, _, err := w.db.GetBestBlock()
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBestBlock")
+		}
+		bi, err := w.db.GetBlockInfo(bestheight)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBlockInfo %v", bestheight)
+		}
+		if bi != nil {
+			toDay = uint32(bi.Time / secondsPerDay)
+		}
+	}
+	if from != "" {
+		if fromDay, err = parseDailyTxsDate(from); err != nil {
+			return nil, err
+		}
+	} else if toDay >= defaultDailyTxsDays-1 {
+		fromDay = toDay - (defaultDailyTxsDays - 1)
+	}
+	if fromDay > toDay {
+		return nil, NewAPIError("Invalid date range", true)
+	}
+	if toDay-fromDay >= maxDailyTxsDays {
+		return nil, NewAPIError(fmt.Sprintf("Date range exceeds %d days", maxDailyTxsDays), true)
+	}
+	if w.is == nil || !w.is.DailyTxsIndex {
+		return nil, NewAPIError("Daily transactions index disabled", true)
+	}
+	dt, err := w.db.GetDailyTxs(fromDay, toDay)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetDailyTxs %v-%v", fromDay, toDay)
+	}
+	r := &DailyTxsSeries{
+		From:        formatDailyTxsDate(fromDay),
+		To:          formatDailyTxsDate(toDay),
+		IndexedFrom: w.is.DailyTxsIndexFrom,
+		Days:        make([]DailyTxs, 0, toDay-fromDay+1),
+	}
+	j := 0
+	for day := fromDay; day <= toDay; day++ {
+		var txs uint64
+		if j < len(dt) && dt[j].Day == day {
+			txs = dt[j].Txs
+			j++
+		}
+		r.Days = append(r.Days, DailyTxs{Date: formatDailyTxsDate(day), Txs: txs})
+	}
+	return r, nil
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// output descriptor checksum as defined in Bitcoin Core, see doc/descriptors.md
+const (
+	descriptorInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	descriptorChecksumLen     = 8
+)
+
+var descriptorGenerator = [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+
+func descriptorPolymod(c uint64, val int) uint64 {
+	c0 := c >> 35
+	c = ((c & 0x7ffffffff) << 5) ^ uint64(val)
+	for i := uint(0); i < 5; i++ {
+		if (c0>>i)&1 != 0 {
+			c ^= descriptorGenerator[i]
+		}
+	}
+	return c
+}
+
+// descriptorChecksum computes the 8 character checksum of the output descriptor without the # suffix
+func descriptorChecksum(desc string) (string, error) {
+	c := uint64(1)
+	cls, clsCount := 0, 0
+	for i := 0; i < len(desc); i++ {
+		pos := strings.IndexByte(descriptorInputCharset, desc[i])
+		if pos < 0 {
+			return "", fmt.Errorf("invalid character '%c' at position %d", desc[i], i)
+		}
+		c = descriptorPolymod(c, pos&31)
+		cls = cls*3 + (pos >> 5)
+		clsCount++
+		if clsCount == 3 {
+			c = descriptorPolymod(c, cls)
+			cls, clsCount = 0, 0
+		}
+	}
+	if clsCount > 0 {
+		c = descriptorPolymod(c, cls)
+	}
+	for i := 0; i < descriptorChecksumLen; i++ {
+		c = descriptorPolymod(c, 0)
+	}
+	c ^= 1
+	checksum := make([]byte, descriptorChecksumLen)
+	for i := range checksum {
+		checksum[i] = descriptorChecksumCharset[(c>>(5*uint(7-i)))&31]
+	}
+	return string(checksum), nil
+}
+
+// validateDescriptorChecksum checks the #checksum suffix of the output descriptor and returns the descriptor without it
+func validateDescriptorChecksum(s string) (string, error) {
+	i := strings.LastIndexByte(s, '#')
+	if i < 0 {
+		return "", NewAPIError("Missing descriptor checksum", true)
+	}
+	desc, checksum := s[:i], s[i+1:]
+	computed, err := descriptorChecksum(desc)
+	if err != nil {
+		return "", NewAPIError(fmt.Sprintf("Invalid descriptor, %v", err), true)
+	}
+	if checksum != computed {
+		return "", NewAPIError(fmt.Sprintf("Descriptor checksum '%v' does not match computed checksum '%v'", checksum, computed), true)
+	}
+	return desc, nil
+}
+
+// xpubFromDescriptor returns the extended public key from the output descriptor, for example
+// pkh([d34db33f/44'/0'/0']xpub.../0/*)#checksum, after validation of the descriptor checksum
+// strings which are not descriptors are returned unchanged
+func xpubFromDescriptor(s string) (string, error) {
+	if !strings.ContainsAny(s, "()") {
+		return s, nil
+	}
+	desc, err := validateDescriptorChecksum(s)
+	if err != nil {
+		return "", err
+	}
+	// the key expression of nested script expressions like sh(wpkh(KEY))
+	i := strings.LastIndexByte(desc, '(')
+	j := strings.IndexByte(desc, ')')
+	if i < 0 || j < i {
+		return "", NewAPIError("Invalid descriptor", true)
+	}
+	key := desc[i+1 : j]
+	// skip the key origin
+	if strings.HasPrefix(key, "[") {
+		k := strings.IndexByte(key, ']')
+		if k < 0 {
+			return "", NewAPIError("Invalid descriptor key origin", true)
+		}
+		key = key[k+1:]
+	}
+	// the derivation is done by the scanning, ignore the derivation path
+	if k := strings.IndexByte(key, '/'); k >= 0 {
+		key = key[:k]
+	}
+	return key, nil
+}
@@ -0,0 +1,94 @@
+// +build unittest
+
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+const testXpub = "upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q"
+
+func Test_descriptorChecksum(t *testing.T) {
+	tests := []struct {
+		desc    string
+		want    string
+		wantErr bool
+	}{
+		{desc: "raw(deadbeef)", want: "89f8spxm"},
+		{desc: "pkh(" + testXpub + ")", want: "dusn37us"},
+		{desc: "sh(wpkh([5c9e228d/49'/1'/33']" + testXpub + "/0/*))", want: "205um5ds"},
+		{desc: "raw(deadbeef)é", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := descriptorChecksum(tt.desc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("descriptorChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("descriptorChecksum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_xpubFromDescriptor(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "plain xpub",
+			s:    testXpub,
+			want: testXpub,
+		},
+		{
+			name: "valid checksum",
+			s:    "pkh(" + testXpub + ")#dusn37us",
+			want: testXpub,
+		},
+		{
+			name: "valid checksum with key origin and path",
+			s:    "sh(wpkh([5c9e228d/49'/1'/33']" + testXpub + "/0/*))#205um5ds",
+			want: testXpub,
+		},
+		{
+			name:    "missing checksum",
+			s:       "pkh(" + testXpub + ")",
+			wantErr: "Missing descriptor checksum",
+		},
+		{
+			name:    "wrong checksum",
+			s:       "pkh(" + testXpub + ")#dusn37uz",
+			wantErr: "Descriptor checksum 'dusn37uz' does not match computed checksum 'dusn37us'",
+		},
+		{
+			name:    "typo in the key",
+			s:       "pkh(" + testXpub[:20] + "X" + testXpub[21:] + ")#dusn37us",
+			wantErr: "Descriptor checksum 'dusn37us' does not match computed checksum",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := xpubFromDescriptor(tt.s)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("xpubFromDescriptor() error = %v, want %v", err, tt.wantErr)
+				}
+				if apiErr, ok := err.(*APIError); !ok || !apiErr.Public {
+					t.Errorf("xpubFromDescriptor() error %v is not public APIError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("xpubFromDescriptor() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("xpubFromDescriptor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"encoding/json"
+	"math/big"
+	"sort"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// DefaultFeeStatsBlocks is the default number of the last blocks from which the fee rate percentiles are computed
+const DefaultFeeStatsBlocks = 6
+
+// MaxFeeStatsBlocks is the maximum number of the last blocks from which the fee rate percentiles are computed,
+// it is also the number of the blocks whose fee rates are cached
+const MaxFeeStatsBlocks = 24
+
+// feeStatsPercentiles are the returned percentiles of the fee rates
+var feeStatsPercentiles = []int{10, 25, 50, 75, 90}
+
+// txFeeRate is the fee rate in satoshis per kilobyte and the size of a confirmed transaction
+type txFeeRate struct {
+	feeRate int64
+	size    int
+}
+
+// blockFeeRates are the fee rates of the transactions of the block with the hash
+type blockFeeRates struct {
+	hash  string
+	rates []txFeeRate
+}
+
+// feeStatsCache keeps the fee rates of the last blocks by height, the entries are checked against the hash
+// of the block in the index so that the blocks replaced by a reorg are downloaded again
+type feeStatsCache map[uint32]blockFeeRates
+
+// SetFeeStatsBlocks sets the default number of the last blocks from which the fee rate percentiles are computed
+func (w *Worker) SetFeeStatsBlocks(n int) {
+	w.feeStatsBlocks = n
+}
+
+// GetFeeRatePercentiles returns the percentiles of the fee rates of the transactions confirmed in the last blocks,
+// the percentiles are weighted by the size of the transactions, the coinbase transactions are not counted
+// if blocks is not positive, the number of blocks set by SetFeeStatsBlocks is used
+func (w *Worker) GetFeeRatePercentiles(blocks int) (*FeeRatePercentiles, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if blocks <= 0 {
+		blocks = w.feeStatsBlocks
+		if blocks <= 0 {
+			blocks = DefaultFeeStatsBlocks
+		}
+	}
+	if blocks > MaxFeeStatsBlocks {
+		return nil, NewAPIError("Too many blocks", true)
+	}
+	samples, fromHeight, toHeight, err := w.getFeeRateSamples(blocks)
+	if err != nil {
+		return nil, err
+	}
+	r := &FeeRatePercentiles{FromHeight: fromHeight, ToHeight: toHeight, Txs: len(samples)}
+	rates := computeFeeRatePercentiles(samples, feeStatsPercentiles)
+	r.Percentiles = make([]FeeRatePercentile, len(rates))
+	for i := range rates {
+		r.Percentiles[i] = FeeRatePercentile{Percentile: feeStatsPercentiles[i], FeeRate: (*Amount)(big.NewInt(rates[i]))}
+	}
+	return r, nil
+}
+
+// GetMedianFeeRate returns the median fee rate of the transactions of the last block, the median is weighted by the size
+// of the transactions and the coinbase transaction is not counted; the minimum relay fee of the backend is returned
+// if there is no other transaction in the block; the fee rates of the block are shared with GetFeeRatePercentiles
+// in the cache of the last blocks, the block is downloaded only once
+func (w *Worker) GetMedianFeeRate() (*MedianFeeRate, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	bestHeight, bestHash, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	samples, found, err := w.getBlockFeeRates(bestHeight)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Errorf("Block %v not found", bestHeight)
+	}
+	feeRate, minRelayFee, err := medianFeeRate(samples, w.getMinRelayFeeRate)
+	if err != nil {
+		return nil, err
+	}
+	return &MedianFeeRate{
+		Height:      bestHeight,
+		Hash:        bestHash,
+		Txs:         len(samples),
+		FeeRate:     (*Amount)(feeRate),
+		MinRelayFee: minRelayFee,
+	}, nil
+}
+
+// medianFeeRate returns the weighted median of the fee rates of the transactions of a block, for a block without
+// other transactions than the coinbase the minimum relay fee returned by minRelayFee is returned together with true
+func medianFeeRate(samples []txFeeRate, minRelayFee func() (*big.Int, error)) (*big.Int, bool, error) {
+	if len(samples) == 0 {
+		feeRate, err := minRelayFee()
+		if err != nil {
+			return nil, false, err
+		}
+		return feeRate, true, nil
+	}
+	return big.NewInt(computeFeeRatePercentiles(samples, []int{50})[0]), false, nil
+}
+
+// getMinRelayFeeRate returns the minimum relay fee of the backend in satoshis per kilobyte
+func (w *Worker) getMinRelayFeeRate() (*big.Int, error) {
+	ci, err := w.chain.GetChainInfo()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetChainInfo")
+	}
+	return relayFeeRate(w.chainParser, ci)
+}
+
+// relayFeeRate converts the minimum relay fee of the chain info in coins per kilobyte to satoshis per kilobyte
+func relayFeeRate(parser bchain.BlockChainParser, ci *bchain.ChainInfo) (*big.Int, error) {
+	if ci.RelayFee == "" {
+		return nil, errors.New("Minimum relay fee not available")
+	}
+	feeRate, err := parser.AmountToBigInt(json.Number(ci.RelayFee))
+	if err != nil {
+		return nil, errors.Annotatef(err, "RelayFee %v", ci.RelayFee)
+	}
+	return &feeRate, nil
+}
+
+// getFeeRateSamples returns the fee rates of the transactions confirmed in the last blocks and the range of the blocks
+func (w *Worker) getFeeRateSamples(blocks int) ([]txFeeRate, uint32, uint32, error) {
+	bestHeight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, 0, 0, errors.Annotatef(err, "GetBestBlock")
+	}
+	samples := []txFeeRate{}
+	var fromHeight uint32
+	for i := 0; i < blocks && uint32(i) <= bestHeight; i++ {
+		height := bestHeight - uint32(i)
+		s, found, err := w.getBlockFeeRates(height)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		// the index does not contain older blocks
+		if !found {
+			break
+		}
+		samples = append(samples, s...)
+		fromHeight = height
+	}
+	return samples, fromHeight, bestHeight, nil
+}
+
+// getBlockFeeRates returns the fee rates of the non coinbase transactions of the block at given height, false if the block is not indexed;
+// the fee rates of the last MaxFeeStatsBlocks blocks are cached, the block is downloaded without holding the lock of the cache
+func (w *Worker) getBlockFeeRates(height uint32) ([]txFeeRate, bool, error) {
+	hash, err := w.db.GetBlockHash(height)
+	if err != nil {
+		return nil, false, errors.Annotatef(err, "GetBlockHash %v", height)
+	}
+	if hash == "" {
+		return nil, false, nil
+	}
+	w.feeStatsMux.Lock()
+	c, found := w.feeStatsCache[height]
+	w.feeStatsMux.Unlock()
+	if found && c.hash == hash {
+		return c.rates, true, nil
+	}
+	rates, err := w.downloadBlockFeeRates(hash, height)
+	if err != nil {
+		return nil, false, err
+	}
+	w.feeStatsMux.Lock()
+	defer w.feeStatsMux.Unlock()
+	if w.feeStatsCache == nil {
+		w.feeStatsCache = make(feeStatsCache)
+	}
+	w.feeStatsCache[height] = blockFeeRates{hash: hash, rates: rates}
+	// keep only the last blocks
+	for h := range w.feeStatsCache {
+		if h+MaxFeeStatsBlocks <= height {
+			delete(w.feeStatsCache, h)
+		}
+	}
+	if len(w.feeStatsCache) > MaxFeeStatsBlocks {
+		var lowest uint32
+		first := true
+		for h := range w.feeStatsCache {
+			if first || h < lowest {
+				lowest, first = h, false
+			}
+		}
+		delete(w.feeStatsCache, lowest)
+	}
+	return rates, true, nil
+}
+
+// downloadBlockFeeRates downloads the block and computes the fee rates of its non coinbase transactions
+func (w *Worker) downloadBlockFeeRates(hash string, height uint32) ([]txFeeRate, error) {
+	block, err := w.chain.GetBlock(hash, height)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlock %v", height)
+	}
+	rates := []txFeeRate{}
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		if len(tx.Vin) > 0 && tx.Vin[0].Coinbase != "" {
+			continue
+		}
+		ta, err := w.db.GetTxAddresses(tx.Txid)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetTxAddresses %v", tx.Txid)
+		}
+		if ta == nil {
+			glog.Warning("DB inconsistency:  tx ", tx.Txid, ": not found in txAddresses")
+			continue
+		}
+		var fee big.Int
+		for j := range ta.Inputs {
+			fee.Add(&fee, &ta.Inputs[j].ValueSat)
+		}
+		for j := range ta.Outputs {
+			fee.Sub(&fee, &ta.Outputs[j].ValueSat)
+		}
+		size := txSize(tx)
+		if fee.Sign() < 0 || size == 0 {
+			continue
+		}
+		rates = append(rates, txFeeRate{feeRate: fee.Int64() * 1000 / int64(size), size: size})
+	}
+	return rates, nil
+}
+
+// txSize returns the size of the serialized transaction, computed from its scripts if the hex of the transaction is not available
+func txSize(tx *bchain.Tx) int {
+	if tx.Hex != "" {
+		return len(tx.Hex) / 2
+	}
+	scriptSize := func(h string) int {
+		l := len(h) / 2
+		return varIntSize(l) + l
+	}
+	// version and lock time
+	size := 4 + 4 + varIntSize(len(tx.Vin)) + varIntSize(len(tx.Vout))
+	for i := range tx.Vin {
+		// outpoint, script and sequence
+		size += 36 + scriptSize(tx.Vin[i].ScriptSig.Hex) + 4
+	}
+	for i := range tx.Vout {
+		// value and script
+		size += 8 + scriptSize(tx.Vout[i].ScriptPubKey.Hex)
+	}
+	return size
+}
+
+func varIntSize(n int) int {
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	case n <= 0xffffffff:
+		return 5
+	}
+	return 9
+}
+
+// computeFeeRatePercentiles returns for each percentile the lowest fee rate not exceeded by the percentile of the total size
+// of the transactions, zero fee rates are returned if there are no transactions
+func computeFeeRatePercentiles(samples []txFeeRate, percentiles []int) []int64 {
+	r := make([]int64, len(percentiles))
+	if len(samples) == 0 {
+		return r
+	}
+	sorted := append([]txFeeRate(nil), samples...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].feeRate < sorted[j].feeRate })
+	var total int64
+	for i := range sorted {
+		total += int64(sorted[i].size)
+	}
+	var cumulative int64
+	j := 0
+	for i := range percentiles {
+		threshold := total * int64(percentiles[i]) / 100
+		for j < len(sorted)-1 && cumulative+int64(sorted[j].size) < threshold {
+			cumulative += int64(sorted[j].size)
+			j++
+		}
+		r[i] = sorted[j].feeRate
+	}
+	return r
+}
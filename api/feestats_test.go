@@ -0,0 +1,156 @@
+// +build unittest
+
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/bch"
+	"blockbook/bchain/coins/btc"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func Test_computeFeeRatePercentiles(t *testing.T) {
+	percentiles := []int{10, 25, 50, 75, 90}
+	tests := []struct {
+		name    string
+		samples []txFeeRate
+		want    []int64
+	}{
+		{
+			name: "no transactions",
+			want: []int64{0, 0, 0, 0, 0},
+		},
+		{
+			name:    "one transaction",
+			samples: []txFeeRate{{feeRate: 1500, size: 250}},
+			want:    []int64{1500, 1500, 1500, 1500, 1500},
+		},
+		{
+			name: "equal sizes",
+			samples: []txFeeRate{
+				{feeRate: 10000, size: 100}, {feeRate: 1000, size: 100}, {feeRate: 9000, size: 100}, {feeRate: 2000, size: 100},
+				{feeRate: 8000, size: 100}, {feeRate: 3000, size: 100}, {feeRate: 7000, size: 100}, {feeRate: 4000, size: 100},
+				{feeRate: 6000, size: 100}, {feeRate: 5000, size: 100},
+			},
+			want: []int64{1000, 3000, 5000, 8000, 9000},
+		},
+		{
+			name: "weighted by size",
+			samples: []txFeeRate{
+				// samples of several blocks, the large transaction with the low fee rate dominates the lower percentiles
+				{feeRate: 1000, size: 6000}, {feeRate: 20000, size: 200}, {feeRate: 5000, size: 2000},
+				{feeRate: 2000, size: 1000}, {feeRate: 50000, size: 800},
+			},
+			want: []int64{1000, 1000, 1000, 5000, 5000},
+		},
+		{
+			name: "high percentiles in small transactions",
+			samples: []txFeeRate{
+				{feeRate: 1000, size: 800}, {feeRate: 90000, size: 50}, {feeRate: 100000, size: 150},
+			},
+			want: []int64{1000, 1000, 1000, 1000, 100000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeFeeRatePercentiles(tt.samples, percentiles)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("computeFeeRatePercentiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_medianFeeRate(t *testing.T) {
+	calls := 0
+	minRelayFee := func() (*big.Int, error) {
+		calls++
+		return big.NewInt(1000), nil
+	}
+	// the first transaction of the block is the coinbase, its fee rate is not among the samples
+	samples := []txFeeRate{{feeRate: 5000, size: 200}, {feeRate: 1500, size: 250}, {feeRate: 20000, size: 100}}
+	got, minRelay, err := medianFeeRate(samples, minRelayFee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Int64() != 5000 || minRelay || calls != 0 {
+		t.Errorf("medianFeeRate() = %v, %v, min relay fee called %d times", got, minRelay, calls)
+	}
+	// coinbase only block
+	got, minRelay, err = medianFeeRate([]txFeeRate{}, minRelayFee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Int64() != 1000 || !minRelay || calls != 1 {
+		t.Errorf("medianFeeRate() coinbase only = %v, %v, min relay fee called %d times", got, minRelay, calls)
+	}
+	if _, _, err = medianFeeRate(nil, func() (*big.Int, error) { return nil, errors.New("backend not available") }); err == nil {
+		t.Error("medianFeeRate() did not return the error of the min relay fee")
+	}
+}
+
+func Test_relayFeeRate(t *testing.T) {
+	parser, err := bch.NewBCashParser(bch.GetChainParams("main"), &btc.Configuration{AddressFormat: "cashaddr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := relayFeeRate(parser, &bchain.ChainInfo{RelayFee: "0.00001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Int64() != 1000 {
+		t.Errorf("relayFeeRate() = %v, want 1000", got)
+	}
+	if _, err = relayFeeRate(parser, &bchain.ChainInfo{}); err == nil {
+		t.Error("relayFeeRate() did not return error without the relay fee")
+	}
+}
+
+func Test_txSize(t *testing.T) {
+	tx := bchain.Tx{
+		Vin:  []bchain.Vin{{ScriptSig: bchain.ScriptSig{Hex: "4730440220037f4ed5427cde81d55b9b6a2fd08c8a25090c2c2fff3a75c1a57625ca8a7118022076c702fe55969fa08137f71afd4851c48e31082dd3c40c919c92cdbc826758d30121029f6da5623c9f9b68a9baf9c1bc7511df88fa34c6c2f71f7c62f2f03ff48dca80"}}},
+		Vout: []bchain.Vout{{ScriptPubKey: bchain.ScriptPubKey{Hex: "a9146144d57c8aff48492c9dfb914e120b20bad72d6f87"}}},
+	}
+	// the size of the serialized transaction 056e3d82e5ffd0e915fb9b62797d76263508c34fe3e5dbed30dd3e943930f204
+	if got := txSize(&tx); got != 189 {
+		t.Errorf("txSize() = %v, want 189", got)
+	}
+	tx.Hex = "0100"
+	if got := txSize(&tx); got != 2 {
+		t.Errorf("txSize() = %v, want 2", got)
+	}
+}
+
+func Test_selectStuckTxs(t *testing.T) {
+	// the fee rates of the recent blocks, the 10th percentile weighted by size is 2000 sat/kB
+	samples := []txFeeRate{
+		{feeRate: 1000, size: 50}, {feeRate: 2000, size: 200}, {feeRate: 5000, size: 400},
+		{feeRate: 8000, size: 250}, {feeRate: 20000, size: 100},
+	}
+	threshold := computeFeeRatePercentiles(samples, []int{10})[0]
+	if threshold != 2000 {
+		t.Fatalf("threshold = %v, want 2000", threshold)
+	}
+	newTx := func(txid string, feeRate int64) StuckMempoolTx {
+		return StuckMempoolTx{Txid: txid, Time: 1534859000, FeeRate: (*Amount)(big.NewInt(feeRate))}
+	}
+	txs := []StuckMempoolTx{
+		newTx("high", 10000),
+		newTx("low", 500),
+		newTx("threshold", 2000),
+		newTx("zero", 0),
+		newTx("medium", 4000),
+	}
+	got := selectStuckTxs(txs, threshold)
+	want := []StuckMempoolTx{txs[1], txs[3]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("selectStuckTxs() = %+v, want %+v", got, want)
+	}
+	// without confirmed transactions in the recent blocks no transaction is reported
+	if got := selectStuckTxs(txs, computeFeeRatePercentiles(nil, []int{10})[0]); len(got) != 0 {
+		t.Errorf("selectStuckTxs() without samples = %+v, want none", got)
+	}
+}
@@ -0,0 +1,20 @@
+package api
+
+// fundingAddresses returns the distinct addresses of the outputs spent by the inputs in the order of their first use,
+// nil for a coinbase transaction or if the addresses of the inputs are not known
+func fundingAddresses(vins []Vin) []string {
+	var r []string
+	seen := make(map[string]struct{})
+	for i := range vins {
+		if !vins[i].Searchable {
+			continue
+		}
+		for _, a := range vins[i].Addresses {
+			if _, found := seen[a]; !found {
+				seen[a] = struct{}{}
+				r = append(r, a)
+			}
+		}
+	}
+	return r
+}
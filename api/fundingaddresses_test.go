@@ -0,0 +1,47 @@
+// +build unittest
+
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_fundingAddresses(t *testing.T) {
+	tests := []struct {
+		name string
+		vins []Vin
+		want []string
+	}{
+		{
+			name: "coinbase",
+			vins: []Vin{{Coinbase: "03c1710300"}},
+		},
+		{
+			name: "multiple inputs reusing address",
+			vins: []Vin{
+				{N: 0, Addresses: []string{"A"}, Searchable: true},
+				{N: 1, Addresses: []string{"B"}, Searchable: true},
+				{N: 2, Addresses: []string{"A"}, Searchable: true},
+				{N: 3, Addresses: []string{"C", "B"}, Searchable: true},
+			},
+			want: []string{"A", "B", "C"},
+		},
+		{
+			name: "unresolved and not searchable inputs",
+			vins: []Vin{
+				{N: 0},
+				{N: 1, Addresses: []string{"OP_RETURN (test)"}},
+				{N: 2, Addresses: []string{"A"}, Searchable: true},
+			},
+			want: []string{"A"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fundingAddresses(tt.vins); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fundingAddresses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
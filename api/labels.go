@@ -0,0 +1,123 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+
+	"github.com/juju/errors"
+)
+
+// AddressLabels is a store of labels of known addresses (exchanges, pools etc.)
+// the labels are stored by address descriptors, so that all forms of an address
+// (for example CashAddr and legacy) match the same label
+// if the store is backed by db, the labels are persisted and survive restart
+type AddressLabels struct {
+	mux      sync.RWMutex
+	parser   bchain.BlockChainParser
+	db       *db.RocksDB
+	labels   map[string]string
+	onChange []func(addrDesc bchain.AddressDescriptor)
+}
+
+// NewAddressLabels returns a store of address labels loaded from db, nil db means not persisted labels
+func NewAddressLabels(parser bchain.BlockChainParser, db *db.RocksDB) (*AddressLabels, error) {
+	l := &AddressLabels{
+		parser: parser,
+		db:     db,
+		labels: make(map[string]string),
+	}
+	if db != nil {
+		labels, err := db.GetAddressLabels()
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetAddressLabels")
+		}
+		l.labels = labels
+	}
+	return l, nil
+}
+
+// OnChange registers a function called with the address descriptor of every changed label
+func (l *AddressLabels) OnChange(fn func(addrDesc bchain.AddressDescriptor)) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.onChange = append(l.onChange, fn)
+}
+
+// LoadFile loads labels from json file containing an object mapping addresses to labels
+func (l *AddressLabels) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Annotatef(err, "ReadFile %v", path)
+	}
+	var labels map[string]string
+	if err = json.Unmarshal(data, &labels); err != nil {
+		return errors.Annotatef(err, "Invalid address labels file %v", path)
+	}
+	for address, label := range labels {
+		if err = l.Set(address, label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set sets the label of the address, empty label removes the label
+func (l *AddressLabels) Set(address string, label string) error {
+	addrDesc, err := l.parser.GetAddrDescFromAddress(address)
+	if err != nil || len(addrDesc) == 0 {
+		return NewAPIError("Invalid address "+address, true)
+	}
+	s := string(addrDesc)
+	l.mux.Lock()
+	old, found := l.labels[s]
+	if label == "" {
+		delete(l.labels, s)
+	} else {
+		l.labels[s] = label
+	}
+	if l.db != nil {
+		if err = l.db.StoreAddressLabels(l.labels); err != nil {
+			// keep the labels in memory consistent with the db
+			if found {
+				l.labels[s] = old
+			} else {
+				delete(l.labels, s)
+			}
+			l.mux.Unlock()
+			return errors.Annotatef(err, "StoreAddressLabels")
+		}
+	}
+	onChange := l.onChange
+	l.mux.Unlock()
+	for _, fn := range onChange {
+		fn(addrDesc)
+	}
+	return nil
+}
+
+// Get returns the label of the address descriptor or empty string if the address is not labeled
+func (l *AddressLabels) Get(addrDesc bchain.AddressDescriptor) string {
+	if l == nil {
+		return ""
+	}
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	return l.labels[string(addrDesc)]
+}
+
+// All returns all labels mapped by the addresses in the form returned by the parser
+func (l *AddressLabels) All() map[string]string {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	r := make(map[string]string, len(l.labels))
+	for d, label := range l.labels {
+		a, _, err := l.parser.GetAddressesFromAddrDesc(bchain.AddressDescriptor(d))
+		if err == nil && len(a) == 1 {
+			r[a[0]] = label
+		}
+	}
+	return r
+}
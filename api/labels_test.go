@@ -0,0 +1,101 @@
+// +build unittest
+
+package api
+
+import (
+	"blockbook/bchain/coins/bch"
+	"blockbook/bchain/coins/btc"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestAddressLabels(t *testing.T) *AddressLabels {
+	parser, err := bch.NewBCashParser(bch.GetChainParams("main"), &btc.Configuration{AddressFormat: "cashaddr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewAddressLabels(parser, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestAddressLabels(t *testing.T) {
+	l := newTestAddressLabels(t)
+	if err := l.Set("bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5", "Exchange"); err != nil {
+		t.Fatal(err)
+	}
+	// legacy form of the address is converted to the canonical CashAddr form
+	if err := l.Set("3EBEFWPtDYWCNszQ7etoqtWmmygccayLiH", "Pool"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Set("invalid address", "Invalid"); err == nil {
+		t.Error("Set() of invalid address did not return error")
+	}
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5", "Exchange"},
+		{"129HiRqekqPVucKy2M8zsqvafGgKypciPp", "Exchange"},
+		{"bitcoincash:pzy0wuj9pjps5v8dmlwq32fatu4wrgcwzuayq5nfhh", "Pool"},
+		{"bitcoincash:pps5f4tu3tl5sjfvnhaeznsjpvst44eddugfcnqpy9", ""},
+	}
+	for _, tt := range tests {
+		addrDesc, err := l.parser.GetAddrDescFromAddress(tt.address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.Get(addrDesc); got != tt.want {
+			t.Errorf("Get(%v) = %v, want %v", tt.address, got, tt.want)
+		}
+	}
+	wantAll := map[string]string{
+		"bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5": "Exchange",
+		"bitcoincash:pzy0wuj9pjps5v8dmlwq32fatu4wrgcwzuayq5nfhh": "Pool",
+	}
+	if got := l.All(); !reflect.DeepEqual(got, wantAll) {
+		t.Errorf("All() = %v, want %v", got, wantAll)
+	}
+	// empty label removes the label
+	if err := l.Set("129HiRqekqPVucKy2M8zsqvafGgKypciPp", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.All(); len(got) != 1 {
+		t.Errorf("All() = %v, want only one label", got)
+	}
+	var nilLabels *AddressLabels
+	if got := nilLabels.Get([]byte{1, 2, 3}); got != "" {
+		t.Errorf("nil AddressLabels Get() = %v, want empty label", got)
+	}
+}
+
+func TestAddressLabels_LoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "labels.json")
+	if err = ioutil.WriteFile(path, []byte(`{"129HiRqekqPVucKy2M8zsqvafGgKypciPp": "Exchange"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := newTestAddressLabels(t)
+	if err = l.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5": "Exchange"}
+	if got := l.All(); !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+	if err = ioutil.WriteFile(path, []byte(`{"invalid": "Exchange"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = l.LoadFile(path); err == nil {
+		t.Error("LoadFile() with invalid address did not return error")
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// maxLockTimeTxs is the maximal number of transactions returned in one request
+const maxLockTimeTxs = 1000
+
+func parseLockTime(s string) (uint32, error) {
+	lt, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, NewAPIError(fmt.Sprintf("Invalid lock time '%s'", s), true)
+	}
+	return uint32(lt), nil
+}
+
+// parseLockTimeKey parses the continuation key lockTime:height:txid returned in the field next of the response
+func parseLockTimeKey(s string) (*db.LockTimeTx, error) {
+	p := strings.Split(s, ":")
+	if len(p) != 3 || p[2] == "" {
+		return nil, NewAPIError(fmt.Sprintf("Invalid continuation key '%s'", s), true)
+	}
+	lockTime, err := strconv.ParseUint(p[0], 10, 32)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid continuation key '%s'", s), true)
+	}
+	height, err := strconv.ParseUint(p[1], 10, 32)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid continuation key '%s'", s), true)
+	}
+	return &db.LockTimeTx{LockTime: uint32(lockTime), Height: uint32(height), Txid: p[2]}, nil
+}
+
+// GetLockTimeTxs returns the transactions with the lock time in the range from..to (both inclusive), ordered by the lock time,
+// the lock times below bchain.LockTimeThreshold are block heights, the others unix times and the range cannot mix them,
+// empty to means the end of the interpretation of from; at most maxLockTimeTxs transactions are returned,
+// the following ones are returned if the continuation key next of the truncated response is passed as from
+func (w *Worker) GetLockTimeTxs(from, to string) (*LockTimeTxs, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if from == "" {
+		return nil, NewAPIError("Missing parameter 'from'", true)
+	}
+	var after *db.LockTimeTx
+	var fromLockTime uint32
+	var err error
+	if strings.IndexByte(from, ':') >= 0 {
+		if after, err = parseLockTimeKey(from); err != nil {
+			return nil, err
+		}
+		fromLockTime = after.LockTime
+	} else if fromLockTime, err = parseLockTime(from); err != nil {
+		return nil, err
+	}
+	r := &LockTimeTxs{From: fromLockTime, LockTimeType: "height", Txs: []LockTimeTx{}}
+	if fromLockTime >= bchain.LockTimeThreshold {
+		r.LockTimeType = "time"
+	}
+	if to != "" {
+		if r.To, err = parseLockTime(to); err != nil {
+			return nil, err
+		}
+		if r.To < r.From {
+			return nil, NewAPIError("Invalid lock time range", true)
+		}
+	} else if r.LockTimeType == "height" {
+		r.To = bchain.LockTimeThreshold - 1
+	} else {
+		r.To = math.MaxUint32
+	}
+	if (r.From < bchain.LockTimeThreshold) != (r.To < bchain.LockTimeThreshold) {
+		return nil, NewAPIError("Lock time range mixes block heights and times", true)
+	}
+	if w.is == nil || !w.is.LockTimeIndex {
+		return nil, NewAPIError("Lock time index disabled", true)
+	}
+	r.IndexedFrom = w.is.LockTimeIndexFrom
+	txs, err := w.db.GetLockTimeTxs(r.From, r.To, after, maxLockTimeTxs+1)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetLockTimeTxs %v-%v", r.From, r.To)
+	}
+	if len(txs) > maxLockTimeTxs {
+		txs = txs[:maxLockTimeTxs]
+		r.Truncated = true
+		last := &txs[len(txs)-1]
+		r.Next = fmt.Sprintf("%d:%d:%s", last.LockTime, last.Height, last.Txid)
+	}
+	for i := range txs {
+		r.Txs = append(r.Txs, LockTimeTx{Txid: txs[i].Txid, Height: txs[i].Height, LockTime: txs[i].LockTime})
+	}
+	return r, nil
+}
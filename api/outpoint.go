@@ -0,0 +1,65 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// GetOutpointInfo returns the address, the value and the spend status of the output vout of the transaction txid
+// together with the confirmation of the transaction, the outpoint is resolved from the index and the outputs
+// of the mempool transactions are not found; the spends by mempool transactions are included
+// without the address index the spent outputs are returned without the spending transaction
+func (w *Worker) GetOutpointInfo(txid string, vout int) (*OutpointInfo, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if vout < 0 {
+		return nil, NewAPIError(fmt.Sprintf("Invalid vout %d", vout), true)
+	}
+	start := time.Now()
+	ta, err := w.db.GetTxAddresses(txid)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid txid '%v', %v", txid, err), true)
+	}
+	r := &OutpointInfo{Txid: txid, Vout: vout}
+	if ta == nil || vout >= len(ta.Outputs) {
+		return r, nil
+	}
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	bi, err := w.db.GetBlockInfo(ta.Height)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockInfo %v", ta.Height)
+	}
+	var s OutputSpendStatus
+	if err = w.setIndexedOutputSpend(txid, ta, vout, &s); err != nil {
+		return nil, err
+	}
+	o := &ta.Outputs[vout]
+	r.Found = true
+	r.Addresses, r.IsAddress, err = o.Addresses(w.chainParser)
+	if err != nil {
+		glog.V(2).Infof("GetOutpointInfo %v:%v addresses error %v", txid, vout, err)
+	}
+	r.ValueSat = (*Amount)(&o.ValueSat)
+	r.Blockheight = int(ta.Height)
+	if bestheight >= ta.Height {
+		r.Confirmations = bestheight - ta.Height + 1
+	}
+	if bi != nil {
+		r.Blocktime = bi.Time
+	}
+	r.Spent = s.Spent
+	r.Unspendable = s.Unspendable
+	r.SpentTxID = s.SpentTxID
+	r.SpentIndex = s.SpentIndex
+	r.SpentHeight = s.SpentHeight
+	glog.Info("GetOutpointInfo ", txid, ":", vout, " finished in ", time.Since(start))
+	return r, nil
+}
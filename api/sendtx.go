@@ -0,0 +1,62 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// MaxSendTxs is the maximum number of transactions in a batch broadcast request
+const MaxSendTxs = 100
+
+// rpcVerifyAlreadyInChain is the error code returned by the backend for transaction already in the blockchain
+const rpcVerifyAlreadyInChain = -27
+
+// SendRawTransactions broadcasts the transactions one by one in the order of the request and returns txid or error
+// of each of them, the failed broadcast of a transaction does not stop the broadcast of the remaining transactions
+// the transactions already known to the backend are considered successfully broadcasted
+func (w *Worker) SendRawTransactions(txs []string) ([]SendTxResult, error) {
+	if len(txs) == 0 {
+		return nil, NewAPIError("Missing tx blob", true)
+	}
+	if len(txs) > MaxSendTxs {
+		return nil, NewAPIError("Too many transactions", true)
+	}
+	r := make([]SendTxResult, len(txs))
+	for i, tx := range txs {
+		txid, err := w.chain.SendRawTransaction(tx)
+		if err != nil && isAlreadyKnownError(err) {
+			txid, err = w.getTxidFromHex(tx)
+		}
+		if err != nil {
+			glog.V(1).Info("SendRawTransactions tx ", i, ": ", err)
+			r[i].Error = err.Error()
+		} else {
+			r[i].Txid = txid
+		}
+	}
+	return r, nil
+}
+
+// isAlreadyKnownError returns true if the backend rejected the transaction because it is already in mempool or blockchain
+func isAlreadyKnownError(err error) bool {
+	if e, ok := err.(*bchain.RPCError); ok && e.Code == rpcVerifyAlreadyInChain {
+		return true
+	}
+	m := err.Error()
+	return strings.Contains(m, "txn-already-known") || strings.Contains(m, "txn-already-in-mempool")
+}
+
+func (w *Worker) getTxidFromHex(txHex string) (string, error) {
+	b, err := hex.DecodeString(txHex)
+	if err != nil {
+		return "", err
+	}
+	tx, err := w.chainParser.ParseTx(b)
+	if err != nil {
+		return "", err
+	}
+	return tx.Txid, nil
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// DefaultStuckTxPercentile is the default percentile of the fee rates of the transactions confirmed in the last blocks,
+// the mempool transactions with a lower fee rate are reported as likely stuck
+const DefaultStuckTxPercentile = 10
+
+// stuckTxsCache keeps the last result of GetStuckMempoolTxs, it is valid until the next block or resync of the mempool
+type stuckTxsCache struct {
+	mux         sync.Mutex
+	bestHash    string
+	mempoolSync time.Time
+	percentile  int
+	blocks      int
+	result      *StuckMempoolTxs
+}
+
+// SetStuckTxPercentile sets the percentile of the fee rates of the last blocks below which a mempool transaction is likely stuck
+func (w *Worker) SetStuckTxPercentile(p int) {
+	w.stuckTxPercentile = p
+}
+
+// GetStuckMempoolTxs returns the mempool transactions with the fee rate lower than the configured percentile of the fee rates
+// of the transactions confirmed in the last blocks, such transactions are unlikely to be confirmed soon;
+// the fee rates of the mempool transactions are taken from the mempool entries of the backend obtained by one call,
+// the transactions without the mempool entry are skipped; the result is cached until the next block or resync of the mempool
+func (w *Worker) GetStuckMempoolTxs() (*StuckMempoolTxs, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	percentile := w.stuckTxPercentile
+	if percentile <= 0 || percentile > 100 {
+		percentile = DefaultStuckTxPercentile
+	}
+	blocks := w.feeStatsBlocks
+	if blocks <= 0 {
+		blocks = DefaultFeeStatsBlocks
+	}
+	_, bestHash, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	_, mempoolSync, _ := w.is.GetMempoolSyncState()
+	c := &w.stuckTxsCache
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.result != nil && c.bestHash == bestHash && c.mempoolSync.Equal(mempoolSync) && c.percentile == percentile && c.blocks == blocks {
+		return c.result, nil
+	}
+	samples, fromHeight, toHeight, err := w.getFeeRateSamples(blocks)
+	if err != nil {
+		return nil, err
+	}
+	threshold := computeFeeRatePercentiles(samples, []int{percentile})[0]
+	entries := w.mempool.GetAllEntries()
+	var mempoolEntries map[string]*bchain.MempoolEntry
+	if len(entries) > 0 {
+		if mempoolEntries, err = w.chain.GetMempoolEntries(); err != nil {
+			return nil, errors.Annotatef(err, "GetMempoolEntries")
+		}
+	}
+	txs := make([]StuckMempoolTx, 0, len(entries))
+	for i := range entries {
+		e, found := mempoolEntries[entries[i].Txid]
+		if !found {
+			continue
+		}
+		rate, ok := e.FeeRate()
+		if !ok {
+			continue
+		}
+		txs = append(txs, StuckMempoolTx{
+			Txid:    entries[i].Txid,
+			Time:    int64(entries[i].Time),
+			FeeRate: (*Amount)(big.NewInt(int64(rate * 1000))),
+		})
+	}
+	r := &StuckMempoolTxs{
+		FromHeight:  fromHeight,
+		ToHeight:    toHeight,
+		Percentile:  percentile,
+		FeeRate:     (*Amount)(big.NewInt(threshold)),
+		MempoolSize: len(entries),
+		Txs:         selectStuckTxs(txs, threshold),
+	}
+	c.bestHash, c.mempoolSync, c.percentile, c.blocks, c.result = bestHash, mempoolSync, percentile, blocks, r
+	return r, nil
+}
+
+// selectStuckTxs returns the transactions with the fee rate in satoshis per kB lower than the threshold
+func selectStuckTxs(txs []StuckMempoolTx, threshold int64) []StuckMempoolTx {
+	stuck := []StuckMempoolTx{}
+	for i := range txs {
+		if (*big.Int)(txs[i].FeeRate).Int64() < threshold {
+			stuck = append(stuck, txs[i])
+		}
+	}
+	return stuck
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+// GetAddressTimeRange returns the address with the transactions in the blocks with time in the interval fromTime..toTime
+// (unix times in UTC, inclusive), toTime math.MaxInt64 means up to the tip of the index; the times are mapped to the heights
+// using the median time past of the blocks stored in the index and the resulting height range is queried, the parts of the interval outside of the chain are ignored
+func (w *Worker) GetAddressTimeRange(address string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, fromTime, toTime int64) (*Address, error) {
+	if fromTime > toTime {
+		return nil, NewAPIError(fmt.Sprintf("Invalid time range %d-%d", fromTime, toTime), true)
+	}
+	fromHeight, toHeight, err := w.heightRangeByTime(fromTime, toTime)
+	if err != nil {
+		return nil, err
+	}
+	f := *filter
+	f.FromHeight = fromHeight
+	f.ToHeight = toHeight
+	return w.GetAddress(address, page, txsOnPage, option, &f)
+}
+
+// heightRangeByTime returns the range of the indexed blocks with time in the interval fromTime..toTime,
+// an empty interval is returned as the range containing only the not yet existing block after the tip of the index
+func (w *Worker) heightRangeByTime(fromTime, toTime int64) (uint32, uint32, error) {
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return 0, 0, errors.Annotatef(err, "GetBestBlock")
+	}
+	// lower is the first block inside the interval, upper the first block after it
+	lower, err := w.heightByTime(fromTime, bestheight)
+	if err != nil {
+		return 0, 0, err
+	}
+	upper := bestheight + 1
+	if toTime < math.MaxInt64 {
+		if upper, err = w.heightByTime(toTime+1, bestheight); err != nil {
+			return 0, 0, err
+		}
+	}
+	// the height filter treats the height 0 as no limit, therefore the range consisting only of the genesis block is empty
+	if upper <= 1 || lower >= upper {
+		return bestheight + 1, bestheight + 1, nil
+	}
+	return lower, upper - 1, nil
+}
+
+// medianTimeSpan is the number of the blocks from which the median time past is computed (BIP113)
+const medianTimeSpan = 11
+
+// heightByTime returns the lowest height of the indexed block with median time past greater or equal to the given time
+// or bestheight + 1 if there is no such block; the block times may decrease with the height, however the median time past
+// does not, therefore the binary search is done over it; the heights not found in the index are treated as the blocks before the time
+func (w *Worker) heightByTime(time int64, bestheight uint32) (uint32, error) {
+	return searchHeightByTime(time, bestheight, w.indexedBlockTime)
+}
+
+// indexedBlockTime returns the time of the block at the height stored in the index, false if the block is not indexed
+func (w *Worker) indexedBlockTime(height uint32) (int64, bool, error) {
+	bi, err := w.db.GetBlockInfo(height)
+	if err != nil {
+		return 0, false, errors.Annotatef(err, "GetBlockInfo %v", height)
+	}
+	if bi == nil {
+		return 0, false, nil
+	}
+	return bi.Time, true, nil
+}
+
+// blockTimeFunc returns the time of the block at the height and false if the block is not known
+type blockTimeFunc func(height uint32) (int64, bool, error)
+
+func searchHeightByTime(time int64, bestheight uint32, blockTime blockTimeFunc) (uint32, error) {
+	lower, upper := uint32(0), bestheight+1
+	for lower < upper {
+		mid := lower + (upper-lower)/2
+		mtp, found, err := medianTimePast(mid, blockTime)
+		if err != nil {
+			return 0, err
+		}
+		if !found || mtp < time {
+			lower = mid + 1
+		} else {
+			upper = mid
+		}
+	}
+	return lower, nil
+}
+
+// medianTimePast returns the median of the times of the block at the height and of the preceding medianTimeSpan - 1 blocks,
+// the preceding blocks not known are skipped, false is returned if the block at the height is not known
+func medianTimePast(height uint32, blockTime blockTimeFunc) (int64, bool, error) {
+	times := make([]int64, 0, medianTimeSpan)
+	for i := uint32(0); i < medianTimeSpan && i <= height; i++ {
+		t, found, err := blockTime(height - i)
+		if err != nil {
+			return 0, false, err
+		}
+		if !found {
+			if i == 0 {
+				return 0, false, nil
+			}
+			continue
+		}
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times[len(times)/2], true, nil
+}
@@ -0,0 +1,79 @@
+// +build unittest
+
+package api
+
+import "testing"
+
+func Test_searchHeightByTime(t *testing.T) {
+	// the times of the blocks 3 and 7 are lower than the times of the preceding blocks
+	times := []int64{100, 200, 300, 250, 400, 500, 600, 350, 700, 800, 900, 1000, 1100, 1200, 1300}
+	blockTime := func(height uint32) (int64, bool, error) {
+		if int(height) >= len(times) {
+			return 0, false, nil
+		}
+		return times[height], true, nil
+	}
+	// median times past are 100, 200, 200, 250, 250, 300, 300, 350, 350, 400, 400, 500, 600, 700, 800
+	bestheight := uint32(len(times) - 1)
+	tests := []struct {
+		time int64
+		want uint32
+	}{
+		{0, 0},
+		{100, 0},
+		{101, 1},
+		{250, 3},
+		{251, 5},
+		{300, 5},
+		{301, 7},
+		{351, 9},
+		{400, 9},
+		{800, 14},
+		{801, 15},
+	}
+	for _, tt := range tests {
+		got, err := searchHeightByTime(tt.time, bestheight, blockTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("searchHeightByTime(%v) = %v, want %v", tt.time, got, tt.want)
+		}
+	}
+	// the blocks not known are treated as the blocks before the time
+	if got, err := searchHeightByTime(801, bestheight+2, blockTime); err != nil || got != bestheight+3 {
+		t.Errorf("searchHeightByTime() with unknown blocks = %v, %v, want %v", got, err, bestheight+3)
+	}
+}
+
+func Test_medianTimePast(t *testing.T) {
+	times := []int64{100, 200, 300, 250, 400, 500, 600, 350, 700, 800, 900, 50, 1100}
+	blockTime := func(height uint32) (int64, bool, error) {
+		// the block 5 is not known
+		if height == 5 || int(height) >= len(times) {
+			return 0, false, nil
+		}
+		return times[height], true, nil
+	}
+	tests := []struct {
+		height    uint32
+		want      int64
+		wantFound bool
+	}{
+		{height: 0, want: 100, wantFound: true},
+		{height: 3, want: 250, wantFound: true},
+		// the times of the blocks 2..12 without the block 5
+		{height: 12, want: 600, wantFound: true},
+		{height: 5, wantFound: false},
+		{height: 13, wantFound: false},
+	}
+	for _, tt := range tests {
+		got, found, err := medianTimePast(tt.height, blockTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want || found != tt.wantFound {
+			t.Errorf("medianTimePast(%v) = %v, %v, want %v, %v", tt.height, got, found, tt.want, tt.wantFound)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"math/big"
+)
+
+// GetTxAddressValue returns the amounts received and sent by the address in the transaction and their difference,
+// the effective change of the balance of the address by the transaction; the address may be on both sides of the transaction,
+// for example in the change output, the values of the inputs are taken from the index, the transaction may be in the mempool
+func (w *Worker) GetTxAddressValue(txid string, address string) (*TxAddressValue, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := w.GetTransaction(txid, false, false)
+	if err != nil {
+		return nil, err
+	}
+	received := tx.getAddrVoutValue(addrDesc)
+	sent := tx.getAddrVinValue(addrDesc)
+	var value big.Int
+	value.Sub(received, sent)
+	return &TxAddressValue{
+		Txid:          tx.Txid,
+		Address:       address,
+		BlockHeight:   tx.Blockheight,
+		Confirmations: tx.Confirmations,
+		ReceivedSat:   (*Amount)(received),
+		SentSat:       (*Amount)(sent),
+		ValueSat:      (*Amount)(&value),
+	}, nil
+}
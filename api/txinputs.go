@@ -0,0 +1,108 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// maxInputsBackendLookups is the maximum number of previous transactions downloaded from the backend
+// to resolve the inputs of one transaction, the inputs spending further transactions are left without value
+const maxInputsBackendLookups = 100
+
+// inputsResolver resolves the addresses and values of the previous outputs spent by the inputs of one transaction
+// each previous transaction is looked up only once even if several inputs spend its outputs
+type inputsResolver struct {
+	w              *Worker
+	tas            map[string]*db.TxAddresses
+	txs            map[string]*bchain.Tx
+	backendLookups int
+}
+
+func newInputsResolver(w *Worker) *inputsResolver {
+	return &inputsResolver{
+		w:   w,
+		tas: make(map[string]*db.TxAddresses),
+		txs: make(map[string]*bchain.Tx),
+	}
+}
+
+// resolve fills the address and the value of the i-th input of bchainTx to vin
+// the previous transaction is read from the index, transactions not in the index (mempool) are downloaded from the backend
+func (r *inputsResolver) resolve(bchainTx *bchain.Tx, i int, vin *Vin) error {
+	w := r.w
+	txid := bchainTx.Vin[i].Txid
+	tas, found := r.tas[txid]
+	if !found {
+		var err error
+		tas, err = w.db.GetTxAddresses(txid)
+		if err != nil {
+			return errors.Annotatef(err, "GetTxAddresses %v", txid)
+		}
+		r.tas[txid] = tas
+	}
+	if tas != nil {
+		if len(tas.Outputs) > int(vin.Vout) {
+			output := &tas.Outputs[vin.Vout]
+			var err error
+			vin.ValueSat = (*Amount)(&output.ValueSat)
+			vin.AddrDesc = output.AddrDesc
+			vin.Addresses, vin.Searchable, err = output.Addresses(w.chainParser)
+			if err != nil {
+				glog.Errorf("output.Addresses error %v, tx %v, output %v", err, txid, i)
+			}
+		}
+		return nil
+	}
+	otx, found := r.txs[txid]
+	if !found {
+		if r.backendLookups >= maxInputsBackendLookups {
+			glog.Warning("tx ", bchainTx.Txid, ": too many previous transactions to download, input ", i, " not resolved")
+			r.resolveUnknown(bchainTx, i, vin)
+			return nil
+		}
+		r.backendLookups++
+		var err error
+		otx, _, err = w.txCache.GetTransaction(txid)
+		if err != nil {
+			if err == bchain.ErrTxNotFound {
+				// try to get AddrDesc using coin specific handling and continue processing the tx
+				r.txs[txid] = nil
+				r.resolveUnknown(bchainTx, i, vin)
+				return nil
+			}
+			return errors.Annotatef(err, "txCache.GetTransaction %v", txid)
+		}
+		r.txs[txid] = otx
+		// mempool transactions are not in TxAddresses but confirmed should be there, log a problem
+		if bchainTx.Confirmations > 0 {
+			glog.Warning("DB inconsistency:  tx ", txid, ": not found in txAddresses")
+		}
+	}
+	if otx == nil {
+		r.resolveUnknown(bchainTx, i, vin)
+		return nil
+	}
+	if len(otx.Vout) > int(vin.Vout) {
+		vout := &otx.Vout[vin.Vout]
+		var err error
+		vin.ValueSat = (*Amount)(&vout.ValueSat)
+		vin.AddrDesc, vin.Addresses, vin.Searchable, err = w.getAddressesFromVout(vout)
+		if err != nil {
+			glog.Errorf("getAddressesFromVout error %v, vout %+v", err, vout)
+		}
+	}
+	return nil
+}
+
+// resolveUnknown fills the address of the i-th input of bchainTx using the coin specific handling, the value stays unknown
+func (r *inputsResolver) resolveUnknown(bchainTx *bchain.Tx, i int, vin *Vin) {
+	var err error
+	vin.AddrDesc = r.w.chainParser.GetAddrDescForUnknownInput(bchainTx, i)
+	vin.Addresses, vin.Searchable, err = r.w.chainParser.GetAddressesFromAddrDesc(vin.AddrDesc)
+	if err != nil {
+		glog.Warning("GetAddressesFromAddrDesc tx ", bchainTx.Vin[i].Txid, ", addrDesc ", vin.AddrDesc, ": ", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"math/big"
+	"strings"
 	"time"
 )
 
@@ -81,6 +82,26 @@ func (a *Amount) DecimalString(d int) string {
 	return bchain.AmountToDecimalString((*big.Int)(a), d)
 }
 
+// FixedDecimalString returns amount with exactly d decimal places, without trimming the trailing zeros
+func (a *Amount) FixedDecimalString(d int) string {
+	if d <= 0 {
+		return a.bigIntOrZero().String()
+	}
+	s := bchain.AmountToDecimalString(a.bigIntOrZero(), d)
+	i := strings.IndexByte(s, '.')
+	if i == -1 {
+		return s + "." + strings.Repeat("0", d)
+	}
+	return s + strings.Repeat("0", d-(len(s)-i-1))
+}
+
+func (a *Amount) bigIntOrZero() *big.Int {
+	if a == nil {
+		return new(big.Int)
+	}
+	return (*big.Int)(a)
+}
+
 // AsBigInt returns big.Int type for the Amount (empty if Amount is nil)
 func (a *Amount) AsBigInt() big.Int {
 	if a == nil {
@@ -176,20 +197,29 @@ type EthereumSpecific struct {
 }
 
 // Tx holds information about a transaction
+// FeeRate is the fee rate in satoshis per byte of a mempool transaction, computed from its fee and size
 type Tx struct {
 	Txid             string            `json:"txid"`
 	Version          int32             `json:"version,omitempty"`
 	Locktime         uint32            `json:"locktime,omitempty"`
 	Vin              []Vin             `json:"vin"`
+	FundingAddresses []string          `json:"fundingAddresses,omitempty"`
 	Vout             []Vout            `json:"vout"`
 	Blockhash        string            `json:"blockhash,omitempty"`
 	Blockheight      int               `json:"blockheight"`
+	TxIndexInBlock   *int              `json:"txIndexInBlock,omitempty"`
 	Confirmations    uint32            `json:"confirmations"`
 	Blocktime        int64             `json:"blocktime"`
+	Block            *TxBlock          `json:"block,omitempty"`
 	Size             int               `json:"size,omitempty"`
 	ValueOutSat      *Amount           `json:"value"`
 	ValueInSat       *Amount           `json:"valueIn,omitempty"`
 	FeesSat          *Amount           `json:"fees,omitempty"`
+	FeeRate          *float64          `json:"feeRate,omitempty"`
+	NonFinal         bool              `json:"nonFinal,omitempty"`
+	FinalHeight      uint32            `json:"finalHeight,omitempty"`
+	FinalTime        int64             `json:"finalTime,omitempty"`
+	ReplacedBy       string            `json:"replacedBy,omitempty"`
 	Hex              string            `json:"hex,omitempty"`
 	CoinSpecificData interface{}       `json:"-"`
 	CoinSpecificJSON json.RawMessage   `json:"-"`
@@ -197,6 +227,102 @@ type Tx struct {
 	EthereumSpecific *EthereumSpecific `json:"ethereumspecific,omitempty"`
 }
 
+// TxBlock identifies the block in which a transaction is confirmed, it is returned only for confirmed transactions
+type TxBlock struct {
+	Hash   string `json:"hash"`
+	Height uint32 `json:"height"`
+	Time   int64  `json:"time"`
+}
+
+// FeeRatePercentile is the fee rate in satoshis per kilobyte not exceeded by the given percentile of the size of the confirmed transactions
+type FeeRatePercentile struct {
+	Percentile int     `json:"percentile"`
+	FeeRate    *Amount `json:"feeRate"`
+}
+
+// FeeRatePercentiles contains percentiles of fee rates of the transactions confirmed in a range of blocks
+type FeeRatePercentiles struct {
+	FromHeight  uint32              `json:"fromHeight"`
+	ToHeight    uint32              `json:"toHeight"`
+	Txs         int                 `json:"txs"`
+	Percentiles []FeeRatePercentile `json:"percentiles"`
+}
+
+// MedianFeeRate is the median fee rate in satoshis per kilobyte of the transactions of the block at height,
+// MinRelayFee is set if the block contains no transactions except the coinbase and the fee rate is the minimum relay fee of the backend
+type MedianFeeRate struct {
+	Height      uint32  `json:"height"`
+	Hash        string  `json:"hash"`
+	Txs         int     `json:"txs"`
+	FeeRate     *Amount `json:"feeRate"`
+	MinRelayFee bool    `json:"minRelayFee,omitempty"`
+}
+
+// StuckMempoolTx is a mempool transaction with the fee rate in satoshis per kB lower than the threshold
+type StuckMempoolTx struct {
+	Txid    string  `json:"txid"`
+	Time    int64   `json:"time"`
+	FeeRate *Amount `json:"feeRate"`
+}
+
+// StuckMempoolTxs contains the mempool transactions with the fee rate lower than the percentile of the fee rates
+// of the transactions confirmed in the blocks fromHeight..toHeight
+type StuckMempoolTxs struct {
+	FromHeight  uint32           `json:"fromHeight"`
+	ToHeight    uint32           `json:"toHeight"`
+	Percentile  int              `json:"percentile"`
+	FeeRate     *Amount          `json:"feeRate"`
+	MempoolSize int              `json:"mempoolSize"`
+	Txs         []StuckMempoolTx `json:"txs"`
+}
+
+// SendTxResult is the result of broadcast of one transaction of a batch, either the txid or the error
+type SendTxResult struct {
+	Txid  string `json:"txid,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// OutputSpendStatus contains information if and by which transaction input is the transaction output spent
+// SpentHeight is zero if the output is spent by a mempool transaction
+type OutputSpendStatus struct {
+	N           int    `json:"n"`
+	Spent       bool   `json:"spent"`
+	Unspendable bool   `json:"unspendable,omitempty"`
+	SpentTxID   string `json:"spentTxId,omitempty"`
+	SpentIndex  int    `json:"spentIndex,omitempty"`
+	SpentHeight int    `json:"spentHeight,omitempty"`
+}
+
+// OutpointInfo contains the output of an indexed transaction with the context of the transaction,
+// Found is false if the outpoint is not in the index, in that case only Txid and Vout are set
+type OutpointInfo struct {
+	Txid          string   `json:"txid"`
+	Vout          int      `json:"vout"`
+	Found         bool     `json:"found"`
+	Addresses     []string `json:"addresses,omitempty"`
+	IsAddress     bool     `json:"isAddress,omitempty"`
+	ValueSat      *Amount  `json:"value,omitempty"`
+	Blockheight   int      `json:"blockheight,omitempty"`
+	Confirmations uint32   `json:"confirmations,omitempty"`
+	Blocktime     int64    `json:"blocktime,omitempty"`
+	Spent         bool     `json:"spent"`
+	Unspendable   bool     `json:"unspendable,omitempty"`
+	SpentTxID     string   `json:"spentTxId,omitempty"`
+	SpentIndex    int      `json:"spentIndex,omitempty"`
+	SpentHeight   int      `json:"spentHeight,omitempty"`
+}
+
+// TxMetadata contains compact information about a transaction obtained from the index without the full transaction data
+type TxMetadata struct {
+	Txid          string  `json:"txid"`
+	Blockheight   int     `json:"blockheight"`
+	Confirmations uint32  `json:"confirmations"`
+	Blocktime     int64   `json:"blocktime"`
+	VinCount      int     `json:"vinCount"`
+	VoutCount     int     `json:"voutCount"`
+	ValueOutSat   *Amount `json:"value"`
+}
+
 // Paging contains information about paging for address, blocks and block
 type Paging struct {
 	Page        int `json:"page,omitempty"`
@@ -207,6 +333,9 @@ type Paging struct {
 // TokensToReturn specifies what tokens are returned by GetAddress and GetXpubAddress
 type TokensToReturn int
 
+// CoinbaseFilter specifies if the coinbase transactions are returned by GetAddress
+type CoinbaseFilter int
+
 const (
 	// AddressFilterVoutOff disables filtering of transactions by vout
 	AddressFilterVoutOff = -1
@@ -221,6 +350,13 @@ const (
 	TokensToReturnUsed TokensToReturn = 1
 	// TokensToReturnDerived - return all derived tokens
 	TokensToReturnDerived TokensToReturn = 2
+
+	// CoinbaseFilterOff - return all transactions
+	CoinbaseFilterOff CoinbaseFilter = 0
+	// CoinbaseFilterOnly - return only coinbase transactions
+	CoinbaseFilterOnly CoinbaseFilter = 1
+	// CoinbaseFilterExclude - return only non-coinbase transactions
+	CoinbaseFilterExclude CoinbaseFilter = 2
 )
 
 // AddressFilter is used to filter data returned from GetAddress api method
@@ -232,18 +368,54 @@ type AddressFilter struct {
 	TokensToReturn TokensToReturn
 	// OnlyConfirmed set to true will ignore mempool transactions; mempool is also ignored if FromHeight/ToHeight filter is specified
 	OnlyConfirmed bool
+	// Ascending set to true returns transactions from the oldest to the newest, the mempool transactions last
+	Ascending bool
+	// Coinbase filters the transactions by the coinbase flag stored in the index, mempool transactions are never coinbase
+	Coinbase CoinbaseFilter
+	// MinConfirmations, if greater than 1, moves the unspent outputs with fewer confirmations from the balance to the unconfirmed balance
+	MinConfirmations int
+	// Fields limits the computed fields of Address, nil means all fields
+	Fields AddressFields
+}
+
+// DenominatedAmount contains amount both in the base unit of the coin and in the human readable unit
+type DenominatedAmount struct {
+	Sat   *Amount `json:"sat"`
+	Value string  `json:"value"`
+}
+
+// NewDenominatedAmount creates DenominatedAmount from the amount in the base unit, using d decimal places of the coin
+func NewDenominatedAmount(a *Amount, d int) *DenominatedAmount {
+	if a == nil {
+		a = (*Amount)(new(big.Int))
+	}
+	return &DenominatedAmount{
+		Sat:   a,
+		Value: a.FixedDecimalString(d),
+	}
+}
+
+// AddressDenominations contains balances of the address in multiple denominations
+type AddressDenominations struct {
+	Decimals           int                `json:"decimals"`
+	Balance            *DenominatedAmount `json:"balance"`
+	TotalReceived      *DenominatedAmount `json:"totalReceived"`
+	TotalSent          *DenominatedAmount `json:"totalSent"`
+	UnconfirmedBalance *DenominatedAmount `json:"unconfirmedBalance"`
 }
 
 // Address holds information about address and its transactions
 type Address struct {
 	Paging
 	AddrStr               string                `json:"address"`
+	Label                 string                `json:"label,omitempty"`
 	BalanceSat            *Amount               `json:"balance"`
 	TotalReceivedSat      *Amount               `json:"totalReceived,omitempty"`
 	TotalSentSat          *Amount               `json:"totalSent,omitempty"`
 	UnconfirmedBalanceSat *Amount               `json:"unconfirmedBalance"`
 	UnconfirmedTxs        int                   `json:"unconfirmedTxs"`
 	Txs                   int                   `json:"txs"`
+	FirstFundedHeight     int                   `json:"firstFundedHeight,omitempty"`
 	NonTokenTxs           int                   `json:"nontokenTxs,omitempty"`
 	Transactions          []*Tx                 `json:"transactions,omitempty"`
 	Txids                 []string              `json:"txids,omitempty"`
@@ -251,6 +423,7 @@ type Address struct {
 	TotalTokens           int                   `json:"totalTokens,omitempty"`
 	Tokens                []Token               `json:"tokens,omitempty"`
 	Erc20Contract         *bchain.Erc20Contract `json:"erc20contract,omitempty"`
+	Denominations         *AddressDenominations `json:"denominations,omitempty"`
 	// helpers for explorer
 	Filter        string              `json:"-"`
 	XPubAddresses map[string]struct{} `json:"-"`
@@ -265,11 +438,30 @@ type Utxo struct {
 	Confirmations int     `json:"confirmations"`
 	Address       string  `json:"address,omitempty"`
 	Path          string  `json:"path,omitempty"`
+	// Spendable is set only for the coins with known coinbase maturity
+	Spendable *bool `json:"spendable,omitempty"`
 }
 
 // Utxos is array of Utxo
 type Utxos []Utxo
 
+// UtxoFilter selects the utxos returned by the utxo queries
+type UtxoFilter struct {
+	OnlyConfirmed bool
+	// MinConfirmations is the minimal number of confirmations of the returned utxos, positive value excludes also the mempool utxos
+	MinConfirmations int
+	// Limit is the maximal number of returned utxos, zero means the limit of the server
+	Limit int
+}
+
+// LimitedUtxos is the list of utxos truncated to a limit together with the summary of all utxos
+type LimitedUtxos struct {
+	Utxos      Utxos   `json:"utxos"`
+	Truncated  bool    `json:"truncated"`
+	TotalUtxos int     `json:"totalUtxos"`
+	TotalSat   *Amount `json:"totalValue"`
+}
+
 func (a Utxos) Len() int      { return len(a) }
 func (a Utxos) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a Utxos) Less(i, j int) bool {
@@ -291,15 +483,182 @@ type Blocks struct {
 	Blocks []db.BlockInfo `json:"blocks"`
 }
 
-// BlockInfo contains extended block header data and a list of block txids
+// BlockSummary contains lightweight data about a block
+type BlockSummary struct {
+	bchain.BlockHeader
+	TxCount int `json:"txCount"`
+}
+
+// BlockRange contains summaries of contiguous range of blocks
+type BlockRange struct {
+	From   uint32         `json:"from"`
+	Blocks []BlockSummary `json:"blocks"`
+}
+
+// BlockInfo contains extended block header data and a list of block txids,
+// Weight is the block weight, see bchain.BlockInfo
 type BlockInfo struct {
 	bchain.BlockHeader
-	Version    json.Number `json:"version"`
-	MerkleRoot string      `json:"merkleroot"`
-	Nonce      string      `json:"nonce"`
-	Bits       string      `json:"bits"`
-	Difficulty string      `json:"difficulty"`
-	Txids      []string    `json:"tx,omitempty"`
+	Version     json.Number `json:"version"`
+	MerkleRoot  string      `json:"merkleroot"`
+	Nonce       string      `json:"nonce"`
+	Bits        string      `json:"bits"`
+	Difficulty  string      `json:"difficulty"`
+	Subsidy     *Amount     `json:"subsidy,omitempty"`
+	Fees        *Amount     `json:"fees,omitempty"`
+	Weight      int         `json:"weight,omitempty"`
+	Txids       []string    `json:"tx,omitempty"`
+	InBestChain bool        `json:"inBestChain"`
+}
+
+// TxStatus values
+const (
+	TxStatusConfirmed   = "confirmed"
+	TxStatusUnconfirmed = "unconfirmed"
+	TxStatusUnknown     = "unknown"
+)
+
+// TxStatus holds information about presence of a transaction in the index or in the mempool
+type TxStatus struct {
+	Txid          string `json:"txid"`
+	Status        string `json:"status"`
+	Height        uint32 `json:"height,omitempty"`
+	Confirmations uint32 `json:"confirmations,omitempty"`
+}
+
+// BlockMerkleRoot contains the merkle root from the block header and the merkle root computed from the block txids
+type BlockMerkleRoot struct {
+	Hash               string `json:"hash"`
+	Height             uint32 `json:"height"`
+	TxCount            int    `json:"txCount"`
+	MerkleRoot         string `json:"merkleRoot"`
+	ComputedMerkleRoot string `json:"computedMerkleRoot"`
+	Match              bool   `json:"match"`
+}
+
+// BlockRawHeader contains the serialized block header
+type BlockRawHeader struct {
+	Hash   string `json:"hash"`
+	Height uint32 `json:"height"`
+	Hex    string `json:"hex"`
+}
+
+// AddressBlockTxs contains the transactions of an address in one block
+type AddressBlockTxs struct {
+	Address      string `json:"address"`
+	Hash         string `json:"hash"`
+	Height       uint32 `json:"height"`
+	Transactions []*Tx  `json:"txs"`
+}
+
+// AddressBalanceDelta contains the amounts received and sent by an address in the blocks fromHeight..toHeight
+type AddressBalanceDelta struct {
+	Address     string  `json:"address"`
+	FromHeight  uint32  `json:"fromHeight"`
+	ToHeight    uint32  `json:"toHeight"`
+	ReceivedSat *Amount `json:"received"`
+	SentSat     *Amount `json:"sent"`
+	DeltaSat    *Amount `json:"delta"`
+	Txs         int     `json:"txs"`
+}
+
+// AddressFees contains the sum of the fees of the transactions in which an address is an input and their number
+type AddressFees struct {
+	Address string  `json:"address"`
+	FeesSat *Amount `json:"fees"`
+	Txs     int     `json:"txs"`
+}
+
+// TxAddressValue contains the amounts received and sent by an address in a transaction and their difference
+type TxAddressValue struct {
+	Txid          string  `json:"txid"`
+	Address       string  `json:"address"`
+	BlockHeight   int     `json:"blockHeight"`
+	Confirmations uint32  `json:"confirmations"`
+	ReceivedSat   *Amount `json:"received"`
+	SentSat       *Amount `json:"sent"`
+	ValueSat      *Amount `json:"value"`
+}
+
+// AddressBalanceAtHeights contains the balances of an address after the blocks fromHeight and toHeight and their difference
+type AddressBalanceAtHeights struct {
+	Address        string  `json:"address"`
+	FromHeight     uint32  `json:"fromHeight"`
+	ToHeight       uint32  `json:"toHeight"`
+	FromBalanceSat *Amount `json:"fromBalance"`
+	ToBalanceSat   *Amount `json:"toBalance"`
+	DeltaSat       *Amount `json:"delta"`
+}
+
+// ChainStats is the snapshot of the state of the chain, the values which could not be obtained from the backend are omitted
+type ChainStats struct {
+	BestHeight   *uint32 `json:"bestHeight,omitempty"`
+	BestHash     string  `json:"bestHash,omitempty"`
+	BestTime     int64   `json:"bestTime,omitempty"`
+	MempoolSize  *int    `json:"mempoolSize,omitempty"`
+	MempoolBytes *int64  `json:"mempoolBytes,omitempty"`
+	Difficulty   string  `json:"difficulty,omitempty"`
+	Connections  *int    `json:"connections,omitempty"`
+	SupplySat    *Amount `json:"supply,omitempty"`
+}
+
+// DailyTxs contains the number of transactions in the blocks with the time in one UTC day
+type DailyTxs struct {
+	Date string `json:"date"`
+	Txs  uint64 `json:"txs"`
+}
+
+// DailyTxsSeries contains the number of transactions per UTC day in the days from..to,
+// IndexedFrom is the height from which the transactions are counted if the index was enabled on an existing database
+type DailyTxsSeries struct {
+	From        string     `json:"from"`
+	To          string     `json:"to"`
+	IndexedFrom uint32     `json:"indexedFrom,omitempty"`
+	Days        []DailyTxs `json:"days"`
+}
+
+// LockTimeTx is a transaction with non-zero lock time
+type LockTimeTx struct {
+	Txid     string `json:"txid"`
+	Height   uint32 `json:"height"`
+	LockTime uint32 `json:"lockTime"`
+}
+
+// LockTimeTxs contains the transactions with the lock time in the range from..to,
+// LockTimeType is "height" if the lock times are block heights or "time" if they are unix times
+type LockTimeTxs struct {
+	From         uint32       `json:"from"`
+	To           uint32       `json:"to"`
+	LockTimeType string       `json:"lockTimeType"`
+	IndexedFrom  uint32       `json:"indexedFrom,omitempty"`
+	Truncated    bool         `json:"truncated,omitempty"`
+	Next         string       `json:"next,omitempty"`
+	Txs          []LockTimeTx `json:"txs"`
+}
+
+// RequiredConfirmations is the suggested number of confirmations after which an output with the value should be credited,
+// Rate and USDValue are omitted and DefaultUsed is set if the USD rate of the coin is not available
+type RequiredConfirmations struct {
+	Value         *Amount  `json:"value"`
+	Rate          *float64 `json:"rate,omitempty"`
+	USDValue      *float64 `json:"usdValue,omitempty"`
+	Confirmations uint32   `json:"confirmations"`
+	DefaultUsed   bool     `json:"defaultUsed,omitempty"`
+}
+
+// ValueTx is a transaction with the total value of its outputs
+type ValueTx struct {
+	Txid  string  `json:"txid"`
+	Value *Amount `json:"value"`
+}
+
+// BlockValueTxs contains the transactions of the block with the total value of the outputs exceeding the threshold
+type BlockValueTxs struct {
+	Hash      string    `json:"hash"`
+	Height    uint32    `json:"height"`
+	Threshold *Amount   `json:"threshold"`
+	Truncated bool      `json:"truncated,omitempty"`
+	Txs       []ValueTx `json:"txs"`
 }
 
 // Block contains information about block
@@ -311,25 +670,33 @@ type Block struct {
 }
 
 // BlockbookInfo contains information about the running blockbook instance
+// TotalTxs is the number of the transactions in the indexed blocks, only for Bitcoin type coins
+// WebsocketConnections is the number of the open websocket connections
 type BlockbookInfo struct {
-	Coin              string                       `json:"coin"`
-	Host              string                       `json:"host"`
-	Version           string                       `json:"version"`
-	GitCommit         string                       `json:"gitcommit"`
-	BuildTime         string                       `json:"buildtime"`
-	SyncMode          bool                         `json:"syncMode"`
-	InitialSync       bool                         `json:"initialsync"`
-	InSync            bool                         `json:"inSync"`
-	BestHeight        uint32                       `json:"bestHeight"`
-	LastBlockTime     time.Time                    `json:"lastBlockTime"`
-	InSyncMempool     bool                         `json:"inSyncMempool"`
-	LastMempoolTime   time.Time                    `json:"lastMempoolTime"`
-	MempoolSize       int                          `json:"mempoolSize"`
-	Decimals          int                          `json:"decimals"`
-	DbSize            int64                        `json:"dbSize"`
-	DbSizeFromColumns int64                        `json:"dbSizeFromColumns,omitempty"`
-	DbColumns         []common.InternalStateColumn `json:"dbColumns,omitempty"`
-	About             string                       `json:"about"`
+	Coin                 string                       `json:"coin"`
+	Host                 string                       `json:"host"`
+	Version              string                       `json:"version"`
+	GitCommit            string                       `json:"gitcommit"`
+	BuildTime            string                       `json:"buildtime"`
+	SyncMode             bool                         `json:"syncMode"`
+	InitialSync          bool                         `json:"initialsync"`
+	InSync               bool                         `json:"inSync"`
+	BestHeight           uint32                       `json:"bestHeight"`
+	LastBlockTime        time.Time                    `json:"lastBlockTime"`
+	InSyncMempool        bool                         `json:"inSyncMempool"`
+	LastMempoolTime      time.Time                    `json:"lastMempoolTime"`
+	MempoolSize          int                          `json:"mempoolSize"`
+	Decimals             int                          `json:"decimals"`
+	DbSize               int64                        `json:"dbSize"`
+	DbSizeFromColumns    int64                        `json:"dbSizeFromColumns,omitempty"`
+	DbColumns            []common.InternalStateColumn `json:"dbColumns,omitempty"`
+	ChainWorkWarning     string                       `json:"chainWorkWarning,omitempty"`
+	BackendSyncETA       int64                        `json:"backendSyncEta,omitempty"`
+	LastCompaction       *time.Time                   `json:"lastCompaction,omitempty"`
+	NoAddressIndex       bool                         `json:"noAddressIndex,omitempty"`
+	TotalTxs             uint64                       `json:"totalTxs,omitempty"`
+	WebsocketConnections int                          `json:"websocketConnections,omitempty"`
+	About                string                       `json:"about"`
 }
 
 // SystemInfo contains information about the running blockbook and backend instance
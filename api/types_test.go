@@ -49,3 +49,84 @@ func TestAmount_MarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func bigIntFromString(s string) *big.Int {
+	b, _ := big.NewInt(0).SetString(s, 10)
+	return b
+}
+
+func TestNewDenominatedAmount(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *Amount
+		d    int
+		want string
+	}{
+		{
+			name: "nil",
+			a:    nil,
+			d:    8,
+			want: `{"sat":"0","value":"0.00000000"}`,
+		},
+		{
+			name: "zero",
+			a:    (*Amount)(big.NewInt(0)),
+			d:    8,
+			want: `{"sat":"0","value":"0.00000000"}`,
+		},
+		{
+			name: "1 sat",
+			a:    (*Amount)(big.NewInt(1)),
+			d:    8,
+			want: `{"sat":"1","value":"0.00000001"}`,
+		},
+		{
+			name: "whole coins",
+			a:    (*Amount)(big.NewInt(2100000000)),
+			d:    8,
+			want: `{"sat":"2100000000","value":"21.00000000"}`,
+		},
+		{
+			name: "trailing zeros",
+			a:    (*Amount)(big.NewInt(123450000)),
+			d:    8,
+			want: `{"sat":"123450000","value":"1.23450000"}`,
+		},
+		{
+			name: "above float64 precision",
+			a:    (*Amount)(bigIntFromString("209999999999999999")),
+			d:    8,
+			want: `{"sat":"209999999999999999","value":"2099999999.99999999"}`,
+		},
+		{
+			name: "huge",
+			a:    (*Amount)(bigIntFromString("123456789012345678901234567890")),
+			d:    18,
+			want: `{"sat":"123456789012345678901234567890","value":"123456789012.345678901234567890"}`,
+		},
+		{
+			name: "negative",
+			a:    (*Amount)(big.NewInt(-12345)),
+			d:    8,
+			want: `{"sat":"-12345","value":"-0.00012345"}`,
+		},
+		{
+			name: "no decimals",
+			a:    (*Amount)(big.NewInt(12345)),
+			d:    0,
+			want: `{"sat":"12345","value":"12345"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(NewDenominatedAmount(tt.a, tt.d))
+			if err != nil {
+				t.Errorf("json.Marshal() error = %v", err)
+				return
+			}
+			if string(b) != tt.want {
+				t.Errorf("NewDenominatedAmount() = %v, want %v", string(b), tt.want)
+			}
+		})
+	}
+}
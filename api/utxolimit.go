@@ -0,0 +1,75 @@
+package api
+
+import (
+	"math/big"
+)
+
+// SetUtxoLimit sets the maximum number of utxos returned by the utxo queries, the larger sets are truncated,
+// zero means no limit
+func (w *Worker) SetUtxoLimit(n int) {
+	w.utxoLimit = n
+}
+
+// utxoCollector collects at most limit utxos, the count and the value of all passed utxos are summed
+type utxoCollector struct {
+	utxos            Utxos
+	limit            int
+	minConfirmations int
+	count            int
+	total            big.Int
+}
+
+// newUtxoCollector returns the collector of at most limit utxos with at least minConfirmations confirmations,
+// zero limit means no limit
+func newUtxoCollector(limit int, minConfirmations int) *utxoCollector {
+	return &utxoCollector{
+		utxos:            make(Utxos, 0, 8),
+		limit:            limit,
+		minConfirmations: minConfirmations,
+	}
+}
+
+// newFilterUtxoCollector returns the collector of the utxos selected by the filter, the lower of the limit of the filter
+// and of the server applies
+func (w *Worker) newFilterUtxoCollector(filter *UtxoFilter) *utxoCollector {
+	limit := filter.Limit
+	if w.utxoLimit > 0 && (limit <= 0 || limit > w.utxoLimit) {
+		limit = w.utxoLimit
+	}
+	return newUtxoCollector(limit, filter.MinConfirmations)
+}
+
+// add passes the utxo to the collector, the utxos with fewer than minConfirmations confirmations are skipped
+func (c *utxoCollector) add(u Utxo) {
+	if u.Confirmations < c.minConfirmations {
+		return
+	}
+	c.count++
+	if u.AmountSat != nil {
+		c.total.Add(&c.total, (*big.Int)(u.AmountSat))
+	}
+	if c.limit <= 0 || len(c.utxos) < c.limit {
+		c.utxos = append(c.utxos, u)
+	}
+}
+
+// merge adds the utxos and the summary of the collector o, the utxos are not truncated until limited is called
+func (c *utxoCollector) merge(o *utxoCollector) {
+	c.utxos = append(c.utxos, o.utxos...)
+	c.count += o.count
+	c.total.Add(&c.total, &o.total)
+}
+
+// limited returns the collected utxos truncated to the limit with the summary of all passed utxos
+func (c *utxoCollector) limited() *LimitedUtxos {
+	utxos := c.utxos
+	if c.limit > 0 && len(utxos) > c.limit {
+		utxos = utxos[:c.limit]
+	}
+	return &LimitedUtxos{
+		Utxos:      utxos,
+		Truncated:  c.count > len(utxos),
+		TotalUtxos: c.count,
+		TotalSat:   (*Amount)(&c.total),
+	}
+}
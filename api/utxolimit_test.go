@@ -0,0 +1,73 @@
+// +build unittest
+
+package api
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func Test_utxoCollector(t *testing.T) {
+	utxos := Utxos{
+		{Txid: "a", AmountSat: (*Amount)(big.NewInt(100)), Height: 3, Confirmations: 1},
+		{Txid: "b", AmountSat: (*Amount)(big.NewInt(20)), Height: 2, Confirmations: 2},
+		{Txid: "c", AmountSat: (*Amount)(big.NewInt(3)), Height: 1, Confirmations: 3},
+	}
+	tests := []struct {
+		name             string
+		limit            int
+		minConfirmations int
+		want             string
+	}{
+		{
+			name:  "exceeded",
+			limit: 2,
+			want:  `{"utxos":[{"txid":"a","vout":0,"value":"100","height":3,"confirmations":1},{"txid":"b","vout":0,"value":"20","height":2,"confirmations":2}],"truncated":true,"totalUtxos":3,"totalValue":"123"}`,
+		},
+		{
+			name:  "not exceeded",
+			limit: 3,
+			want:  `{"utxos":[{"txid":"a","vout":0,"value":"100","height":3,"confirmations":1},{"txid":"b","vout":0,"value":"20","height":2,"confirmations":2},{"txid":"c","vout":0,"value":"3","height":1,"confirmations":3}],"truncated":false,"totalUtxos":3,"totalValue":"123"}`,
+		},
+		{
+			name:  "no limit",
+			limit: 0,
+			want:  `{"utxos":[{"txid":"a","vout":0,"value":"100","height":3,"confirmations":1},{"txid":"b","vout":0,"value":"20","height":2,"confirmations":2},{"txid":"c","vout":0,"value":"3","height":1,"confirmations":3}],"truncated":false,"totalUtxos":3,"totalValue":"123"}`,
+		},
+		{
+			name:             "min confirmations",
+			limit:            1,
+			minConfirmations: 2,
+			want:             `{"utxos":[{"txid":"b","vout":0,"value":"20","height":2,"confirmations":2}],"truncated":true,"totalUtxos":2,"totalValue":"23"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newUtxoCollector(tt.limit, tt.minConfirmations)
+			for i := range utxos {
+				c.add(utxos[i])
+			}
+			b, err := json.Marshal(c.limited())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("limited() = %v, want %v", string(b), tt.want)
+			}
+		})
+	}
+	// the merged collectors keep all their utxos until limited is called
+	all := newUtxoCollector(2, 0)
+	for i := range utxos {
+		c := newUtxoCollector(2, 0)
+		c.add(utxos[i])
+		all.merge(c)
+	}
+	if len(all.utxos) != 3 {
+		t.Errorf("merge() = %+v, want 3 utxos", all.utxos)
+	}
+	if r := all.limited(); len(r.Utxos) != 2 || !r.Truncated || r.TotalUtxos != 3 || r.TotalSat.String() != "123" {
+		t.Errorf("limited() = %+v", r)
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+// maxValueTxs is the maximal number of transactions returned in one request
+const maxValueTxs = 1000
+
+// GetBlockValueTxs returns the transactions of the block given by height or hash with the total value of the outputs
+// exceeding threshold (in satoshis), in the order of the block or ordered by the value descending if byValue is set;
+// at most maxValueTxs transactions are returned, the ones with the highest value if ordered by value
+func (w *Worker) GetBlockValueTxs(bid string, threshold string, byValue bool) (*BlockValueTxs, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if threshold == "" {
+		return nil, NewAPIError("Missing parameter 'threshold'", true)
+	}
+	t, ok := new(big.Int).SetString(threshold, 10)
+	if !ok || t.Sign() < 0 {
+		return nil, NewAPIError(fmt.Sprintf("Invalid threshold '%s'", threshold), true)
+	}
+	bi, err := w.getBlockInfoFromBlockID(bid)
+	if err != nil {
+		return nil, err
+	}
+	block, err := w.chain.GetBlock(bi.Hash, bi.Height)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlock %v", bi.Hash)
+	}
+	r := &BlockValueTxs{Hash: bi.Hash, Height: bi.Height, Threshold: (*Amount)(t)}
+	r.Txs, r.Truncated = selectValueTxs(block.Txs, t, byValue, maxValueTxs)
+	return r, nil
+}
+
+// selectValueTxs returns at most limit transactions with the total value of the outputs greater than threshold
+// and true if some transactions were left out
+func selectValueTxs(txs []bchain.Tx, threshold *big.Int, byValue bool, limit int) ([]ValueTx, bool) {
+	r := []ValueTx{}
+	for i := range txs {
+		value := new(big.Int)
+		for j := range txs[i].Vout {
+			value.Add(value, &txs[i].Vout[j].ValueSat)
+		}
+		if value.Cmp(threshold) > 0 {
+			r = append(r, ValueTx{Txid: txs[i].Txid, Value: (*Amount)(value)})
+		}
+	}
+	if byValue {
+		sort.SliceStable(r, func(i, j int) bool { return (*big.Int)(r[i].Value).Cmp((*big.Int)(r[j].Value)) > 0 })
+	}
+	if len(r) > limit {
+		return r[:limit], true
+	}
+	return r, false
+}
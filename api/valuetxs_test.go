@@ -0,0 +1,87 @@
+// +build unittest
+
+package api
+
+import (
+	"blockbook/bchain"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func Test_selectValueTxs(t *testing.T) {
+	newTx := func(txid string, values ...int64) bchain.Tx {
+		tx := bchain.Tx{Txid: txid}
+		for _, v := range values {
+			tx.Vout = append(tx.Vout, bchain.Vout{ValueSat: *big.NewInt(v)})
+		}
+		return tx
+	}
+	txs := []bchain.Tx{
+		newTx("coinbase", 5000000000),
+		newTx("below", 400, 500),
+		// the value equal to the threshold does not exceed it
+		newTx("equal", 600, 400),
+		newTx("above", 700, 400),
+		newTx("many outputs", 300, 300, 300, 300),
+		newTx("no outputs"),
+	}
+	valueTxs := func(txids []string, values []int64) []ValueTx {
+		r := []ValueTx{}
+		for i := range txids {
+			r = append(r, ValueTx{Txid: txids[i], Value: (*Amount)(big.NewInt(values[i]))})
+		}
+		return r
+	}
+	tests := []struct {
+		name          string
+		threshold     int64
+		byValue       bool
+		limit         int
+		want          []ValueTx
+		wantTruncated bool
+	}{
+		{
+			name:      "block order",
+			threshold: 1000,
+			limit:     10,
+			want:      valueTxs([]string{"coinbase", "above", "many outputs"}, []int64{5000000000, 1100, 1200}),
+		},
+		{
+			name:      "by value",
+			threshold: 1000,
+			byValue:   true,
+			limit:     10,
+			want:      valueTxs([]string{"coinbase", "many outputs", "above"}, []int64{5000000000, 1200, 1100}),
+		},
+		{
+			name:          "by value truncated",
+			threshold:     1000,
+			byValue:       true,
+			limit:         2,
+			want:          valueTxs([]string{"coinbase", "many outputs"}, []int64{5000000000, 1200}),
+			wantTruncated: true,
+		},
+		{
+			name:          "block order truncated",
+			threshold:     0,
+			limit:         3,
+			want:          valueTxs([]string{"coinbase", "below", "equal"}, []int64{5000000000, 900, 1000}),
+			wantTruncated: true,
+		},
+		{
+			name:      "none above",
+			threshold: 5000000000,
+			limit:     10,
+			want:      []ValueTx{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := selectValueTxs(txs, big.NewInt(tt.threshold), tt.byValue, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) || truncated != tt.wantTruncated {
+				t.Errorf("selectValueTxs() = %+v, %v, want %+v, %v", got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
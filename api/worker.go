@@ -6,14 +6,20 @@ import (
 	"blockbook/common"
 	"blockbook/db"
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math/big"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcutil/txscript"
 )
 
 // Worker is handle to api worker
@@ -25,6 +31,25 @@ type Worker struct {
 	chainType   bchain.ChainType
 	mempool     bchain.Mempool
 	is          *common.InternalState
+	labels      *AddressLabels
+	// balancesConcurrency is the number of addresses of a bulk balances request resolved in parallel
+	balancesConcurrency int
+	// feeStatsBlocks is the default number of the last blocks from which the fee rate percentiles are computed
+	feeStatsBlocks int
+	feeStatsMux    sync.Mutex
+	feeStatsCache  feeStatsCache
+	// stuckTxPercentile is the percentile of the fee rates of the last blocks below which a mempool transaction is likely stuck
+	stuckTxPercentile int
+	stuckTxsCache     stuckTxsCache
+	// xpubMaxAddresses is the maximum number of addresses derived from one xpub on both chains together
+	xpubMaxAddresses int
+	// confirmationPolicy and fiatRates suggest the confirmations required for the value of an output
+	confirmationPolicy *ConfirmationPolicy
+	fiatRates          FiatRateProvider
+	// refuseInIBD refuses the address queries while the backend is in the initial block download
+	refuseInIBD bool
+	// utxoLimit is the maximum number of utxos returned by the utxo queries, zero means no limit
+	utxoLimit int
 }
 
 // NewWorker creates new api worker
@@ -41,6 +66,11 @@ func NewWorker(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool,
 	return w, nil
 }
 
+// SetAddressLabels sets the store of labels attached to the returned addresses
+func (w *Worker) SetAddressLabels(labels *AddressLabels) {
+	w.labels = labels
+}
+
 func (w *Worker) getAddressesFromVout(vout *bchain.Vout) (bchain.AddressDescriptor, []string, bool, error) {
 	addrDesc, err := w.chainParser.GetAddrDescFromVout(vout)
 	if err != nil {
@@ -53,7 +83,27 @@ func (w *Worker) getAddressesFromVout(vout *bchain.Vout) (bchain.AddressDescript
 // setSpendingTxToVout is helper function, that finds transaction that spent given output and sets it to the output
 // there is no direct index for the operation, it must be found using addresses -> txaddresses -> tx
 func (w *Worker) setSpendingTxToVout(vout *Vout, txid string, height uint32) error {
-	err := w.db.GetAddrDescTransactions(vout.AddrDesc, height, maxUint32, func(t string, height uint32, indexes []int32) error {
+	spentTxid, spentIndex, spentHeight, err := w.findSpendingTx(vout.AddrDesc, (*big.Int)(vout.ValueSat), txid, height)
+	if err != nil {
+		return err
+	}
+	if spentTxid != "" {
+		vout.SpentTxID = spentTxid
+		vout.SpentHeight = spentHeight
+		vout.SpentIndex = spentIndex
+	}
+	return nil
+}
+
+// findSpendingTx finds the confirmed transaction spending the output of transaction txid with given address and value,
+// returns empty string if the spending transaction is not found
+func (w *Worker) findSpendingTx(addrDesc bchain.AddressDescriptor, value *big.Int, txid string, height uint32) (string, int, int, error) {
+	if err := w.checkSpendIndex(); err != nil {
+		return "", 0, 0, err
+	}
+	var spentTxid string
+	var spentIndex, spentHeight int
+	err := w.db.GetAddrDescTransactions(addrDesc, height, maxUint32, func(t string, height uint32, indexes []int32) error {
 		for _, index := range indexes {
 			// take only inputs
 			if index < 0 {
@@ -64,16 +114,16 @@ func (w *Worker) setSpendingTxToVout(vout *Vout, txid string, height uint32) err
 				} else if tsp == nil {
 					glog.Warning("DB inconsistency:  tx ", t, ": not found in txAddresses")
 				} else if len(tsp.Inputs) > int(index) {
-					if tsp.Inputs[index].ValueSat.Cmp((*big.Int)(vout.ValueSat)) == 0 {
-						spentTx, spentHeight, err := w.txCache.GetTransaction(t)
+					if tsp.Inputs[index].ValueSat.Cmp(value) == 0 {
+						spentTx, h, err := w.txCache.GetTransaction(t)
 						if err != nil {
 							glog.Warning("Tx ", t, ": not found")
 						} else {
 							if len(spentTx.Vin) > int(index) {
 								if spentTx.Vin[index].Txid == txid {
-									vout.SpentTxID = t
-									vout.SpentHeight = int(spentHeight)
-									vout.SpentIndex = int(index)
+									spentTxid = t
+									spentHeight = int(h)
+									spentIndex = int(index)
 									return &db.StopIteration{}
 								}
 							}
@@ -84,7 +134,83 @@ func (w *Worker) setSpendingTxToVout(vout *Vout, txid string, height uint32) err
 		}
 		return nil
 	})
-	return err
+	return spentTxid, spentIndex, spentHeight, err
+}
+
+// GetTxSpendStatus returns for each output of the transaction if and by which transaction input it is spent,
+// the spends by mempool transactions are included, OP_RETURN outputs are marked as unspendable
+func (w *Worker) GetTxSpendStatus(txid string) ([]OutputSpendStatus, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if err := w.checkSpendIndex(); err != nil {
+		return nil, err
+	}
+	ta, err := w.db.GetTxAddresses(txid)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid txid '%v', %v", txid, err), true)
+	}
+	var rv []OutputSpendStatus
+	if ta != nil {
+		rv = make([]OutputSpendStatus, len(ta.Outputs))
+		for i := range ta.Outputs {
+			if err = w.setIndexedOutputSpend(txid, ta, i, &rv[i]); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// the outputs of a mempool transaction can be spent only by other mempool transactions
+		tx, err := w.GetTransaction(txid, false, false)
+		if err != nil {
+			return nil, err
+		}
+		rv = make([]OutputSpendStatus, len(tx.Vout))
+		for i := range tx.Vout {
+			s := &rv[i]
+			s.N = i
+			if isUnspendable(tx.Vout[i].AddrDesc) {
+				s.Unspendable = true
+			} else {
+				w.setMempoolSpend(txid, s)
+			}
+		}
+	}
+	return rv, nil
+}
+
+// setIndexedOutputSpend sets the spend status of the output n of the indexed transaction ta with given txid,
+// the spending transaction is not set if the blocks were indexed without the address index
+func (w *Worker) setIndexedOutputSpend(txid string, ta *db.TxAddresses, n int, s *OutputSpendStatus) error {
+	o := &ta.Outputs[n]
+	s.N = n
+	if isUnspendable(o.AddrDesc) {
+		s.Unspendable = true
+	} else if o.Spent {
+		var err error
+		s.Spent = true
+		// the spent flag is stored with the output, the spending transaction is found only by the address index
+		if w.checkSpendIndex() != nil {
+			return nil
+		}
+		s.SpentTxID, s.SpentIndex, s.SpentHeight, err = w.findSpendingTx(o.AddrDesc, &o.ValueSat, txid, ta.Height)
+		return err
+	} else {
+		w.setMempoolSpend(txid, s)
+	}
+	return nil
+}
+
+func (w *Worker) setMempoolSpend(txid string, s *OutputSpendStatus) {
+	if spentTxid, spentIndex := w.mempool.GetSpendingTx(bchain.Outpoint{Txid: txid, Vout: int32(s.N)}); spentTxid != "" {
+		s.Spent = true
+		s.SpentTxID = spentTxid
+		s.SpentIndex = spentIndex
+	}
+}
+
+// isUnspendable returns true for the provably unspendable OP_RETURN outputs
+func isUnspendable(addrDesc bchain.AddressDescriptor) bool {
+	return len(addrDesc) > 0 && addrDesc[0] == txscript.OP_RETURN
 }
 
 // GetSpendingTxid returns transaction id of transaction that spent given output
@@ -110,6 +236,15 @@ func (w *Worker) GetTransaction(txid string, spendingTxs bool, specificJSON bool
 	bchainTx, height, err := w.txCache.GetTransaction(txid)
 	if err != nil {
 		if err == bchain.ErrTxNotFound {
+			// the transaction dropped from the mempool because of a confirmed conflicting transaction is not known to the backend
+			if replacedBy := w.mempool.GetReplacedBy(txid); replacedBy != "" {
+				return &Tx{
+					Txid:       txid,
+					Vin:        []Vin{},
+					Vout:       []Vout{},
+					ReplacedBy: replacedBy,
+				}, nil
+			}
 			return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found", txid), true)
 		}
 		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found (%v)", txid, err), true)
@@ -117,27 +252,201 @@ func (w *Worker) GetTransaction(txid string, spendingTxs bool, specificJSON bool
 	return w.GetTransactionFromBchainTx(bchainTx, height, spendingTxs, specificJSON)
 }
 
+// SetTxIndexInBlock sets the index of the confirmed transaction in its block, the coinbase transaction has index 0
+// the txids of the block are read from the index for the recent blocks, for the older blocks from the backend only if fromBackend is set;
+// the index is omitted if it cannot be determined, the errors are only logged
+func (w *Worker) SetTxIndexInBlock(tx *Tx, fromBackend bool) {
+	if tx.Confirmations == 0 || tx.Blockhash == "" {
+		return
+	}
+	txids, err := w.db.GetBlockTxids(uint32(tx.Blockheight))
+	if err != nil {
+		glog.Error("GetBlockTxids ", tx.Blockheight, ": ", err)
+		return
+	}
+	if txids == nil {
+		if !fromBackend {
+			return
+		}
+		bi, err := w.chain.GetBlockInfo(tx.Blockhash)
+		if err != nil {
+			glog.Warning("GetBlockInfo ", tx.Blockhash, ": ", err)
+			return
+		}
+		txids = bi.Txids
+	}
+	for i := range txids {
+		if txids[i] == tx.Txid {
+			tx.TxIndexInBlock = &i
+			return
+		}
+	}
+	glog.Warning("Tx ", tx.Txid, " not found in block ", tx.Blockhash)
+}
+
+// SetTxHex makes sure that the transaction contains its raw hex, if the hex is not known, it is downloaded from the backend;
+// the hex is verified to hash to the txid of the transaction
+func (w *Worker) SetTxHex(tx *Tx) error {
+	if w.chainType != bchain.ChainBitcoinType {
+		return NewAPIError("Not supported", true)
+	}
+	if tx.Hex == "" {
+		btx, err := w.chain.GetTransaction(tx.Txid)
+		if err != nil {
+			return errors.Annotatef(err, "GetTransaction %v", tx.Txid)
+		}
+		tx.Hex = btx.Hex
+	}
+	if tx.Hex == "" {
+		return NewAPIError(fmt.Sprintf("Raw data of transaction %v not available", tx.Txid), true)
+	}
+	b, err := hex.DecodeString(tx.Hex)
+	if err != nil {
+		return errors.Annotatef(err, "Invalid hex of tx %v", tx.Txid)
+	}
+	ptx, err := w.chainParser.ParseTx(b)
+	if err != nil {
+		return errors.Annotatef(err, "ParseTx %v", tx.Txid)
+	}
+	if ptx.Txid != tx.Txid {
+		return errors.Errorf("Hex of tx %v hashes to txid %v", tx.Txid, ptx.Txid)
+	}
+	return nil
+}
+
+// SetTxAsm sets the disassembled scripts of the inputs and outputs of the transaction,
+// the scripts which cannot be fully disassembled are returned up to the error marked by [error]
+func (w *Worker) SetTxAsm(tx *Tx) error {
+	if w.chainType != bchain.ChainBitcoinType {
+		return NewAPIError("Not supported", true)
+	}
+	disassemble := func(h string) (string, error) {
+		script, err := hex.DecodeString(h)
+		if err != nil {
+			return "", errors.Annotatef(err, "Invalid script of tx %v", tx.Txid)
+		}
+		asm, err := w.chainParser.DisassembleScript(script)
+		if err != nil {
+			// partial asm is returned for malformed scripts, no asm means that the disassembly is not supported
+			if asm == "" {
+				return "", NewAPIError("Script disassembly not supported", true)
+			}
+			glog.Warningf("DisassembleScript tx %v: %v", tx.Txid, err)
+		}
+		return asm, nil
+	}
+	var err error
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		// the coinbase input does not have script
+		if vin.Coinbase == "" && vin.Hex != "" {
+			if vin.Asm, err = disassemble(vin.Hex); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range tx.Vout {
+		vout := &tx.Vout[i]
+		if vout.Hex != "" {
+			if vout.Asm, err = disassemble(vout.Hex); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetChainStats returns the snapshot of the state of the chain obtained from the backend
+func (w *Worker) GetChainStats() (*ChainStats, error) {
+	cs, err := w.chain.GetChainStats()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetChainStats")
+	}
+	return &ChainStats{
+		BestHeight:   cs.BestHeight,
+		BestHash:     cs.BestHash,
+		BestTime:     cs.BestTime,
+		MempoolSize:  cs.MempoolSize,
+		MempoolBytes: cs.MempoolBytes,
+		Difficulty:   cs.Difficulty,
+		Connections:  cs.Connections,
+		SupplySat:    (*Amount)(cs.Supply),
+	}, nil
+}
+
+// HasTransaction checks whether the transaction is confirmed in the index or is in the mempool,
+// the transaction itself is not downloaded from the backend
+func (w *Worker) HasTransaction(txid string) (*TxStatus, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	ta, err := w.db.GetTxAddresses(txid)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid txid '%v', %v", txid, err), true)
+	}
+	if ta != nil {
+		bestheight, _, err := w.db.GetBestBlock()
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBestBlock")
+		}
+		return &TxStatus{
+			Txid:          txid,
+			Status:        TxStatusConfirmed,
+			Height:        ta.Height,
+			Confirmations: bestheight - ta.Height + 1,
+		}, nil
+	}
+	if w.mempool.GetTransactionTime(txid) != 0 {
+		return &TxStatus{
+			Txid:   txid,
+			Status: TxStatusUnconfirmed,
+		}, nil
+	}
+	return &TxStatus{
+		Txid:   txid,
+		Status: TxStatusUnknown,
+	}, nil
+}
+
 // GetTransactionFromBchainTx reads transaction data from txid
 func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height uint32, spendingTxs bool, specificJSON bool) (*Tx, error) {
+	if spendingTxs {
+		if err := w.checkSpendIndex(); err != nil {
+			return nil, err
+		}
+	}
 	var err error
 	var ta *db.TxAddresses
 	var tokens []TokenTransfer
 	var ethSpecific *EthereumSpecific
 	var blockhash string
+	var block *TxBlock
 	if bchainTx.Confirmations > 0 {
 		if w.chainType == bchain.ChainBitcoinType {
 			ta, err = w.db.GetTxAddresses(bchainTx.Txid)
 			if err != nil {
 				return nil, errors.Annotatef(err, "GetTxAddresses %v", bchainTx.Txid)
 			}
-		}
-		blockhash, err = w.db.GetBlockHash(height)
-		if err != nil {
-			return nil, errors.Annotatef(err, "GetBlockHash %v", height)
+			// the block details are taken from the index, the block may be not yet indexed
+			bi, err := w.db.GetBlockInfo(height)
+			if err != nil {
+				return nil, errors.Annotatef(err, "GetBlockInfo %v", height)
+			}
+			if bi != nil {
+				blockhash = bi.Hash
+				block = &TxBlock{Hash: bi.Hash, Height: height, Time: bi.Time}
+			}
+		} else {
+			blockhash, err = w.db.GetBlockHash(height)
+			if err != nil {
+				return nil, errors.Annotatef(err, "GetBlockHash %v", height)
+			}
 		}
 	}
 	var valInSat, valOutSat, feesSat big.Int
 	var pValInSat *big.Int
+	useTaInputs := ta != nil && len(ta.Inputs) == len(bchainTx.Vin)
+	inputs := newInputsResolver(w)
 	vins := make([]Vin, len(bchainTx.Vin))
 	for i := range bchainTx.Vin {
 		bchainVin := &bchainTx.Vin[i]
@@ -151,48 +460,17 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height uint32,
 		if w.chainType == bchain.ChainBitcoinType {
 			//  bchainVin.Txid=="" is coinbase transaction
 			if bchainVin.Txid != "" {
-				// load spending addresses from TxAddresses
-				tas, err := w.db.GetTxAddresses(bchainVin.Txid)
-				if err != nil {
-					return nil, errors.Annotatef(err, "GetTxAddresses %v", bchainVin.Txid)
-				}
-				if tas == nil {
-					// try to load from backend
-					otx, _, err := w.txCache.GetTransaction(bchainVin.Txid)
+				if useTaInputs && len(ta.Inputs[i].AddrDesc) > 0 {
+					// the spent outputs of an indexed transaction are stored with it
+					input := &ta.Inputs[i]
+					vin.ValueSat = (*Amount)(&input.ValueSat)
+					vin.AddrDesc = input.AddrDesc
+					vin.Addresses, vin.Searchable, err = input.Addresses(w.chainParser)
 					if err != nil {
-						if err == bchain.ErrTxNotFound {
-							// try to get AddrDesc using coin specific handling and continue processing the tx
-							vin.AddrDesc = w.chainParser.GetAddrDescForUnknownInput(bchainTx, i)
-							vin.Addresses, vin.Searchable, err = w.chainParser.GetAddressesFromAddrDesc(vin.AddrDesc)
-							if err != nil {
-								glog.Warning("GetAddressesFromAddrDesc tx ", bchainVin.Txid, ", addrDesc ", vin.AddrDesc, ": ", err)
-							}
-							continue
-						}
-						return nil, errors.Annotatef(err, "txCache.GetTransaction %v", bchainVin.Txid)
-					}
-					// mempool transactions are not in TxAddresses but confirmed should be there, log a problem
-					if bchainTx.Confirmations > 0 {
-						glog.Warning("DB inconsistency:  tx ", bchainVin.Txid, ": not found in txAddresses")
-					}
-					if len(otx.Vout) > int(vin.Vout) {
-						vout := &otx.Vout[vin.Vout]
-						vin.ValueSat = (*Amount)(&vout.ValueSat)
-						vin.AddrDesc, vin.Addresses, vin.Searchable, err = w.getAddressesFromVout(vout)
-						if err != nil {
-							glog.Errorf("getAddressesFromVout error %v, vout %+v", err, vout)
-						}
-					}
-				} else {
-					if len(tas.Outputs) > int(vin.Vout) {
-						output := &tas.Outputs[vin.Vout]
-						vin.ValueSat = (*Amount)(&output.ValueSat)
-						vin.AddrDesc = output.AddrDesc
-						vin.Addresses, vin.Searchable, err = output.Addresses(w.chainParser)
-						if err != nil {
-							glog.Errorf("output.Addresses error %v, tx %v, output %v", err, bchainVin.Txid, i)
-						}
+						glog.Errorf("input.Addresses error %v, tx %v, input %v", err, bchainTx.Txid, i)
 					}
+				} else if err = inputs.resolve(bchainTx, i, vin); err != nil {
+					return nil, err
 				}
 				if vin.ValueSat != nil {
 					valInSat.Add(&valInSat, (*big.Int)(vin.ValueSat))
@@ -295,15 +573,40 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height uint32,
 		}
 	}
 	// for mempool transaction get first seen time
+	final := true
+	var finalHeight uint32
+	var finalTime int64
+	var feeRate *float64
 	if bchainTx.Confirmations == 0 {
 		bchainTx.Blocktime = int64(w.mempool.GetTransactionTime(bchainTx.Txid))
+		if w.chainType == bchain.ChainBitcoinType {
+			final, finalHeight, finalTime, err = w.checkFinalTx(bchainTx)
+			if err != nil {
+				return nil, err
+			}
+			// without the values of the inputs the fee is not known
+			var fee *big.Int
+			if pValInSat != nil {
+				fee = &feesSat
+			}
+			feeRate = w.mempoolFeeRate(bchainTx, fee)
+		}
+	}
+	var funding []string
+	if w.chainType == bchain.ChainBitcoinType {
+		funding = fundingAddresses(vins)
 	}
 	r := &Tx{
 		Blockhash:        blockhash,
 		Blockheight:      int(height),
 		Blocktime:        bchainTx.Blocktime,
+		Block:            block,
 		Confirmations:    bchainTx.Confirmations,
 		FeesSat:          (*Amount)(&feesSat),
+		FeeRate:          feeRate,
+		NonFinal:         !final,
+		FinalHeight:      finalHeight,
+		FinalTime:        finalTime,
 		Locktime:         bchainTx.LockTime,
 		Txid:             bchainTx.Txid,
 		ValueInSat:       (*Amount)(pValInSat),
@@ -311,6 +614,7 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height uint32,
 		Version:          bchainTx.Version,
 		Hex:              bchainTx.Hex,
 		Vin:              vins,
+		FundingAddresses: funding,
 		Vout:             vouts,
 		CoinSpecificData: bchainTx.CoinSpecificData,
 		CoinSpecificJSON: sj,
@@ -320,6 +624,53 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height uint32,
 	return r, nil
 }
 
+// mempoolFeeRate returns the fee rate of the mempool transaction in satoshis per byte, primarily from the mempool entry
+// of the backend, which accounts for the prioritisation and the unconfirmed ancestors of the transaction;
+// if the entry is not available, the rate is computed from the fee and size, nil if the fee or the size is not known
+func (w *Worker) mempoolFeeRate(tx *bchain.Tx, fee *big.Int) *float64 {
+	e, err := w.chain.GetMempoolEntry(tx.Txid)
+	if err == nil {
+		if r, ok := e.MiningFeeRate(); ok {
+			return &r
+		}
+	} else {
+		glog.V(1).Info("GetMempoolEntry ", tx.Txid, ": ", err)
+	}
+	if fee == nil {
+		return nil
+	}
+	size := txSize(tx)
+	if size == 0 {
+		return nil
+	}
+	r, _ := new(big.Float).Quo(new(big.Float).SetInt(fee), big.NewFloat(float64(size))).Float64()
+	return &r
+}
+
+// checkFinalTx checks if the mempool transaction can be included in the next block, relative to the best block in db
+// the time lock is compared with the median time past of the best block (BIP113), it is taken from the backend if supported,
+// otherwise it is computed from the times of the last blocks in db
+func (w *Worker) checkFinalTx(bchainTx *bchain.Tx) (bool, uint32, int64, error) {
+	if bchainTx.LockTime == 0 {
+		return true, 0, 0, nil
+	}
+	bestheight, besthash, err := w.db.GetBestBlock()
+	if err != nil {
+		return false, 0, 0, errors.Annotatef(err, "GetBestBlock")
+	}
+	var medianTime int64
+	if bchainTx.LockTime >= bchain.LockTimeThreshold {
+		if medianTime, err = w.chain.GetMedianTimePast(besthash); err != nil {
+			glog.V(1).Info("GetMedianTimePast ", besthash, ": ", err)
+			if medianTime, _, err = medianTimePast(bestheight, w.indexedBlockTime); err != nil {
+				return false, 0, 0, err
+			}
+		}
+	}
+	final, finalHeight, finalTime := bchain.IsFinalTx(bchainTx, bestheight, medianTime)
+	return final, finalHeight, finalTime, nil
+}
+
 func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool, filter *AddressFilter, maxResults int) ([]string, error) {
 	var err error
 	txids := make([]string, 0, 4)
@@ -352,12 +703,20 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 			return nil
 		}
 	}
+	if filter.Coinbase != CoinbaseFilterOff {
+		callback = w.coinbaseFilterCallback(filter.Coinbase, callback)
+	}
 	if mempool {
 		uniqueTxs := make(map[string]struct{})
 		o, err := w.mempool.GetAddrDescTransactions(addrDesc)
 		if err != nil {
 			return nil, err
 		}
+		if filter.Ascending {
+			for i, j := 0, len(o)-1; i < j; i, j = i+1, j-1 {
+				o[i], o[j] = o[j], o[i]
+			}
+		}
 		for _, m := range o {
 			if _, found := uniqueTxs[m.Txid]; !found {
 				l := len(txids)
@@ -372,7 +731,11 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 		if to == 0 {
 			to = maxUint32
 		}
-		err = w.db.GetAddrDescTransactions(addrDesc, filter.FromHeight, to, callback)
+		if filter.Ascending {
+			err = w.db.GetAddrDescTransactionsAscending(addrDesc, filter.FromHeight, to, callback)
+		} else {
+			err = w.db.GetAddrDescTransactions(addrDesc, filter.FromHeight, to, callback)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -380,6 +743,25 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 	return txids, nil
 }
 
+// coinbaseFilterCallback wraps the callback to pass only the transactions matching the coinbase filter
+func (w *Worker) coinbaseFilterCallback(coinbase CoinbaseFilter, callback db.GetTransactionsCallback) db.GetTransactionsCallback {
+	return func(txid string, height uint32, indexes []int32) error {
+		isCoinbase := false
+		// mempool transactions are never coinbase
+		if height > 0 {
+			ta, err := w.db.GetTxAddresses(txid)
+			if err != nil {
+				return err
+			}
+			isCoinbase = ta != nil && ta.Coinbase
+		}
+		if isCoinbase != (coinbase == CoinbaseFilterOnly) {
+			return nil
+		}
+		return callback(txid, height, indexes)
+	}
+}
+
 func (t *Tx) getAddrVoutValue(addrDesc bchain.AddressDescriptor) *big.Int {
 	var val big.Int
 	for _, vout := range t.Vout {
@@ -450,20 +832,74 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 		feesSat.SetUint64(0)
 	}
 	r := &Tx{
-		Blockhash:     bi.Hash,
-		Blockheight:   int(ta.Height),
-		Blocktime:     bi.Time,
-		Confirmations: bestheight - ta.Height + 1,
-		FeesSat:       (*Amount)(&feesSat),
-		Txid:          txid,
-		ValueInSat:    (*Amount)(&valInSat),
-		ValueOutSat:   (*Amount)(&valOutSat),
-		Vin:           vins,
-		Vout:          vouts,
+		Blockhash:        bi.Hash,
+		Blockheight:      int(ta.Height),
+		Blocktime:        bi.Time,
+		Block:            &TxBlock{Hash: bi.Hash, Height: ta.Height, Time: bi.Time},
+		Confirmations:    bestheight - ta.Height + 1,
+		FeesSat:          (*Amount)(&feesSat),
+		Txid:             txid,
+		ValueInSat:       (*Amount)(&valInSat),
+		ValueOutSat:      (*Amount)(&valOutSat),
+		Vin:              vins,
+		FundingAddresses: fundingAddresses(vins),
+		Vout:             vouts,
 	}
 	return r
 }
 
+// GetTxMetadata returns compact information about the transaction, for confirmed transactions of Bitcoin type coins
+// the data are taken from the txAddresses index without downloading the transaction from the backend
+func (w *Worker) GetTxMetadata(txid string) (*TxMetadata, error) {
+	if w.chainType == bchain.ChainBitcoinType {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return nil, NewAPIError(fmt.Sprintf("Invalid txid '%v', %v", txid, err), true)
+		}
+		if ta != nil {
+			bestheight, _, err := w.db.GetBestBlock()
+			if err != nil {
+				return nil, errors.Annotatef(err, "GetBestBlock")
+			}
+			bi, err := w.db.GetBlockInfo(ta.Height)
+			if err != nil {
+				return nil, errors.Annotatef(err, "GetBlockInfo %v", ta.Height)
+			}
+			if bi == nil {
+				glog.Warning("DB inconsistency:  block height ", ta.Height, ": not found in db")
+				bi = &db.BlockInfo{}
+			}
+			var valOutSat big.Int
+			for i := range ta.Outputs {
+				valOutSat.Add(&valOutSat, &ta.Outputs[i].ValueSat)
+			}
+			return &TxMetadata{
+				Txid:          txid,
+				Blockheight:   int(ta.Height),
+				Confirmations: bestheight - ta.Height + 1,
+				Blocktime:     bi.Time,
+				VinCount:      len(ta.Inputs),
+				VoutCount:     len(ta.Outputs),
+				ValueOutSat:   (*Amount)(&valOutSat),
+			}, nil
+		}
+	}
+	// mempool transactions and other coin types require the full transaction
+	tx, err := w.GetTransaction(txid, false, false)
+	if err != nil {
+		return nil, err
+	}
+	return &TxMetadata{
+		Txid:          tx.Txid,
+		Blockheight:   tx.Blockheight,
+		Confirmations: tx.Confirmations,
+		Blocktime:     tx.Blocktime,
+		VinCount:      len(tx.Vin),
+		VoutCount:     len(tx.Vout),
+		ValueOutSat:   tx.ValueOutSat,
+	}, nil
+}
+
 func computePaging(count, page, itemsOnPage int) (Paging, int, int, int) {
 	from := page * itemsOnPage
 	totalPages := (count - 1) / itemsOnPage
@@ -485,6 +921,26 @@ func computePaging(count, page, itemsOnPage int) (Paging, int, int, int) {
 	}, from, to, page
 }
 
+// computeAscendingPaging computes the paging of the confirmed transactions in the ascending order followed by the unconfirmed transactions,
+// loaded is the number of the loaded confirmed transactions, which are all of them if it is lower than maxResults,
+// totalResults is the number of all confirmed transactions or -1 if it is not known
+func computeAscendingPaging(loaded, maxResults, totalResults, unconfirmed, page, itemsOnPage int) (Paging, int, int, int) {
+	count := loaded
+	// the unconfirmed transactions can be on the page only if all confirmed transactions are loaded
+	if loaded < maxResults || (totalResults >= 0 && loaded >= totalResults) {
+		count += unconfirmed
+	}
+	pg, from, to, page := computePaging(count, page, itemsOnPage)
+	if count >= itemsOnPage {
+		if totalResults < 0 {
+			pg.TotalPages = -1
+		} else {
+			pg, _, _, _ = computePaging(totalResults+unconfirmed, page, itemsOnPage)
+		}
+	}
+	return pg, from, to, page
+}
+
 func (w *Worker) getEthereumTypeAddressBalances(addrDesc bchain.AddressDescriptor, details AccountDetails, filter *AddressFilter) (*db.AddrBalance, []Token, *bchain.Erc20Contract, uint64, int, int, error) {
 	var (
 		ba             *db.AddrBalance
@@ -629,24 +1085,115 @@ func (w *Worker) txFromTxid(txid string, bestheight uint32, option AccountDetail
 	return tx, nil
 }
 
+// checkSpendIndex returns error if the blocks were indexed without the address index,
+// the spending transactions are found using the address index
+func (w *Worker) checkSpendIndex() error {
+	if w.is != nil && w.is.NoAddressIndex {
+		return NewAPIError("Address index disabled", true)
+	}
+	return nil
+}
+
+// checkAddressIndex returns error if the blocks were indexed without the address index and the addresses cannot be served
+// or if the queries are refused while the backend is in the initial block download
+func (w *Worker) checkAddressIndex() error {
+	if err := w.checkSpendIndex(); err != nil {
+		return err
+	}
+	if w.refuseInIBD && w.is != nil && w.is.IsBackendInitialBlockDownload() {
+		return NewAPIError("Backend is in initial block download, the address data is incomplete", true)
+	}
+	return nil
+}
+
+// SetRefuseQueriesInInitialBlockDownload sets whether the address queries are refused while the backend is in the initial block download
+func (w *Worker) SetRefuseQueriesInInitialBlockDownload(refuse bool) {
+	w.refuseInIBD = refuse
+}
+
 func (w *Worker) getAddrDescAndNormalizeAddress(address string) (bchain.AddressDescriptor, string, error) {
 	addrDesc, err := w.chainParser.GetAddrDescFromAddress(address)
 	if err != nil {
 		return nil, "", NewAPIError(fmt.Sprintf("Invalid address, %v", err), true)
 	}
-	// convert the address to the format defined by the parser
+	return addrDesc, w.normalizeAddrDesc(addrDesc, address), nil
+}
+
+// normalizeAddrDesc converts the address with the descriptor addrDesc to the format defined by the parser,
+// the address is returned unchanged if the conversion fails
+func (w *Worker) normalizeAddrDesc(addrDesc bchain.AddressDescriptor, address string) string {
 	addresses, _, err := w.chainParser.GetAddressesFromAddrDesc(addrDesc)
 	if err != nil {
 		glog.V(2).Infof("GetAddressesFromAddrDesc error %v, %v", err, addrDesc)
 	}
 	if len(addresses) == 1 {
-		address = addresses[0]
+		return addresses[0]
+	}
+	return address
+}
+
+// NormalizeAddresses returns the addresses in the format defined by the parser, which is the format used in all responses,
+// for example the default format set by address_format for the coins with CashAddr and legacy addresses;
+// the addresses which cannot be parsed are returned unchanged
+func (w *Worker) NormalizeAddresses(addresses []string) []string {
+	r := make([]string, len(addresses))
+	for i, a := range addresses {
+		r[i] = a
+		if addrDesc, err := w.chainParser.GetAddrDescFromAddress(a); err == nil {
+			r[i] = w.normalizeAddrDesc(addrDesc, a)
+		}
 	}
-	return addrDesc, address, nil
+	return r
 }
 
 // GetAddress computes address value and gets transactions for given address
 func (w *Worker) GetAddress(address string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter) (*Address, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return w.getAddrDescAddress(addrDesc, address, page, txsOnPage, option, filter)
+}
+
+// getAddrDescFromScriptHash finds the output script by its hash, the hash is sha256 of the script in the reversed byte order
+func (w *Worker) getAddrDescFromScriptHash(scriptHash string) (bchain.AddressDescriptor, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	h, err := hex.DecodeString(scriptHash)
+	if err != nil || len(h) != sha256.Size {
+		return nil, NewAPIError(fmt.Sprintf("Invalid script hash '%v'", scriptHash), true)
+	}
+	for i, j := 0, len(h)-1; i < j; i, j = i+1, j-1 {
+		h[i], h[j] = h[j], h[i]
+	}
+	addrDesc, err := w.db.GetAddrDescByScriptHash(h)
+	if err != nil {
+		return nil, err
+	}
+	if addrDesc == nil {
+		return nil, NewAPIError(fmt.Sprintf("Script hash '%v' not found", scriptHash), true)
+	}
+	return addrDesc, nil
+}
+
+// GetScriptHashAddress computes value and gets transactions of the output script with given hash,
+// applicable to scripts which cannot be searched by an address, for example bare multisig
+func (w *Worker) GetScriptHashAddress(scriptHash string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter) (*Address, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
+	addrDesc, err := w.getAddrDescFromScriptHash(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	return w.getAddrDescAddress(addrDesc, scriptHash, page, txsOnPage, option, filter)
+}
+
+func (w *Worker) getAddrDescAddress(addrDesc bchain.AddressDescriptor, address string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter) (*Address, error) {
 	start := time.Now()
 	page--
 	if page < 0 {
@@ -659,6 +1206,8 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		txm                      []string
 		txs                      []*Tx
 		txids                    []string
+		utxs                     []*Tx
+		utxids                   []string
 		pg                       Paging
 		uBalSat                  big.Int
 		totalReceived, totalSent *big.Int
@@ -666,10 +1215,11 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		unconfirmedTxs           int
 		nonTokenTxs              int
 		totalResults             int
+		err                      error
 	)
-	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
-	if err != nil {
-		return nil, err
+	// the history is not needed if the transactions are not in the requested fields
+	if option > AccountDetailsTokenBalances && !filter.Fields.hasAny("transactions", "txids", "page", "totalPages", "itemsOnPage") {
+		option = AccountDetailsTokenBalances
 	}
 	if w.chainType == bchain.ChainEthereumType {
 		var n uint64
@@ -686,7 +1236,7 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		}
 		if ba != nil {
 			// totalResults is known only if there is no filter
-			if filter.Vout == AddressFilterVoutOff && filter.FromHeight == 0 && filter.ToHeight == 0 {
+			if filter.Vout == AddressFilterVoutOff && filter.FromHeight == 0 && filter.ToHeight == 0 && filter.Coinbase == CoinbaseFilterOff {
 				totalResults = int(ba.Txs)
 			} else {
 				totalResults = -1
@@ -698,8 +1248,8 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		ba = &db.AddrBalance{}
 		page = 0
 	}
-	// process mempool, only if toHeight is not specified
-	if filter.ToHeight == 0 && !filter.OnlyConfirmed {
+	// process mempool, only if toHeight is not specified and the mempool data are requested
+	if filter.ToHeight == 0 && !filter.OnlyConfirmed && filter.Fields.hasAny("unconfirmedBalance", "unconfirmedTxs", "transactions", "txids") {
 		txm, err = w.getAddressTxids(addrDesc, true, filter, maxInt)
 		if err != nil {
 			return nil, errors.Annotatef(err, "getAddressTxids %v true", addrDesc)
@@ -710,25 +1260,29 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 			if err != nil || tx == nil {
 				glog.Warning("GetTransaction in mempool: ", err)
 			} else {
-				// skip already confirmed txs, mempool may be out of sync
-				if tx.Confirmations == 0 {
+				// skip already confirmed and replaced txs, mempool may be out of sync
+				if tx.Confirmations == 0 && tx.ReplacedBy == "" {
 					unconfirmedTxs++
 					uBalSat.Add(&uBalSat, tx.getAddrVoutValue(addrDesc))
 					uBalSat.Sub(&uBalSat, tx.getAddrVinValue(addrDesc))
-					if page == 0 {
-						if option == AccountDetailsTxidHistory {
-							txids = append(txids, tx.Txid)
-						} else if option >= AccountDetailsTxHistoryLight {
-							txs = append(txs, tx)
-						}
+					if option == AccountDetailsTxidHistory {
+						utxids = append(utxids, tx.Txid)
+					} else if option >= AccountDetailsTxHistoryLight {
+						utxs = append(utxs, tx)
 					}
 				}
 			}
 		}
 	}
+	// in the default order the unconfirmed transactions are at the beginning of the first page
+	if !filter.Ascending && page == 0 {
+		txids = append(txids, utxids...)
+		txs = append(txs, utxs...)
+	}
 	// get tx history if requested by option or check mempool if there are some transactions for a new address
 	if option >= AccountDetailsTxidHistory {
-		txc, err := w.getAddressTxids(addrDesc, false, filter, (page+1)*txsOnPage)
+		maxResults := (page + 1) * txsOnPage
+		txc, err := w.getAddressTxids(addrDesc, false, filter, maxResults)
 		if err != nil {
 			return nil, errors.Annotatef(err, "getAddressTxids %v false", addrDesc)
 		}
@@ -737,15 +1291,28 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 			return nil, errors.Annotatef(err, "GetBestBlock")
 		}
 		var from, to int
-		pg, from, to, page = computePaging(len(txc), page, txsOnPage)
-		if len(txc) >= txsOnPage {
-			if totalResults < 0 {
-				pg.TotalPages = -1
-			} else {
-				pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+		if filter.Ascending {
+			pg, from, to, page = computeAscendingPaging(len(txc), maxResults, totalResults, len(utxids)+len(utxs), page, txsOnPage)
+		} else {
+			pg, from, to, page = computePaging(len(txc), page, txsOnPage)
+			if len(txc) >= txsOnPage {
+				if totalResults < 0 {
+					pg.TotalPages = -1
+				} else {
+					pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+				}
 			}
 		}
 		for i := from; i < to; i++ {
+			// in the ascending order the unconfirmed transactions follow the confirmed ones
+			if i >= len(txc) {
+				if option == AccountDetailsTxidHistory {
+					txids = append(txids, utxids[i-len(txc)])
+				} else {
+					txs = append(txs, utxs[i-len(txc)])
+				}
+				continue
+			}
 			txid := txc[i]
 			if option == AccountDetailsTxidHistory {
 				txids = append(txids, txid)
@@ -758,17 +1325,38 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 			}
 		}
 	}
+	balanceSat := &ba.BalanceSat
+	var firstFundedHeight int
 	if w.chainType == bchain.ChainBitcoinType {
-		totalReceived = ba.ReceivedSat()
-		totalSent = &ba.SentSat
+		if filter.Fields.Has("totalReceived") {
+			totalReceived = ba.ReceivedSat()
+		}
+		if filter.Fields.Has("totalSent") {
+			totalSent = &ba.SentSat
+		}
+		if filter.Fields.Has("firstFundedHeight") {
+			firstFundedHeight, err = w.getFirstFundedHeight(addrDesc)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if filter.MinConfirmations > 1 && !IsZeroBigInt(&ba.BalanceSat) {
+			shallow, err := w.getShallowBalance(addrDesc, ba, filter.MinConfirmations)
+			if err != nil {
+				return nil, err
+			}
+			balanceSat = new(big.Int).Sub(&ba.BalanceSat, shallow)
+			uBalSat.Add(&uBalSat, shallow)
+		}
 	}
 	r := &Address{
 		Paging:                pg,
 		AddrStr:               address,
-		BalanceSat:            (*Amount)(&ba.BalanceSat),
+		BalanceSat:            (*Amount)(balanceSat),
 		TotalReceivedSat:      (*Amount)(totalReceived),
 		TotalSentSat:          (*Amount)(totalSent),
 		Txs:                   int(ba.Txs),
+		FirstFundedHeight:     firstFundedHeight,
 		NonTokenTxs:           nonTokenTxs,
 		UnconfirmedBalanceSat: (*Amount)(&uBalSat),
 		UnconfirmedTxs:        unconfirmedTxs,
@@ -777,20 +1365,88 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		Tokens:                tokens,
 		Erc20Contract:         erc20c,
 		Nonce:                 nonce,
+		Label:                 w.labels.Get(addrDesc),
 	}
 	glog.Info("GetAddress ", address, " finished in ", time.Since(start))
 	return r, nil
 }
 
+// getFirstFundedHeight returns the height of the block with the first output paying to the address,
+// -1 if the address has never received funds in a confirmed transaction
+func (w *Worker) getFirstFundedHeight(addrDesc bchain.AddressDescriptor) (int, error) {
+	height, found, err := w.db.GetAddrDescFirstFundedHeight(addrDesc)
+	if err != nil {
+		return 0, errors.Annotatef(err, "GetAddrDescFirstFundedHeight %v", addrDesc)
+	}
+	if !found {
+		return -1, nil
+	}
+	return int(height), nil
+}
+
+// getShallowBalance returns the sum of the confirmed unspent outputs of the address with fewer than minConfirmations confirmations
+func (w *Worker) getShallowBalance(addrDesc bchain.AddressDescriptor, ba *db.AddrBalance, minConfirmations int) (*big.Int, error) {
+	utxos, err := w.getAddrDescUtxo(addrDesc, ba, true, false)
+	if err != nil {
+		return nil, err
+	}
+	var shallow big.Int
+	for i := range utxos {
+		if utxos[i].Confirmations < minConfirmations {
+			shallow.Add(&shallow, (*big.Int)(utxos[i].AmountSat))
+		}
+	}
+	return &shallow, nil
+}
+
+// SetAddressDenominations fills the balances of the address both in the base unit and in the human readable unit of the coin
+func (w *Worker) SetAddressDenominations(a *Address) {
+	d := w.chainParser.AmountDecimals()
+	a.Denominations = &AddressDenominations{
+		Decimals:           d,
+		Balance:            NewDenominatedAmount(a.BalanceSat, d),
+		TotalReceived:      NewDenominatedAmount(a.TotalReceivedSat, d),
+		TotalSent:          NewDenominatedAmount(a.TotalSentSat, d),
+		UnconfirmedBalance: NewDenominatedAmount(a.UnconfirmedBalanceSat, d),
+	}
+}
+
+// utxoSpendable returns true if the utxo can be spent in the next block, the outputs of a coinbase transaction
+// must have at least maturity confirmations, the other outputs must be confirmed
+func utxoSpendable(confirmations int, coinbase bool, maturity uint32) bool {
+	if coinbase {
+		return confirmations > 0 && uint32(confirmations) >= maturity
+	}
+	return confirmations > 0
+}
+
 func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrBalance, onlyConfirmed bool, onlyMempool bool) (Utxos, error) {
+	c := newUtxoCollector(0, 0)
+	if err := w.collectAddrDescUtxo(c, addrDesc, ba, onlyConfirmed, onlyMempool); err != nil {
+		return nil, err
+	}
+	return c.utxos, nil
+}
+
+// collectAddrDescUtxo passes the unspent outputs of the address to the collector, the mempool outputs first
+// and then the confirmed ones from the newest
+func (w *Worker) collectAddrDescUtxo(c *utxoCollector, addrDesc bchain.AddressDescriptor, ba *db.AddrBalance, onlyConfirmed bool, onlyMempool bool) error {
 	var err error
-	r := make(Utxos, 0, 8)
+	// the spendability is reported only if the coinbase maturity of the coin is known
+	maturity, errMaturity := w.chainParser.CoinbaseMaturity()
+	spendable := func(confirmations int, coinbase bool) *bool {
+		if errMaturity != nil {
+			return nil
+		}
+		s := utxoSpendable(confirmations, coinbase, maturity)
+		return &s
+	}
 	spentInMempool := make(map[string]struct{})
 	if !onlyConfirmed {
 		// get utxo from mempool
 		txm, err := w.getAddressTxids(addrDesc, true, &AddressFilter{Vout: AddressFilterVoutOff}, maxInt)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if len(txm) > 0 {
 			mc := make([]*bchain.Tx, len(txm))
@@ -818,10 +1474,11 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 							// report only outpoints that are not spent in mempool
 							_, e := spentInMempool[bchainTx.Txid+strconv.Itoa(i)]
 							if !e {
-								r = append(r, Utxo{
+								c.add(Utxo{
 									Txid:      bchainTx.Txid,
 									Vout:      int32(i),
 									AmountSat: (*Amount)(&vout.ValueSat),
+									Spendable: spendable(0, false),
 								})
 							}
 						}
@@ -835,7 +1492,7 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 		if ba == nil {
 			ba, err = w.db.GetAddrDescBalance(addrDesc)
 			if err != nil {
-				return nil, NewAPIError(fmt.Sprintf("Address not found, %v", err), true)
+				return NewAPIError(fmt.Sprintf("Address not found, %v", err), true)
 			}
 		}
 		// ba can be nil if the address is only in mempool!
@@ -851,7 +1508,7 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 				return nil
 			})
 			if err != nil {
-				return nil, err
+				return err
 			}
 			var lastTxid string
 			var ta *db.TxAddresses
@@ -859,7 +1516,7 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 			checksum.Set(&ba.BalanceSat)
 			b, _, err := w.db.GetBestBlock()
 			if err != nil {
-				return nil, err
+				return err
 			}
 			bestheight := int(b)
 			for i := 0; i < len(outpoints) && checksum.Int64() > 0; i++ {
@@ -867,7 +1524,7 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 				if lastTxid != o.Txid {
 					ta, err = w.db.GetTxAddresses(o.Txid)
 					if err != nil {
-						return nil, err
+						return err
 					}
 					lastTxid = o.Txid
 				}
@@ -882,12 +1539,14 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 							// report only outpoints that are not spent in mempool
 							_, e := spentInMempool[o.Txid+strconv.Itoa(int(o.Vout))]
 							if !e {
-								r = append(r, Utxo{
+								confirmations := bestheight - int(ta.Height) + 1
+								c.add(Utxo{
 									Txid:          o.Txid,
 									Vout:          o.Vout,
 									AmountSat:     (*Amount)(&v),
 									Height:        int(ta.Height),
-									Confirmations: bestheight - int(ta.Height) + 1,
+									Confirmations: confirmations,
+									Spendable:     spendable(confirmations, ta.Coinbase),
 								})
 							}
 							checksum.Sub(&checksum, &v)
@@ -900,11 +1559,23 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 			}
 		}
 	}
-	return r, nil
+	return nil
 }
 
-// GetAddressUtxo returns unspent outputs for given address
+// GetAddressUtxo returns unspent outputs for given address, at most the number of utxos allowed by the server limit
 func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool) (Utxos, error) {
+	r, err := w.GetAddressLimitedUtxo(address, &UtxoFilter{OnlyConfirmed: onlyConfirmed})
+	if err != nil {
+		return nil, err
+	}
+	return r.Utxos, nil
+}
+
+// GetAddressLimitedUtxo returns the unspent outputs of the address selected by the filter together with the summary of all of them
+func (w *Worker) GetAddressLimitedUtxo(address string, filter *UtxoFilter) (*LimitedUtxos, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
 	if w.chainType != bchain.ChainBitcoinType {
 		return nil, NewAPIError("Not supported", true)
 	}
@@ -913,14 +1584,33 @@ func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool) (Utxos, erro
 	if err != nil {
 		return nil, NewAPIError(fmt.Sprintf("Invalid address '%v', %v", address, err), true)
 	}
-	r, err := w.getAddrDescUtxo(addrDesc, nil, onlyConfirmed, false)
-	if err != nil {
+	c := w.newFilterUtxoCollector(filter)
+	if err = w.collectAddrDescUtxo(c, addrDesc, nil, filter.OnlyConfirmed, false); err != nil {
 		return nil, err
 	}
-	glog.Info("GetAddressUtxo ", address, ", ", len(r), " utxos, finished in ", time.Since(start))
+	r := c.limited()
+	glog.Info("GetAddressUtxo ", address, ", ", r.TotalUtxos, " utxos, finished in ", time.Since(start))
 	return r, nil
 }
 
+// GetScriptHashUtxo returns unspent outputs of the output script with given hash
+func (w *Worker) GetScriptHashUtxo(scriptHash string, onlyConfirmed bool) (Utxos, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	addrDesc, err := w.getAddrDescFromScriptHash(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	c := w.newFilterUtxoCollector(&UtxoFilter{OnlyConfirmed: onlyConfirmed})
+	if err = w.collectAddrDescUtxo(c, addrDesc, nil, onlyConfirmed, false); err != nil {
+		return nil, err
+	}
+	glog.Info("GetScriptHashUtxo ", scriptHash, ", ", c.count, " utxos, finished in ", time.Since(start))
+	return c.utxos, nil
+}
+
 // GetBlocks returns BlockInfo for blocks on given page
 func (w *Worker) GetBlocks(page int, blocksOnPage int) (*Blocks, error) {
 	start := time.Now()
@@ -951,31 +1641,122 @@ func (w *Worker) GetBlocks(page int, blocksOnPage int) (*Blocks, error) {
 	return r, nil
 }
 
-// GetBlock returns paged data about block
-func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
+// maxBlockRangeCount is the maximum number of blocks returned by GetBlockRange
+const maxBlockRangeCount = 100
+
+// blockRangeWorkers is the number of concurrent requests to the backend in GetBlockRange
+const blockRangeWorkers = 8
+
+// GetBlockRange returns summaries of count contiguous blocks starting at the height from
+// the range is truncated at the best block, count is capped to maxBlockRangeCount
+func (w *Worker) GetBlockRange(from int, count int) (*BlockRange, error) {
 	start := time.Now()
-	page--
-	if page < 0 {
-		page = 0
+	if from < 0 || from >= int(maxUint32) {
+		return nil, NewAPIError("Invalid block height", true)
 	}
-	// try to decide if passed string (bid) is block height or block hash
-	// if it's a number, must be less than int32
-	var hash string
-	height, err := strconv.Atoi(bid)
-	if err == nil && height < int(maxUint32) {
-		hash, err = w.db.GetBlockHash(uint32(height))
+	if count <= 0 {
+		return nil, NewAPIError("Invalid count", true)
+	}
+	if count > maxBlockRangeCount {
+		count = maxBlockRangeCount
+	}
+	b, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	bestheight := int(b)
+	if from > bestheight {
+		return nil, NewAPIError("Block not found", true)
+	}
+	if from+count > bestheight+1 {
+		count = bestheight + 1 - from
+	}
+	r := &BlockRange{
+		From:   uint32(from),
+		Blocks: make([]BlockSummary, count),
+	}
+	errs := make([]error, count)
+	heights := make(chan int)
+	var wg sync.WaitGroup
+	workers := blockRangeWorkers
+	if workers > count {
+		workers = count
+	}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range heights {
+				errs[i] = w.getBlockSummary(uint32(from+i), &r.Blocks[i])
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		heights <- i
+	}
+	close(heights)
+	wg.Wait()
+	for _, err := range errs {
 		if err != nil {
-			hash = bid
+			return nil, err
 		}
-	} else {
-		hash = bid
+	}
+	glog.Info("GetBlockRange ", from, ", count ", count, " finished in ", time.Since(start))
+	return r, nil
+}
+
+func (w *Worker) getBlockSummary(height uint32, bs *BlockSummary) error {
+	hash, err := w.db.GetBlockHash(height)
+	if err != nil {
+		return errors.Annotatef(err, "GetBlockHash %v", height)
 	}
 	bi, err := w.chain.GetBlockInfo(hash)
 	if err != nil {
 		if err == bchain.ErrBlockNotFound {
-			return nil, NewAPIError("Block not found", true)
+			return NewAPIError(fmt.Sprintf("Block %v not found", height), true)
 		}
-		return nil, NewAPIError(fmt.Sprintf("Block not found, %v", err), true)
+		return errors.Annotatef(err, "GetBlockInfo %v", hash)
+	}
+	bs.BlockHeader = bi.BlockHeader
+	// the block is addressed by height from the index, the backend may not return it in the header
+	bs.Height = height
+	bs.TxCount = len(bi.Txids)
+	return nil
+}
+
+// setBlockLinks sets the hashes of the previous and the next block of the block header,
+// the previous block is taken from the header if the backend returns it, the next block always from the index,
+// the block at the tip of the index has no next block even if the backend already knows it
+func (w *Worker) setBlockLinks(bh *bchain.BlockHeader, bestheight uint32) {
+	if bh.Prev == "" && bh.Height != 0 {
+		bh.Prev, _ = w.db.GetBlockHash(bh.Height - 1)
+	}
+	bh.Next = ""
+	if bh.Height < bestheight {
+		bh.Next, _ = w.db.GetBlockHash(bh.Height + 1)
+	}
+}
+
+// isInBestChain returns true if the block is in the chain of the tip of the index, i.e. the index contains the block at its height,
+// a block orphaned by a reorg is replaced in the index by the block of the new chain
+func (w *Worker) isInBestChain(hash string, height uint32) (bool, error) {
+	h, err := w.db.GetBlockHash(height)
+	if err != nil {
+		return false, errors.Annotatef(err, "GetBlockHash %v", height)
+	}
+	return h == hash, nil
+}
+
+// GetBlock returns paged data about block
+func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	bi, err := w.getBlockInfoFromBlockID(bid)
+	if err != nil {
+		return nil, err
 	}
 	dbi := &db.BlockInfo{
 		Hash:   bi.Hash,
@@ -987,16 +1768,36 @@ func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
 	if err != nil {
 		return nil, errors.Annotatef(err, "GetBestBlock")
 	}
+	inBestChain, err := w.isInBestChain(bi.Hash, bi.Height)
+	if err != nil {
+		return nil, err
+	}
 	pg, from, to, page := computePaging(txCount, page, txsOnPage)
 	txs := make([]*Tx, to-from)
 	txi := 0
 	for i := from; i < to; i++ {
 		txid := bi.Txids[i]
-		if w.chainType == bchain.ChainBitcoinType {
+		if !inBestChain {
+			// the index does not contain the transactions of an orphaned block, they may be in another block or nowhere
+			txs[txi], err = w.GetTransaction(txid, false, false)
+			if err != nil {
+				glog.Warning("GetBlock ", bi.Hash, ": orphaned block tx ", txid, ": ", err)
+				continue
+			}
+		} else if w.chainType == bchain.ChainBitcoinType {
 			ta, err := w.db.GetTxAddresses(txid)
 			if err != nil {
 				return nil, errors.Annotatef(err, "GetTxAddresses %v", txid)
 			}
+			if ta == nil && bi.Height == 0 {
+				// the genesis block is not indexed if the index was started from a later block
+				if txs[txi], err = w.GetTransaction(txid, false, false); err != nil {
+					glog.Warning("GetBlock ", bi.Hash, ": genesis tx ", txid, ": ", err)
+					continue
+				}
+				txi++
+				continue
+			}
 			if ta == nil {
 				glog.Warning("DB inconsistency:  tx ", txid, ": not found in txAddresses")
 				continue
@@ -1010,11 +1811,18 @@ func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
 		}
 		txi++
 	}
-	if bi.Prev == "" && bi.Height != 0 {
-		bi.Prev, _ = w.db.GetBlockHash(bi.Height - 1)
+	w.setBlockLinks(&bi.BlockHeader, bestheight)
+	if !inBestChain {
+		// the next block in the index is not a successor of the orphaned block
+		bi.Next = ""
 	}
-	if bi.Next == "" && bi.Height != bestheight {
-		bi.Next, _ = w.db.GetBlockHash(bi.Height + 1)
+	var subsidy, fees *big.Int
+	if w.chainType == bchain.ChainBitcoinType && inBestChain && txCount > 0 {
+		// the reward is not essential for the block, on error it is only left out
+		if subsidy, fees, err = w.getBlockReward(bi.Height, bi.Txids[0]); err != nil {
+			glog.Error("getBlockReward ", bi.Height, ": ", err)
+			subsidy, fees = nil, nil
+		}
 	}
 	txs = txs[:txi]
 	bi.Txids = nil
@@ -1027,14 +1835,193 @@ func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
 			Difficulty:  string(bi.Difficulty),
 			MerkleRoot:  bi.MerkleRoot,
 			Nonce:       string(bi.Nonce),
+			Subsidy:     (*Amount)(subsidy),
+			Fees:        (*Amount)(fees),
+			Weight:      bi.Weight,
 			Txids:       bi.Txids,
 			Version:     bi.Version,
+			InBestChain: inBestChain,
 		},
 		TxCount:      txCount,
 		Transactions: txs,
 	}, nil
 }
 
+// getBlockInfoFromBlockID returns block info from the backend for the block specified by height or hash
+func (w *Worker) getBlockInfoFromBlockID(bid string) (*bchain.BlockInfo, error) {
+	// try to decide if passed string (bid) is block height or block hash
+	// if it's a number, must be less than int32; a height is shorter than the minimal truncated hash, therefore
+	// a truncated hash consisting only of decimal digits is not taken for a height
+	var hash string
+	height, err := strconv.Atoi(bid)
+	if err == nil && len(bid) < minBlockHashPrefix && height < int(maxUint32) {
+		hash, err = w.db.GetBlockHash(uint32(height))
+		if err != nil {
+			hash = bid
+		}
+	} else if len(bid) < 2*w.chainParser.PackedTxidLen() && isHex(bid) {
+		// a truncated block hash is resolved from the index
+		if hash, err = w.findBlockHashByPrefix(bid); err != nil {
+			return nil, err
+		}
+	} else {
+		hash = bid
+	}
+	bi, err := w.chain.GetBlockInfo(hash)
+	if err != nil {
+		if err == bchain.ErrBlockNotFound {
+			return nil, NewAPIError("Block not found", true)
+		}
+		return nil, NewAPIError(fmt.Sprintf("Block not found, %v", err), true)
+	}
+	return bi, nil
+}
+
+// GetBlockMerkleRoot recomputes the merkle root of the block from its txids and compares it with the header
+func (w *Worker) GetBlockMerkleRoot(bid string) (*BlockMerkleRoot, error) {
+	start := time.Now()
+	bi, err := w.getBlockInfoFromBlockID(bid)
+	if err != nil {
+		return nil, err
+	}
+	computed, err := computeMerkleRoot(bi.Txids)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Cannot compute merkle root, %v", err), true)
+	}
+	match := computed == bi.MerkleRoot
+	if !match {
+		glog.Warning("Block ", bi.Height, " ", bi.Hash, ": merkle root mismatch, header ", bi.MerkleRoot, ", computed ", computed)
+	}
+	glog.Info("GetBlockMerkleRoot ", bid, " finished in ", time.Since(start))
+	return &BlockMerkleRoot{
+		Hash:               bi.Hash,
+		Height:             bi.Height,
+		TxCount:            len(bi.Txids),
+		MerkleRoot:         bi.MerkleRoot,
+		ComputedMerkleRoot: computed,
+		Match:              match,
+	}, nil
+}
+
+// GetBlockRawHeader returns the serialized 80 byte header of the block, it is reconstructed from the fields of the header
+// and checked that it hashes to the block hash
+func (w *Worker) GetBlockRawHeader(bid string) (*BlockRawHeader, error) {
+	bi, err := w.getBlockInfoFromBlockID(bid)
+	if err != nil {
+		return nil, err
+	}
+	header, err := serializeBlockHeader(bi)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Cannot serialize block header, %v", err), true)
+	}
+	if h := chainhash.DoubleHashH(header); h.String() != bi.Hash {
+		glog.Warning("Block ", bi.Height, " ", bi.Hash, ": serialized header hashes to ", h.String())
+		return nil, NewAPIError("Serialized block header does not match the block hash", true)
+	}
+	return &BlockRawHeader{
+		Hash:   bi.Hash,
+		Height: bi.Height,
+		Hex:    hex.EncodeToString(header),
+	}, nil
+}
+
+// serializeBlockHeader serializes the version, previous block hash, merkle root, time, bits and nonce to the 80 byte block header
+func serializeBlockHeader(bi *bchain.BlockInfo) ([]byte, error) {
+	version, err := strconv.ParseInt(string(bi.Version), 10, 32)
+	if err != nil {
+		return nil, errors.Annotatef(err, "version %v", bi.Version)
+	}
+	nonce, err := strconv.ParseUint(string(bi.Nonce), 10, 32)
+	if err != nil {
+		return nil, errors.Annotatef(err, "nonce %v", bi.Nonce)
+	}
+	bits, err := strconv.ParseUint(bi.Bits, 16, 32)
+	if err != nil {
+		return nil, errors.Annotatef(err, "bits %v", bi.Bits)
+	}
+	// the genesis block has no previous block
+	prev := &chainhash.Hash{}
+	if bi.Prev != "" {
+		if prev, err = chainhash.NewHashFromStr(bi.Prev); err != nil {
+			return nil, errors.Annotatef(err, "previous block hash %v", bi.Prev)
+		}
+	}
+	merkleRoot, err := chainhash.NewHashFromStr(bi.MerkleRoot)
+	if err != nil {
+		return nil, errors.Annotatef(err, "merkle root %v", bi.MerkleRoot)
+	}
+	header := make([]byte, 80)
+	binary.LittleEndian.PutUint32(header[0:], uint32(version))
+	copy(header[4:], prev[:])
+	copy(header[36:], merkleRoot[:])
+	binary.LittleEndian.PutUint32(header[68:], uint32(bi.Time))
+	binary.LittleEndian.PutUint32(header[72:], uint32(bits))
+	binary.LittleEndian.PutUint32(header[76:], uint32(nonce))
+	return header, nil
+}
+
+// computeMerkleRoot computes the merkle root from the txids using the double-SHA256 tree
+// the last hash of a level with odd number of hashes is paired with itself
+func computeMerkleRoot(txids []string) (string, error) {
+	if len(txids) == 0 {
+		return "", errors.New("No transactions")
+	}
+	hashes := make([]chainhash.Hash, len(txids))
+	for i, txid := range txids {
+		h, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return "", errors.Annotatef(err, "txid %v", txid)
+		}
+		hashes[i] = *h
+	}
+	var buf [2 * chainhash.HashSize]byte
+	for len(hashes) > 1 {
+		if len(hashes)%2 == 1 {
+			hashes = append(hashes, hashes[len(hashes)-1])
+		}
+		for i := 0; i < len(hashes)/2; i++ {
+			copy(buf[:chainhash.HashSize], hashes[2*i][:])
+			copy(buf[chainhash.HashSize:], hashes[2*i+1][:])
+			hashes[i] = chainhash.DoubleHashH(buf[:])
+		}
+		hashes = hashes[:len(hashes)/2]
+	}
+	return hashes[0].String(), nil
+}
+
+// getBlockReward splits the value of the coinbase transaction of the block to the block subsidy and the fees
+// if the coin does not support computation of subsidy, nil values are returned
+func (w *Worker) getBlockReward(height uint32, coinbaseTxid string) (*big.Int, *big.Int, error) {
+	subsidy, err := w.chainParser.GetBlockSubsidy(height)
+	if err != nil {
+		return nil, nil, nil
+	}
+	ta, err := w.db.GetTxAddresses(coinbaseTxid)
+	if err != nil {
+		return nil, nil, errors.Annotatef(err, "GetTxAddresses %v", coinbaseTxid)
+	}
+	if ta == nil {
+		glog.Warning("DB inconsistency:  tx ", coinbaseTxid, ": not found in txAddresses")
+		return nil, nil, nil
+	}
+	var coinbaseValue big.Int
+	for i := range ta.Outputs {
+		coinbaseValue.Add(&coinbaseValue, &ta.Outputs[i].ValueSat)
+	}
+	return subsidy, blockFees(height, subsidy, &coinbaseValue), nil
+}
+
+// blockFees attributes the part of coinbase value exceeding the subsidy to the fees
+// the coinbase value lower than the subsidy is a discrepancy, it is logged and zero fees are returned
+func blockFees(height uint32, subsidy, coinbaseValue *big.Int) *big.Int {
+	fees := new(big.Int).Sub(coinbaseValue, subsidy)
+	if fees.Sign() < 0 {
+		glog.Warningf("Block %v: coinbase value %v is lower than subsidy %v", height, coinbaseValue, subsidy)
+		fees.SetInt64(0)
+	}
+	return fees
+}
+
 // GetSystemInfo returns information about system
 func (w *Worker) GetSystemInfo(internal bool) (*SystemInfo, error) {
 	start := time.Now()
@@ -1042,6 +2029,7 @@ func (w *Worker) GetSystemInfo(internal bool) (*SystemInfo, error) {
 	if err != nil {
 		return nil, errors.Annotatef(err, "GetChainInfo")
 	}
+	w.is.SetBackendInitialBlockDownload(ci.InitialBlockDownload)
 	vi := common.GetVersionInfo()
 	ss, bh, st := w.is.GetSyncState()
 	ms, mt, msz := w.is.GetMempoolSyncState()
@@ -1052,29 +2040,55 @@ func (w *Worker) GetSystemInfo(internal bool) (*SystemInfo, error) {
 		dbs = w.is.DBSizeTotal()
 	}
 	bi := &BlockbookInfo{
-		Coin:              w.is.Coin,
-		Host:              w.is.Host,
-		Version:           vi.Version,
-		GitCommit:         vi.GitCommit,
-		BuildTime:         vi.BuildTime,
-		SyncMode:          w.is.SyncMode,
-		InitialSync:       w.is.InitialSync,
-		InSync:            ss,
-		BestHeight:        bh,
-		LastBlockTime:     st,
-		InSyncMempool:     ms,
-		LastMempoolTime:   mt,
-		MempoolSize:       msz,
-		Decimals:          w.chainParser.AmountDecimals(),
-		DbSize:            w.db.DatabaseSizeOnDisk(),
-		DbSizeFromColumns: dbs,
-		DbColumns:         dbc,
-		About:             Text.BlockbookAbout,
+		Coin:                 w.is.Coin,
+		Host:                 w.is.Host,
+		Version:              vi.Version,
+		GitCommit:            vi.GitCommit,
+		BuildTime:            vi.BuildTime,
+		SyncMode:             w.is.SyncMode,
+		InitialSync:          w.is.InitialSync,
+		InSync:               ss,
+		BestHeight:           bh,
+		LastBlockTime:        st,
+		InSyncMempool:        ms,
+		LastMempoolTime:      mt,
+		MempoolSize:          msz,
+		Decimals:             w.chainParser.AmountDecimals(),
+		DbSize:               w.db.DatabaseSizeOnDisk(),
+		DbSizeFromColumns:    dbs,
+		DbColumns:            dbc,
+		ChainWorkWarning:     w.getChainWorkWarning(ci),
+		BackendSyncETA:       w.getBackendSyncETA(ci),
+		NoAddressIndex:       w.is.NoAddressIndex,
+		WebsocketConnections: w.is.GetWebsocketConnections(),
+		About:                Text.BlockbookAbout,
+	}
+	if lc := w.is.GetLastCompaction(); !lc.IsZero() {
+		bi.LastCompaction = &lc
+	}
+	if w.chainType == bchain.ChainBitcoinType {
+		if bi.TotalTxs, err = w.db.GetTotalTxs(); err != nil {
+			glog.Error("GetTotalTxs error ", err)
+		}
 	}
 	glog.Info("GetSystemInfo finished in ", time.Since(start))
 	return &SystemInfo{bi, ci}, nil
 }
 
+// getBackendSyncETA returns the estimated number of seconds to full synchronization of the backend,
+// 0 if the backend is synchronized and -1 if the time cannot be estimated; the progress is sampled by the periodic
+// storing of the internal state, not by the requests
+func (w *Worker) getBackendSyncETA(ci *bchain.ChainInfo) int64 {
+	if ci.VerificationProgress <= 0 {
+		return 0
+	}
+	eta, ok := w.is.GetBackendSyncETA()
+	if !ok {
+		return -1
+	}
+	return int64(eta.Seconds())
+}
+
 // GetMempool returns a page of mempool txids
 func (w *Worker) GetMempool(page int, itemsOnPage int) (*MempoolTxids, error) {
 	page--
@@ -0,0 +1,290 @@
+// +build unittest
+
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/bch"
+	"blockbook/bchain/coins/btc"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+)
+
+func Test_blockFees(t *testing.T) {
+	tests := []struct {
+		name          string
+		height        uint32
+		subsidy       int64
+		coinbaseValue int64
+		want          int64
+	}{
+		{
+			name:          "no fees",
+			height:        100,
+			subsidy:       5000000000,
+			coinbaseValue: 5000000000,
+			want:          0,
+		},
+		{
+			name:          "last block before halving",
+			height:        209999,
+			subsidy:       5000000000,
+			coinbaseValue: 5000012345,
+			want:          12345,
+		},
+		{
+			name:          "first block after halving",
+			height:        210000,
+			subsidy:       2500000000,
+			coinbaseValue: 2500012345,
+			want:          12345,
+		},
+		{
+			name:          "high fees",
+			height:        500000,
+			subsidy:       1250000000,
+			coinbaseValue: 1250000000 + 987654321012,
+			want:          987654321012,
+		},
+		{
+			name:          "coinbase lower than subsidy",
+			height:        500000,
+			subsidy:       1250000000,
+			coinbaseValue: 1249999999,
+			want:          0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := blockFees(tt.height, big.NewInt(tt.subsidy), big.NewInt(tt.coinbaseValue))
+			if got.Int64() != tt.want {
+				t.Errorf("blockFees() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_computeMerkleRoot(t *testing.T) {
+	block100000 := []string{
+		"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+		"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+	}
+	tests := []struct {
+		name    string
+		txids   []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "single tx",
+			txids: block100000[:1],
+			want:  "8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		},
+		{
+			name:  "odd number of txs",
+			txids: block100000[:3],
+			want:  "fa435470825de273081dcc706b25514c936fa6dc80ab965ce6970d68ddd0b553",
+		},
+		{
+			name:  "block 100000",
+			txids: block100000,
+			want:  "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+		},
+		{
+			name:  "five txs",
+			txids: append(append([]string{}, block100000...), block100000[0]),
+			want:  "294b257084a14ef954334f28cffb6f7724e27b025bfc8111ed89a503184eb42f",
+		},
+		{
+			name:    "no txs",
+			txids:   []string{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid txid",
+			txids:   []string{"xyz"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := computeMerkleRoot(tt.txids)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("computeMerkleRoot() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("computeMerkleRoot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_serializeBlockHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		bi   bchain.BlockInfo
+		want string
+	}{
+		{
+			// raw header returned by getblockheader 000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506 false
+			name: "block 100000",
+			bi: bchain.BlockInfo{
+				BlockHeader: bchain.BlockHeader{
+					Hash: "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506",
+					Prev: "000000000002d01c1fccc21636b607dfd930d31d01c3a62104612a1719011250",
+					Time: 1293623863,
+				},
+				Version:    "1",
+				MerkleRoot: "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+				Nonce:      "274148111",
+				Bits:       "1b04864c",
+			},
+			want: "0100000050120119172a610421a6c3011dd330d9df07b63616c2cc1f1cd00200000000006657a9252aacd5c0b2940996ecff952228c3067cc38d4885efb5a4ac4247e9f337221b4d4c86041b0f2b5710",
+		},
+		{
+			// raw header returned by getblockheader 000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f false
+			name: "genesis block",
+			bi: bchain.BlockInfo{
+				BlockHeader: bchain.BlockHeader{
+					Hash: "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f",
+					Time: 1231006505,
+				},
+				Version:    "1",
+				MerkleRoot: "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+				Nonce:      "2083236893",
+				Bits:       "1d00ffff",
+			},
+			want: "0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := serializeBlockHeader(&tt.bi)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := hex.EncodeToString(got); h != tt.want {
+				t.Errorf("serializeBlockHeader() = %v, want %v", h, tt.want)
+			}
+			if h := chainhash.DoubleHashH(got); h.String() != tt.bi.Hash {
+				t.Errorf("serializeBlockHeader() hashes to %v, want %v", h, tt.bi.Hash)
+			}
+		})
+	}
+}
+
+func Test_utxoSpendable(t *testing.T) {
+	tests := []struct {
+		name          string
+		confirmations int
+		coinbase      bool
+		want          bool
+	}{
+		{name: "immature coinbase", confirmations: 99, coinbase: true, want: false},
+		{name: "mature coinbase", confirmations: 100, coinbase: true, want: true},
+		{name: "unconfirmed", confirmations: 0, want: false},
+		{name: "confirmed", confirmations: 1, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := utxoSpendable(tt.confirmations, tt.coinbase, 100); got != tt.want {
+				t.Errorf("utxoSpendable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_NormalizeAddresses(t *testing.T) {
+	legacy := "129HiRqekqPVucKy2M8zsqvafGgKypciPp"
+	cashAddr := "bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5"
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "cashaddr", want: cashAddr},
+		{format: "legacy", want: legacy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			p, err := bch.NewBCashParser(bch.GetChainParams("main"), &btc.Configuration{AddressFormat: tt.format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := &Worker{chainParser: p}
+			got := w.NormalizeAddresses([]string{legacy, cashAddr, "invalid"})
+			if want := []string{tt.want, tt.want, "invalid"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("NormalizeAddresses() = %v, want %v", got, want)
+			}
+			for _, a := range []string{legacy, cashAddr} {
+				_, got, err := w.getAddrDescAndNormalizeAddress(a)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("getAddrDescAndNormalizeAddress(%v) = %v, want %v", a, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func Test_computeAscendingPaging(t *testing.T) {
+	tests := []struct {
+		name                                                        string
+		loaded, maxResults, totalResults, unconfirmed, page, onPage int
+		want                                                        Paging
+		wantFrom, wantTo, wantPage                                  int
+	}{
+		{
+			name:   "first page, more confirmed transactions",
+			loaded: 3, maxResults: 3, totalResults: 5, unconfirmed: 2, page: 0, onPage: 3,
+			want:     Paging{Page: 1, TotalPages: 3, ItemsOnPage: 3},
+			wantFrom: 0, wantTo: 3, wantPage: 0,
+		},
+		{
+			name:   "page with the last confirmed and the first unconfirmed transaction",
+			loaded: 5, maxResults: 6, totalResults: 5, unconfirmed: 2, page: 1, onPage: 3,
+			want:     Paging{Page: 2, TotalPages: 3, ItemsOnPage: 3},
+			wantFrom: 3, wantTo: 6, wantPage: 1,
+		},
+		{
+			name:   "last page with unconfirmed transaction only",
+			loaded: 5, maxResults: 9, totalResults: 5, unconfirmed: 2, page: 2, onPage: 3,
+			want:     Paging{Page: 3, TotalPages: 3, ItemsOnPage: 3},
+			wantFrom: 6, wantTo: 7, wantPage: 2,
+		},
+		{
+			name:   "page after the end",
+			loaded: 5, maxResults: 33, totalResults: 5, unconfirmed: 2, page: 10, onPage: 3,
+			want:     Paging{Page: 3, TotalPages: 3, ItemsOnPage: 3},
+			wantFrom: 6, wantTo: 7, wantPage: 2,
+		},
+		{
+			name:   "unknown number of confirmed transactions",
+			loaded: 2, maxResults: 3, totalResults: -1, unconfirmed: 2, page: 0, onPage: 3,
+			want:     Paging{Page: 1, TotalPages: -1, ItemsOnPage: 3},
+			wantFrom: 0, wantTo: 3, wantPage: 0,
+		},
+		{
+			name:   "only unconfirmed transactions",
+			loaded: 0, maxResults: 3, totalResults: 0, unconfirmed: 2, page: 0, onPage: 3,
+			want:     Paging{Page: 1, TotalPages: 1, ItemsOnPage: 3},
+			wantFrom: 0, wantTo: 2, wantPage: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, from, to, page := computeAscendingPaging(tt.loaded, tt.maxResults, tt.totalResults, tt.unconfirmed, tt.page, tt.onPage)
+			if !reflect.DeepEqual(got, tt.want) || from != tt.wantFrom || to != tt.wantTo || page != tt.wantPage {
+				t.Errorf("computeAscendingPaging() = %+v, %v, %v, %v, want %+v, %v, %v, %v", got, from, to, page, tt.want, tt.wantFrom, tt.wantTo, tt.wantPage)
+			}
+		})
+	}
+}
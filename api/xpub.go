@@ -17,6 +17,9 @@ const xpubLen = 111
 const defaultAddressesGap = 20
 const maxAddressesGap = 10000
 
+// DefaultXpubMaxAddresses is the default maximum number of addresses derived from one xpub on both chains together
+const DefaultXpubMaxAddresses = 50000
+
 const txInput = 1
 const txOutput = 2
 
@@ -172,7 +175,21 @@ func (w *Worker) xpubDerivedAddressBalance(data *xpubData, ad *xpubAddress) (boo
 	return false, nil
 }
 
-func (w *Worker) xpubScanAddresses(xpub string, data *xpubData, addresses []xpubAddress, gap int, change int, minDerivedIndex int, fork bool) (int, []xpubAddress, error) {
+// SetXpubMaxAddresses sets the maximum number of addresses derived from one xpub on both chains together
+func (w *Worker) SetXpubMaxAddresses(n int) {
+	w.xpubMaxAddresses = n
+}
+
+func (w *Worker) getXpubMaxAddresses() int {
+	if w.xpubMaxAddresses <= 0 {
+		return DefaultXpubMaxAddresses
+	}
+	return w.xpubMaxAddresses
+}
+
+// xpubScanAddresses rescans the known addresses of the chain and derives new ones until there is the gap of unused addresses
+// at most maxAddresses addresses are derived, if the gap is not found within them, an error is returned
+func (w *Worker) xpubScanAddresses(xpub string, data *xpubData, addresses []xpubAddress, gap int, change int, minDerivedIndex int, maxAddresses int, fork bool) (int, []xpubAddress, error) {
 	// rescan known addresses
 	lastUsed := 0
 	for i := range addresses {
@@ -200,6 +217,9 @@ func (w *Worker) xpubScanAddresses(xpub string, data *xpubData, addresses []xpub
 		if to < minDerivedIndex {
 			to = minDerivedIndex
 		}
+		if to > maxAddresses {
+			return 0, nil, NewAPIError(fmt.Sprintf("Too many addresses derived from xpub, the limit is %d addresses", w.getXpubMaxAddresses()), true)
+		}
 		descriptors, err := w.chainParser.DeriveAddressDescriptorsFromTo(xpub, uint32(change), uint32(from), uint32(to))
 		if err != nil {
 			return 0, nil, err
@@ -271,11 +291,14 @@ func evictXpubCacheItems() {
 }
 
 func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int) (*xpubData, uint32, error) {
+	xpub, err := xpubFromDescriptor(xpub)
+	if err != nil {
+		return nil, 0, err
+	}
 	if w.chainType != bchain.ChainBitcoinType || len(xpub) != xpubLen {
 		return nil, 0, ErrUnsupportedXpub
 	}
 	var (
-		err        error
 		bestheight uint32
 		besthash   string
 	)
@@ -285,6 +308,11 @@ func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option Accoun
 		// limit the maximum gap to protect against unreasonably big values that could cause high load of the server
 		gap = maxAddressesGap
 	}
+	maxAddresses := w.getXpubMaxAddresses()
+	// the gap must fit on both chains within the limit of derived addresses
+	if gap >= maxAddresses/2 {
+		gap = maxAddresses/2 - 1
+	}
 	// gap is increased one as there must be gap of empty addresses before the derivation is stopped
 	gap++
 	var processedHash string
@@ -326,11 +354,11 @@ func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option Accoun
 			data.sentSat = *new(big.Int)
 			data.txCountEstimate = 0
 			var lastUsedIndex int
-			lastUsedIndex, data.addresses, err = w.xpubScanAddresses(xpub, &data, data.addresses, gap, 0, 0, fork)
+			lastUsedIndex, data.addresses, err = w.xpubScanAddresses(xpub, &data, data.addresses, gap, 0, 0, maxAddresses-gap, fork)
 			if err != nil {
 				return nil, 0, err
 			}
-			_, data.changeAddresses, err = w.xpubScanAddresses(xpub, &data, data.changeAddresses, gap, 1, lastUsedIndex, fork)
+			_, data.changeAddresses, err = w.xpubScanAddresses(xpub, &data, data.changeAddresses, gap, 1, lastUsedIndex, maxAddresses-len(data.addresses), fork)
 			if err != nil {
 				return nil, 0, err
 			}
@@ -357,6 +385,9 @@ func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option Accoun
 
 // GetXpubAddress computes address value and gets transactions for given address
 func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int) (*Address, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
 	start := time.Now()
 	page--
 	if page < 0 {
@@ -426,8 +457,8 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 						}
 						txmMap[txid.txid] = tx
 					}
-					// skip already confirmed txs, mempool may be out of sync
-					if tx.Confirmations == 0 {
+					// skip already confirmed and replaced txs, mempool may be out of sync
+					if tx.Confirmations == 0 && tx.ReplacedBy == "" {
 						if !foundTx {
 							unconfirmedTxs++
 						}
@@ -550,43 +581,57 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 	return &addr, nil
 }
 
-// GetXpubUtxo returns unspent outputs for given xpub
+// GetXpubUtxo returns unspent outputs for given xpub, at most the number of utxos allowed by the server limit
 func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, error) {
+	r, err := w.GetXpubLimitedUtxo(xpub, &UtxoFilter{OnlyConfirmed: onlyConfirmed}, gap)
+	if err != nil {
+		return nil, err
+	}
+	return r.Utxos, nil
+}
+
+// GetXpubLimitedUtxo returns the unspent outputs of the xpub selected by the filter together with the summary of all of them
+func (w *Worker) GetXpubLimitedUtxo(xpub string, filter *UtxoFilter, gap int) (*LimitedUtxos, error) {
+	if err := w.checkAddressIndex(); err != nil {
+		return nil, err
+	}
 	start := time.Now()
 	data, _, err := w.getXpubData(xpub, 0, 1, AccountDetailsBasic, &AddressFilter{
 		Vout:          AddressFilterVoutOff,
-		OnlyConfirmed: onlyConfirmed,
+		OnlyConfirmed: filter.OnlyConfirmed,
 	}, gap)
 	if err != nil {
 		return nil, err
 	}
-	r := make(Utxos, 0, 8)
+	all := w.newFilterUtxoCollector(filter)
 	for ci, da := range [][]xpubAddress{data.addresses, data.changeAddresses} {
 		for i := range da {
 			ad := &da[i]
 			onlyMempool := false
 			if ad.balance == nil {
-				if onlyConfirmed {
+				if filter.OnlyConfirmed {
 					continue
 				}
 				onlyMempool = true
 			}
-			utxos, err := w.getAddrDescUtxo(ad.addrDesc, ad.balance, onlyConfirmed, onlyMempool)
-			if err != nil {
+			// each address contributes at most the limit of its newest utxos, the newest of all are selected after sorting
+			c := w.newFilterUtxoCollector(filter)
+			if err = w.collectAddrDescUtxo(c, ad.addrDesc, ad.balance, filter.OnlyConfirmed, onlyMempool); err != nil {
 				return nil, err
 			}
-			if len(utxos) > 0 {
+			if utxos := c.utxos; len(utxos) > 0 {
 				t := w.tokenFromXpubAddress(data, ad, ci, i, AccountDetailsTokens)
 				for j := range utxos {
 					a := &utxos[j]
 					a.Address = t.Name
 					a.Path = t.Path
 				}
-				r = append(r, utxos...)
 			}
+			all.merge(c)
 		}
 	}
-	sort.Stable(r)
-	glog.Info("GetXpubUtxo ", xpub[:16], ", ", len(r), " utxos, finished in ", time.Since(start))
+	sort.Stable(all.utxos)
+	r := all.limited()
+	glog.Info("GetXpubUtxo ", xpub[:16], ", ", r.TotalUtxos, " utxos, finished in ", time.Since(start))
 	return r, nil
 }
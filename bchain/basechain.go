@@ -34,6 +34,21 @@ func (b *BaseChain) GetMempoolEntry(txid string) (*MempoolEntry, error) {
 	return nil, errors.New("GetMempoolEntry: not supported")
 }
 
+// GetMempoolEntries is not supported by default
+func (b *BaseChain) GetMempoolEntries() (map[string]*MempoolEntry, error) {
+	return nil, errors.New("GetMempoolEntries: not supported")
+}
+
+// GetMedianTimePast is not supported by default
+func (b *BaseChain) GetMedianTimePast(hash string) (int64, error) {
+	return 0, errors.New("GetMedianTimePast: not supported")
+}
+
+// GetChainStats is not supported by default
+func (b *BaseChain) GetChainStats() (*ChainStats, error) {
+	return nil, errors.New("GetChainStats: not supported")
+}
+
 // EthereumTypeGetBalance is not supported
 func (b *BaseChain) EthereumTypeGetBalance(addrDesc AddressDescriptor) (*big.Int, error) {
 	return nil, errors.New("Not supported")
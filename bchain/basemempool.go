@@ -13,11 +13,17 @@ type addrIndex struct {
 type txEntry struct {
 	addrIndexes []addrIndex
 	time        uint32
+	// fee rate in satoshis per kB, set only if the number of tracked transactions is limited
+	feePerKB int64
+	// outpoints spent by the transaction
+	inputs []Outpoint
 }
 
 type txidio struct {
-	txid string
-	io   []addrIndex
+	txid     string
+	io       []addrIndex
+	feePerKB int64
+	inputs   []Outpoint
 }
 
 // BaseMempool is mempool base handle
@@ -27,6 +33,10 @@ type BaseMempool struct {
 	txEntries    map[string]txEntry
 	addrDescToTx map[string][]Outpoint
 	OnNewTxAddr  OnNewTxAddrFunc
+	// IsWatchedAddrDesc protects the transactions of the watched addresses from eviction
+	IsWatchedAddrDesc IsWatchedAddrDescFunc
+	// spentOutpoints maps outpoints spent by the mempool transactions to the spending txid
+	spentOutpoints map[Outpoint]string
 }
 
 // GetTransactions returns slice of mempool transactions for given address
@@ -68,6 +78,11 @@ func (a MempoolTxidEntries) Less(i, j int) bool {
 // removeEntryFromMempool removes entry from mempool structs. The caller is responsible for locking!
 func (m *BaseMempool) removeEntryFromMempool(txid string, entry txEntry) {
 	delete(m.txEntries, txid)
+	for _, o := range entry.inputs {
+		if m.spentOutpoints[o] == txid {
+			delete(m.spentOutpoints, o)
+		}
+	}
 	for _, si := range entry.addrIndexes {
 		outpoints, found := m.addrDescToTx[si.addrDesc]
 		if found {
@@ -86,6 +101,35 @@ func (m *BaseMempool) removeEntryFromMempool(txid string, entry txEntry) {
 	}
 }
 
+// GetSpendingTx returns txid and input index of the mempool transaction spending the outpoint
+// or empty string if the outpoint is not spent by a mempool transaction
+func (m *BaseMempool) GetSpendingTx(outpoint Outpoint) (string, int) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	txid, found := m.spentOutpoints[outpoint]
+	if !found {
+		return "", 0
+	}
+	// mempool transactions are not coinbase, the inputs match the vins of the transaction
+	for i, o := range m.txEntries[txid].inputs {
+		if o == outpoint {
+			return txid, i
+		}
+	}
+	return txid, 0
+}
+
+// GetTxInputs returns the outpoints spent by the mempool transaction, false if the transaction is not in the mempool
+func (m *BaseMempool) GetTxInputs(txid string) ([]Outpoint, bool) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	e, found := m.txEntries[txid]
+	if !found {
+		return nil, false
+	}
+	return append([]Outpoint(nil), e.inputs...), true
+}
+
 // GetAllEntries returns all mempool entries sorted by fist seen time in descending order
 func (m *BaseMempool) GetAllEntries() MempoolTxidEntries {
 	i := 0
@@ -113,3 +157,12 @@ func (m *BaseMempool) GetTransactionTime(txid string) uint32 {
 	}
 	return e.time
 }
+
+// OnConnectedBlock is called when a block is connected to the index, the base implementation does nothing
+func (m *BaseMempool) OnConnectedBlock(block *Block) {
+}
+
+// GetReplacedBy returns txid of the confirmed transaction that replaced the mempool transaction, the base implementation returns empty string
+func (m *BaseMempool) GetReplacedBy(txid string) string {
+	return ""
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"math/big"
+	"strconv"
 	"strings"
 
 	"github.com/gogo/protobuf/proto"
@@ -39,16 +40,26 @@ func (p *BaseParser) GetAddrDescForUnknownInput(tx *Tx, input int) AddressDescri
 
 const zeros = "0000000000000000000000000000000000000000"
 
+// maxAmountExponent limits the exponent of amounts in exponent notation
+const maxAmountExponent = 1000
+
 // AmountToBigInt converts amount in json.Number (string) to big.Int
 // it uses string operations to avoid problems with rounding
 func (p *BaseParser) AmountToBigInt(n json.Number) (big.Int, error) {
 	var r big.Int
-	s := string(n)
-	i := strings.IndexByte(s, '.')
+	s := strings.TrimSpace(string(n))
 	d := p.AmountDecimalPoint
 	if d > len(zeros) {
 		d = len(zeros)
 	}
+	// amounts in exponent notation (for example 1e-05) are converted to the plain notation first
+	if e := strings.IndexAny(s, "eE"); e != -1 {
+		var err error
+		if s, err = expandExponent(s[:e], s[e+1:]); err != nil {
+			return r, err
+		}
+	}
+	i := strings.IndexByte(s, '.')
 	if i == -1 {
 		s = s + zeros[:d]
 	} else {
@@ -65,6 +76,37 @@ func (p *BaseParser) AmountToBigInt(n json.Number) (big.Int, error) {
 	return r, nil
 }
 
+// expandExponent converts number given by mantissa and decimal exponent to the plain decimal notation
+// only string operations are used, the conversion does not lose precision
+func expandExponent(m string, e string) (string, error) {
+	exp, err := strconv.Atoi(e)
+	if err != nil || len(m) == 0 || exp > maxAmountExponent || exp < -maxAmountExponent {
+		return "", errors.New("AmountToBigInt: failed to convert")
+	}
+	var sign string
+	if m[0] == '-' || m[0] == '+' {
+		if m[0] == '-' {
+			sign = "-"
+		}
+		m = m[1:]
+	}
+	// position of the decimal point in the digits of the mantissa
+	i := strings.IndexByte(m, '.')
+	if i == -1 {
+		i = len(m)
+	} else {
+		m = m[:i] + m[i+1:]
+	}
+	i += exp
+	if i <= 0 {
+		return sign + "0." + strings.Repeat("0", -i) + m, nil
+	}
+	if i >= len(m) {
+		return sign + m + strings.Repeat("0", i-len(m)), nil
+	}
+	return sign + m[:i] + "." + m[i:], nil
+}
+
 // AmountToDecimalString converts amount in big.Int to string with decimal point in the place defined by the parameter d
 func AmountToDecimalString(a *big.Int, d int) string {
 	if a == nil {
@@ -156,6 +198,11 @@ func (p *BaseParser) UnpackBlockHash(buf []byte) (string, error) {
 	return hex.EncodeToString(buf), nil
 }
 
+// IsTolerantBlockParsing returns false, by default a block with a transaction which cannot be parsed fails to parse
+func (p *BaseParser) IsTolerantBlockParsing() bool {
+	return false
+}
+
 // GetChainType is type of the blockchain, default is ChainBitcoinType
 func (p *BaseParser) GetChainType() ChainType {
 	return ChainBitcoinType
@@ -268,6 +315,21 @@ func (p *BaseParser) UnpackTx(buf []byte) (*Tx, uint32, error) {
 	return &tx, pt.Height, nil
 }
 
+// DisassembleScript is unsupported
+func (p *BaseParser) DisassembleScript(script []byte) (string, error) {
+	return "", errors.New("Not supported")
+}
+
+// GetBlockSubsidy is unsupported
+func (p *BaseParser) GetBlockSubsidy(height uint32) (*big.Int, error) {
+	return nil, errors.New("Not supported")
+}
+
+// CoinbaseMaturity is unsupported
+func (p *BaseParser) CoinbaseMaturity() (uint32, error) {
+	return 0, errors.New("Not supported")
+}
+
 // DerivationBasePath is unsupported
 func (p *BaseParser) DerivationBasePath(xpub string) (string, error) {
 	return "", errors.New("Not supported")
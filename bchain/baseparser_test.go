@@ -31,6 +31,70 @@ var amounts = []struct {
 	{big.NewInt(12345678), "0.0000000000000000000000000000000012345678", 1234, "!"}, // test of too big number decimal places
 }
 
+func bigIntFromString(s string) *big.Int {
+	b, _ := big.NewInt(0).SetString(s, 10)
+	return b
+}
+
+// amounts that cannot be represented exactly by float64 (more than 53 bits of mantissa)
+var preciseAmounts = []struct {
+	s string
+	a *big.Int
+}{
+	{"90071992.54740993", bigIntFromString("9007199254740993")},
+	{"90071992.54740992", bigIntFromString("9007199254740992")},
+	{"-90071992.54740993", bigIntFromString("-9007199254740993")},
+	{"2099999999.99999999", bigIntFromString("209999999999999999")},
+	{"21000000000.00000001", bigIntFromString("2100000000000000001")},
+	{"123456789012345678901234567890.12345679", bigIntFromString("12345678901234567890123456789012345679")},
+	{"9.007199254740993e7", bigIntFromString("9007199254740993")},
+	{"9007199254740993E-8", bigIntFromString("9007199254740993")},
+	{"2.1e+09", bigIntFromString("210000000000000000")},
+	{"1e-08", big.NewInt(1)},
+	{"-1.5e-7", big.NewInt(-15)},
+	{"1.23456789123e-1", big.NewInt(12345678)},
+	{"0.0000000000000000000000001e25", big.NewInt(100000000)},
+	{" 1.00000001 ", big.NewInt(100000001)},
+}
+
+func TestBaseParser_AmountToBigInt_Precision(t *testing.T) {
+	for _, tt := range preciseAmounts {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := NewBaseParser(8).AmountToBigInt(json.Number(tt.s))
+			if err != nil {
+				t.Errorf("BaseParser.AmountToBigInt() error = %v", err)
+				return
+			}
+			if got.Cmp(tt.a) != 0 {
+				t.Errorf("BaseParser.AmountToBigInt() = %v, want %v", got.String(), tt.a)
+			}
+		})
+	}
+}
+
+func TestBaseParser_AmountToBigInt_Invalid(t *testing.T) {
+	for _, s := range []string{"abc", "1.2.3", "1e", "e5", "1e99999", "1ex"} {
+		t.Run(s, func(t *testing.T) {
+			if got, err := NewBaseParser(8).AmountToBigInt(json.Number(s)); err == nil {
+				t.Errorf("BaseParser.AmountToBigInt() = %v, want error", got.String())
+			}
+		})
+	}
+}
+
+func TestBaseParser_ParseTxFromJson_Precision(t *testing.T) {
+	tx, err := NewBaseParser(8).ParseTxFromJson(json.RawMessage(`{"txid":"1","vout":[{"value":90071992.54740993,"n":0},{"value":2.1e+09,"n":1}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"9007199254740993", "210000000000000000"}
+	for i := range want {
+		if got := tx.Vout[i].ValueSat.String(); got != want[i] {
+			t.Errorf("ParseTxFromJson() vout %d ValueSat = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
 func TestBaseParser_AmountToDecimalString(t *testing.T) {
 	for _, tt := range amounts {
 		t.Run(tt.s, func(t *testing.T) {
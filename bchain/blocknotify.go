@@ -0,0 +1,77 @@
+package bchain
+
+import (
+	"sync"
+	"time"
+)
+
+// BlockNotificationCoalescer batches the notifications about new blocks connected within a time window
+// into a single notification about the range of blocks, which prevents flooding of the subscribers during catch up.
+// Pending notifications are sent at the latest after the window elapses or immediately by Flush,
+// which is expected to be called after the index is caught up with the tip of the chain.
+// With zero window the notifications are passed through one by one.
+type BlockNotificationCoalescer struct {
+	mux         sync.Mutex
+	window      time.Duration
+	onNewBlocks OnNewBlocksFunc
+	pending     bool
+	fromHeight  uint32
+	hash        string
+	height      uint32
+	timer       *time.Timer
+	// batch identifies the pending range so that a late timer of already sent range does not send the next one
+	batch uint64
+}
+
+// NewBlockNotificationCoalescer returns new BlockNotificationCoalescer sending the notifications to onNewBlocks
+func NewBlockNotificationCoalescer(window time.Duration, onNewBlocks OnNewBlocksFunc) *BlockNotificationCoalescer {
+	return &BlockNotificationCoalescer{
+		window:      window,
+		onNewBlocks: onNewBlocks,
+	}
+}
+
+// OnNewBlock takes the notification about a new block, it has the signature of OnNewBlockFunc
+func (c *BlockNotificationCoalescer) OnNewBlock(hash string, height uint32) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.window <= 0 {
+		c.onNewBlocks(height, hash, height)
+		return
+	}
+	// a block with lower height means a reorg, the pending range is sent as it is and a new one started
+	if c.pending && height <= c.height {
+		c.flush()
+	}
+	if !c.pending {
+		c.pending = true
+		c.fromHeight = height
+		c.batch++
+		batch := c.batch
+		c.timer = time.AfterFunc(c.window, func() {
+			c.mux.Lock()
+			defer c.mux.Unlock()
+			if batch == c.batch {
+				c.flush()
+			}
+		})
+	}
+	c.hash = hash
+	c.height = height
+}
+
+// Flush sends the pending notification
+func (c *BlockNotificationCoalescer) Flush() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.flush()
+}
+
+func (c *BlockNotificationCoalescer) flush() {
+	if !c.pending {
+		return
+	}
+	c.timer.Stop()
+	c.pending = false
+	c.onNewBlocks(c.fromHeight, c.hash, c.height)
+}
@@ -0,0 +1,104 @@
+package bchain
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type blocksNotification struct {
+	fromHeight uint32
+	hash       string
+	height     uint32
+}
+
+type blocksNotificationRecorder struct {
+	mux           sync.Mutex
+	notifications []blocksNotification
+}
+
+func (r *blocksNotificationRecorder) onNewBlocks(fromHeight uint32, hash string, height uint32) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.notifications = append(r.notifications, blocksNotification{fromHeight, hash, height})
+}
+
+func (r *blocksNotificationRecorder) get() []blocksNotification {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return append([]blocksNotification(nil), r.notifications...)
+}
+
+func feedBlocks(c *BlockNotificationCoalescer, from, to uint32) {
+	for h := from; h <= to; h++ {
+		c.OnNewBlock("hash"+strconv.Itoa(int(h)), h)
+	}
+}
+
+func TestBlockNotificationCoalescer_Burst(t *testing.T) {
+	r := &blocksNotificationRecorder{}
+	c := NewBlockNotificationCoalescer(time.Hour, r.onNewBlocks)
+	feedBlocks(c, 101, 150)
+	if n := r.get(); len(n) != 0 {
+		t.Fatalf("notifications sent before flush: %+v", n)
+	}
+	// caught up, the burst is sent as one notification and the following blocks one by one
+	c.Flush()
+	c.OnNewBlock("hash151", 151)
+	c.Flush()
+	c.Flush()
+	want := []blocksNotification{
+		{101, "hash150", 150},
+		{151, "hash151", 151},
+	}
+	if got := r.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %+v, want %+v", got, want)
+	}
+}
+
+func TestBlockNotificationCoalescer_Window(t *testing.T) {
+	r := &blocksNotificationRecorder{}
+	c := NewBlockNotificationCoalescer(20*time.Millisecond, r.onNewBlocks)
+	feedBlocks(c, 1, 10)
+	want := []blocksNotification{{1, "hash10", 10}}
+	for i := 0; i < 100; i++ {
+		if len(r.get()) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := r.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %+v, want %+v", got, want)
+	}
+}
+
+func TestBlockNotificationCoalescer_Reorg(t *testing.T) {
+	r := &blocksNotificationRecorder{}
+	c := NewBlockNotificationCoalescer(time.Hour, r.onNewBlocks)
+	feedBlocks(c, 1, 5)
+	feedBlocks(c, 4, 6)
+	c.Flush()
+	want := []blocksNotification{
+		{1, "hash5", 5},
+		{4, "hash6", 6},
+	}
+	if got := r.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %+v, want %+v", got, want)
+	}
+}
+
+func TestBlockNotificationCoalescer_Disabled(t *testing.T) {
+	r := &blocksNotificationRecorder{}
+	c := NewBlockNotificationCoalescer(0, r.onNewBlocks)
+	feedBlocks(c, 1, 3)
+	want := []blocksNotification{
+		{1, "hash1", 1},
+		{2, "hash2", 2},
+		{3, "hash3", 3},
+	}
+	if got := r.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %+v, want %+v", got, want)
+	}
+}
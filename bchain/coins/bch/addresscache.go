@@ -0,0 +1,66 @@
+package bch
+
+import (
+	"container/list"
+	"sync"
+)
+
+// addressCacheEntry is the result of the conversion of an output script to addresses
+type addressCacheEntry struct {
+	script     string
+	addresses  []string
+	searchable bool
+}
+
+// addressCache keeps the addresses of the recently converted output scripts so that the scripts recurring
+// across blocks are not encoded again, the cache holds at most size entries, the least recently used entry is evicted first
+type addressCache struct {
+	mux     sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// lru holds the entries ordered from the most recently used to the least recently used
+	lru *list.List
+}
+
+func newAddressCache(size int) *addressCache {
+	return &addressCache{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		lru:     list.New(),
+	}
+}
+
+// get returns the copy of the cached addresses of the script so that the caller cannot modify the cache
+func (c *addressCache) get(script []byte) ([]string, bool, bool) {
+	c.mux.Lock()
+	el, found := c.entries[string(script)]
+	if !found {
+		c.mux.Unlock()
+		return nil, false, false
+	}
+	c.lru.MoveToFront(el)
+	e := el.Value.(*addressCacheEntry)
+	addresses := make([]string, len(e.addresses))
+	copy(addresses, e.addresses)
+	searchable := e.searchable
+	c.mux.Unlock()
+	return addresses, searchable, true
+}
+
+func (c *addressCache) add(script []byte, addresses []string, searchable bool) {
+	e := &addressCacheEntry{script: string(script), addresses: make([]string, len(addresses)), searchable: searchable}
+	copy(e.addresses, addresses)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if el, found := c.entries[e.script]; found {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	if c.lru.Len() >= c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*addressCacheEntry).script)
+	}
+	c.entries[e.script] = c.lru.PushFront(e)
+}
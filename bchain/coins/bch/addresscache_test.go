@@ -0,0 +1,139 @@
+// +build unittest
+
+package bch
+
+import (
+	"blockbook/bchain/coins/btc"
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var addressCacheScripts = []string{
+	"76a914010d39800f86122416e28f485029acf77507169288ac",
+	"a9146144d57c8aff48492c9dfb914e120b20bad72d6f87",
+	"76a9144fa927fd3bcf57d4e3c582c3d2eb2bd3df8df47c88ac",
+	"6a072020f1686f6a20",
+	"00",
+}
+
+func addressCacheScript(t testing.TB, i int) []byte {
+	b, err := hex.DecodeString(addressCacheScripts[i%len(addressCacheScripts)])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBCashParser_AddressCache(t *testing.T) {
+	for _, format := range []string{"cashaddr", "legacy"} {
+		t.Run(format, func(t *testing.T) {
+			uncached, err := NewBCashParser(GetChainParams("main"), &btc.Configuration{AddressFormat: format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the cache smaller than the number of the scripts to exercise the eviction
+			cached, err := NewBCashParser(GetChainParams("main"), &btc.Configuration{AddressFormat: format, AddressCacheSize: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 4*len(addressCacheScripts); i++ {
+				script := addressCacheScript(t, i)
+				want, wantSearchable, err := uncached.GetAddressesFromAddrDesc(script)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, gotSearchable, err := cached.GetAddressesFromAddrDesc(script)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) || gotSearchable != wantSearchable {
+					t.Errorf("%d: GetAddressesFromAddrDesc() = %v, %v, want %v, %v", i, got, gotSearchable, want, wantSearchable)
+				}
+				// modification of the returned addresses must not change the cache
+				if len(got) > 0 {
+					got[0] = "modified"
+				}
+				got, _, _ = cached.GetAddressesFromAddrDesc(script)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%d: GetAddressesFromAddrDesc() after modification = %v, want %v", i, got, want)
+				}
+				if len(cached.addressCache.entries) > 2 || cached.addressCache.lru.Len() > 2 {
+					t.Fatalf("%d: cache size %d exceeds the limit", i, len(cached.addressCache.entries))
+				}
+			}
+		})
+	}
+}
+
+func Test_addressCache_LRU(t *testing.T) {
+	c := newAddressCache(2)
+	c.add([]byte("a"), []string{"A"}, true)
+	c.add([]byte("b"), []string{"B"}, true)
+	// the use of a makes b the least recently used entry
+	if _, _, found := c.get([]byte("a")); !found {
+		t.Fatal("a not found")
+	}
+	c.add([]byte("c"), []string{"C"}, true)
+	if _, _, found := c.get([]byte("b")); found {
+		t.Error("b not evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if got, _, found := c.get([]byte(k)); !found || !reflect.DeepEqual(got, []string{strings.ToUpper(k)}) {
+			t.Errorf("get(%v) = %v, %v", k, got, found)
+		}
+	}
+	// the update of an entry makes it the most recently used
+	c.add([]byte("a"), []string{"A2"}, false)
+	c.add([]byte("d"), []string{"D"}, true)
+	if _, _, found := c.get([]byte("c")); found {
+		t.Error("c not evicted")
+	}
+	if got, searchable, found := c.get([]byte("a")); !found || !reflect.DeepEqual(got, []string{"A2"}) || searchable {
+		t.Errorf("get(a) = %v, %v, %v", got, searchable, found)
+	}
+}
+
+// isCached returns true if the script is in the cache c, without changing the order of the entries
+func isCached(c *addressCache, script []byte) bool {
+	if c == nil {
+		return false
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	_, found := c.entries[string(script)]
+	return found
+}
+
+// benchmarkAddressCache converts the outputs of blocks paying repeatedly to a small set of hot scripts
+// the scripts not found in the cache are counted by the benchmark as the encodings, the parser does not count them
+func benchmarkAddressCache(b *testing.B, cacheSize int) {
+	parser, err := NewBCashParser(GetChainParams("main"), &btc.Configuration{AddressCacheSize: cacheSize})
+	if err != nil {
+		b.Fatal(err)
+	}
+	const outputsPerBlock = 1000
+	scripts := make([][]byte, outputsPerBlock)
+	for i := range scripts {
+		scripts[i] = addressCacheScript(b, i)
+	}
+	encodings := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, s := range scripts {
+			if !isCached(parser.addressCache, s) {
+				encodings++
+			}
+			parser.GetAddressesFromAddrDesc(s)
+		}
+	}
+	b.ReportMetric(float64(encodings)/float64(b.N), "encodings/block")
+}
+
+func BenchmarkBCashParser_AddressCache(b *testing.B) {
+	for _, size := range []int{0, 1000} {
+		b.Run("size"+strconv.Itoa(size), func(b *testing.B) { benchmarkAddressCache(b, size) })
+	}
+}
@@ -3,10 +3,20 @@ package bch
 import (
 	"blockbook/bchain"
 	"blockbook/bchain/coins/btc"
+	"bytes"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
 
+	"github.com/golang/glog"
 	"github.com/martinboehm/bchutil"
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
 	"github.com/martinboehm/btcutil"
+	"github.com/martinboehm/btcutil/base58"
 	"github.com/martinboehm/btcutil/chaincfg"
 	"github.com/martinboehm/btcutil/txscript"
 	"github.com/schancel/cashaddr-converter/address"
@@ -51,10 +61,23 @@ func init() {
 	RegtestParams.Net = bchutil.Regtestmagic
 }
 
+// maxUnparsedTxids is the number of the last unparsed txids kept by the parser
+const maxUnparsedTxids = 100
+
 // BCashParser handle
 type BCashParser struct {
 	*btc.BitcoinParser
 	AddressFormat AddressFormat
+	// TolerantBlockParsing skips the transactions which cannot be parsed instead of failing the whole block
+	TolerantBlockParsing bool
+	unparsedMux          sync.Mutex
+	unparsedTxs          int
+	unparsedTxids        []string
+	// addressCache keeps the addresses of the output scripts across blocks, nil if the caching is disabled
+	addressCache *addressCache
+	// blockSubsidy and subsidyReductionInterval are the emission schedule of the coin, blockSubsidy is 0 if it is not known
+	blockSubsidy             int64
+	subsidyReductionInterval uint32
 }
 
 // NewBCashParser returns new BCashParser instance
@@ -71,8 +94,17 @@ func NewBCashParser(params *chaincfg.Params, c *btc.Configuration) (*BCashParser
 		return nil, fmt.Errorf("Unknown address format: %s", c.AddressFormat)
 	}
 	p := &BCashParser{
-		BitcoinParser: btc.NewBitcoinParser(params, c),
-		AddressFormat: format,
+		BitcoinParser:            btc.NewBitcoinParser(params, c),
+		AddressFormat:            format,
+		TolerantBlockParsing:     c.TolerantBlockParsing,
+		blockSubsidy:             c.BlockSubsidy,
+		subsidyReductionInterval: c.SubsidyReductionInterval,
+	}
+	if p.subsidyReductionInterval == 0 {
+		p.subsidyReductionInterval = uint32(params.SubsidyReductionInterval)
+	}
+	if c.AddressCacheSize > 0 {
+		p.addressCache = newAddressCache(c.AddressCacheSize)
 	}
 	p.OutputScriptToAddressesFunc = p.outputScriptToAddresses
 	return p, nil
@@ -109,10 +141,25 @@ func (p *BCashParser) GetAddrDescFromAddress(address string) (bchain.AddressDesc
 	return p.addressToOutputScript(address)
 }
 
+// toCashAddr returns the CashAddr address in lowercase with the network prefix,
+// false if the address is not in the CashAddr format
+func (p *BCashParser) toCashAddr(address string) (string, bool) {
+	// CashAddr is case insensitive, unlike the base58 legacy addresses
+	a := strings.ToLower(address)
+	if isCashAddr(a) {
+		return a, true
+	}
+	// the payload of P2PKH and P2SH CashAddr starts with q or p, the legacy addresses of the supported networks never do
+	if len(a) > 0 && (a[0] == 'q' || a[0] == 'p') && strings.IndexByte(a, ':') < 0 {
+		return bchutil.Prefixes[p.Params.Name] + ":" + a, true
+	}
+	return address, false
+}
+
 // addressToOutputScript converts bitcoin address to ScriptPubKey
 func (p *BCashParser) addressToOutputScript(address string) ([]byte, error) {
-	if isCashAddr(address) {
-		da, err := bchutil.DecodeAddress(address, p.Params)
+	if ca, ok := p.toCashAddr(address); ok {
+		da, err := bchutil.DecodeAddress(ca, p.Params)
 		if err != nil {
 			return nil, err
 		}
@@ -122,6 +169,9 @@ func (p *BCashParser) addressToOutputScript(address string) ([]byte, error) {
 		}
 		return script, nil
 	}
+	if script, ok, err := p.legacyHashAddressToOutputScript(address); ok {
+		return script, err
+	}
 	da, err := btcutil.DecodeAddress(address, p.Params)
 	if err != nil {
 		return nil, err
@@ -133,6 +183,29 @@ func (p *BCashParser) addressToOutputScript(address string) ([]byte, error) {
 	return script, nil
 }
 
+// legacyHashAddressToOutputScript returns the P2PKH or P2SH output script of the base58 legacy address with 20 byte hash,
+// false if the address is not of this form; the type of the script is given only by the version of the address in the chain params,
+// btcutil.DecodeAddress looks up the version in all registered networks, where it can denote the other type
+func (p *BCashParser) legacyHashAddressToOutputScript(address string) ([]byte, bool, error) {
+	hash, version, err := base58.CheckDecode(address, p.Params.AddressMagicLen, p.Params.Base58CksumHasher)
+	if err != nil || len(hash) != 20 {
+		return nil, false, nil
+	}
+	switch {
+	case bytes.Equal(version, p.Params.PubKeyHashAddrID):
+		script := make([]byte, 0, 25)
+		script = append(script, txscript.OP_DUP, txscript.OP_HASH160, txscript.OP_DATA_20)
+		script = append(script, hash...)
+		return append(script, txscript.OP_EQUALVERIFY, txscript.OP_CHECKSIG), true, nil
+	case bytes.Equal(version, p.Params.ScriptHashAddrID):
+		script := make([]byte, 0, 23)
+		script = append(script, txscript.OP_HASH160, txscript.OP_DATA_20)
+		script = append(script, hash...)
+		return append(script, txscript.OP_EQUAL), true, nil
+	}
+	return nil, true, fmt.Errorf("Address %v is not valid for the network", address)
+}
+
 func isCashAddr(addr string) bool {
 	n := len(addr)
 	switch {
@@ -147,8 +220,24 @@ func isCashAddr(addr string) bool {
 	return false
 }
 
-// outputScriptToAddresses converts ScriptPubKey to bitcoin addresses
+// outputScriptToAddresses converts ScriptPubKey to bitcoin addresses, the result is taken from the address cache if possible
 func (p *BCashParser) outputScriptToAddresses(script []byte) ([]string, bool, error) {
+	if p.addressCache == nil {
+		return p.encodeOutputScript(script)
+	}
+	if addresses, searchable, found := p.addressCache.get(script); found {
+		return addresses, searchable, nil
+	}
+	addresses, searchable, err := p.encodeOutputScript(script)
+	if err != nil {
+		return nil, false, err
+	}
+	p.addressCache.add(script, addresses, searchable)
+	return addresses, searchable, nil
+}
+
+// encodeOutputScript converts ScriptPubKey to bitcoin addresses in the configured address format
+func (p *BCashParser) encodeOutputScript(script []byte) ([]string, bool, error) {
 	// convert possible P2PK script to P2PK, which bchutil can process
 	var err error
 	script, err = txscript.ConvertP2PKtoP2PKH(script)
@@ -186,3 +275,237 @@ func (p *BCashParser) outputScriptToAddresses(script []byte) ([]string, bool, er
 	}
 	return []string{addr}, len(addr) > 0, nil
 }
+
+// GetBlockSubsidy returns the subsidy of the block at given height by the emission schedule in the configuration,
+// the subsidy is halved every subsidy reduction interval; it is not supported if the schedule is not configured
+func (p *BCashParser) GetBlockSubsidy(height uint32) (*big.Int, error) {
+	if p.blockSubsidy == 0 {
+		return p.BitcoinParser.GetBlockSubsidy(height)
+	}
+	interval := p.subsidyReductionInterval
+	if interval == 0 {
+		return big.NewInt(p.blockSubsidy), nil
+	}
+	halvings := height / interval
+	// the subsidy drops to zero after 64 halvings
+	if halvings >= 64 {
+		return new(big.Int), nil
+	}
+	return big.NewInt(p.blockSubsidy >> halvings), nil
+}
+
+// CoinbaseMaturity returns the coinbase maturity of the chain params
+func (p *BCashParser) CoinbaseMaturity() (uint32, error) {
+	return uint32(p.Params.CoinbaseMaturity), nil
+}
+
+// ParseBlockStream parses raw block and passes its header to onHeader and then its transactions one by one to onTx,
+// the transactions are decoded as they are read so that the whole parsed block is not held in memory
+// the header contains only the size and time of the block, the transactions contain the hex and addresses as returned by ParseTx
+func (p *BCashParser) ParseBlockStream(b []byte, onHeader func(*bchain.BlockHeader) error, onTx func(*bchain.Tx) error) error {
+	r := bytes.NewReader(b)
+	h := wire.BlockHeader{}
+	if err := h.Deserialize(r); err != nil {
+		return err
+	}
+	if err := onHeader(&bchain.BlockHeader{
+		Size: len(b),
+		Time: h.Timestamp.Unix(),
+	}); err != nil {
+		return err
+	}
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < count; i++ {
+		start := len(b) - r.Len()
+		t, err := p.readBlockTx(b, r)
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			continue
+		}
+		tx := p.TxFromMsgTx(t, true)
+		tx.Hex = hex.EncodeToString(b[start : len(b)-r.Len()])
+		if err := onTx(&tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseBlock parses raw block to our Block struct, in the tolerant mode the transactions which cannot be parsed are skipped
+func (p *BCashParser) ParseBlock(b []byte) (*bchain.Block, error) {
+	if !p.TolerantBlockParsing {
+		return p.BitcoinParser.ParseBlock(b)
+	}
+	r := bytes.NewReader(b)
+	h := wire.BlockHeader{}
+	if err := h.Deserialize(r); err != nil {
+		return nil, err
+	}
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	var txs []bchain.Tx
+	for i := uint64(0); i < count; i++ {
+		t, err := p.readBlockTx(b, r)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			txs = append(txs, p.TxFromMsgTx(t, false))
+		}
+	}
+	return &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Size: len(b),
+			Time: h.Timestamp.Unix(),
+		},
+		Txs: txs,
+	}, nil
+}
+
+// readBlockTx deserializes the next transaction of the raw block b from r
+// in the tolerant mode the transaction which cannot be deserialized is skipped, recorded as unparsed and nil is returned
+func (p *BCashParser) readBlockTx(b []byte, r *bytes.Reader) (*wire.MsgTx, error) {
+	start := len(b) - r.Len()
+	t := wire.MsgTx{}
+	err := t.Deserialize(r)
+	if err == nil {
+		return &t, nil
+	}
+	if !p.TolerantBlockParsing {
+		return nil, err
+	}
+	// the block fails only if even the raw structure of the transaction is broken
+	l, lerr := rawTxLength(b[start:])
+	if lerr != nil {
+		return nil, err
+	}
+	p.addUnparsedTx(chainhash.DoubleHashH(b[start:start+l]).String(), err)
+	if _, err := r.Seek(int64(start+l), io.SeekStart); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// rawTxLength returns the length of the serialized transaction at the beginning of b, only the structure of the transaction is checked
+func rawTxLength(b []byte) (int, error) {
+	r := bytes.NewReader(b)
+	skip := func(n uint64) error {
+		if n > uint64(r.Len()) {
+			return io.ErrUnexpectedEOF
+		}
+		_, err := r.Seek(int64(n), io.SeekCurrent)
+		return err
+	}
+	// version
+	if err := skip(4); err != nil {
+		return 0, err
+	}
+	inputs, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return 0, err
+	}
+	// zero inputs followed by the flag 1 is the marker of the segwit serialization
+	witness := false
+	if inputs == 0 && r.Len() > 0 && b[len(b)-r.Len()] == 1 {
+		witness = true
+		if err := skip(1); err != nil {
+			return 0, err
+		}
+		if inputs, err = wire.ReadVarInt(r, 0); err != nil {
+			return 0, err
+		}
+	}
+	for i := uint64(0); i < inputs; i++ {
+		// previous outpoint
+		if err := skip(36); err != nil {
+			return 0, err
+		}
+		l, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return 0, err
+		}
+		// signature script and sequence
+		if err := skip(l); err != nil {
+			return 0, err
+		}
+		if err := skip(4); err != nil {
+			return 0, err
+		}
+	}
+	outputs, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return 0, err
+	}
+	for i := uint64(0); i < outputs; i++ {
+		// value
+		if err := skip(8); err != nil {
+			return 0, err
+		}
+		l, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return 0, err
+		}
+		if err := skip(l); err != nil {
+			return 0, err
+		}
+	}
+	if witness {
+		for i := uint64(0); i < inputs; i++ {
+			items, err := wire.ReadVarInt(r, 0)
+			if err != nil {
+				return 0, err
+			}
+			for j := uint64(0); j < items; j++ {
+				l, err := wire.ReadVarInt(r, 0)
+				if err != nil {
+					return 0, err
+				}
+				if err := skip(l); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	// lock time
+	if err := skip(4); err != nil {
+		return 0, err
+	}
+	return len(b) - r.Len(), nil
+}
+
+func (p *BCashParser) addUnparsedTx(txid string, err error) {
+	glog.Error("ParseBlock: skipping unparsed tx ", txid, ": ", err)
+	p.unparsedMux.Lock()
+	defer p.unparsedMux.Unlock()
+	p.unparsedTxs++
+	if len(p.unparsedTxids) >= maxUnparsedTxids {
+		p.unparsedTxids = p.unparsedTxids[1:]
+	}
+	p.unparsedTxids = append(p.unparsedTxids, txid)
+}
+
+// IsTolerantBlockParsing returns true if the transactions which cannot be parsed are skipped
+func (p *BCashParser) IsTolerantBlockParsing() bool {
+	return p.TolerantBlockParsing
+}
+
+// UnparsedTxs returns the number of the transactions skipped by the tolerant block parsing
+func (p *BCashParser) UnparsedTxs() int {
+	p.unparsedMux.Lock()
+	defer p.unparsedMux.Unlock()
+	return p.unparsedTxs
+}
+
+// UnparsedTxids returns the txids of the last transactions skipped by the tolerant block parsing
+func (p *BCashParser) UnparsedTxids() []string {
+	p.unparsedMux.Lock()
+	defer p.unparsedMux.Unlock()
+	return append([]string(nil), p.unparsedTxids...)
+}
@@ -5,12 +5,16 @@ package bch
 import (
 	"blockbook/bchain"
 	"blockbook/bchain/coins/btc"
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"math/big"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/martinboehm/btcd/wire"
 	"github.com/martinboehm/btcutil/chaincfg"
 )
 
@@ -71,6 +75,48 @@ func Test_GetAddrDescFromAddress(t *testing.T) {
 			hex:       "a91488f772450c830a30eddfdc08a93d5f2ae1a30e1787",
 			wantErr:   false,
 		},
+		{
+			name:      "main-P2SH-unprefixed",
+			parser:    mainParserCashAddr,
+			addresses: []string{"pzy0wuj9pjps5v8dmlwq32fatu4wrgcwzuayq5nfhh"},
+			hex:       "a91488f772450c830a30eddfdc08a93d5f2ae1a30e1787",
+			wantErr:   false,
+		},
+		{
+			name:      "main-P2PKH-uppercase",
+			parser:    mainParserLegacy,
+			addresses: []string{"BITCOINCASH:QQXGJELX8QK85T9XFK8G2ZLUNXMHXMS6P55XARV2R5"},
+			hex:       "76a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac",
+			wantErr:   false,
+		},
+		{
+			name:      "test-P2PKH-unprefixed",
+			parser:    testParserCashAddr,
+			addresses: []string{"qp86jfla8084048rckpv85ht90falr050s03ejaesm"},
+			hex:       "76a9144fa927fd3bcf57d4e3c582c3d2eb2bd3df8df47c88ac",
+			wantErr:   false,
+		},
+		{
+			name:      "main-P2PKH-same-hash-as-P2SH",
+			parser:    mainParserLegacy,
+			addresses: []string{"1DVDKxuSfeBpHiHxzZEDRG9qdTPu8s9qrt"},
+			hex:       "76a91488f772450c830a30eddfdc08a93d5f2ae1a30e1788ac",
+			wantErr:   false,
+		},
+		{
+			name:      "main-legacy-wrong-network",
+			parser:    mainParserLegacy,
+			addresses: []string{"mnnAKPTSrWjgoi3uEYaQkHA1QEC5btFeBr"},
+			hex:       "",
+			wantErr:   true,
+		},
+		{
+			name:      "test-P2PKH-wrong-network",
+			parser:    testParserCashAddr,
+			addresses: []string{"bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5"},
+			hex:       "",
+			wantErr:   true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,6 +134,122 @@ func Test_GetAddrDescFromAddress(t *testing.T) {
 	}
 }
 
+// Test_AddressTypesOfSameHash checks that the P2PKH and P2SH addresses with the same hash are converted to different scripts
+func Test_AddressTypesOfSameHash(t *testing.T) {
+	mainParserCashAddr, mainParserLegacy, _, _ := setupParsers(t)
+	scripts := []string{
+		"76a91488f772450c830a30eddfdc08a93d5f2ae1a30e1788ac",
+		"a91488f772450c830a30eddfdc08a93d5f2ae1a30e1787",
+	}
+	for _, parser := range []*BCashParser{mainParserCashAddr, mainParserLegacy} {
+		var addresses []string
+		for _, script := range scripts {
+			a, _, err := parser.GetAddressesFromAddrDesc(hexToBytes(t, script))
+			if err != nil || len(a) != 1 {
+				t.Fatalf("GetAddressesFromAddrDesc(%v) = %v, %v", script, a, err)
+			}
+			addresses = append(addresses, a[0])
+			got, err := parser.GetAddrDescFromAddress(a[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := hex.EncodeToString(got); h != script {
+				t.Errorf("GetAddrDescFromAddress(%v) = %v, want %v", a[0], h, script)
+			}
+		}
+		if addresses[0] == addresses[1] {
+			t.Errorf("P2PKH and P2SH address are the same %v", addresses[0])
+		}
+	}
+}
+
+// Test_NormalizeAddress checks the conversion of an address to the descriptor and back, by which the api
+// returns the addresses in the canonical form, CashAddr in lowercase with the network prefix
+func Test_NormalizeAddress(t *testing.T) {
+	mainParserCashAddr, mainParserLegacy, testParserCashAddr, _ := setupParsers(t)
+	tests := []struct {
+		name    string
+		parser  *BCashParser
+		address string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "main-P2PKH-prefixed",
+			parser:  mainParserCashAddr,
+			address: "bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5",
+			want:    "bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5",
+		},
+		{
+			name:    "main-P2PKH-unprefixed",
+			parser:  mainParserCashAddr,
+			address: "qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5",
+			want:    "bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5",
+		},
+		{
+			name:    "main-P2PKH-uppercase",
+			parser:  mainParserCashAddr,
+			address: "BITCOINCASH:QQXGJELX8QK85T9XFK8G2ZLUNXMHXMS6P55XARV2R5",
+			want:    "bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5",
+		},
+		{
+			name:    "main-P2PKH-uppercase-unprefixed",
+			parser:  mainParserCashAddr,
+			address: "QQXGJELX8QK85T9XFK8G2ZLUNXMHXMS6P55XARV2R5",
+			want:    "bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5",
+		},
+		{
+			name:    "main-P2PKH-mixed-case",
+			parser:  mainParserCashAddr,
+			address: "BitcoinCash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5",
+			want:    "bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5",
+		},
+		{
+			name:    "main-P2PKH-legacy",
+			parser:  mainParserCashAddr,
+			address: "129HiRqekqPVucKy2M8zsqvafGgKypciPp",
+			want:    "bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5",
+		},
+		{
+			name:    "main-P2SH-unprefixed-legacy-parser",
+			parser:  mainParserLegacy,
+			address: "pzy0wuj9pjps5v8dmlwq32fatu4wrgcwzuayq5nfhh",
+			want:    "3EBEFWPtDYWCNszQ7etoqtWmmygccayLiH",
+		},
+		{
+			name:    "test-P2PKH-unprefixed",
+			parser:  testParserCashAddr,
+			address: "QP86JFLA8084048RCKPV85HT90FALR050S03EJAESM",
+			want:    "bchtest:qp86jfla8084048rckpv85ht90falr050s03ejaesm",
+		},
+		{
+			name:    "main-P2PKH-bad-checksum",
+			parser:  mainParserCashAddr,
+			address: "qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r6",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addrDesc, err := tt.parser.GetAddrDescFromAddress(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAddrDescFromAddress() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, _, err := tt.parser.GetAddressesFromAddrDesc(addrDesc)
+			if err != nil {
+				t.Fatalf("GetAddressesFromAddrDesc() error = %v", err)
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("GetAddressesFromAddrDesc() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_GetAddressesFromAddrDesc(t *testing.T) {
 	mainParserCashAddr, mainParserLegacy, testParserCashAddr, testParserLegacy := setupParsers(t)
 	tests := []struct {
@@ -206,19 +368,19 @@ var (
 )
 
 func setupParsers(t *testing.T) (mainParserCashAddr, mainParserLegacy, testParserCashAddr, testParserLegacy *BCashParser) {
-	parser1, err := NewBCashParser(GetChainParams("main"), &btc.Configuration{AddressFormat: "cashaddr"})
+	parser1, err := NewBCashParser(GetChainParams("main"), &btc.Configuration{AddressFormat: "cashaddr", BlockSubsidy: 5000000000})
 	if err != nil {
 		t.Fatalf("NewBCashParser() error = %v", err)
 	}
-	parser2, err := NewBCashParser(GetChainParams("main"), &btc.Configuration{AddressFormat: "legacy"})
+	parser2, err := NewBCashParser(GetChainParams("main"), &btc.Configuration{AddressFormat: "legacy", BlockSubsidy: 5000000000})
 	if err != nil {
 		t.Fatalf("NewBCashParser() error = %v", err)
 	}
-	parser3, err := NewBCashParser(GetChainParams("test"), &btc.Configuration{AddressFormat: "cashaddr"})
+	parser3, err := NewBCashParser(GetChainParams("test"), &btc.Configuration{AddressFormat: "cashaddr", BlockSubsidy: 5000000000})
 	if err != nil {
 		t.Fatalf("NewBCashParser() error = %v", err)
 	}
-	parser4, err := NewBCashParser(GetChainParams("test"), &btc.Configuration{AddressFormat: "legacy"})
+	parser4, err := NewBCashParser(GetChainParams("test"), &btc.Configuration{AddressFormat: "legacy", BlockSubsidy: 5000000000})
 	if err != nil {
 		t.Fatalf("NewBCashParser() error = %v", err)
 	}
@@ -350,3 +512,228 @@ func Test_UnpackTx(t *testing.T) {
 		})
 	}
 }
+
+func Test_GetBlockSubsidy(t *testing.T) {
+	mainParser, _, _, _ := setupParsers(t)
+	regtestParser, err := NewBCashParser(GetChainParams("regtest"), &btc.Configuration{BlockSubsidy: 5000000000})
+	if err != nil {
+		t.Fatalf("NewBCashParser() error = %v", err)
+	}
+	scheduleParser, err := NewBCashParser(GetChainParams("main"), &btc.Configuration{BlockSubsidy: 1000, SubsidyReductionInterval: 10})
+	if err != nil {
+		t.Fatalf("NewBCashParser() error = %v", err)
+	}
+	tests := []struct {
+		name   string
+		parser *BCashParser
+		height uint32
+		want   int64
+	}{
+		{name: "genesis", parser: mainParser, height: 0, want: 5000000000},
+		{name: "last before 1st halving", parser: mainParser, height: 209999, want: 5000000000},
+		{name: "1st halving", parser: mainParser, height: 210000, want: 2500000000},
+		{name: "last before 2nd halving", parser: mainParser, height: 419999, want: 2500000000},
+		{name: "2nd halving", parser: mainParser, height: 420000, want: 1250000000},
+		{name: "33rd halving", parser: mainParser, height: 33 * 210000, want: 0},
+		{name: "regtest 1st halving", parser: regtestParser, height: 150, want: 2500000000},
+		{name: "configured schedule", parser: scheduleParser, height: 9, want: 1000},
+		{name: "configured schedule 1st halving", parser: scheduleParser, height: 10, want: 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.GetBlockSubsidy(tt.height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Int64() != tt.want {
+				t.Errorf("GetBlockSubsidy(%v) = %v, want %v", tt.height, got, tt.want)
+			}
+		})
+	}
+	// the subsidy is not computed without the emission schedule
+	unknownParser, err := NewBCashParser(GetChainParams("main"), &btc.Configuration{})
+	if err != nil {
+		t.Fatalf("NewBCashParser() error = %v", err)
+	}
+	if got, err := unknownParser.GetBlockSubsidy(0); err == nil {
+		t.Errorf("GetBlockSubsidy() without the schedule = %v, want error", got)
+	}
+}
+
+func Test_ParseBlockStream(t *testing.T) {
+	mainParser, _, _, _ := setupParsers(t)
+	var buf bytes.Buffer
+	header := wire.BlockHeader{Version: 1, Timestamp: time.Unix(1550000000, 0), Bits: 0x18044a6e, Nonce: 1876521596}
+	if err := header.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := wire.WriteVarInt(&buf, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	txs := []*bchain.Tx{&testTx1, &testTx2}
+	for _, tx := range txs {
+		buf.Write(hexToBytes(t, tx.Hex))
+	}
+	rawBlock := buf.Bytes()
+
+	full, err := mainParser.ParseBlock(rawBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamedHeader *bchain.BlockHeader
+	var streamedTxs []bchain.Tx
+	err = mainParser.ParseBlockStream(rawBlock, func(h *bchain.BlockHeader) error {
+		if len(streamedTxs) > 0 {
+			t.Error("header delivered after transactions")
+		}
+		streamedHeader = h
+		return nil
+	}, func(tx *bchain.Tx) error {
+		if streamedHeader == nil {
+			t.Error("transaction delivered before header")
+		}
+		streamedTxs = append(streamedTxs, *tx)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*streamedHeader, full.BlockHeader) {
+		t.Errorf("ParseBlockStream() header = %+v, want %+v", *streamedHeader, full.BlockHeader)
+	}
+	if len(streamedTxs) != len(full.Txs) {
+		t.Fatalf("ParseBlockStream() %v transactions, want %v", len(streamedTxs), len(full.Txs))
+	}
+	for i := range streamedTxs {
+		if streamedTxs[i].Txid != full.Txs[i].Txid {
+			t.Errorf("ParseBlockStream() tx %d txid = %v, want %v", i, streamedTxs[i].Txid, full.Txs[i].Txid)
+		}
+		want, err := mainParser.ParseTx(hexToBytes(t, txs[i].Hex))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(&streamedTxs[i], want) {
+			t.Errorf("ParseBlockStream() tx %d = %+v, want %+v", i, streamedTxs[i], want)
+		}
+	}
+
+	// the processing stops on error of the callback
+	stopErr := errors.New("stop")
+	count := 0
+	err = mainParser.ParseBlockStream(rawBlock, func(h *bchain.BlockHeader) error { return nil }, func(tx *bchain.Tx) error {
+		count++
+		return stopErr
+	})
+	if err != stopErr || count != 1 {
+		t.Errorf("ParseBlockStream() error = %v after %d transactions, want %v after 1", err, count, stopErr)
+	}
+
+	if err = mainParser.ParseBlockStream(rawBlock[:len(rawBlock)-10], func(h *bchain.BlockHeader) error { return nil }, func(tx *bchain.Tx) error { return nil }); err == nil {
+		t.Error("ParseBlockStream() of truncated block did not return error")
+	}
+}
+
+func Test_ParseBlock_Tolerant(t *testing.T) {
+	// transaction without inputs and two outputs, structurally valid but rejected by the deserialization
+	// because the zero input count is interpreted as the segwit marker followed by an invalid flag
+	const unparsedHex = "010000000002e8030000000000000151e803000000000000015100000000"
+	const unparsedTxid = "079479cbf715f63466b9cb08642d8dd6e50900bedc7d7cd868d044dd496bd574"
+	var buf bytes.Buffer
+	header := wire.BlockHeader{Version: 1, Timestamp: time.Unix(1550000000, 0), Bits: 0x18044a6e, Nonce: 1876521596}
+	if err := header.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := wire.WriteVarInt(&buf, 0, 3); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(hexToBytes(t, testTx1.Hex))
+	buf.Write(hexToBytes(t, unparsedHex))
+	buf.Write(hexToBytes(t, testTx2.Hex))
+	rawBlock := buf.Bytes()
+
+	parser, _, _, _ := setupParsers(t)
+	if _, err := parser.ParseBlock(rawBlock); err == nil {
+		t.Fatal("ParseBlock() of block with unparseable transaction did not return error")
+	}
+
+	parser.TolerantBlockParsing = true
+	block, err := parser.ParseBlock(rawBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTxids := []string{testTx1.Txid, testTx2.Txid}
+	if len(block.Txs) != len(wantTxids) {
+		t.Fatalf("ParseBlock() %d transactions, want %d", len(block.Txs), len(wantTxids))
+	}
+	for i := range block.Txs {
+		if block.Txs[i].Txid != wantTxids[i] {
+			t.Errorf("ParseBlock() tx %d txid = %v, want %v", i, block.Txs[i].Txid, wantTxids[i])
+		}
+	}
+	if len(block.Txs[1].Vout) != len(testTx2.Vout) || block.Txs[1].Vout[0].ValueSat.Cmp(&testTx2.Vout[0].ValueSat) != 0 {
+		t.Errorf("ParseBlock() tx after the unparsed tx = %+v, want %+v", block.Txs[1], testTx2)
+	}
+	var streamedTxids []string
+	err = parser.ParseBlockStream(rawBlock, func(h *bchain.BlockHeader) error { return nil }, func(tx *bchain.Tx) error {
+		streamedTxids = append(streamedTxids, tx.Txid)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(streamedTxids, wantTxids) {
+		t.Errorf("ParseBlockStream() txids = %v, want %v", streamedTxids, wantTxids)
+	}
+	if got := parser.UnparsedTxs(); got != 2 {
+		t.Errorf("UnparsedTxs() = %v, want 2", got)
+	}
+	if got := parser.UnparsedTxids(); !reflect.DeepEqual(got, []string{unparsedTxid, unparsedTxid}) {
+		t.Errorf("UnparsedTxids() = %v, want %v", got, []string{unparsedTxid, unparsedTxid})
+	}
+
+	// the block with broken structure of a transaction still fails
+	if _, err = parser.ParseBlock(rawBlock[:len(rawBlock)-10]); err == nil {
+		t.Error("ParseBlock() of truncated block did not return error")
+	}
+}
+
+func hexToBytes(t *testing.T, h string) []byte {
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func Test_ParseTxSequence(t *testing.T) {
+	mainParser, _, _, _ := setupParsers(t)
+	sequences := []uint32{0, 0xfffffffd, 0xfffffffe, bchain.SequenceFinal}
+	msgTx := wire.NewMsgTx(1)
+	for i, s := range sequences {
+		in := wire.NewTxIn(&wire.OutPoint{Index: uint32(i)}, []byte{0x51}, nil)
+		in.PreviousOutPoint.Hash[0] = byte(i + 1)
+		in.Sequence = s
+		msgTx.AddTxIn(in)
+	}
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	msgTx.LockTime = 570000
+	var buf bytes.Buffer
+	if err := msgTx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := mainParser.ParseTx(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Vin) != len(sequences) {
+		t.Fatalf("ParseTx() %d inputs, want %d", len(tx.Vin), len(sequences))
+	}
+	for i, s := range sequences {
+		if tx.Vin[i].Sequence != s {
+			t.Errorf("ParseTx() input %d sequence = %d, want %d", i, tx.Vin[i].Sequence, s)
+		}
+	}
+	if tx.Vin[3].Sequence != wire.MaxTxInSequenceNum {
+		t.Errorf("ParseTx() final sequence = %d, want %d", tx.Vin[3].Sequence, wire.MaxTxInSequenceNum)
+	}
+}
@@ -3,9 +3,12 @@ package bch
 import (
 	"blockbook/bchain"
 	"blockbook/bchain/coins/btc"
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"io"
 	"math/big"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/juju/errors"
@@ -15,6 +18,53 @@ import (
 // BCashRPC is an interface to JSON-RPC bitcoind service.
 type BCashRPC struct {
 	*btc.BitcoinRPC
+	blockHashesSupport int32
+	parserCheck        parserCheckConfiguration
+	parserCheckStop    chan struct{}
+	sendTx             sendTxConfiguration
+	estimateFee        estimateFeeConfiguration
+	blockSize          blockSizeConfiguration
+	medianTime         medianTimeCache
+	chainStats         chainStatsCache
+	genesis            genesisCache
+}
+
+// MaxStandardTxSize is the maximum size in bytes of a standard transaction relayed by the backend
+const MaxStandardTxSize = 100000
+
+// sendTxConfiguration configures the check of the size of the transactions before they are sent to the backend
+type sendTxConfiguration struct {
+	// MaxTxSize is the maximum size of a sent transaction in bytes, 0 means MaxStandardTxSize, negative value disables the check
+	MaxTxSize int `json:"max_tx_size,omitempty"`
+}
+
+// DefaultMaxBlockSize is the default limit of the size of a raw block downloaded from the backend,
+// generously above the maximum block size of the chain (32 MB)
+const DefaultMaxBlockSize = 128 * 1024 * 1024
+
+// maxBlockResponseOverhead is the size of the response of getblock without the hex of the block
+const maxBlockResponseOverhead = 1024
+
+// blockSizeConfiguration configures the sanity check of the size of the raw blocks before they are parsed
+type blockSizeConfiguration struct {
+	// MaxBlockSize is the maximum size of a raw block in bytes, 0 means DefaultMaxBlockSize, negative value disables the check
+	MaxBlockSize int `json:"max_block_size,omitempty"`
+}
+
+// estimateFeeConfiguration configures the use of estimatesmartfee, which is supported only by some versions of the backend
+type estimateFeeConfiguration struct {
+	// EstimateSmartFee enables the use of estimatesmartfee, otherwise only estimatefee is used
+	EstimateSmartFee bool `json:"estimate_smart_fee,omitempty"`
+	// EstimateSmartFeeFallback enables the use of estimatefee if estimatesmartfee has insufficient data for the target
+	EstimateSmartFeeFallback bool `json:"estimate_smart_fee_fallback,omitempty"`
+}
+
+// parserCheckConfiguration configures the periodic check of the parser against the backend, disabled if the interval is not set
+type parserCheckConfiguration struct {
+	// ParserCheckInterval is the interval of the check in seconds
+	ParserCheckInterval int `json:"parser_check_interval,omitempty"`
+	// ParserCheckBlocks is the number of the last blocks whose transactions are checked
+	ParserCheckBlocks int `json:"parser_check_blocks,omitempty"`
 }
 
 // NewBCashRPC returns new BCashRPC instance.
@@ -25,9 +75,30 @@ func NewBCashRPC(config json.RawMessage, pushHandler func(bchain.NotificationTyp
 	}
 
 	s := &BCashRPC{
-		b.(*btc.BitcoinRPC),
+		BitcoinRPC: b.(*btc.BitcoinRPC),
 	}
 	s.ChainConfig.SupportsEstimateSmartFee = false
+	if err = json.Unmarshal(config, &s.parserCheck); err != nil {
+		return nil, errors.Annotatef(err, "Invalid configuration file")
+	}
+	if s.parserCheck.ParserCheckBlocks < 1 {
+		s.parserCheck.ParserCheckBlocks = 1
+	}
+	if err = json.Unmarshal(config, &s.sendTx); err != nil {
+		return nil, errors.Annotatef(err, "Invalid configuration file")
+	}
+	if s.sendTx.MaxTxSize == 0 {
+		s.sendTx.MaxTxSize = MaxStandardTxSize
+	}
+	if err = json.Unmarshal(config, &s.estimateFee); err != nil {
+		return nil, errors.Annotatef(err, "Invalid configuration file")
+	}
+	if err = json.Unmarshal(config, &s.blockSize); err != nil {
+		return nil, errors.Annotatef(err, "Invalid configuration file")
+	}
+	if s.blockSize.MaxBlockSize == 0 {
+		s.blockSize.MaxBlockSize = DefaultMaxBlockSize
+	}
 
 	return s, nil
 }
@@ -60,9 +131,55 @@ func (b *BCashRPC) Initialize() error {
 
 	glog.Info("rpc: block chain ", params.Name)
 
+	if b.parserCheck.ParserCheckInterval > 0 && b.parserCheckStop == nil {
+		b.parserCheckStop = make(chan struct{})
+		go b.runParserCheck(time.Duration(b.parserCheck.ParserCheckInterval)*time.Second, b.parserCheck.ParserCheckBlocks, b.parserCheckStop)
+		glog.Info("rpc: parser check every ", b.parserCheck.ParserCheckInterval, "s of ", b.parserCheck.ParserCheckBlocks, " blocks")
+	}
+
 	return nil
 }
 
+// Shutdown stops the parser check and shuts down the BitcoinRPC
+func (b *BCashRPC) Shutdown(ctx context.Context) error {
+	if b.parserCheckStop != nil {
+		close(b.parserCheckStop)
+		b.parserCheckStop = nil
+	}
+	return b.BitcoinRPC.Shutdown(ctx)
+}
+
+// GetChainInfo returns information about the connected backend, the number of the transactions skipped by the parser,
+// the median time past of the best block and the uptime of the backend, if the backend supports it
+func (b *BCashRPC) GetChainInfo() (*bchain.ChainInfo, error) {
+	ci, err := b.BitcoinRPC.GetChainInfo()
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := b.Parser.(*BCashParser); ok {
+		ci.UnparsedTxs = p.UnparsedTxs()
+	}
+	if ci.MedianTime, err = b.GetMedianTimePast(ci.Bestblockhash); err != nil {
+		glog.Warning("GetMedianTimePast ", ci.Bestblockhash, ": ", err)
+	}
+	if uptime, err := b.GetUptime(); err == nil {
+		ci.Uptime = uptime
+		ci.StartTime = time.Now().Unix() - uptime
+	} else if err != btc.ErrUptimeNotSupported {
+		glog.Warning("GetUptime: ", err)
+	}
+	return ci, nil
+}
+
+// SendRawTransaction sends the transaction to the backend, a transaction bigger than the configured maximum size
+// is rejected without contacting the backend
+func (b *BCashRPC) SendRawTransaction(tx string) (string, error) {
+	if size := len(tx) / 2; b.sendTx.MaxTxSize > 0 && size > b.sendTx.MaxTxSize {
+		return "", errors.Errorf("Transaction size %d bytes exceeds the maximum size %d bytes", size, b.sendTx.MaxTxSize)
+	}
+	return b.BitcoinRPC.SendRawTransaction(tx)
+}
+
 // getblock
 
 type cmdGetBlock struct {
@@ -82,10 +199,19 @@ type cmdEstimateSmartFee struct {
 	} `json:"params"`
 }
 
-// GetBlock returns block with given hash.
+type resEstimateSmartFee struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result struct {
+		Feerate json.Number `json:"feerate"`
+		Errors  []string    `json:"errors"`
+		Blocks  int         `json:"blocks"`
+	} `json:"result"`
+}
+
+// GetBlock returns block with given hash or, if the hash is empty, at given height, including the genesis block
 func (b *BCashRPC) GetBlock(hash string, height uint32) (*bchain.Block, error) {
 	var err error
-	if hash == "" && height > 0 {
+	if hash == "" {
 		hash, err = b.GetBlockHash(height)
 		if err != nil {
 			return nil, err
@@ -95,14 +221,10 @@ func (b *BCashRPC) GetBlock(hash string, height uint32) (*bchain.Block, error) {
 	if err != nil {
 		return nil, err
 	}
-	data, err := b.GetBlockRaw(hash)
+	block, err := b.getParsedBlock(hash)
 	if err != nil {
 		return nil, err
 	}
-	block, err := b.Parser.ParseBlock(data)
-	if err != nil {
-		return nil, errors.Annotatef(err, "hash %v", hash)
-	}
 	// size is not returned by GetBlockHeader and would be overwritten
 	size := block.Size
 	block.BlockHeader = *header
@@ -110,6 +232,31 @@ func (b *BCashRPC) GetBlock(hash string, height uint32) (*bchain.Block, error) {
 	return block, nil
 }
 
+// getParsedBlock downloads and parses the raw block, the download is repeated once if the block is truncated,
+// which is usually caused by a partial response of the backend, other parse errors are deterministic and returned immediately
+func (b *BCashRPC) getParsedBlock(hash string) (*bchain.Block, error) {
+	for retry := false; ; retry = true {
+		data, err := b.GetBlockRaw(hash)
+		if err != nil {
+			return nil, err
+		}
+		block, err := b.Parser.ParseBlock(data)
+		if err == nil {
+			return block, nil
+		}
+		if retry || !isTruncatedBlock(err) {
+			return nil, errors.Annotatef(err, "hash %v", hash)
+		}
+		glog.Warning("rpc: block ", hash, " truncated (", len(data), " bytes): ", err, ", downloading again")
+	}
+}
+
+// isTruncatedBlock returns true if the parse error is caused by the end of the data in the middle of the block
+func isTruncatedBlock(err error) bool {
+	err = errors.Cause(err)
+	return err == io.ErrUnexpectedEOF || err == io.EOF
+}
+
 // GetBlockRaw returns block with given hash as bytes.
 func (b *BCashRPC) GetBlockRaw(hash string) ([]byte, error) {
 	glog.V(1).Info("rpc: getblock (verbose=0) ", hash)
@@ -118,9 +265,16 @@ func (b *BCashRPC) GetBlockRaw(hash string) ([]byte, error) {
 	req := cmdGetBlock{Method: "getblock"}
 	req.Params.BlockHash = hash
 	req.Params.Verbose = false
-	err := b.Call(&req, &res)
+	// the response with the block in hex is not read beyond twice the maximum block size,
+	// the memory of an oversized block is not allocated
+	maxBytes := b.MaxResponseBytes
+	if b.blockSize.MaxBlockSize > 0 {
+		maxBytes = 2*int64(b.blockSize.MaxBlockSize) + maxBlockResponseOverhead
+	}
+	err := b.CallWithMaxResponseBytes(&req, &res, maxBytes)
 
 	if err != nil {
+		glog.Error("rpc: getblock ", hash, ": ", err)
 		return nil, errors.Annotatef(err, "hash %v", hash)
 	}
 	if res.Error != nil {
@@ -129,17 +283,74 @@ func (b *BCashRPC) GetBlockRaw(hash string) ([]byte, error) {
 		}
 		return nil, errors.Annotatef(res.Error, "hash %v", hash)
 	}
+	// the size is checked before the block is decoded and parsed, which would allocate memory proportional to the size
+	if size := len(res.Result) / 2; b.blockSize.MaxBlockSize > 0 && size > b.blockSize.MaxBlockSize {
+		glog.Error("rpc: getblock ", hash, ": block size ", size, " bytes exceeds the maximum block size ", b.blockSize.MaxBlockSize, " bytes")
+		return nil, errors.Errorf("Block %v size %d bytes exceeds the maximum block size %d bytes", hash, size, b.blockSize.MaxBlockSize)
+	}
 	return hex.DecodeString(res.Result)
 }
 
 // GetBlockInfo returns extended header (more info than in bchain.BlockHeader) with a list of txids
 func (b *BCashRPC) GetBlockInfo(hash string) (*bchain.BlockInfo, error) {
-	glog.V(1).Info("rpc: getblock (verbosity=1) ", hash)
+	bi, err := b.GetBlockInfoVerbose(hash, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &bi.BlockInfo, nil
+}
 
-	res := btc.ResGetBlockInfo{}
-	req := cmdGetBlock{Method: "getblock"}
+// GetCoinbaseTx returns the coinbase transaction of the block with given hash,
+// only the list of txids of the block and the coinbase transaction are downloaded, not the whole block
+func (b *BCashRPC) GetCoinbaseTx(hash string) (*bchain.Tx, error) {
+	bi, err := b.GetBlockInfo(hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(bi.Txids) == 0 {
+		return nil, errors.Errorf("Block %v without transactions", hash)
+	}
+	tx, err := b.GetTransaction(bi.Txids[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(tx.Vin) == 0 || tx.Vin[0].Coinbase == "" {
+		return nil, errors.Errorf("The first transaction %v of block %v is not coinbase", tx.Txid, hash)
+	}
+	return tx, nil
+}
+
+type cmdGetBlockVerbosity struct {
+	Method string `json:"method"`
+	Params struct {
+		BlockHash string `json:"blockhash"`
+		Verbosity int    `json:"verbose"`
+	} `json:"params"`
+}
+
+type resGetBlockInfoVerbose struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result json.RawMessage  `json:"result"`
+}
+
+// BlockInfoVerbose is extended header with a list of txids and for verbosity 2 also with the block transactions
+type BlockInfoVerbose struct {
+	bchain.BlockInfo
+	Txs []bchain.Tx
+}
+
+// GetBlockInfoVerbose returns extended header of the block using getblock with given verbosity
+// verbosity 1 returns only the txids of the block, verbosity 2 returns also the block transactions
+func (b *BCashRPC) GetBlockInfoVerbose(hash string, verbosity int) (*BlockInfoVerbose, error) {
+	if verbosity != 1 && verbosity != 2 {
+		return nil, errors.Errorf("Unsupported verbosity %v", verbosity)
+	}
+	glog.V(1).Info("rpc: getblock (verbosity=", verbosity, ") ", hash)
+
+	res := resGetBlockInfoVerbose{}
+	req := cmdGetBlockVerbosity{Method: "getblock"}
 	req.Params.BlockHash = hash
-	req.Params.Verbose = true
+	req.Params.Verbosity = verbosity
 	err := b.Call(&req, &res)
 
 	if err != nil {
@@ -151,12 +362,89 @@ func (b *BCashRPC) GetBlockInfo(hash string) (*bchain.BlockInfo, error) {
 		}
 		return nil, errors.Annotatef(res.Error, "hash %v", hash)
 	}
-	return &res.Result, nil
+	bi, err := b.parseBlockInfoVerbose(res.Result)
+	if err != nil {
+		return nil, errors.Annotatef(err, "hash %v", hash)
+	}
+	return bi, nil
+}
+
+// witnessScaleFactor is the number of weight units per byte of non-witness data (BIP141)
+const witnessScaleFactor = 4
+
+// parseBlockInfoVerbose parses result of getblock, the tx field contains txids (verbosity 1) or transactions (verbosity 2)
+func (b *BCashRPC) parseBlockInfoVerbose(data json.RawMessage) (*BlockInfoVerbose, error) {
+	var r struct {
+		bchain.BlockInfo
+		Txs []json.RawMessage `json:"tx"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	bi := &BlockInfoVerbose{BlockInfo: r.BlockInfo}
+	// the chain has no witness data, the weight units of all bytes of the block are witnessScaleFactor
+	bi.Weight = bi.Size * witnessScaleFactor
+	bi.Txids = make([]string, len(r.Txs))
+	for i, m := range r.Txs {
+		if len(m) > 0 && m[0] == '"' {
+			if err := json.Unmarshal(m, &bi.Txids[i]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		tx, err := b.Parser.ParseTxFromJson(m)
+		if err != nil {
+			return nil, err
+		}
+		bi.Txids[i] = tx.Txid
+		bi.Txs = append(bi.Txs, *tx)
+	}
+	return bi, nil
 }
 
-// GetBlockFull returns block with given hash.
+// GetBlockFull returns block with given hash including the hex and addresses of the transactions.
 func (b *BCashRPC) GetBlockFull(hash string) (*bchain.Block, error) {
-	return nil, errors.New("Not implemented")
+	block := &bchain.Block{}
+	err := b.GetBlockTxsStream(hash, func(header *bchain.BlockHeader) error {
+		block.BlockHeader = *header
+		return nil
+	}, func(tx *bchain.Tx) error {
+		block.Txs = append(block.Txs, *tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// GetBlockTxsStream passes the header of the block with given hash to onHeader and then the block transactions one by one to onTx,
+// the transactions are decoded from the raw block as they are processed, which limits the memory needed for large blocks
+// the processing stops on the first error returned by a callback
+func (b *BCashRPC) GetBlockTxsStream(hash string, onHeader func(*bchain.BlockHeader) error, onTx func(*bchain.Tx) error) error {
+	parser, ok := b.Parser.(*BCashParser)
+	if !ok {
+		return errors.New("Unsupported parser")
+	}
+	header, err := b.GetBlockHeader(hash)
+	if err != nil {
+		return err
+	}
+	data, err := b.GetBlockRaw(hash)
+	if err != nil {
+		return err
+	}
+	err = parser.ParseBlockStream(data, func(h *bchain.BlockHeader) error {
+		// size is not returned by GetBlockHeader and would be overwritten
+		size := h.Size
+		*h = *header
+		h.Size = size
+		return onHeader(h)
+	}, onTx)
+	if err != nil {
+		return errors.Annotatef(err, "hash %v", hash)
+	}
+	return nil
 }
 
 func isErrBlockNotFound(err *bchain.RPCError) bool {
@@ -193,11 +481,49 @@ func (b *BCashRPC) EstimateFee(blocks int) (big.Int, error) {
 	if err != nil {
 		return r, err
 	}
-	return r, nil
+	return b.LimitEstimatedFee(r, "estimatefee"), nil
 }
 
-// EstimateSmartFee returns fee estimation
+// EstimateSmartFee returns fee estimation, estimatesmartfee is used only if enabled in the configuration,
+// the estimation mode is not supported by the backend and conservative is ignored
 func (b *BCashRPC) EstimateSmartFee(blocks int, conservative bool) (big.Int, error) {
-	// EstimateSmartFee is not supported by bcash
-	return b.EstimateFee(blocks)
+	if !b.estimateFee.EstimateSmartFee {
+		// EstimateSmartFee is not supported by bcash
+		return b.EstimateFee(blocks)
+	}
+	r, _, err := b.EstimateSmartFeeWithFallback(blocks)
+	return r, err
+}
+
+// EstimateSmartFeeWithFallback returns fee estimation by estimatesmartfee; if the backend has insufficient data for the target
+// and the fallback is enabled in the configuration, the estimation by estimatefee for the same target is returned together with true
+func (b *BCashRPC) EstimateSmartFeeWithFallback(blocks int) (big.Int, bool, error) {
+	glog.V(1).Info("rpc: estimatesmartfee ", blocks)
+
+	res := resEstimateSmartFee{}
+	req := cmdEstimateSmartFee{Method: "estimatesmartfee"}
+	req.Params.Blocks = blocks
+	err := b.Call(&req, &res)
+
+	var r big.Int
+	if err != nil {
+		return r, false, err
+	}
+	if res.Error != nil {
+		return r, false, res.Error
+	}
+	if res.Result.Feerate != "" {
+		if r, err = b.Parser.AmountToBigInt(res.Result.Feerate); err != nil {
+			return r, false, err
+		}
+		if r.Sign() > 0 {
+			return b.LimitEstimatedFee(r, "estimatesmartfee"), false, nil
+		}
+	}
+	if !b.estimateFee.EstimateSmartFeeFallback {
+		return r, false, errors.Errorf("estimatesmartfee %d: insufficient data %v", blocks, res.Result.Errors)
+	}
+	glog.V(1).Info("rpc: estimatesmartfee ", blocks, ": insufficient data ", res.Result.Errors, ", using estimatefee")
+	r, err = b.EstimateFee(blocks)
+	return r, true, err
 }
@@ -0,0 +1,505 @@
+// +build unittest
+
+package bch
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testBlockHash = "000000000000000001a29ba066b9aff73e5c3c8cfe08bc7e0aad6d53e6fc2e4e"
+
+// recorded responses of getblock with verbosity 1 and 2
+var getBlockResponses = map[int]string{
+	1: `{"result":{"hash":"000000000000000001a29ba066b9aff73e5c3c8cfe08bc7e0aad6d53e6fc2e4e","confirmations":3,"size":2345,"height":570000,"version":536870912,"versionHex":"20000000","merkleroot":"6c7ec485e18dd6eaab9c12e7ac4e2a1e9ca9f0e9d3ecff6d4ea618c5686e0d53","tx":["4f3f3e2a1b7c92a58e5a34505e2b3d3fd06d8b52babc3a0d64c43b3843d4e1e2","d31b3a2a1ca8fe0bca0a2ed6d95dd1840d7a5c237e6ff0d11cfb2d4b06e2e5be"],"time":1550000000,"mediantime":1549999000,"nonce":1876521596,"bits":"18044a6e","difficulty":"253948779484.1987","chainwork":"000000000000000000000000000000000000000000e9f8b918de3e8ad7b99d5e","previousblockhash":"0000000000000000031d0b4d2fb0bc8b7a3bd1a2de36d9e0bf1c4bd7ebcbb0cf","nextblockhash":"0000000000000000020ce0b3b3bdb5c1bfc1e1d71a6d1ed0f8bbd20327792896"},"error":null,"id":"1"}`,
+	2: `{"result":{"hash":"000000000000000001a29ba066b9aff73e5c3c8cfe08bc7e0aad6d53e6fc2e4e","confirmations":3,"size":2345,"height":570000,"version":536870912,"versionHex":"20000000","merkleroot":"6c7ec485e18dd6eaab9c12e7ac4e2a1e9ca9f0e9d3ecff6d4ea618c5686e0d53","tx":[{"txid":"4f3f3e2a1b7c92a58e5a34505e2b3d3fd06d8b52babc3a0d64c43b3843d4e1e2","hash":"4f3f3e2a1b7c92a58e5a34505e2b3d3fd06d8b52babc3a0d64c43b3843d4e1e2","version":1,"size":160,"locktime":0,"vin":[{"coinbase":"0390b2080400e1f505","sequence":4294967295}],"vout":[{"value":12.50012345,"n":0,"scriptPubKey":{"hex":"76a914010d39800f86122416e28f485029acf77507169288ac","type":"pubkeyhash","addresses":["bitcoincash:qqqs6wvqp7rpyfqku28y55pf4nmh2pckzgs8swxkd8"]}}]},{"txid":"d31b3a2a1ca8fe0bca0a2ed6d95dd1840d7a5c237e6ff0d11cfb2d4b06e2e5be","hash":"d31b3a2a1ca8fe0bca0a2ed6d95dd1840d7a5c237e6ff0d11cfb2d4b06e2e5be","version":2,"size":225,"locktime":569999,"vin":[{"txid":"425fed43ba74e9205875eb934d5bcf7bf338f146f70d4002d94bf5cbc9229a7f","vout":4,"scriptSig":{"hex":"00"},"sequence":4294967294}],"vout":[{"value":0.00038812,"n":0,"scriptPubKey":{"hex":"a9146144d57c8aff48492c9dfb914e120b20bad72d6f87","type":"scripthash","addresses":["bitcoincash:pps5f4tu3tl5sjfvnhaeznsjpvst44eddugfcnqpy9"]}}]}],"time":1550000000,"mediantime":1549999000,"nonce":1876521596,"bits":"18044a6e","difficulty":"253948779484.1987","chainwork":"000000000000000000000000000000000000000000e9f8b918de3e8ad7b99d5e","previousblockhash":"0000000000000000031d0b4d2fb0bc8b7a3bd1a2de36d9e0bf1c4bd7ebcbb0cf","nextblockhash":"0000000000000000020ce0b3b3bdb5c1bfc1e1d71a6d1ed0f8bbd20327792896"},"error":null,"id":"1"}`,
+}
+
+func setupRPC(t *testing.T, handler http.HandlerFunc) (*BCashRPC, func()) {
+	return setupRPCWithConfig(t, handler, nil)
+}
+
+// setupRPCWithConfig creates BCashRPC connected to the handler with the additional configuration params
+func setupRPCWithConfig(t *testing.T, handler http.HandlerFunc, params map[string]interface{}) (*BCashRPC, func()) {
+	ts := httptest.NewServer(handler)
+	cfg := map[string]interface{}{
+		"rpc_url":     ts.URL,
+		"rpc_timeout": 5,
+	}
+	for k, v := range params {
+		cfg[k] = v
+	}
+	config, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewBCashRPC(config, nil)
+	if err != nil {
+		ts.Close()
+		t.Fatal(err)
+	}
+	b := c.(*BCashRPC)
+	mainParser, _, _, _ := setupParsers(t)
+	b.Parser = mainParser
+	return b, ts.Close
+}
+
+func getBlockHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string `json:"method"`
+			Params struct {
+				BlockHash string `json:"blockhash"`
+				Verbose   int    `json:"verbose"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Method != "getblock" || req.Params.BlockHash != testBlockHash {
+			w.Write([]byte(`{"result":null,"error":{"code":-5,"message":"Block not found"},"id":"1"}`))
+			return
+		}
+		w.Write([]byte(getBlockResponses[req.Params.Verbose]))
+	}
+}
+
+func Test_GetBlockInfoVerbose(t *testing.T) {
+	b, closeServer := setupRPC(t, getBlockHandler(t))
+	defer closeServer()
+
+	wantInfo := bchain.BlockInfo{
+		BlockHeader: bchain.BlockHeader{
+			Hash:          testBlockHash,
+			Prev:          "0000000000000000031d0b4d2fb0bc8b7a3bd1a2de36d9e0bf1c4bd7ebcbb0cf",
+			Next:          "0000000000000000020ce0b3b3bdb5c1bfc1e1d71a6d1ed0f8bbd20327792896",
+			Height:        570000,
+			Confirmations: 3,
+			Size:          2345,
+			Time:          1550000000,
+		},
+		Version:    "536870912",
+		MerkleRoot: "6c7ec485e18dd6eaab9c12e7ac4e2a1e9ca9f0e9d3ecff6d4ea618c5686e0d53",
+		Nonce:      "1876521596",
+		Bits:       "18044a6e",
+		Difficulty: "253948779484.1987",
+		Chainwork:  "000000000000000000000000000000000000000000e9f8b918de3e8ad7b99d5e",
+		Weight:     9380,
+		Txids: []string{
+			"4f3f3e2a1b7c92a58e5a34505e2b3d3fd06d8b52babc3a0d64c43b3843d4e1e2",
+			"d31b3a2a1ca8fe0bca0a2ed6d95dd1840d7a5c237e6ff0d11cfb2d4b06e2e5be",
+		},
+	}
+
+	t.Run("verbosity 1", func(t *testing.T) {
+		got, err := b.GetBlockInfoVerbose(testBlockHash, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.BlockInfo, wantInfo) {
+			t.Errorf("GetBlockInfoVerbose() = %+v, want %+v", got.BlockInfo, wantInfo)
+		}
+		if len(got.Txs) != 0 {
+			t.Errorf("GetBlockInfoVerbose() returned %v txs, want 0", len(got.Txs))
+		}
+	})
+
+	t.Run("verbosity 2", func(t *testing.T) {
+		got, err := b.GetBlockInfoVerbose(testBlockHash, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.BlockInfo, wantInfo) {
+			t.Errorf("GetBlockInfoVerbose() = %+v, want %+v", got.BlockInfo, wantInfo)
+		}
+		if len(got.Txs) != 2 {
+			t.Fatalf("GetBlockInfoVerbose() returned %v txs, want 2", len(got.Txs))
+		}
+		if got.Txs[0].Vin[0].Coinbase != "0390b2080400e1f505" {
+			t.Errorf("GetBlockInfoVerbose() coinbase = %v", got.Txs[0].Vin[0].Coinbase)
+		}
+		if got.Txs[0].Vout[0].ValueSat.Cmp(big.NewInt(1250012345)) != 0 {
+			t.Errorf("GetBlockInfoVerbose() coinbase value = %v, want 1250012345", got.Txs[0].Vout[0].ValueSat.String())
+		}
+		if got.Txs[1].LockTime != 569999 || got.Txs[1].Vin[0].Sequence != 4294967294 {
+			t.Errorf("GetBlockInfoVerbose() tx = %+v", got.Txs[1])
+		}
+	})
+
+	t.Run("GetBlockInfo", func(t *testing.T) {
+		got, err := b.GetBlockInfo(testBlockHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*got, wantInfo) {
+			t.Errorf("GetBlockInfo() = %+v, want %+v", *got, wantInfo)
+		}
+		if got.Weight != got.Size*4 {
+			t.Errorf("GetBlockInfo() weight = %v, want 4 * size %v", got.Weight, got.Size)
+		}
+	})
+
+	t.Run("unsupported verbosity", func(t *testing.T) {
+		if _, err := b.GetBlockInfoVerbose(testBlockHash, 0); err == nil {
+			t.Error("GetBlockInfoVerbose() expected error for verbosity 0")
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if _, err := b.GetBlockInfoVerbose("0000000000000000000000000000000000000000000000000000000000000000", 1); err != bchain.ErrBlockNotFound {
+			t.Errorf("GetBlockInfoVerbose() error = %v, want %v", err, bchain.ErrBlockNotFound)
+		}
+	})
+}
+
+func Test_GetCoinbaseTx(t *testing.T) {
+	const coinbaseTx = `{"txid":"4f3f3e2a1b7c92a58e5a34505e2b3d3fd06d8b52babc3a0d64c43b3843d4e1e2","hash":"4f3f3e2a1b7c92a58e5a34505e2b3d3fd06d8b52babc3a0d64c43b3843d4e1e2","version":1,"size":160,"locktime":0,"vin":[{"coinbase":"0390b2080400e1f505","sequence":4294967295}],"vout":[{"value":12.50012345,"n":0,"scriptPubKey":{"hex":"76a914010d39800f86122416e28f485029acf77507169288ac","type":"pubkeyhash","addresses":["bitcoincash:qqqs6wvqp7rpyfqku285s5pf4nmh2pckjgsgwcgfht"]}}],"blockhash":"000000000000000001a29ba066b9aff73e5c3c8cfe08bc7e0aad6d53e6fc2e4e","confirmations":3,"time":1550000000,"blocktime":1550000000}`
+	const emptyBlockHash = "0000000000000000020ce0b3b3bdb5c1bfc1e1d71a6d1ed0f8bbd20327792896"
+	var methods []string
+	b, closeServer := setupRPC(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string `json:"method"`
+			Params struct {
+				BlockHash string          `json:"blockhash"`
+				Txid      string          `json:"txid"`
+				Verbose   json.RawMessage `json:"verbose"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		methods = append(methods, req.Method)
+		switch {
+		case req.Method == "getblock" && req.Params.BlockHash == testBlockHash && string(req.Params.Verbose) == "1":
+			w.Write([]byte(getBlockResponses[1]))
+		case req.Method == "getblock" && req.Params.BlockHash == emptyBlockHash:
+			w.Write([]byte(`{"result":{"hash":"` + emptyBlockHash + `","height":570001,"tx":[]},"error":null,"id":"1"}`))
+		case req.Method == "getrawtransaction" && req.Params.Txid == "4f3f3e2a1b7c92a58e5a34505e2b3d3fd06d8b52babc3a0d64c43b3843d4e1e2":
+			w.Write([]byte(`{"result":` + coinbaseTx + `,"error":null,"id":"1"}`))
+		default:
+			t.Errorf("unexpected request %s", body)
+			w.Write([]byte(`{"result":null,"error":{"code":-5,"message":"Not found"},"id":"1"}`))
+		}
+	})
+	defer closeServer()
+
+	tx, err := b.GetCoinbaseTx(testBlockHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Txid != "4f3f3e2a1b7c92a58e5a34505e2b3d3fd06d8b52babc3a0d64c43b3843d4e1e2" || tx.Vin[0].Coinbase != "0390b2080400e1f505" {
+		t.Errorf("GetCoinbaseTx() = %+v", tx)
+	}
+	if tx.Vout[0].ValueSat.Cmp(big.NewInt(1250012345)) != 0 {
+		t.Errorf("GetCoinbaseTx() value = %v, want 1250012345", tx.Vout[0].ValueSat.String())
+	}
+	// only the txids of the block are downloaded
+	if want := []string{"getblock", "getrawtransaction"}; !reflect.DeepEqual(methods, want) {
+		t.Errorf("GetCoinbaseTx() called %v, want %v", methods, want)
+	}
+
+	if _, err = b.GetCoinbaseTx(emptyBlockHash); err == nil {
+		t.Error("GetCoinbaseTx() of block without transactions did not return error")
+	}
+}
+
+func Test_SendRawTransaction_MaxTxSize(t *testing.T) {
+	var sent int
+	b, closeServer := setupRPC(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), `"sendrawtransaction"`) {
+			t.Errorf("unexpected request %s", body)
+		}
+		sent++
+		w.Write([]byte(`{"result":"d31b3a2a1ca8fe0bca0a2ed6d95dd1840d7a5c237e6ff0d11cfb2d4b06e2e5be","error":null,"id":"1"}`))
+	})
+	defer closeServer()
+
+	// the standard size is the default
+	if b.sendTx.MaxTxSize != MaxStandardTxSize {
+		t.Errorf("MaxTxSize = %v, want %v", b.sendTx.MaxTxSize, MaxStandardTxSize)
+	}
+	normal := strings.Repeat("00", 250)
+	oversized := strings.Repeat("00", MaxStandardTxSize+1)
+
+	txid, err := b.SendRawTransaction(normal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txid != "d31b3a2a1ca8fe0bca0a2ed6d95dd1840d7a5c237e6ff0d11cfb2d4b06e2e5be" || sent != 1 {
+		t.Errorf("SendRawTransaction() = %v, sent %d times", txid, sent)
+	}
+
+	_, err = b.SendRawTransaction(oversized)
+	if want := "Transaction size 100001 bytes exceeds the maximum size 100000 bytes"; err == nil || err.Error() != want {
+		t.Errorf("SendRawTransaction() error = %v, want %v", err, want)
+	}
+	if sent != 1 {
+		t.Error("SendRawTransaction() sent oversized transaction to the backend")
+	}
+
+	// the check is disabled
+	b.sendTx.MaxTxSize = -1
+	if _, err = b.SendRawTransaction(oversized); err != nil {
+		t.Fatal(err)
+	}
+	if sent != 2 {
+		t.Error("SendRawTransaction() did not send transaction to the backend with disabled check")
+	}
+}
+
+// truncatedBlockHandler returns the raw blocks from the list responses one by one, the last one repeatedly
+func truncatedBlockHandler(t *testing.T, responses [][]byte, getBlockCalls *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		var result interface{}
+		switch req.Method {
+		case "getblockheader":
+			result = map[string]interface{}{"hash": testBlockHash, "height": 1, "time": 1550000000}
+		case "getblock":
+			i := *getBlockCalls
+			if i >= len(responses) {
+				i = len(responses) - 1
+			}
+			*getBlockCalls++
+			result = hex.EncodeToString(responses[i])
+		default:
+			t.Fatalf("unexpected method %v", req.Method)
+		}
+		res, _ := json.Marshal(map[string]interface{}{"result": result, "error": nil, "id": "1"})
+		w.Write(res)
+	}
+}
+
+func Test_GetBlock_Truncated(t *testing.T) {
+	rawBlock := testParserCheckBlock(t)
+	// header and count of transactions of the valid block, followed by a transaction with the input script longer than allowed
+	invalidBlock := append(append([]byte{}, rawBlock[:81]...), 1, 0, 0, 0, 1)
+	invalidBlock = append(invalidBlock, make([]byte, 36)...)
+	invalidBlock = append(invalidBlock, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	tests := []struct {
+		name      string
+		responses [][]byte
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "complete", responses: [][]byte{rawBlock}, wantCalls: 1},
+		{name: "truncated then complete", responses: [][]byte{rawBlock[:len(rawBlock)-10], rawBlock}, wantCalls: 2},
+		{name: "truncated header then complete", responses: [][]byte{rawBlock[:40], rawBlock}, wantCalls: 2},
+		{name: "truncated twice", responses: [][]byte{rawBlock[:len(rawBlock)-10]}, wantCalls: 2, wantErr: true},
+		{name: "invalid", responses: [][]byte{invalidBlock, rawBlock}, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			b, closeServer := setupRPC(t, truncatedBlockHandler(t, tt.responses, &calls))
+			defer closeServer()
+			block, err := b.GetBlock(testBlockHash, 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBlock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("GetBlock() called getblock %d times, want %d", calls, tt.wantCalls)
+			}
+			if err == nil && (len(block.Txs) != 1 || block.Txs[0].Txid != testTx1.Txid || block.Size != len(rawBlock)) {
+				t.Errorf("GetBlock() = %+v", block)
+			}
+		})
+	}
+}
+
+func Test_GetBlock_MaxBlockSize(t *testing.T) {
+	rawBlock := testParserCheckBlock(t)
+	tests := []struct {
+		name         string
+		maxBlockSize int
+		// padding is appended to the raw block returned by the backend
+		padding int
+		wantErr bool
+	}{
+		{name: "default limit", maxBlockSize: DefaultMaxBlockSize},
+		{name: "block of the maximum size", maxBlockSize: len(rawBlock)},
+		{name: "oversized block", maxBlockSize: len(rawBlock) - 1, wantErr: true},
+		{name: "response not read", maxBlockSize: len(rawBlock), padding: 2 * maxBlockResponseOverhead, wantErr: true},
+		{name: "check disabled", maxBlockSize: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			raw := append(append([]byte{}, rawBlock...), make([]byte, tt.padding)...)
+			b, closeServer := setupRPC(t, truncatedBlockHandler(t, [][]byte{raw}, &calls))
+			defer closeServer()
+			b.blockSize.MaxBlockSize = tt.maxBlockSize
+			block, err := b.GetBlock(testBlockHash, 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBlock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// the oversized block is not downloaded again
+			if calls != 1 {
+				t.Errorf("GetBlock() called getblock %d times, want 1", calls)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "exceeds the maximum block size") && !strings.Contains(err.Error(), "Response exceeds the maximum size") {
+					t.Errorf("GetBlock() error = %v", err)
+				}
+				return
+			}
+			if len(block.Txs) != 1 || block.Size != len(rawBlock) {
+				t.Errorf("GetBlock() = %+v", block)
+			}
+		})
+	}
+}
+
+func Test_EstimateFee_Limit(t *testing.T) {
+	tests := []struct {
+		name   string
+		result string
+		max    string
+		want   string
+	}{
+		{name: "above limit", result: "1000.0", max: "0.001", want: "100000"},
+		{name: "below limit", result: "0.00001", max: "0.001", want: "1000"},
+		{name: "no estimate", result: "-1", max: "0.001", want: "-100000000"},
+		{name: "no limit", result: "1000.0", want: "100000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params map[string]interface{}
+			if tt.max != "" {
+				params = map[string]interface{}{"max_estimated_fee": tt.max}
+			}
+			b, closeServer := setupRPCWithConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(body), `"method":"estimatefee"`) {
+					t.Errorf("unexpected request %s", body)
+				}
+				w.Write([]byte(`{"result":` + tt.result + `,"error":null,"id":"1"}`))
+			}, params)
+			defer closeServer()
+			got, err := b.EstimateFee(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("EstimateFee() = %v, want %v", got.String(), tt.want)
+			}
+			got, err = b.EstimateSmartFee(1, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("EstimateSmartFee() = %v, want %v", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_EstimateFee_InvalidLimit(t *testing.T) {
+	for _, max := range []string{"abc", "0", "-0.001"} {
+		config, _ := json.Marshal(map[string]interface{}{"rpc_url": "http://localhost:1", "max_estimated_fee": max})
+		if _, err := NewBCashRPC(config, nil); err == nil {
+			t.Errorf("NewBCashRPC() with max_estimated_fee %v did not return error", max)
+		}
+	}
+}
+
+func Test_EstimateSmartFee_Fallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		smartResult  string
+		fallback     bool
+		want         string
+		wantFallback bool
+		wantErr      bool
+	}{
+		{name: "smart estimate", smartResult: `{"feerate":0.00002,"blocks":2}`, fallback: true, want: "2000"},
+		{name: "insufficient data", smartResult: `{"errors":["Insufficient data or no feerate found"],"blocks":0}`, fallback: true, want: "1000", wantFallback: true},
+		{name: "no feerate", smartResult: `{"feerate":-1,"blocks":0}`, fallback: true, want: "1000", wantFallback: true},
+		{name: "insufficient data without fallback", smartResult: `{"errors":["Insufficient data or no feerate found"],"blocks":0}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var methods []string
+			b, closeServer := setupRPC(t, func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var req struct {
+					Method string `json:"method"`
+				}
+				if err := json.Unmarshal(body, &req); err != nil {
+					t.Fatal(err)
+				}
+				methods = append(methods, req.Method)
+				switch req.Method {
+				case "estimatesmartfee":
+					w.Write([]byte(`{"result":` + tt.smartResult + `,"error":null,"id":"1"}`))
+				case "estimatefee":
+					w.Write([]byte(`{"result":0.00001,"error":null,"id":"1"}`))
+				default:
+					t.Errorf("unexpected request %s", body)
+				}
+			})
+			defer closeServer()
+			b.estimateFee = estimateFeeConfiguration{EstimateSmartFee: true, EstimateSmartFeeFallback: tt.fallback}
+			got, fallback, err := b.EstimateSmartFeeWithFallback(2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EstimateSmartFeeWithFallback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.String() != tt.want || fallback != tt.wantFallback {
+				t.Errorf("EstimateSmartFeeWithFallback() = %v, %v, want %v, %v", got.String(), fallback, tt.want, tt.wantFallback)
+			}
+			got, err = b.EstimateSmartFee(2, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("EstimateSmartFee() = %v, want %v", got.String(), tt.want)
+			}
+			// the fallback calls estimatefee after estimatesmartfee
+			want := []string{"estimatesmartfee", "estimatesmartfee"}
+			if tt.wantFallback {
+				want = []string{"estimatesmartfee", "estimatefee", "estimatesmartfee", "estimatefee"}
+			}
+			if !reflect.DeepEqual(methods, want) {
+				t.Errorf("called methods %v, want %v", methods, want)
+			}
+		})
+	}
+}
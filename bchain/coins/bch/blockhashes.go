@@ -0,0 +1,114 @@
+package bch
+
+import (
+	"blockbook/bchain"
+	"sync/atomic"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// support of the getblockhashes RPC method by the backend, detected on the first call
+const (
+	blockHashesUnknown int32 = iota
+	blockHashesNative
+	blockHashesFallback
+)
+
+type cmdGetBlockHashes struct {
+	Method string `json:"method"`
+	Params struct {
+		High int64 `json:"high"`
+		Low  int64 `json:"low"`
+	} `json:"params"`
+}
+
+type resGetBlockHashes struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result []string         `json:"result"`
+}
+
+// GetBlockHashesByTime returns hashes of the blocks with time in the interval from..to (inclusive), ordered by height
+// the getblockhashes method of the backend is used if it is supported (backends with the address index patch),
+// otherwise the block heights are found by the binary search of block times; the method is considered unsupported
+// after the first error returned by the backend, errors of the connection are returned to the caller
+func (b *BCashRPC) GetBlockHashesByTime(from, to int64) ([]string, error) {
+	if from > to {
+		return nil, errors.Errorf("Invalid time range %d-%d", from, to)
+	}
+	if atomic.LoadInt32(&b.blockHashesSupport) != blockHashesFallback {
+		hashes, err := b.getBlockHashes(from, to)
+		if err == nil {
+			atomic.StoreInt32(&b.blockHashesSupport, blockHashesNative)
+			return hashes, nil
+		}
+		// any error returned by the backend means that the method is not usable, for example
+		// it is not implemented or the timestamp index of the backend is not enabled
+		e, ok := err.(*bchain.RPCError)
+		if !ok {
+			return nil, err
+		}
+		glog.Info("rpc: getblockhashes not supported by the backend (", e, "), using binary search of block times")
+		atomic.StoreInt32(&b.blockHashesSupport, blockHashesFallback)
+	}
+	lower, err := b.getBlockHeightByTime(from)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := b.getBlockHeightByTime(to + 1)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, upper-lower)
+	for h := lower; h < upper; h++ {
+		hash, err := b.GetBlockHash(h)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (b *BCashRPC) getBlockHashes(from, to int64) ([]string, error) {
+	glog.V(1).Info("rpc: getblockhashes ", from, " ", to)
+	res := resGetBlockHashes{}
+	req := cmdGetBlockHashes{Method: "getblockhashes"}
+	// the upper bound of getblockhashes is exclusive
+	req.Params.High = to + 1
+	req.Params.Low = from
+	if err := b.Call(&req, &res); err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return res.Result, nil
+}
+
+// getBlockHeightByTime returns the lowest height of the block with time greater or equal to the given time
+// or the best height + 1 if there is no such block, the times of the blocks are expected to be ascending
+func (b *BCashRPC) getBlockHeightByTime(time int64) (uint32, error) {
+	best, err := b.GetBestBlockHeight()
+	if err != nil {
+		return 0, err
+	}
+	lower, upper := uint32(0), best+1
+	for lower < upper {
+		mid := lower + (upper-lower)/2
+		hash, err := b.GetBlockHash(mid)
+		if err != nil {
+			return 0, err
+		}
+		header, err := b.GetBlockHeader(hash)
+		if err != nil {
+			return 0, err
+		}
+		if header.Time < time {
+			lower = mid + 1
+		} else {
+			upper = mid
+		}
+	}
+	return lower, nil
+}
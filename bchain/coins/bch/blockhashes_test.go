@@ -0,0 +1,160 @@
+// +build unittest
+
+package bch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// fakeChainBlockTime is the time of the block at given height of the fake chain, there are 10 blocks 600 seconds apart
+func fakeChainBlockTime(height int) int64 {
+	return 1550000000 + int64(height)*600
+}
+
+const fakeChainBestHeight = 9
+
+func fakeChainHash(height int) string {
+	return fmt.Sprintf("%064x", height+1)
+}
+
+// fakeChainHandler serves the fake chain, getblockhashes fails with the rpcError if it is not empty
+func fakeChainHandler(t *testing.T, rpcError string, calls map[string]int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		calls[req.Method]++
+		var result interface{}
+		switch req.Method {
+		case "getblockhashes":
+			if rpcError != "" {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"result":null,"error":` + rpcError + `,"id":"1"}`))
+				return
+			}
+			var p struct {
+				High int64 `json:"high"`
+				Low  int64 `json:"low"`
+			}
+			if err := json.Unmarshal(req.Params, &p); err != nil {
+				t.Fatal(err)
+			}
+			hashes := []string{}
+			for h := 0; h <= fakeChainBestHeight; h++ {
+				if bt := fakeChainBlockTime(h); bt >= p.Low && bt < p.High {
+					hashes = append(hashes, fakeChainHash(h))
+				}
+			}
+			result = hashes
+		case "getblockcount":
+			result = fakeChainBestHeight
+		case "getblockhash":
+			var p struct {
+				Height int `json:"height"`
+			}
+			if err := json.Unmarshal(req.Params, &p); err != nil {
+				t.Fatal(err)
+			}
+			result = fakeChainHash(p.Height)
+		case "getblockheader":
+			var p struct {
+				BlockHash string `json:"blockhash"`
+			}
+			if err := json.Unmarshal(req.Params, &p); err != nil {
+				t.Fatal(err)
+			}
+			var height int
+			fmt.Sscanf(p.BlockHash, "%x", &height)
+			height--
+			result = map[string]interface{}{"hash": p.BlockHash, "height": height, "time": fakeChainBlockTime(height)}
+		default:
+			t.Fatalf("unexpected method %v", req.Method)
+		}
+		res, _ := json.Marshal(map[string]interface{}{"result": result, "error": nil, "id": "1"})
+		w.Write(res)
+	}
+}
+
+func Test_GetBlockHashesByTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int64
+		want     []string
+	}{
+		{
+			name: "all blocks",
+			from: fakeChainBlockTime(0),
+			to:   fakeChainBlockTime(fakeChainBestHeight),
+			want: []string{fakeChainHash(0), fakeChainHash(1), fakeChainHash(2), fakeChainHash(3), fakeChainHash(4), fakeChainHash(5), fakeChainHash(6), fakeChainHash(7), fakeChainHash(8), fakeChainHash(9)},
+		},
+		{
+			name: "inclusive bounds",
+			from: fakeChainBlockTime(3),
+			to:   fakeChainBlockTime(5),
+			want: []string{fakeChainHash(3), fakeChainHash(4), fakeChainHash(5)},
+		},
+		{
+			name: "bounds between blocks",
+			from: fakeChainBlockTime(3) + 1,
+			to:   fakeChainBlockTime(5) - 1,
+			want: []string{fakeChainHash(4)},
+		},
+		{
+			name: "no blocks",
+			from: fakeChainBlockTime(fakeChainBestHeight) + 1,
+			to:   fakeChainBlockTime(fakeChainBestHeight) + 100,
+			want: []string{},
+		},
+	}
+	backends := []struct {
+		name     string
+		rpcError string
+	}{
+		{"native", ""},
+		{"method not found", `{"code":-32601,"message":"Method not found"}`},
+		{"index not enabled", `{"code":-1,"message":"No information available for block hashes"}`},
+	}
+	for _, backend := range backends {
+		calls := make(map[string]int)
+		b, closeServer := setupRPC(t, fakeChainHandler(t, backend.rpcError, calls))
+		native := backend.rpcError == ""
+		for _, tt := range tests {
+			t.Run(fmt.Sprint(tt.name, " ", backend.name), func(t *testing.T) {
+				got, err := b.GetBlockHashesByTime(tt.from, tt.to)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("GetBlockHashesByTime() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+		if native {
+			if calls["getblockheader"] != 0 {
+				t.Errorf("native getblockhashes used binary search, calls %v", calls)
+			}
+		} else if calls["getblockhashes"] != 1 {
+			// the unsupported method is probed only once
+			t.Errorf("getblockhashes called %d times, want 1", calls["getblockhashes"])
+		}
+		closeServer()
+	}
+	b, closeServer := setupRPC(t, fakeChainHandler(t, "", make(map[string]int)))
+	defer closeServer()
+	if _, err := b.GetBlockHashesByTime(2, 1); err == nil {
+		t.Error("GetBlockHashesByTime() of invalid range did not return error")
+	}
+}
@@ -0,0 +1,77 @@
+package bch
+
+import (
+	"blockbook/bchain"
+
+	"github.com/juju/errors"
+)
+
+// BlockRangeProgress is reported by GetBlockRange after each processed block
+type BlockRangeProgress struct {
+	// Height is the height of the last processed block
+	Height uint32
+	// Blocks is the number of the processed blocks out of TotalBlocks
+	Blocks      int
+	TotalBlocks int
+	// Bytes is the size of the processed blocks
+	Bytes int64
+}
+
+type blockRangeResult struct {
+	block *bchain.Block
+	err   error
+}
+
+// GetBlockRange downloads the blocks fromHeight..toHeight (both inclusive) in the order of the height,
+// passes each block to onBlock and then reports the progress to onProgress (which can be nil),
+// the next block is downloaded while the current one is processed so that at most two blocks are held in memory
+// an error returned by a callback cancels the download and is returned
+func (b *BCashRPC) GetBlockRange(fromHeight, toHeight uint32, onBlock func(*bchain.Block) error, onProgress func(BlockRangeProgress) error) error {
+	if fromHeight > toHeight {
+		return errors.Errorf("Invalid block range %d-%d", fromHeight, toHeight)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	results := make(chan blockRangeResult)
+	go func() {
+		defer close(results)
+		for height := fromHeight; ; height++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			var r blockRangeResult
+			var hash string
+			if hash, r.err = b.GetBlockHash(height); r.err == nil {
+				r.block, r.err = b.GetBlock(hash, height)
+			}
+			select {
+			case results <- r:
+			case <-done:
+				return
+			}
+			if r.err != nil || height == toHeight {
+				return
+			}
+		}
+	}()
+	progress := BlockRangeProgress{TotalBlocks: int(toHeight-fromHeight) + 1}
+	for r := range results {
+		if r.err != nil {
+			return r.err
+		}
+		if err := onBlock(r.block); err != nil {
+			return err
+		}
+		progress.Height = r.block.Height
+		progress.Blocks++
+		progress.Bytes += int64(r.block.Size)
+		if onProgress != nil {
+			if err := onProgress(progress); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// +build unittest
+
+package bch
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// blockRangeHandler serves the blocks of all heights as the same raw block and counts the getblock calls
+func blockRangeHandler(t *testing.T, rawBlock []byte, mux *sync.Mutex, getBlockCalls *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string `json:"method"`
+			Params struct {
+				Height    uint32 `json:"height"`
+				BlockHash string `json:"blockhash"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		var result interface{}
+		switch req.Method {
+		case "getblockhash":
+			result = fmt.Sprintf("%064x", req.Params.Height)
+		case "getblockheader":
+			var height uint32
+			fmt.Sscanf(req.Params.BlockHash, "%x", &height)
+			result = map[string]interface{}{"hash": req.Params.BlockHash, "height": height, "time": 1550000000}
+		case "getblock":
+			mux.Lock()
+			*getBlockCalls++
+			mux.Unlock()
+			result = hex.EncodeToString(rawBlock)
+		default:
+			t.Fatalf("unexpected method %v", req.Method)
+		}
+		res, _ := json.Marshal(map[string]interface{}{"result": result, "error": nil, "id": "1"})
+		w.Write(res)
+	}
+}
+
+func Test_GetBlockRange(t *testing.T) {
+	rawBlock := testParserCheckBlock(t)
+	var mux sync.Mutex
+	calls := 0
+	b, closeServer := setupRPC(t, blockRangeHandler(t, rawBlock, &mux, &calls))
+	defer closeServer()
+
+	t.Run("progress", func(t *testing.T) {
+		var heights []uint32
+		var progress []BlockRangeProgress
+		err := b.GetBlockRange(100, 103, func(block *bchain.Block) error {
+			heights = append(heights, block.Height)
+			return nil
+		}, func(p BlockRangeProgress) error {
+			progress = append(progress, p)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []uint32{100, 101, 102, 103}; !reflect.DeepEqual(heights, want) {
+			t.Errorf("GetBlockRange() heights = %v, want %v", heights, want)
+		}
+		size := int64(len(rawBlock))
+		want := []BlockRangeProgress{
+			{Height: 100, Blocks: 1, TotalBlocks: 4, Bytes: size},
+			{Height: 101, Blocks: 2, TotalBlocks: 4, Bytes: 2 * size},
+			{Height: 102, Blocks: 3, TotalBlocks: 4, Bytes: 3 * size},
+			{Height: 103, Blocks: 4, TotalBlocks: 4, Bytes: 4 * size},
+		}
+		if !reflect.DeepEqual(progress, want) {
+			t.Errorf("GetBlockRange() progress = %+v, want %+v", progress, want)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		mux.Lock()
+		calls = 0
+		mux.Unlock()
+		errCancel := errors.New("cancel")
+		blocks := 0
+		err := b.GetBlockRange(100, 199, func(block *bchain.Block) error {
+			blocks++
+			return nil
+		}, func(p BlockRangeProgress) error {
+			if p.Blocks == 2 {
+				return errCancel
+			}
+			return nil
+		})
+		if err != errCancel {
+			t.Fatalf("GetBlockRange() error = %v, want %v", err, errCancel)
+		}
+		if blocks != 2 {
+			t.Errorf("GetBlockRange() processed %d blocks, want 2", blocks)
+		}
+		// only one block can be downloaded ahead of the processed block
+		mux.Lock()
+		defer mux.Unlock()
+		if calls > 3 {
+			t.Errorf("GetBlockRange() downloaded %d blocks after cancel, want at most 3", calls)
+		}
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		if err := b.GetBlockRange(2, 1, func(*bchain.Block) error { return nil }, nil); err == nil {
+			t.Error("GetBlockRange() expected error")
+		}
+	})
+}
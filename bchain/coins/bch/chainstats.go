@@ -0,0 +1,130 @@
+package bch
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// chainStatsTTL is the time for which the snapshot returned by GetChainStats is cached
+const chainStatsTTL = 10 * time.Second
+
+// chainStatsCache holds the last snapshot returned by GetChainStats
+type chainStatsCache struct {
+	mux   sync.Mutex
+	stats *bchain.ChainStats
+	time  time.Time
+}
+
+type cmdGetMempoolInfo struct {
+	Method string `json:"method"`
+}
+
+type resGetMempoolInfo struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result struct {
+		Size  int   `json:"size"`
+		Bytes int64 `json:"bytes"`
+	} `json:"result"`
+}
+
+// GetChainStats returns the snapshot of the best block, the mempool, the difficulty and the number of connections
+// and the issued supply, the values which cannot be obtained from the backend are omitted,
+// an error is returned only if none of them is available; the snapshot is cached for chainStatsTTL
+func (b *BCashRPC) GetChainStats() (*bchain.ChainStats, error) {
+	b.chainStats.mux.Lock()
+	defer b.chainStats.mux.Unlock()
+	if b.chainStats.stats != nil && time.Since(b.chainStats.time) < chainStatsTTL {
+		return b.chainStats.stats, nil
+	}
+	// the independent calls are made in one batch, only the header of the best block needs another round trip
+	glog.V(1).Info("rpc: getbestblockhash, getblockchaininfo, getnetworkinfo, getmempoolinfo")
+	resHash := btc.ResGetBestBlockHash{}
+	resCi := btc.ResGetBlockChainInfo{}
+	resNi := btc.ResGetNetworkInfo{}
+	resMi := resGetMempoolInfo{}
+	err := b.CallBatch([]interface{}{
+		&btc.CmdGetBestBlockHash{Method: "getbestblockhash"},
+		&btc.CmdGetBlockChainInfo{Method: "getblockchaininfo"},
+		&btc.CmdGetNetworkInfo{Method: "getnetworkinfo"},
+		&cmdGetMempoolInfo{Method: "getmempoolinfo"},
+	}, []interface{}{&resHash, &resCi, &resNi, &resMi})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetChainStats")
+	}
+	// each value is filled regardless of the failures of the other calls
+	stats := &bchain.ChainStats{}
+	available := false
+	if chainStatsResult("getbestblockhash", resHash.Error) {
+		if header, err := b.GetBlockHeader(resHash.Result); err != nil {
+			glog.Warning("GetChainStats: best block: ", err)
+		} else {
+			stats.BestHeight = &header.Height
+			stats.BestHash = header.Hash
+			stats.BestTime = header.Time
+			stats.Supply = b.issuedSupply(header.Height)
+			available = true
+		}
+	}
+	if chainStatsResult("getblockchaininfo", resCi.Error) {
+		stats.Difficulty = string(resCi.Result.Difficulty)
+		available = true
+	}
+	if chainStatsResult("getnetworkinfo", resNi.Error) {
+		stats.Connections = &resNi.Result.Connections
+		available = true
+	}
+	if chainStatsResult("getmempoolinfo", resMi.Error) {
+		stats.MempoolSize = &resMi.Result.Size
+		stats.MempoolBytes = &resMi.Result.Bytes
+		available = true
+	}
+	if !available {
+		return nil, errors.New("GetChainStats: backend not available")
+	}
+	b.chainStats.stats = stats
+	b.chainStats.time = time.Now()
+	return stats, nil
+}
+
+// chainStatsResult logs the error of the call of the backend method and returns true if the call succeeded
+func chainStatsResult(method string, err *bchain.RPCError) bool {
+	if err != nil {
+		glog.Warning("GetChainStats: ", method, ": ", err)
+		return false
+	}
+	return true
+}
+
+// issuedSupply returns the sum of the subsidies of the blocks from the genesis block to the block at height,
+// nil if the emission schedule of the coin is not configured
+func (b *BCashRPC) issuedSupply(height uint32) *big.Int {
+	parser, ok := b.Parser.(*BCashParser)
+	if !ok || parser.blockSubsidy == 0 {
+		return nil
+	}
+	supply := new(big.Int)
+	interval := uint64(parser.subsidyReductionInterval)
+	blocks := uint64(height) + 1
+	if interval == 0 {
+		return supply.Mul(big.NewInt(parser.blockSubsidy), new(big.Int).SetUint64(blocks))
+	}
+	// sum the subsidies per halving period
+	for from := uint64(0); from < blocks; from += interval {
+		subsidy, err := parser.GetBlockSubsidy(uint32(from))
+		if err != nil || subsidy.Sign() == 0 {
+			break
+		}
+		n := interval
+		if blocks-from < n {
+			n = blocks - from
+		}
+		supply.Add(supply, new(big.Int).Mul(subsidy, new(big.Int).SetUint64(n)))
+	}
+	return supply
+}
@@ -0,0 +1,147 @@
+// +build unittest
+
+package bch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+type chainStatsRequest struct {
+	Method string          `json:"method"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// chainStatsHandler serves the single and the batch requests, the responses of a batch are returned in the reverse order,
+// calls counts the calls of the methods and the key "http" the http requests
+func chainStatsHandler(t *testing.T, failed map[string]bool, calls map[string]int, mux *sync.Mutex) http.HandlerFunc {
+	response := func(req chainStatsRequest) interface{} {
+		mux.Lock()
+		calls[req.Method]++
+		mux.Unlock()
+		id := req.ID
+		if id == nil {
+			id = json.RawMessage(`"1"`)
+		}
+		if failed[req.Method] {
+			return map[string]interface{}{"result": nil, "error": map[string]interface{}{"code": -32601, "message": "Method not found"}, "id": id}
+		}
+		var result interface{}
+		switch req.Method {
+		case "getbestblockhash":
+			result = testBlockHash
+		case "getblockheader":
+			result = map[string]interface{}{"hash": testBlockHash, "height": 210000, "time": 1550000000}
+		case "getmempoolinfo":
+			result = map[string]interface{}{"size": 12, "bytes": 3456, "usage": 10000}
+		case "getblockchaininfo":
+			result = json.RawMessage(`{"chain":"main","blocks":210000,"bestblockhash":"` + testBlockHash + `","difficulty":253948779484.1987}`)
+		case "getnetworkinfo":
+			result = map[string]interface{}{"version": 190000, "connections": 0}
+		default:
+			t.Errorf("unexpected method %v", req.Method)
+		}
+		return map[string]interface{}{"result": result, "error": nil, "id": id}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mux.Lock()
+		calls["http"]++
+		mux.Unlock()
+		var res interface{}
+		if len(body) > 0 && body[0] == '[' {
+			var reqs []chainStatsRequest
+			if err := json.Unmarshal(body, &reqs); err != nil {
+				t.Fatal(err)
+			}
+			batch := make([]interface{}, len(reqs))
+			for i := range reqs {
+				batch[len(reqs)-1-i] = response(reqs[i])
+			}
+			res = batch
+		} else {
+			var req chainStatsRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Fatal(err)
+			}
+			res = response(req)
+		}
+		d, _ := json.Marshal(res)
+		w.Write(d)
+	}
+}
+
+func Test_GetChainStats(t *testing.T) {
+	var mux sync.Mutex
+	calls := make(map[string]int)
+	b, closeServer := setupRPC(t, chainStatsHandler(t, map[string]bool{"getmempoolinfo": true}, calls, &mux))
+	defer closeServer()
+	got, err := b.GetChainStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BestHeight == nil || *got.BestHeight != 210000 || got.BestHash != testBlockHash || got.BestTime != 1550000000 {
+		t.Errorf("GetChainStats() best block = %v %v %v", got.BestHeight, got.BestHash, got.BestTime)
+	}
+	// the failed call is omitted
+	if got.MempoolSize != nil || got.MempoolBytes != nil {
+		t.Errorf("GetChainStats() mempool = %v %v, want nil", got.MempoolSize, got.MempoolBytes)
+	}
+	if got.Difficulty != "253948779484.1987" {
+		t.Errorf("GetChainStats() difficulty = %v", got.Difficulty)
+	}
+	// zero connections are returned, not omitted
+	if got.Connections == nil || *got.Connections != 0 {
+		t.Errorf("GetChainStats() connections = %v, want 0", got.Connections)
+	}
+	// 210000 blocks with subsidy 50 and one block with subsidy 25
+	if got.Supply == nil || got.Supply.String() != "1050002500000000" {
+		t.Errorf("GetChainStats() supply = %v, want 1050002500000000", got.Supply)
+	}
+	// the second call is served from the cache
+	if _, err = b.GetChainStats(); err != nil {
+		t.Fatal(err)
+	}
+	if calls["getblockchaininfo"] != 1 || calls["getnetworkinfo"] != 1 {
+		t.Errorf("GetChainStats() called getblockchaininfo %d, getnetworkinfo %d times, want 1", calls["getblockchaininfo"], calls["getnetworkinfo"])
+	}
+	// the batch and the header of the best block
+	if calls["http"] != 2 {
+		t.Errorf("GetChainStats() made %d http requests, want 2", calls["http"])
+	}
+}
+
+func Test_GetChainStats_Failed(t *testing.T) {
+	var mux sync.Mutex
+	failed := map[string]bool{"getbestblockhash": true, "getmempoolinfo": true, "getblockchaininfo": true, "getnetworkinfo": true}
+	b, closeServer := setupRPC(t, chainStatsHandler(t, failed, make(map[string]int), &mux))
+	defer closeServer()
+	if _, err := b.GetChainStats(); err == nil {
+		t.Error("GetChainStats() did not return error when all calls failed")
+	}
+	// the difficulty is returned without the other values, regardless of the failure of getnetworkinfo
+	delete(failed, "getblockchaininfo")
+	got, err := b.GetChainStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Difficulty != "253948779484.1987" || got.Connections != nil || got.BestHeight != nil || got.Supply != nil || got.MempoolSize != nil {
+		t.Errorf("GetChainStats() = %+v", got)
+	}
+	// the connections are returned without the difficulty
+	b.chainStats.stats = nil
+	failed["getblockchaininfo"] = true
+	delete(failed, "getnetworkinfo")
+	if got, err = b.GetChainStats(); err != nil {
+		t.Fatal(err)
+	}
+	if got.Difficulty != "" || got.Connections == nil || *got.Connections != 0 {
+		t.Errorf("GetChainStats() = %+v", got)
+	}
+}
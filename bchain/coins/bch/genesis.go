@@ -0,0 +1,86 @@
+package bch
+
+import (
+	"blockbook/bchain"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// genesisRetryInterval is the time after which the download of the genesis block is retried after a failure
+const genesisRetryInterval = time.Minute
+
+// genesisCache keeps the coinbase transaction of the genesis block, which does not change,
+// failed is the time of the last failed download of the genesis block
+type genesisCache struct {
+	mux    sync.Mutex
+	tx     *bchain.Tx
+	failed time.Time
+}
+
+// errGenesisCoinbaseRead stops the reading of the genesis block after its coinbase transaction
+var errGenesisCoinbaseRead = errors.New("genesis coinbase read")
+
+// GetTransaction returns the transaction with given txid; the coinbase transaction of the genesis block is unspendable
+// and the backends do not keep it in the transaction index, it is parsed from the genesis block instead
+func (b *BCashRPC) GetTransaction(txid string) (*bchain.Tx, error) {
+	tx, err := b.BitcoinRPC.GetTransaction(txid)
+	if err == nil {
+		return tx, nil
+	}
+	genesis := b.getGenesisCoinbase()
+	if genesis == nil || genesis.Txid != txid {
+		return nil, err
+	}
+	best, berr := b.GetBestBlockHeight()
+	if berr != nil {
+		return nil, berr
+	}
+	gtx := *genesis
+	gtx.Confirmations = best + 1
+	return &gtx, nil
+}
+
+// getGenesisCoinbase returns the cached coinbase transaction of the genesis block, it is downloaded on the first call
+// and after a failure it is not downloaded again sooner than genesisRetryInterval; nil is returned if it is not available
+func (b *BCashRPC) getGenesisCoinbase() *bchain.Tx {
+	b.genesis.mux.Lock()
+	defer b.genesis.mux.Unlock()
+	if b.genesis.tx == nil && time.Since(b.genesis.failed) >= genesisRetryInterval {
+		tx, err := b.downloadGenesisCoinbase()
+		if err != nil {
+			glog.Error("rpc: genesis coinbase: ", err)
+			b.genesis.failed = time.Now()
+		}
+		b.genesis.tx = tx
+	}
+	return b.genesis.tx
+}
+
+// downloadGenesisCoinbase reads the coinbase transaction of the genesis block from the backend
+func (b *BCashRPC) downloadGenesisCoinbase() (*bchain.Tx, error) {
+	hash, err := b.GetBlockHash(0)
+	if err != nil {
+		return nil, err
+	}
+	var tx *bchain.Tx
+	var blockTime int64
+	err = b.GetBlockTxsStream(hash, func(h *bchain.BlockHeader) error {
+		blockTime = h.Time
+		return nil
+	}, func(t *bchain.Tx) error {
+		tx = t
+		return errGenesisCoinbaseRead
+	})
+	if errors.Cause(err) != errGenesisCoinbaseRead {
+		if err == nil {
+			err = errors.Errorf("Genesis block %v without transactions", hash)
+		}
+		return nil, err
+	}
+	tx.Blocktime = blockTime
+	tx.Time = blockTime
+	return tx, nil
+}
@@ -0,0 +1,150 @@
+// +build unittest
+
+package bch
+
+import (
+	"blockbook/bchain"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+const (
+	genesisHash   = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	genesisTxid   = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+	genesisTime   = 1231006505
+	genesisHeight = 0
+)
+
+// genesisHandler serves the genesis block of the main network, the genesis coinbase is not in the transaction index
+func genesisHandler(t *testing.T, calls map[string]int) http.HandlerFunc {
+	var raw bytes.Buffer
+	if err := MainNetParams.GenesisBlock.Serialize(&raw); err != nil {
+		t.Fatal(err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		calls[req.Method]++
+		var result interface{}
+		switch req.Method {
+		case "getblockhash":
+			result = genesisHash
+		case "getblockcount":
+			result = 9
+		case "getblockheader":
+			result = map[string]interface{}{"hash": genesisHash, "height": genesisHeight, "time": genesisTime, "confirmations": 10}
+		case "getblock":
+			result = hex.EncodeToString(raw.Bytes())
+		case "getrawtransaction":
+			w.Write([]byte(`{"result":null,"error":{"code":-5,"message":"The genesis block coinbase is not considered an ordinary transaction and cannot be retrieved"},"id":"1"}`))
+			return
+		default:
+			t.Errorf("unexpected method %v", req.Method)
+		}
+		res, _ := json.Marshal(map[string]interface{}{"result": result, "error": nil, "id": "1"})
+		w.Write(res)
+	}
+}
+
+func Test_GetGenesis(t *testing.T) {
+	calls := make(map[string]int)
+	b, closeServer := setupRPC(t, genesisHandler(t, calls))
+	defer closeServer()
+
+	block, err := b.GetBlock("", genesisHeight)
+	if err != nil {
+		t.Fatalf("GetBlock() error = %v", err)
+	}
+	if block.Hash != genesisHash || block.Time != genesisTime || block.Height != genesisHeight {
+		t.Errorf("GetBlock() = %v %v %v, want %v %v %v", block.Hash, block.Time, block.Height, genesisHash, genesisTime, genesisHeight)
+	}
+	if len(block.Txs) != 1 || block.Txs[0].Txid != genesisTxid {
+		t.Fatalf("GetBlock() txs = %+v, want the genesis coinbase", block.Txs)
+	}
+
+	tx, err := b.GetTransaction(genesisTxid)
+	if err != nil {
+		t.Fatalf("GetTransaction() error = %v", err)
+	}
+	if tx.Txid != genesisTxid || tx.Blocktime != genesisTime || tx.Confirmations != 10 {
+		t.Errorf("GetTransaction() = %v %v %v", tx.Txid, tx.Blocktime, tx.Confirmations)
+	}
+	if len(tx.Vin) != 1 || tx.Vin[0].Coinbase == "" {
+		t.Errorf("GetTransaction() vin = %+v, want coinbase", tx.Vin)
+	}
+	// the P2PK output of the genesis coinbase is parsed to the address of its public key
+	if len(tx.Vout) != 1 || tx.Vout[0].ValueSat.Int64() != 5000000000 {
+		t.Fatalf("GetTransaction() vout = %+v", tx.Vout)
+	}
+	addresses, _, err := b.Parser.GetAddressesFromAddrDesc(mustDecodeHex(t, tx.Vout[0].ScriptPubKey.Hex))
+	if err != nil || len(addresses) != 1 || addresses[0] != "bitcoincash:qp3wjpa3tjlj042z2wv7hahsldgwhwy0rq9sywjpyy" {
+		t.Errorf("genesis output addresses = %v, %v", addresses, err)
+	}
+
+	// the genesis coinbase is cached, other missing transactions are still not found
+	getblocks := calls["getblock"]
+	if _, err := b.GetTransaction(genesisTxid); err != nil {
+		t.Fatalf("GetTransaction() second call error = %v", err)
+	}
+	if calls["getblock"] != getblocks {
+		t.Errorf("the genesis block downloaded again")
+	}
+	// the confirmations are computed only for the genesis coinbase
+	getblockcounts := calls["getblockcount"]
+	if _, err := b.GetTransaction("aa" + genesisTxid[2:]); err != bchain.ErrTxNotFound {
+		t.Errorf("GetTransaction() of missing tx error = %v, want %v", err, bchain.ErrTxNotFound)
+	}
+	if calls["getblockcount"] != getblockcounts {
+		t.Errorf("the best block height fetched for a missing tx")
+	}
+}
+
+func Test_GetTransaction_GenesisFailure(t *testing.T) {
+	calls := make(map[string]int)
+	handler := genesisHandler(t, calls)
+	b, closeServer := setupRPC(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(body, []byte(`"getblock"`)) {
+			calls["getblock"]++
+			w.Write([]byte(`{"result":null,"error":{"code":-1,"message":"failure"},"id":"1"}`))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		handler(w, r)
+	})
+	defer closeServer()
+
+	// the download of the genesis block fails and is not retried for the following missing transactions
+	for i := 0; i < 3; i++ {
+		if _, err := b.GetTransaction(genesisTxid); err != bchain.ErrTxNotFound {
+			t.Errorf("GetTransaction() error = %v, want %v", err, bchain.ErrTxNotFound)
+		}
+	}
+	if calls["getblock"] != 1 {
+		t.Errorf("getblock called %d times, want 1", calls["getblock"])
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
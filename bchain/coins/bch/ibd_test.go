@@ -0,0 +1,52 @@
+// +build unittest
+
+package bch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func chainInfoHandler(t *testing.T, ibd bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		var result interface{}
+		switch req.Method {
+		case "getblockchaininfo":
+			result = map[string]interface{}{"chain": "main", "blocks": 1000, "headers": 210000, "bestblockhash": testBlockHash,
+				"difficulty": 1, "verificationprogress": 0.0047, "initialblockdownload": ibd}
+		case "getnetworkinfo":
+			result = map[string]interface{}{"version": 190000, "protocolversion": 70015}
+		default:
+			w.Write([]byte(`{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":"1"}`))
+			return
+		}
+		res, _ := json.Marshal(map[string]interface{}{"result": result, "error": nil, "id": "1"})
+		w.Write(res)
+	}
+}
+
+func Test_GetChainInfo_InitialBlockDownload(t *testing.T) {
+	for _, ibd := range []bool{true, false} {
+		b, closeServer := setupRPC(t, chainInfoHandler(t, ibd))
+		ci, err := b.GetChainInfo()
+		closeServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ci.InitialBlockDownload != ibd || ci.Blocks != 1000 || ci.Headers != 210000 {
+			t.Errorf("GetChainInfo() = %+v, want initialblockdownload %v", ci, ibd)
+		}
+	}
+}
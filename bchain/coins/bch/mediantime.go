@@ -0,0 +1,50 @@
+package bch
+
+import (
+	"sort"
+	"sync"
+)
+
+// medianTimeSpan is the number of the last blocks from which the median time past is computed
+const medianTimeSpan = 11
+
+// medianTimeCache holds the median time past of the last requested block, it is recomputed only when the tip changes
+type medianTimeCache struct {
+	mux  sync.Mutex
+	hash string
+	time int64
+}
+
+// MedianTimePast returns the median of the block times, the times of the blocks before the genesis block are not counted
+func MedianTimePast(times []int64) int64 {
+	if len(times) == 0 {
+		return 0
+	}
+	sorted := make([]int64, len(times))
+	copy(sorted, times)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// GetMedianTimePast returns the median time past of the block with given hash, computed from the times of the block
+// and the 10 preceding blocks, the value for the last requested block is cached
+func (b *BCashRPC) GetMedianTimePast(hash string) (int64, error) {
+	b.medianTime.mux.Lock()
+	defer b.medianTime.mux.Unlock()
+	if hash == b.medianTime.hash {
+		return b.medianTime.time, nil
+	}
+	times := make([]int64, 0, medianTimeSpan)
+	for h := hash; h != "" && len(times) < medianTimeSpan; {
+		header, err := b.GetBlockHeader(h)
+		if err != nil {
+			return 0, err
+		}
+		times = append(times, header.Time)
+		h = header.Prev
+	}
+	t := MedianTimePast(times)
+	b.medianTime.hash = hash
+	b.medianTime.time = t
+	return t, nil
+}
@@ -0,0 +1,106 @@
+// +build unittest
+
+package bch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestMedianTimePast(t *testing.T) {
+	tests := []struct {
+		name  string
+		times []int64
+		want  int64
+	}{
+		{name: "empty", times: nil, want: 0},
+		{name: "genesis", times: []int64{1231006505}, want: 1231006505},
+		{name: "two blocks", times: []int64{1231469665, 1231006505}, want: 1231469665},
+		{name: "unordered", times: []int64{1000, 1600, 1200, 1100, 1500, 1300, 1400, 1700, 900, 1800, 1000}, want: 1300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MedianTimePast(tt.times); got != tt.want {
+				t.Errorf("MedianTimePast() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// the headers of the blocks h0..h12, block hN has time headerTimes[N]
+var headerTimes = []int64{1000, 1010, 1005, 1030, 1020, 1040, 1100, 1060, 1050, 1090, 1070, 1080, 1075}
+
+func headersHandler(t *testing.T, calls *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string `json:"method"`
+			Params struct {
+				BlockHash string `json:"blockhash"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Method != "getblockheader" {
+			t.Errorf("unexpected request %s", body)
+			return
+		}
+		*calls++
+		n, err := strconv.Atoi(req.Params.BlockHash[1:])
+		if err != nil || n >= len(headerTimes) {
+			w.Write([]byte(`{"result":null,"error":{"code":-5,"message":"Block not found"},"id":"1"}`))
+			return
+		}
+		prev := ""
+		if n > 0 {
+			prev = fmt.Sprintf(`"previousblockhash":"h%d",`, n-1)
+		}
+		fmt.Fprintf(w, `{"result":{"hash":"h%d",%s"height":%d,"time":%d},"error":null,"id":"1"}`, n, prev, n, headerTimes[n])
+	}
+}
+
+func TestBCashRPC_GetMedianTimePast(t *testing.T) {
+	var calls int
+	b, closeServer := setupRPC(t, headersHandler(t, &calls))
+	defer closeServer()
+	tests := []struct {
+		name      string
+		hash      string
+		want      int64
+		wantCalls int
+	}{
+		// the times of the blocks h2..h12 sorted: 1005 1020 1030 1040 1050 1060 1070 1075 1080 1090 1100
+		{name: "tip", hash: "h12", want: 1060, wantCalls: 11},
+		{name: "cached tip", hash: "h12", want: 1060, wantCalls: 0},
+		// the times of the blocks h1..h11 sorted: 1005 1010 1020 1030 1040 1050 1060 1070 1080 1090 1100
+		{name: "previous block", hash: "h11", want: 1050, wantCalls: 11},
+		// only 3 blocks from the genesis block
+		{name: "near genesis", hash: "h2", want: 1005, wantCalls: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			got, err := b.GetMedianTimePast(tt.hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GetMedianTimePast() = %v, want %v", got, tt.want)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("GetMedianTimePast() called getblockheader %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+	if _, err := b.GetMedianTimePast("h20"); err == nil {
+		t.Error("GetMedianTimePast() of unknown block did not return error")
+	}
+}
@@ -0,0 +1,190 @@
+package bch
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"encoding/json"
+	"math/big"
+	"sort"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// maxMempoolAncestors is the maximum number of ancestors reconstructed from the mempool transactions,
+// the backend does not accept transactions with more unconfirmed ancestors
+const maxMempoolAncestors = 100
+
+// MempoolAncestors are the unconfirmed ancestors of a mempool transaction with their total size and fees
+type MempoolAncestors struct {
+	Txids   []string
+	Size    int
+	FeesSat big.Int
+}
+
+// getmempoolancestors
+
+type cmdGetMempoolAncestors struct {
+	Method string `json:"method"`
+	Params struct {
+		Txid    string `json:"txid"`
+		Verbose bool   `json:"verbose"`
+	} `json:"params"`
+}
+
+type mempoolAncestorEntry struct {
+	Size int         `json:"size"`
+	Fee  json.Number `json:"fee"`
+	Fees struct {
+		Base json.Number `json:"base"`
+	} `json:"fees"`
+}
+
+type resGetMempoolAncestors struct {
+	Error  *bchain.RPCError                `json:"error"`
+	Result map[string]mempoolAncestorEntry `json:"result"`
+}
+
+// GetMempoolAncestors returns the unconfirmed ancestors of the mempool transaction with given txid and their total size and fees,
+// the ancestors are sorted by txid
+// if the backend does not support getmempoolancestors, the ancestors are reconstructed from the inputs of the mempool transactions
+func (b *BCashRPC) GetMempoolAncestors(txid string) (*MempoolAncestors, error) {
+	glog.V(1).Info("rpc: getmempoolancestors ", txid)
+
+	res := resGetMempoolAncestors{}
+	req := cmdGetMempoolAncestors{Method: "getmempoolancestors"}
+	req.Params.Txid = txid
+	req.Params.Verbose = true
+	err := b.Call(&req, &res)
+
+	if err != nil {
+		return nil, errors.Annotatef(err, "txid %v", txid)
+	}
+	if res.Error != nil {
+		if res.Error.Code == btc.ErrCodeMethodNotFound {
+			return b.reconstructMempoolAncestors(txid, b.mempoolTxInputs)
+		}
+		return nil, errors.Annotatef(res.Error, "txid %v", txid)
+	}
+	r := &MempoolAncestors{Txids: make([]string, 0, len(res.Result))}
+	for t, e := range res.Result {
+		fee := e.Fees.Base
+		if fee == "" {
+			fee = e.Fee
+		}
+		f, err := b.Parser.AmountToBigInt(fee)
+		if err != nil {
+			return nil, errors.Annotatef(err, "txid %v, ancestor %v", txid, t)
+		}
+		r.Txids = append(r.Txids, t)
+		r.Size += e.Size
+		r.FeesSat.Add(&r.FeesSat, &f)
+	}
+	sort.Strings(r.Txids)
+	return r, nil
+}
+
+// mempoolTxInputs returns the outpoints spent by the transaction of the mempool tracked by blockbook,
+// false if the mempool is not created or the transaction is not in it
+func (b *BCashRPC) mempoolTxInputs(txid string) ([]bchain.Outpoint, bool) {
+	if b.Mempool == nil {
+		return nil, false
+	}
+	return b.Mempool.GetTxInputs(txid)
+}
+
+// reconstructMempoolAncestors finds the ancestors of the mempool transaction by following its inputs to other unconfirmed transactions,
+// the inputs are taken from mempoolInputs and only the transactions missing there are requested from the backend;
+// the fee of an ancestor is computed from the values of the outputs it spends
+func (b *BCashRPC) reconstructMempoolAncestors(txid string, mempoolInputs func(string) ([]bchain.Outpoint, bool)) (*MempoolAncestors, error) {
+	txs := make(map[string]*bchain.Tx)
+	getTx := func(txid string) (*bchain.Tx, error) {
+		if tx, found := txs[txid]; found {
+			return tx, nil
+		}
+		tx, err := b.GetTransaction(txid)
+		if err != nil {
+			return nil, err
+		}
+		txs[txid] = tx
+		return tx, nil
+	}
+	// unconfirmedInputs returns the outpoints spent by the transaction, false if the transaction is confirmed
+	unconfirmedInputs := func(txid string) ([]bchain.Outpoint, bool, error) {
+		if inputs, found := mempoolInputs(txid); found {
+			return inputs, true, nil
+		}
+		tx, err := getTx(txid)
+		if err != nil {
+			return nil, false, err
+		}
+		if tx.Confirmations > 0 {
+			return nil, false, nil
+		}
+		inputs := make([]bchain.Outpoint, 0, len(tx.Vin))
+		for i := range tx.Vin {
+			if tx.Vin[i].Txid != "" {
+				inputs = append(inputs, bchain.Outpoint{Txid: tx.Vin[i].Txid, Vout: int32(tx.Vin[i].Vout)})
+			}
+		}
+		return inputs, true, nil
+	}
+	inputs, unconfirmed, err := unconfirmedInputs(txid)
+	if err != nil {
+		return nil, err
+	}
+	if !unconfirmed {
+		return nil, errors.Errorf("Transaction %v is not in mempool", txid)
+	}
+	r := &MempoolAncestors{}
+	visited := map[string]struct{}{txid: {}}
+	queue := [][]bchain.Outpoint{inputs}
+	for len(queue) > 0 {
+		inputs := queue[0]
+		queue = queue[1:]
+		for _, o := range inputs {
+			if _, found := visited[o.Txid]; found {
+				continue
+			}
+			visited[o.Txid] = struct{}{}
+			prevInputs, unconfirmed, err := unconfirmedInputs(o.Txid)
+			if err != nil {
+				return nil, err
+			}
+			if !unconfirmed {
+				continue
+			}
+			if len(r.Txids) >= maxMempoolAncestors {
+				return nil, errors.Errorf("Transaction %v has more than %d unconfirmed ancestors", txid, maxMempoolAncestors)
+			}
+			r.Txids = append(r.Txids, o.Txid)
+			queue = append(queue, prevInputs)
+		}
+	}
+	// the size and the fee of the ancestors are not in the tracked mempool, the ancestors are requested from the backend
+	for _, a := range r.Txids {
+		atx, err := getTx(a)
+		if err != nil {
+			return nil, err
+		}
+		r.Size += len(atx.Hex) / 2
+		var fee big.Int
+		for i := range atx.Vin {
+			prev, err := getTx(atx.Vin[i].Txid)
+			if err != nil {
+				return nil, err
+			}
+			vout := atx.Vin[i].Vout
+			if int(vout) >= len(prev.Vout) {
+				return nil, errors.Errorf("Transaction %v spends nonexistent output %v:%v", a, prev.Txid, vout)
+			}
+			fee.Add(&fee, &prev.Vout[vout].ValueSat)
+		}
+		for i := range atx.Vout {
+			fee.Sub(&fee, &atx.Vout[i].ValueSat)
+		}
+		r.FeesSat.Add(&r.FeesSat, &fee)
+	}
+	sort.Strings(r.Txids)
+	return r, nil
+}
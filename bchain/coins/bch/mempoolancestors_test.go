@@ -0,0 +1,121 @@
+// +build unittest
+
+package bch
+
+import (
+	"blockbook/bchain"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chain of transactions confirmed -> a -> b -> c, c spends also the second output of a
+var ancestorsTxs = map[string]string{
+	"confirmed": `{"txid":"confirmed","hex":"00","vin":[{"coinbase":"03","sequence":4294967295}],"vout":[{"value":1.0,"n":0,"scriptPubKey":{"hex":"76a914010d39800f86122416e28f485029acf77507169288ac"}}],"confirmations":10}`,
+	"a":         `{"txid":"a","hex":"` + strings.Repeat("00", 200) + `","vin":[{"txid":"confirmed","vout":0,"scriptSig":{"hex":""},"sequence":4294967295}],"vout":[{"value":0.6,"n":0,"scriptPubKey":{"hex":"76a914010d39800f86122416e28f485029acf77507169288ac"}},{"value":0.3999,"n":1,"scriptPubKey":{"hex":"76a914010d39800f86122416e28f485029acf77507169288ac"}}]}`,
+	"b":         `{"txid":"b","hex":"` + strings.Repeat("00", 150) + `","vin":[{"txid":"a","vout":0,"scriptSig":{"hex":""},"sequence":4294967295}],"vout":[{"value":0.5998,"n":0,"scriptPubKey":{"hex":"76a914010d39800f86122416e28f485029acf77507169288ac"}}]}`,
+	"c":         `{"txid":"c","hex":"` + strings.Repeat("00", 300) + `","vin":[{"txid":"b","vout":0,"scriptSig":{"hex":""},"sequence":4294967295},{"txid":"a","vout":1,"scriptSig":{"hex":""},"sequence":4294967295}],"vout":[{"value":0.99,"n":0,"scriptPubKey":{"hex":"76a914010d39800f86122416e28f485029acf77507169288ac"}}]}`,
+}
+
+func mempoolAncestorsHandler(t *testing.T, supported bool, methods *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string `json:"method"`
+			Params struct {
+				Txid string `json:"txid"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Method == "getrawtransaction" {
+			*methods = append(*methods, req.Method+" "+req.Params.Txid)
+		} else {
+			*methods = append(*methods, req.Method)
+		}
+		switch req.Method {
+		case "getmempoolancestors":
+			if !supported {
+				w.Write([]byte(`{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":"1"}`))
+				return
+			}
+			if req.Params.Txid != "c" {
+				w.Write([]byte(`{"result":null,"error":{"code":-5,"message":"Transaction not in mempool"},"id":"1"}`))
+				return
+			}
+			w.Write([]byte(`{"result":{"a":{"size":200,"fee":0.0001},"b":{"size":150,"fees":{"base":0.0002}}},"error":null,"id":"1"}`))
+		case "getrawtransaction":
+			tx, found := ancestorsTxs[req.Params.Txid]
+			if !found {
+				w.Write([]byte(`{"result":null,"error":{"code":-5,"message":"No such mempool or blockchain transaction"},"id":"1"}`))
+				return
+			}
+			w.Write([]byte(`{"result":` + tx + `,"error":null,"id":"1"}`))
+		default:
+			t.Errorf("unexpected request %s", body)
+		}
+	}
+}
+
+func Test_GetMempoolAncestors(t *testing.T) {
+	for _, supported := range []bool{true, false} {
+		var methods []string
+		b, closeServer := setupRPC(t, mempoolAncestorsHandler(t, supported, &methods))
+		got, err := b.GetMempoolAncestors("c")
+		if err != nil {
+			t.Fatalf("supported %v: GetMempoolAncestors() error = %v", supported, err)
+		}
+		if want := []string{"a", "b"}; !reflect.DeepEqual(got.Txids, want) {
+			t.Errorf("supported %v: GetMempoolAncestors() txids = %v, want %v", supported, got.Txids, want)
+		}
+		if got.Size != 350 {
+			t.Errorf("supported %v: GetMempoolAncestors() size = %v, want 350", supported, got.Size)
+		}
+		if got.FeesSat.String() != "30000" {
+			t.Errorf("supported %v: GetMempoolAncestors() fees = %v, want 30000", supported, got.FeesSat.String())
+		}
+		if supported && len(methods) != 1 {
+			t.Errorf("GetMempoolAncestors() called %v, want only getmempoolancestors", methods)
+		}
+		if _, err = b.GetMempoolAncestors("confirmed"); err == nil {
+			t.Errorf("supported %v: GetMempoolAncestors() of confirmed transaction did not return error", supported)
+		}
+		closeServer()
+	}
+}
+
+func Test_reconstructMempoolAncestors_trackedMempool(t *testing.T) {
+	var methods []string
+	b, closeServer := setupRPC(t, mempoolAncestorsHandler(t, false, &methods))
+	defer closeServer()
+	// the tracked mempool contains c and b, a is missing there and is requested from the backend
+	mempool := map[string][]bchain.Outpoint{
+		"c": {{Txid: "b", Vout: 0}, {Txid: "a", Vout: 1}},
+		"b": {{Txid: "a", Vout: 0}},
+	}
+	got, err := b.reconstructMempoolAncestors("c", func(txid string) ([]bchain.Outpoint, bool) {
+		inputs, found := mempool[txid]
+		return inputs, found
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got.Txids, want) {
+		t.Errorf("reconstructMempoolAncestors() txids = %v, want %v", got.Txids, want)
+	}
+	if got.Size != 350 || got.FeesSat.String() != "30000" {
+		t.Errorf("reconstructMempoolAncestors() size = %v, fees = %v, want 350, 30000", got.Size, got.FeesSat.String())
+	}
+	for _, m := range methods {
+		if m == "getrawtransaction c" {
+			t.Errorf("reconstructMempoolAncestors() requested the transaction c found in the tracked mempool, calls %v", methods)
+		}
+	}
+}
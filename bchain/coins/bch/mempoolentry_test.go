@@ -0,0 +1,144 @@
+// +build unittest
+
+package bch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+// mempool entries as returned by the backends, with the fee in the old and the new format, without vsize and without fee
+var mempoolEntries = map[string]string{
+	"vsize":   `{"size":250,"vsize":200,"fee":0.00001,"modifiedfee":0.00001,"time":1560000000,"height":100}`,
+	"size":    `{"size":226,"fee":0.00000452,"modifiedfee":0.00000452,"time":1560000000,"height":100}`,
+	"fees":    `{"vsize":141,"fees":{"base":0.00000282,"modified":0.00000282},"time":1560000000,"height":100}`,
+	"no-fee":  `{"size":226,"time":1560000000,"height":100}`,
+	"no-size": `{"fee":0.00001,"time":1560000000,"height":100}`,
+	// prioritised transactions with an unconfirmed ancestor, in the old and the new format
+	"ancestor":      `{"size":250,"vsize":200,"fee":0.00001,"modifiedfee":0.00002,"ancestorcount":2,"ancestorsize":400,"ancestorfees":3000,"time":1560000000,"height":100}`,
+	"fees-ancestor": `{"vsize":200,"fees":{"base":0.00001,"modified":0.00002,"ancestor":0.00005},"ancestorcount":2,"ancestorsize":400,"time":1560000000,"height":100}`,
+}
+
+func mempoolEntryHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string   `json:"method"`
+			Params []string `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Method != "getmempoolentry" || len(req.Params) != 1 {
+			t.Fatalf("unexpected request %s", body)
+		}
+		e, found := mempoolEntries[req.Params[0]]
+		if !found {
+			w.Write([]byte(`{"result":null,"error":{"code":-5,"message":"Transaction not in mempool"},"id":"1"}`))
+			return
+		}
+		w.Write([]byte(`{"result":` + e + `,"error":null,"id":"1"}`))
+	}
+}
+
+func Test_MempoolEntry_FeeRate(t *testing.T) {
+	b, closeServer := setupRPC(t, mempoolEntryHandler(t))
+	defer closeServer()
+	tests := []struct {
+		txid   string
+		want   float64
+		wantOk bool
+	}{
+		{txid: "vsize", want: 5, wantOk: true},
+		{txid: "size", want: 2, wantOk: true},
+		{txid: "fees", want: 2, wantOk: true},
+		{txid: "no-fee"},
+		{txid: "no-size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.txid, func(t *testing.T) {
+			e, err := b.GetMempoolEntry(tt.txid)
+			if err != nil {
+				t.Fatalf("GetMempoolEntry() error = %v", err)
+			}
+			got, ok := e.FeeRate()
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("FeeRate() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+	if _, err := b.GetMempoolEntry("missing"); err == nil {
+		t.Error("GetMempoolEntry() of missing transaction did not return error")
+	}
+}
+
+func Test_MempoolEntry_MiningFeeRate(t *testing.T) {
+	b, closeServer := setupRPC(t, mempoolEntryHandler(t))
+	defer closeServer()
+	tests := []struct {
+		txid   string
+		want   float64
+		wantOk bool
+	}{
+		{txid: "vsize", want: 5, wantOk: true},
+		// the rate of the package 3000/400 is lower than the modified rate 2000/200
+		{txid: "ancestor", want: 7.5, wantOk: true},
+		// the rate of the package 5000/400 is higher than the modified rate 2000/200
+		{txid: "fees-ancestor", want: 10, wantOk: true},
+		{txid: "no-fee"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.txid, func(t *testing.T) {
+			e, err := b.GetMempoolEntry(tt.txid)
+			if err != nil {
+				t.Fatalf("GetMempoolEntry() error = %v", err)
+			}
+			got, ok := e.MiningFeeRate()
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("MiningFeeRate() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_MempoolEntries_FeeRate(t *testing.T) {
+	calls := 0
+	b, closeServer := setupRPC(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != `{"method":"getrawmempool","params":{"verbose":true}}` {
+			t.Fatalf("unexpected request %s", body)
+		}
+		calls++
+		result := "{"
+		for txid, e := range mempoolEntries {
+			if len(result) > 1 {
+				result += ","
+			}
+			result += `"` + txid + `":` + e
+		}
+		w.Write([]byte(`{"result":` + result + `},"error":null,"id":"1"}`))
+	})
+	defer closeServer()
+	entries, err := b.GetMempoolEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || len(entries) != len(mempoolEntries) {
+		t.Fatalf("GetMempoolEntries() returned %d entries in %d calls, want %d entries in 1 call", len(entries), calls, len(mempoolEntries))
+	}
+	want := map[string]float64{"vsize": 5, "size": 2, "fees": 2, "ancestor": 5, "fees-ancestor": 5}
+	for txid, e := range entries {
+		got, ok := e.FeeRate()
+		if w, wantOk := want[txid]; ok != wantOk || got != w {
+			t.Errorf("%s: FeeRate() = %v, %v, want %v, %v", txid, got, ok, w, wantOk)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// +build unittest
+
+package bch
+
+import (
+	"blockbook/bchain"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	zmq "github.com/pebbe/zmq4"
+)
+
+func setupMQRPC(t *testing.T, binding string, pushHandler func(bchain.NotificationType)) *BCashRPC {
+	config, err := json.Marshal(map[string]interface{}{
+		"rpc_url":               "http://127.0.0.1:1",
+		"rpc_timeout":           5,
+		"message_queue_binding": binding,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewBCashRPC(config, pushHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := c.(*BCashRPC)
+	if _, err = b.CreateMempool(b); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.InitializeMempool(nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func Test_MQ_NewBlock(t *testing.T) {
+	pub, err := zmq.NewSocket(zmq.PUB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	if err = pub.Bind("tcp://127.0.0.1:*"); err != nil {
+		t.Fatal(err)
+	}
+	binding, err := pub.GetLastEndpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifications := make(chan bchain.NotificationType, 16)
+	b := setupMQRPC(t, binding, func(nt bchain.NotificationType) {
+		notifications <- nt
+	})
+	hash := []byte{0x00, 0x00, 0x00, 0x00, 0x41, 0x19, 0xdd, 0x73, 0xc5, 0x4b, 0x0b, 0x1a, 0x5e, 0x2f, 0x19, 0x37, 0xf9, 0xfc, 0xbc, 0x29, 0x3d, 0xab, 0x3b, 0x4b, 0x8c, 0xf9, 0x67, 0x5c, 0x38, 0x4e, 0x4d, 0xf6}
+	// the subscription is established asynchronously, the messages sent before are lost
+	timeout := time.After(5 * time.Second)
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+	received := false
+	for !received {
+		select {
+		case <-tick.C:
+			if _, err = pub.SendMessage("hashblock", hash, []byte{1, 0, 0, 0}); err != nil {
+				t.Fatal(err)
+			}
+		case nt := <-notifications:
+			if nt != bchain.NotificationNewBlock {
+				t.Fatalf("notification %v, want %v", nt, bchain.NotificationNewBlock)
+			}
+			received = true
+		case <-timeout:
+			t.Fatal("notification about new block not received")
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = b.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_MQ_NotConfigured(t *testing.T) {
+	b := setupMQRPC(t, "", func(nt bchain.NotificationType) {
+		t.Errorf("unexpected notification %v", nt)
+	})
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
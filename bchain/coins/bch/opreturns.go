@@ -0,0 +1,76 @@
+package bch
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcutil/txscript"
+)
+
+// OpReturnData is the payload of an OP_RETURN output returned by GetBlockOpReturns
+type OpReturnData struct {
+	Txid string
+	Vout uint32
+	// Hex is the payload in hex, Text the payload with the bytes which are not printable ASCII characters replaced by '.'
+	Hex  string
+	Text string
+}
+
+// errOpReturnsLimit stops the reading of the block after the limit of the OP_RETURN outputs is exceeded
+var errOpReturnsLimit = errors.New("OP_RETURN limit exceeded")
+
+// GetBlockOpReturns returns the payloads of at most limit OP_RETURN outputs of the block with given hash in the order
+// of the block and true if the block contains more of them, the block is decoded only until the limit is exceeded
+func (b *BCashRPC) GetBlockOpReturns(hash string, limit int) ([]OpReturnData, bool, error) {
+	if limit <= 0 {
+		return nil, false, errors.Errorf("Invalid limit %d", limit)
+	}
+	r := []OpReturnData{}
+	truncated := false
+	err := b.GetBlockTxsStream(hash, func(*bchain.BlockHeader) error { return nil }, func(tx *bchain.Tx) error {
+		for i := range tx.Vout {
+			script, err := hex.DecodeString(tx.Vout[i].ScriptPubKey.Hex)
+			if err != nil || len(script) == 0 || script[0] != txscript.OP_RETURN {
+				continue
+			}
+			if len(r) == limit {
+				truncated = true
+				return errOpReturnsLimit
+			}
+			data := opReturnPayload(script)
+			r = append(r, OpReturnData{Txid: tx.Txid, Vout: tx.Vout[i].N, Hex: hex.EncodeToString(data), Text: printableText(data)})
+		}
+		return nil
+	})
+	if err != nil && errors.Cause(err) != errOpReturnsLimit {
+		return nil, false, err
+	}
+	return r, truncated, nil
+}
+
+// opReturnPayload returns the concatenated data pushed after the OP_RETURN opcode,
+// the bytes after the opcode unchanged if they are not a valid sequence of pushes
+func opReturnPayload(script []byte) []byte {
+	pushes, err := txscript.PushedData(script[1:])
+	if err != nil {
+		return script[1:]
+	}
+	var data []byte
+	for _, p := range pushes {
+		data = append(data, p...)
+	}
+	return data
+}
+
+func printableText(data []byte) string {
+	t := make([]byte, len(data))
+	for i, c := range data {
+		if c >= 32 && c < 127 {
+			t[i] = c
+		} else {
+			t[i] = '.'
+		}
+	}
+	return string(t)
+}
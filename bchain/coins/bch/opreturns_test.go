@@ -0,0 +1,77 @@
+// +build unittest
+
+package bch
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
+)
+
+// testOpReturnsBlock returns raw block with testTx1 and a transaction with several OP_RETURN outputs between other outputs
+func testOpReturnsBlock(t *testing.T) ([]byte, string) {
+	var buf bytes.Buffer
+	header := wire.BlockHeader{Version: 1, Timestamp: time.Unix(1550000000, 0), Bits: 0x18044a6e, Nonce: 1876521596}
+	if err := header.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := wire.WriteVarInt(&buf, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(hexToBytes(t, testTx1.Hex))
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), []byte{0}, nil))
+	// text
+	tx.AddTxOut(wire.NewTxOut(0, append([]byte{0x6a, 0x05}, "hello"...)))
+	tx.AddTxOut(wire.NewTxOut(1000, hexToBytes(t, "76a914010d39800f86122416e28f485029acf77507169288ac")))
+	// two pushes with binary data
+	tx.AddTxOut(wire.NewTxOut(0, []byte{0x6a, 0x02, 0x6d, 0x02, 0x4c, 0x03, 0x00, 0x41, 0xff}))
+	// an invalid push, the bytes after OP_RETURN are returned
+	tx.AddTxOut(wire.NewTxOut(0, []byte{0x6a, 0x05, 0x41}))
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), tx.TxHash().String()
+}
+
+func Test_GetBlockOpReturns(t *testing.T) {
+	rawBlock, txid := testOpReturnsBlock(t)
+	calls := 0
+	b, closeServer := setupRPC(t, truncatedBlockHandler(t, [][]byte{rawBlock}, &calls))
+	defer closeServer()
+	all := []OpReturnData{
+		{Txid: txid, Vout: 0, Hex: "68656c6c6f", Text: "hello"},
+		{Txid: txid, Vout: 2, Hex: "6d020041ff", Text: "m..A."},
+		{Txid: txid, Vout: 3, Hex: "0541", Text: ".A"},
+	}
+	tests := []struct {
+		name          string
+		limit         int
+		want          []OpReturnData
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{name: "all", limit: 10, want: all},
+		{name: "exactly limit", limit: 3, want: all},
+		{name: "truncated", limit: 2, want: all[:2], wantTruncated: true},
+		{name: "invalid limit", limit: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated, err := b.GetBlockOpReturns(testBlockHash, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBlockOpReturns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) || truncated != tt.wantTruncated {
+				t.Errorf("GetBlockOpReturns() = %+v, %v, want %+v, %v", got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
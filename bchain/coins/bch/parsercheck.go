@@ -0,0 +1,155 @@
+package bch
+
+import (
+	"blockbook/bchain"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// ParserMismatch describes a difference between the transaction decoded by the Blockbook parser and by the backend
+type ParserMismatch struct {
+	Txid    string `json:"txid"`
+	Field   string `json:"field"`
+	Parsed  string `json:"parsed"`
+	Backend string `json:"backend"`
+}
+
+// decoderawtransaction
+
+type cmdDecodeRawTransaction struct {
+	Method string `json:"method"`
+	Params struct {
+		HexString string `json:"hexstring"`
+	} `json:"params"`
+}
+
+type resDecodeRawTransaction struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result json.RawMessage  `json:"result"`
+}
+
+// CheckParser decodes the transactions of the last blocks both by the Blockbook parser and by the decoderawtransaction
+// method of the backend and returns the discrepancies in the vin/vout counts, output values and addresses
+func (b *BCashRPC) CheckParser(blocks int) ([]ParserMismatch, error) {
+	best, err := b.GetBestBlockHeight()
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []ParserMismatch
+	for i := 0; i < blocks && uint32(i) <= best; i++ {
+		hash, err := b.GetBlockHash(best - uint32(i))
+		if err != nil {
+			return nil, err
+		}
+		err = b.GetBlockTxsStream(hash, func(*bchain.BlockHeader) error { return nil }, func(tx *bchain.Tx) error {
+			m, err := b.checkParsedTx(tx)
+			if err != nil {
+				return err
+			}
+			mismatches = append(mismatches, m...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mismatches, nil
+}
+
+// runParserCheck periodically checks the parser against the backend and logs the discrepancies until stop is closed
+func (b *BCashRPC) runParserCheck(interval time.Duration, blocks int, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			mismatches, err := b.CheckParser(blocks)
+			if err != nil {
+				glog.Error("CheckParser error ", err)
+				continue
+			}
+			for _, m := range mismatches {
+				glog.Errorf("Parser mismatch in tx %v: %v parsed %v, backend %v", m.Txid, m.Field, m.Parsed, m.Backend)
+			}
+			glog.Info("CheckParser: checked ", blocks, " blocks, ", len(mismatches), " mismatches")
+		}
+	}
+}
+
+func (b *BCashRPC) decodeRawTransaction(txHex string) (*bchain.Tx, error) {
+	glog.V(1).Info("rpc: decoderawtransaction")
+
+	res := resDecodeRawTransaction{}
+	req := cmdDecodeRawTransaction{Method: "decoderawtransaction"}
+	req.Params.HexString = txHex
+	if err := b.Call(&req, &res); err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return b.Parser.ParseTxFromJson(res.Result)
+}
+
+// checkParsedTx compares the parsed transaction with the transaction decoded by the backend
+func (b *BCashRPC) checkParsedTx(tx *bchain.Tx) ([]ParserMismatch, error) {
+	decoded, err := b.decodeRawTransaction(tx.Hex)
+	if err != nil {
+		return nil, errors.Annotatef(err, "txid %v", tx.Txid)
+	}
+	var mismatches []ParserMismatch
+	add := func(field, parsed, backend string) {
+		if parsed != backend {
+			mismatches = append(mismatches, ParserMismatch{Txid: tx.Txid, Field: field, Parsed: parsed, Backend: backend})
+		}
+	}
+	add("txid", tx.Txid, decoded.Txid)
+	add("vin count", strconv.Itoa(len(tx.Vin)), strconv.Itoa(len(decoded.Vin)))
+	add("vout count", strconv.Itoa(len(tx.Vout)), strconv.Itoa(len(decoded.Vout)))
+	for i := range tx.Vout {
+		if i >= len(decoded.Vout) {
+			break
+		}
+		field := "vout " + strconv.Itoa(i)
+		add(field+" value", tx.Vout[i].ValueSat.String(), decoded.Vout[i].ValueSat.String())
+		add(field+" addresses", strings.Join(b.searchableAddresses(&tx.Vout[i]), ","), strings.Join(b.normalizeAddresses(decoded.Vout[i].ScriptPubKey.Addresses), ","))
+	}
+	return mismatches, nil
+}
+
+// searchableAddresses returns the addresses of the output, the descriptions of the other outputs (OP_RETURN etc.) are not returned by the backend
+func (b *BCashRPC) searchableAddresses(vout *bchain.Vout) []string {
+	addrDesc, err := b.Parser.GetAddrDescFromVout(vout)
+	if err != nil {
+		return nil
+	}
+	addresses, searchable, err := b.Parser.GetAddressesFromAddrDesc(addrDesc)
+	if err != nil || !searchable {
+		return nil
+	}
+	return addresses
+}
+
+// normalizeAddresses converts the addresses returned by the backend to the form returned by the parser
+func (b *BCashRPC) normalizeAddresses(addresses []string) []string {
+	r := make([]string, len(addresses))
+	for i, a := range addresses {
+		r[i] = a
+		addrDesc, err := b.Parser.GetAddrDescFromAddress(a)
+		if err != nil {
+			continue
+		}
+		n, _, err := b.Parser.GetAddressesFromAddrDesc(addrDesc)
+		if err == nil && len(n) == 1 {
+			r[i] = n[0]
+		}
+	}
+	return r
+}
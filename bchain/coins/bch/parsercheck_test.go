@@ -0,0 +1,123 @@
+// +build unittest
+
+package bch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/martinboehm/btcd/wire"
+)
+
+// decoded testTx1 as returned by decoderawtransaction of the backend
+const testTx1Decoded = `{"txid":"056e3d82e5ffd0e915fb9b62797d76263508c34fe3e5dbed30dd3e943930f204","hash":"056e3d82e5ffd0e915fb9b62797d76263508c34fe3e5dbed30dd3e943930f204","version":1,"size":191,"locktime":512115,"vin":[{"txid":"425fed43ba74e9205875eb934d5bcf7bf338f146f70d4002d94bf5cbc9229a7f","vout":4,"scriptSig":{"hex":"4730440220037f4ed5427cde81d55b9b6a2fd08c8a25090c2c2fff3a75c1a57625ca8a7118022076c702fe55969fa08137f71afd4851c48e31082dd3c40c919c92cdbc826758d30121029f6da5623c9f9b68a9baf9c1bc7511df88fa34c6c2f71f7c62f2f03ff48dca80"},"sequence":4294967294}],"vout":[{"value":0.00038812,"n":0,"scriptPubKey":{"hex":"a9146144d57c8aff48492c9dfb914e120b20bad72d6f87","type":"scripthash","addresses":["bitcoincash:pps5f4tu3tl5sjfvnhaeznsjpvst44eddugfcnqpy9"]}}]}`
+
+func parserCheckHandler(t *testing.T, rawBlock []byte, decoded string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		var result interface{}
+		switch req.Method {
+		case "getblockcount":
+			result = 0
+		case "getblockhash":
+			result = testBlockHash
+		case "getblockheader":
+			result = map[string]interface{}{"hash": testBlockHash, "height": 0, "time": 1550000000}
+		case "getblock":
+			result = hex.EncodeToString(rawBlock)
+		case "decoderawtransaction":
+			result = json.RawMessage(decoded)
+		default:
+			t.Fatalf("unexpected method %v", req.Method)
+		}
+		res, _ := json.Marshal(map[string]interface{}{"result": result, "error": nil, "id": "1"})
+		w.Write(res)
+	}
+}
+
+func testParserCheckBlock(t *testing.T) []byte {
+	var buf bytes.Buffer
+	header := wire.BlockHeader{Version: 1, Timestamp: time.Unix(1550000000, 0), Bits: 0x18044a6e, Nonce: 1876521596}
+	if err := header.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := wire.WriteVarInt(&buf, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(hexToBytes(t, testTx1.Hex))
+	return buf.Bytes()
+}
+
+func Test_CheckParser(t *testing.T) {
+	rawBlock := testParserCheckBlock(t)
+	b, closeServer := setupRPC(t, parserCheckHandler(t, rawBlock, testTx1Decoded))
+	got, err := b.CheckParser(3)
+	closeServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("CheckParser() = %+v, want no mismatches", got)
+	}
+
+	// the backend decodes different value than the parser
+	different := bytes.Replace([]byte(testTx1Decoded), []byte(`"value":0.00038812`), []byte(`"value":0.00038813`), 1)
+	b, closeServer = setupRPC(t, parserCheckHandler(t, rawBlock, string(different)))
+	got, err = b.CheckParser(1)
+	closeServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ParserMismatch{{Txid: testTx1.Txid, Field: "vout 0 value", Parsed: "38812", Backend: "38813"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckParser() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_checkParsedTx_BrokenParse(t *testing.T) {
+	b, closeServer := setupRPC(t, parserCheckHandler(t, nil, testTx1Decoded))
+	defer closeServer()
+	tx, err := b.Parser.ParseTx(hexToBytes(t, testTx1.Hex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.checkParsedTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("checkParsedTx() = %+v, want no mismatches", got)
+	}
+	// simulate a broken parser returning wrong value and script of the output and losing an input
+	tx.Vout[0].ValueSat = *big.NewInt(3881200)
+	tx.Vout[0].ScriptPubKey.Hex = "76a914010d39800f86122416e28f485029acf77507169288ac"
+	tx.Vin = nil
+	got, err = b.checkParsedTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ParserMismatch{
+		{Txid: testTx1.Txid, Field: "vin count", Parsed: "0", Backend: "1"},
+		{Txid: testTx1.Txid, Field: "vout 0 value", Parsed: "3881200", Backend: "38812"},
+		{Txid: testTx1.Txid, Field: "vout 0 addresses", Parsed: "bitcoincash:qqqs6wvqp7rpyfqku285s5pf4nmh2pckjgsgwcgfht", Backend: "bitcoincash:pps5f4tu3tl5sjfvnhaeznsjpvst44eddugfcnqpy9"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkParsedTx() = %+v, want %+v", got, want)
+	}
+}
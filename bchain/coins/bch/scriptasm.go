@@ -0,0 +1,82 @@
+package bch
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcutil/txscript"
+)
+
+// opcodeNames maps the opcodes to their names, the opcodes redefined or added by the Bitcoin Cash upgrades
+// have the Bitcoin Cash names
+var opcodeNames = func() map[byte]string {
+	names := make(map[byte]string, len(txscript.OpcodeByName))
+	for n, op := range txscript.OpcodeByName {
+		// skip the aliases and the data pushes, which are formatted separately
+		if n == "OP_FALSE" || n == "OP_TRUE" || n == "OP_NOP2" || n == "OP_NOP3" || strings.HasPrefix(n, "OP_DATA_") {
+			continue
+		}
+		names[op] = n
+	}
+	names[0x7f] = "OP_SPLIT"
+	names[0x80] = "OP_NUM2BIN"
+	names[0x81] = "OP_BIN2NUM"
+	names[0xba] = "OP_CHECKDATASIG"
+	names[0xbb] = "OP_CHECKDATASIGVERIFY"
+	return names
+}()
+
+// DisassembleScript returns the opcodes of the script separated by spaces, the pushed data are in hex,
+// the small integers are formatted as numbers, in the same way as the asm of the backend
+// the script with truncated push is disassembled up to the error, which is marked by [error]
+func (p *BCashParser) DisassembleScript(script []byte) (string, error) {
+	var asm []string
+	for i := 0; i < len(script); {
+		pos, op := i, script[i]
+		i++
+		var l int
+		switch {
+		case op == txscript.OP_0:
+			asm = append(asm, "0")
+			continue
+		case op == txscript.OP_1NEGATE:
+			asm = append(asm, "-1")
+			continue
+		case op >= txscript.OP_1 && op <= txscript.OP_16:
+			asm = append(asm, strconv.Itoa(int(op-txscript.OP_1+1)))
+			continue
+		case op < txscript.OP_PUSHDATA1:
+			l = int(op)
+		case op <= txscript.OP_PUSHDATA4:
+			s := 1 << (op - txscript.OP_PUSHDATA1)
+			if i+s > len(script) {
+				return strings.Join(append(asm, "[error]"), " "), errors.Errorf("Truncated push at position %d", pos)
+			}
+			switch s {
+			case 1:
+				l = int(script[i])
+			case 2:
+				l = int(binary.LittleEndian.Uint16(script[i:]))
+			default:
+				l = int(binary.LittleEndian.Uint32(script[i:]))
+			}
+			i += s
+		default:
+			n, found := opcodeNames[op]
+			if !found {
+				n = "OP_UNKNOWN" + strconv.Itoa(int(op))
+			}
+			asm = append(asm, n)
+			continue
+		}
+		if l < 0 || l > len(script)-i {
+			return strings.Join(append(asm, "[error]"), " "), errors.Errorf("Truncated push at position %d", pos)
+		}
+		asm = append(asm, hex.EncodeToString(script[i:i+l]))
+		i += l
+	}
+	return strings.Join(asm, " "), nil
+}
@@ -0,0 +1,91 @@
+// +build unittest
+
+package bch
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestBCashParser_DisassembleScript(t *testing.T) {
+	parser, _, _, _ := setupParsers(t)
+	tests := []struct {
+		name    string
+		script  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "P2PKH",
+			script: "76a914010d39800f86122416e28f485029acf77507169288ac",
+			want:   "OP_DUP OP_HASH160 010d39800f86122416e28f485029acf775071692 OP_EQUALVERIFY OP_CHECKSIG",
+		},
+		{
+			name:   "P2SH",
+			script: "a91452724c5178682f70e0ba31c6ec0633755a3b41d987",
+			want:   "OP_HASH160 52724c5178682f70e0ba31c6ec0633755a3b41d9 OP_EQUAL",
+		},
+		{
+			name:   "OP_RETURN",
+			script: "6a072020f1686f6a20",
+			want:   "OP_RETURN 2020f1686f6a20",
+		},
+		{
+			name:   "OP_RETURN with OP_PUSHDATA1",
+			script: "6a4c050102030405",
+			want:   "OP_RETURN 0102030405",
+		},
+		{
+			name:   "multisig with small integers",
+			script: "5121020102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2051ae",
+			want:   "1 020102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20 1 OP_CHECKMULTISIG",
+		},
+		{
+			name:   "empty push and negative one",
+			script: "004f",
+			want:   "0 -1",
+		},
+		{
+			name:   "Bitcoin Cash opcodes",
+			script: "7e7f8081babb",
+			want:   "OP_CAT OP_SPLIT OP_NUM2BIN OP_BIN2NUM OP_CHECKDATASIG OP_CHECKDATASIGVERIFY",
+		},
+		{
+			name:   "unknown opcode",
+			script: "6ac0",
+			want:   "OP_RETURN OP_UNKNOWN192",
+		},
+		{
+			name:    "truncated push",
+			script:  "6a0501020304",
+			want:    "OP_RETURN [error]",
+			wantErr: true,
+		},
+		{
+			name:    "truncated OP_PUSHDATA2 length",
+			script:  "6a4d01",
+			want:    "OP_RETURN [error]",
+			wantErr: true,
+		},
+		{
+			name:   "empty",
+			script: "",
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := hex.DecodeString(tt.script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parser.DisassembleScript(script)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DisassembleScript() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DisassembleScript() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package bch
+
+import (
+	"blockbook/bchain"
+	"container/heap"
+	"math/big"
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+// TopTxsSortKey is the key by which GetBlockTopTxs selects the transactions
+type TopTxsSortKey int
+
+const (
+	// TopTxsByValue selects the transactions by the total value of their outputs
+	TopTxsByValue TopTxsSortKey = iota
+	// TopTxsBySize selects the transactions by their size in bytes
+	TopTxsBySize
+)
+
+// TopTx is a transaction returned by GetBlockTopTxs
+type TopTx struct {
+	Txid     string
+	ValueSat big.Int
+	Size     int
+	// index of the transaction in the block, the coinbase transaction has index 0,
+	// the transactions skipped by the tolerant block parsing are not counted
+	Index int
+}
+
+// topTxsHeap keeps the selected transactions with the smallest one on top so that it can be replaced by a bigger one
+type topTxsHeap struct {
+	txs []TopTx
+	key TopTxsSortKey
+}
+
+// less returns true if the transaction i is sorted after the transaction j,
+// the transactions with the same key are sorted in the order of the block
+func (h *topTxsHeap) less(i, j *TopTx) bool {
+	var c int
+	if h.key == TopTxsBySize {
+		c = i.Size - j.Size
+	} else {
+		c = i.ValueSat.Cmp(&j.ValueSat)
+	}
+	if c != 0 {
+		return c < 0
+	}
+	return i.Index > j.Index
+}
+
+func (h *topTxsHeap) Len() int           { return len(h.txs) }
+func (h *topTxsHeap) Less(i, j int) bool { return h.less(&h.txs[i], &h.txs[j]) }
+func (h *topTxsHeap) Swap(i, j int)      { h.txs[i], h.txs[j] = h.txs[j], h.txs[i] }
+func (h *topTxsHeap) Push(x interface{}) { h.txs = append(h.txs, x.(TopTx)) }
+func (h *topTxsHeap) Pop() (x interface{}) {
+	x, h.txs = h.txs[len(h.txs)-1], h.txs[:len(h.txs)-1]
+	return
+}
+
+// GetBlockTopTxs returns at most n transactions of the block with given hash with the biggest total value of the outputs
+// or size, sorted from the biggest, the block is decoded only once and only the n selected transactions are kept in memory
+func (b *BCashRPC) GetBlockTopTxs(hash string, n int, key TopTxsSortKey) ([]TopTx, error) {
+	if n <= 0 {
+		return nil, errors.Errorf("Invalid number of transactions %d", n)
+	}
+	if key != TopTxsByValue && key != TopTxsBySize {
+		return nil, errors.Errorf("Invalid sort key %d", key)
+	}
+	h := &topTxsHeap{key: key}
+	index := 0
+	err := b.GetBlockTxsStream(hash, func(*bchain.BlockHeader) error { return nil }, func(tx *bchain.Tx) error {
+		t := TopTx{Txid: tx.Txid, Size: len(tx.Hex) / 2, Index: index}
+		index++
+		for i := range tx.Vout {
+			t.ValueSat.Add(&t.ValueSat, &tx.Vout[i].ValueSat)
+		}
+		if h.Len() < n {
+			heap.Push(h, t)
+		} else if h.less(&h.txs[0], &t) {
+			h.txs[0] = t
+			heap.Fix(h, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(h.txs, func(i, j int) bool { return h.less(&h.txs[j], &h.txs[i]) })
+	return h.txs, nil
+}
@@ -0,0 +1,77 @@
+// +build unittest
+
+package bch
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
+)
+
+// testTopTxsBlock returns raw block with testTx1, testTx2 and a transaction with the smallest value and the biggest size
+func testTopTxsBlock(t *testing.T) ([]byte, string) {
+	var buf bytes.Buffer
+	header := wire.BlockHeader{Version: 1, Timestamp: time.Unix(1550000000, 0), Bits: 0x18044a6e, Nonce: 1876521596}
+	if err := header.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := wire.WriteVarInt(&buf, 0, 3); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(hexToBytes(t, testTx1.Hex))
+	buf.Write(hexToBytes(t, testTx2.Hex))
+	bigTx := wire.NewMsgTx(1)
+	bigTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), []byte{0}, nil))
+	bigTx.AddTxOut(wire.NewTxOut(1, append([]byte{0x6a, 0x4d, 0xf4, 0x01}, make([]byte, 500)...)))
+	if err := bigTx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), bigTx.TxHash().String()
+}
+
+func Test_GetBlockTopTxs(t *testing.T) {
+	rawBlock, bigTxid := testTopTxsBlock(t)
+	calls := 0
+	b, closeServer := setupRPC(t, truncatedBlockHandler(t, [][]byte{rawBlock}, &calls))
+	defer closeServer()
+	tests := []struct {
+		name    string
+		n       int
+		key     TopTxsSortKey
+		want    []string
+		wantErr bool
+	}{
+		{name: "value top 2", n: 2, key: TopTxsByValue, want: []string{testTx2.Txid, testTx1.Txid}},
+		{name: "value top 1", n: 1, key: TopTxsByValue, want: []string{testTx2.Txid}},
+		{name: "size top 2", n: 2, key: TopTxsBySize, want: []string{bigTxid, testTx2.Txid}},
+		{name: "size all", n: 5, key: TopTxsBySize, want: []string{bigTxid, testTx2.Txid, testTx1.Txid}},
+		{name: "invalid n", n: 0, key: TopTxsBySize, wantErr: true},
+		{name: "invalid key", n: 1, key: TopTxsSortKey(5), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.GetBlockTopTxs(testBlockHash, tt.n, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBlockTopTxs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetBlockTopTxs() = %+v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Txid != tt.want[i] {
+					t.Errorf("GetBlockTopTxs() %d = %v, want %v", i, got[i].Txid, tt.want[i])
+				}
+			}
+		})
+	}
+	got, err := b.GetBlockTopTxs(testBlockHash, 3, TopTxsByValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].ValueSat.Int64() != 930081157 || got[0].Size != len(testTx2.Hex)/2 || got[0].Index != 1 || got[2].ValueSat.Int64() != 1 {
+		t.Errorf("GetBlockTopTxs() = %+v", got)
+	}
+}
@@ -0,0 +1,113 @@
+package bch
+
+import (
+	"github.com/juju/errors"
+)
+
+// TxSizeScriptType is the type of the script of an input or output, used to estimate the size of a transaction
+type TxSizeScriptType int
+
+const (
+	// TxSizeP2PKH is pay to public key hash script
+	TxSizeP2PKH TxSizeScriptType = iota
+	// TxSizeP2SH is pay to script hash script, for inputs the redeem script is expected to be m-of-n multisig
+	TxSizeP2SH
+)
+
+// TxSizeInput describes an input of the transaction for EstimateTxSize
+// RequiredSigs and PubKeys specify the m-of-n multisig redeem script of P2SH inputs and are ignored for P2PKH inputs
+type TxSizeInput struct {
+	Type         TxSizeScriptType
+	RequiredSigs int
+	PubKeys      int
+}
+
+// sizes of the parts of the serialized transaction, in bytes
+const (
+	txSizeVersionLockTime = 4 + 4
+	txSizeOutpoint        = 32 + 4
+	txSizeSequence        = 4
+	txSizeValue           = 8
+	// DER encoded signature of the maximal length including the sighash type byte
+	txSizeSignature = 72
+	// compressed public key
+	txSizePubKey       = 33
+	txSizeP2PKHScript  = 25
+	txSizeP2SHScript   = 23
+	maxMultisigPubKeys = 16
+)
+
+func varIntSize(n int) int {
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	case n <= 0xffffffff:
+		return 5
+	}
+	return 9
+}
+
+// pushDataSize returns the size of the opcode pushing data of length n to the stack
+func pushDataSize(n int) int {
+	switch {
+	case n < 0x4c:
+		return 1
+	case n <= 0xff:
+		return 2
+	case n <= 0xffff:
+		return 3
+	}
+	return 5
+}
+
+func inputScriptSize(in *TxSizeInput) (int, error) {
+	switch in.Type {
+	case TxSizeP2PKH:
+		return pushDataSize(txSizeSignature) + txSizeSignature + pushDataSize(txSizePubKey) + txSizePubKey, nil
+	case TxSizeP2SH:
+		if in.PubKeys < 1 || in.PubKeys > maxMultisigPubKeys || in.RequiredSigs < 1 || in.RequiredSigs > in.PubKeys {
+			return 0, errors.Errorf("Invalid multisig %d-of-%d", in.RequiredSigs, in.PubKeys)
+		}
+		// OP_m <pubkeys> OP_n OP_CHECKMULTISIG
+		redeemScript := 1 + in.PubKeys*(pushDataSize(txSizePubKey)+txSizePubKey) + 1 + 1
+		// OP_0 <signatures> <redeem script>, OP_0 is required because of the CHECKMULTISIG off by one bug
+		return 1 + in.RequiredSigs*(pushDataSize(txSizeSignature)+txSizeSignature) + pushDataSize(redeemScript) + redeemScript, nil
+	}
+	return 0, errors.Errorf("Unknown input type %d", in.Type)
+}
+
+func outputScriptSize(t TxSizeScriptType) (int, error) {
+	switch t {
+	case TxSizeP2PKH:
+		return txSizeP2PKHScript, nil
+	case TxSizeP2SH:
+		return txSizeP2SHScript, nil
+	}
+	return 0, errors.Errorf("Unknown output type %d", t)
+}
+
+// EstimateTxSize returns the expected size in bytes of the serialized signed transaction with given inputs and outputs
+// the signatures are assumed to have the maximal length, therefore the real size can be a few bytes smaller
+func EstimateTxSize(inputs []TxSizeInput, outputs []TxSizeScriptType) (int, error) {
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return 0, errors.New("Transaction must have at least one input and one output")
+	}
+	size := txSizeVersionLockTime + varIntSize(len(inputs)) + varIntSize(len(outputs))
+	for i := range inputs {
+		s, err := inputScriptSize(&inputs[i])
+		if err != nil {
+			return 0, errors.Annotatef(err, "input %d", i)
+		}
+		size += txSizeOutpoint + varIntSize(s) + s + txSizeSequence
+	}
+	for i, t := range outputs {
+		s, err := outputScriptSize(t)
+		if err != nil {
+			return 0, errors.Annotatef(err, "output %d", i)
+		}
+		size += txSizeValue + varIntSize(s) + s
+	}
+	return size, nil
+}
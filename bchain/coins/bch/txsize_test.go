@@ -0,0 +1,94 @@
+// +build unittest
+
+package bch
+
+import (
+	"testing"
+)
+
+func TestEstimateTxSize(t *testing.T) {
+	p2pkh := TxSizeInput{Type: TxSizeP2PKH}
+	p2sh2of3 := TxSizeInput{Type: TxSizeP2SH, RequiredSigs: 2, PubKeys: 3}
+	manyInputs := make([]TxSizeInput, 253)
+	for i := range manyInputs {
+		manyInputs[i] = p2pkh
+	}
+	tests := []struct {
+		name    string
+		inputs  []TxSizeInput
+		outputs []TxSizeScriptType
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "P2PKH to P2PKH",
+			inputs:  []TxSizeInput{p2pkh},
+			outputs: []TxSizeScriptType{TxSizeP2PKH},
+			want:    192,
+		},
+		{
+			name:    "P2PKH to P2PKH and change",
+			inputs:  []TxSizeInput{p2pkh},
+			outputs: []TxSizeScriptType{TxSizeP2PKH, TxSizeP2PKH},
+			want:    226,
+		},
+		{
+			name:    "2 P2PKH to P2SH",
+			inputs:  []TxSizeInput{p2pkh, p2pkh},
+			outputs: []TxSizeScriptType{TxSizeP2SH},
+			want:    338,
+		},
+		{
+			name:    "P2SH 2-of-3 to 2 P2PKH",
+			inputs:  []TxSizeInput{p2sh2of3},
+			outputs: []TxSizeScriptType{TxSizeP2PKH, TxSizeP2PKH},
+			want:    375,
+		},
+		{
+			name:    "P2SH 1-of-1 to P2SH",
+			inputs:  []TxSizeInput{{Type: TxSizeP2SH, RequiredSigs: 1, PubKeys: 1}},
+			outputs: []TxSizeScriptType{TxSizeP2SH},
+			want:    195,
+		},
+		{
+			name:    "253 P2PKH inputs, 3 byte input count",
+			inputs:  manyInputs,
+			outputs: []TxSizeScriptType{TxSizeP2PKH},
+			want:    37490,
+		},
+		{
+			name:    "no inputs",
+			outputs: []TxSizeScriptType{TxSizeP2PKH},
+			wantErr: true,
+		},
+		{
+			name:    "no outputs",
+			inputs:  []TxSizeInput{p2pkh},
+			wantErr: true,
+		},
+		{
+			name:    "invalid multisig",
+			inputs:  []TxSizeInput{{Type: TxSizeP2SH, RequiredSigs: 3, PubKeys: 2}},
+			outputs: []TxSizeScriptType{TxSizeP2PKH},
+			wantErr: true,
+		},
+		{
+			name:    "unknown output type",
+			inputs:  []TxSizeInput{p2pkh},
+			outputs: []TxSizeScriptType{TxSizeScriptType(99)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EstimateTxSize(tt.inputs, tt.outputs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EstimateTxSize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EstimateTxSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
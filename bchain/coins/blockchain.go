@@ -156,8 +156,8 @@ func (c *blockChainWithMetrics) CreateMempool(chain bchain.BlockChain) (bchain.M
 	return c.b.CreateMempool(chain)
 }
 
-func (c *blockChainWithMetrics) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc) error {
-	return c.b.InitializeMempool(addrDescForOutpoint, onNewTxAddr)
+func (c *blockChainWithMetrics) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, isWatchedAddrDesc bchain.IsWatchedAddrDescFunc) error {
+	return c.b.InitializeMempool(addrDescForOutpoint, onNewTxAddr, isWatchedAddrDesc)
 }
 
 func (c *blockChainWithMetrics) Shutdown(ctx context.Context) error {
@@ -255,6 +255,21 @@ func (c *blockChainWithMetrics) GetMempoolEntry(txid string) (v *bchain.MempoolE
 	return c.b.GetMempoolEntry(txid)
 }
 
+func (c *blockChainWithMetrics) GetMempoolEntries() (v map[string]*bchain.MempoolEntry, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetMempoolEntries", s, err) }(time.Now())
+	return c.b.GetMempoolEntries()
+}
+
+func (c *blockChainWithMetrics) GetMedianTimePast(hash string) (v int64, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetMedianTimePast", s, err) }(time.Now())
+	return c.b.GetMedianTimePast(hash)
+}
+
+func (c *blockChainWithMetrics) GetChainStats() (v *bchain.ChainStats, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetChainStats", s, err) }(time.Now())
+	return c.b.GetChainStats()
+}
+
 func (c *blockChainWithMetrics) GetChainParser() bchain.BlockChainParser {
 	return c.b.GetChainParser()
 }
@@ -324,3 +339,15 @@ func (c *mempoolWithMetrics) GetAllEntries() (v bchain.MempoolTxidEntries) {
 func (c *mempoolWithMetrics) GetTransactionTime(txid string) uint32 {
 	return c.mempool.GetTransactionTime(txid)
 }
+
+func (c *mempoolWithMetrics) OnConnectedBlock(block *bchain.Block) {
+	c.mempool.OnConnectedBlock(block)
+}
+
+func (c *mempoolWithMetrics) GetReplacedBy(txid string) string {
+	return c.mempool.GetReplacedBy(txid)
+}
+
+func (c *mempoolWithMetrics) GetSpendingTx(outpoint bchain.Outpoint) (string, int) {
+	return c.mempool.GetSpendingTx(outpoint)
+}
@@ -8,10 +8,16 @@ import (
 	"encoding/json"
 	"io"
 	"io/ioutil"
+	"math"
 	"math/big"
 	"net"
 	"net/http"
+	"reflect"
 	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/glog"
@@ -32,8 +38,26 @@ type BitcoinRPC struct {
 	mq           *bchain.MQ
 	ChainConfig  *Configuration
 	RPCMarshaler RPCMarshaler
+	// allowedMethods and deniedMethods restrict the RPC methods called by Call, nil means no restriction
+	allowedMethods map[string]struct{}
+	deniedMethods  map[string]struct{}
+	// MaxResponseBytes is the maximum size of the body of the response read by Call
+	MaxResponseBytes int64
+	// PoolResponseBuffers makes Call read the responses to buffers reused by the following calls
+	PoolResponseBuffers bool
+	// MaxEstimatedFee is the parsed max_estimated_fee of the configuration in satoshis per kB, nil means no limit
+	MaxEstimatedFee *big.Int
+	// uptimeNotSupported is set to 1 when the backend reports the uptime RPC method as not found, then GetUptime does not call it
+	uptimeNotSupported int32
 }
 
+// DefaultMaxResponseBytes is the default limit of the size of the RPC response,
+// large enough for a verbose getblock of the biggest blocks
+const DefaultMaxResponseBytes = 512 * 1024 * 1024
+
+// DefaultRPCMaxIdleConns is the default maximum number of idle keep-alive connections to the backend
+const DefaultRPCMaxIdleConns = 100
+
 // Configuration represents json config file
 type Configuration struct {
 	CoinName                 string `json:"coin_name"`
@@ -48,6 +72,8 @@ type Configuration struct {
 	BlockAddressesToKeep     int    `json:"block_addresses_to_keep"`
 	MempoolWorkers           int    `json:"mempool_workers"`
 	MempoolSubWorkers        int    `json:"mempool_sub_workers"`
+	MempoolMaxTxs            int    `json:"mempool_max_txs,omitempty"`
+	MempoolMaxAgeHours       int    `json:"mempool_max_age_hours,omitempty"`
 	AddressFormat            string `json:"address_format"`
 	SupportsEstimateFee      bool   `json:"supports_estimate_fee"`
 	SupportsEstimateSmartFee bool   `json:"supports_estimate_smart_fee"`
@@ -55,6 +81,37 @@ type Configuration struct {
 	XPubMagicSegwitP2sh      uint32 `json:"xpub_magic_segwit_p2sh,omitempty"`
 	XPubMagicSegwitNative    uint32 `json:"xpub_magic_segwit_native,omitempty"`
 	Slip44                   uint32 `json:"slip44,omitempty"`
+	// RPCAllowedMethods, if not empty, lists the only RPC methods which can be called
+	RPCAllowedMethods []string `json:"rpc_allowed_methods,omitempty"`
+	// RPCDeniedMethods lists RPC methods which cannot be called
+	RPCDeniedMethods []string `json:"rpc_denied_methods,omitempty"`
+	// RPCMaxResponseBytes limits the size of the RPC response, DefaultMaxResponseBytes if not set
+	RPCMaxResponseBytes int64 `json:"rpc_max_response_bytes,omitempty"`
+	// RPCPoolResponseBuffers enables the reuse of the buffers to which the RPC responses are read
+	RPCPoolResponseBuffers bool `json:"rpc_pool_response_buffers,omitempty"`
+	// TolerantBlockParsing skips the transactions which cannot be parsed instead of failing the whole block
+	TolerantBlockParsing bool `json:"tolerant_block_parsing,omitempty"`
+	// AddressCacheSize is the number of the output scripts whose addresses are cached by the parser across blocks,
+	// the cache is disabled if not set
+	AddressCacheSize int `json:"address_cache_size,omitempty"`
+	// BlockSubsidy is the subsidy in satoshis of the blocks before the first reduction in the emission schedule of the coin,
+	// the subsidy is halved every SubsidyReductionInterval blocks, the interval of the chain params if not set;
+	// the block subsidy is not computed if BlockSubsidy is not set
+	BlockSubsidy             int64  `json:"block_subsidy,omitempty"`
+	SubsidyReductionInterval uint32 `json:"subsidy_reduction_interval,omitempty"`
+	// RPCMaxIdleConns is the maximum number of idle keep-alive connections to the backend, DefaultRPCMaxIdleConns if not set
+	RPCMaxIdleConns int `json:"rpc_max_idle_conns,omitempty"`
+	// RPCMaxIdleConnsPerHost is the maximum number of idle keep-alive connections per host, RPCMaxIdleConns if not set
+	RPCMaxIdleConnsPerHost int `json:"rpc_max_idle_conns_per_host,omitempty"`
+	// RPCIdleConnTimeout is the time in seconds after which an idle connection is closed, idle connections are not closed if not set
+	RPCIdleConnTimeout int `json:"rpc_idle_conn_timeout,omitempty"`
+	// MaxEstimatedFee is the upper limit of the fee rate per kB in coins returned by the fee estimation, higher values
+	// returned by a misbehaving backend are replaced by the limit, the fee rate is not limited if not set
+	MaxEstimatedFee json.Number `json:"max_estimated_fee,omitempty"`
+	// MessageQueueReconnectInterval and MessageQueueReconnectMaxInterval are the initial and the maximum delay in milliseconds
+	// of the reconnection of the ZeroMQ subscription, bchain.DefaultMQReconnectInterval and bchain.DefaultMQReconnectMaxInterval if not set
+	MessageQueueReconnectInterval    int `json:"message_queue_reconnect_interval,omitempty"`
+	MessageQueueReconnectMaxInterval int `json:"message_queue_reconnect_max_interval,omitempty"`
 }
 
 // NewBitcoinRPC returns new BitcoinRPC instance.
@@ -76,14 +133,26 @@ func NewBitcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationT
 	if c.MempoolSubWorkers < 1 {
 		c.MempoolSubWorkers = 1
 	}
+	if c.RPCMaxResponseBytes <= 0 {
+		c.RPCMaxResponseBytes = DefaultMaxResponseBytes
+	}
 	// btc supports both calls, other coins overriding BitcoinRPC can change this
 	c.SupportsEstimateFee = true
 	c.SupportsEstimateSmartFee = true
 
+	if c.RPCMaxIdleConns <= 0 {
+		c.RPCMaxIdleConns = DefaultRPCMaxIdleConns
+	}
+	// the default of http.Transport is only 2 idle connections per host, too few for the parallel requests to the backend
+	if c.RPCMaxIdleConnsPerHost <= 0 {
+		c.RPCMaxIdleConnsPerHost = c.RPCMaxIdleConns
+	}
+
 	transport := &http.Transport{
 		Dial:                (&net.Dialer{KeepAlive: 600 * time.Second}).Dial,
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 100, // necessary to not to deplete ports
+		MaxIdleConns:        c.RPCMaxIdleConns,
+		MaxIdleConnsPerHost: c.RPCMaxIdleConnsPerHost, // necessary to not to deplete ports
+		IdleConnTimeout:     time.Duration(c.RPCIdleConnTimeout) * time.Second,
 	}
 
 	s := &BitcoinRPC{
@@ -97,10 +166,33 @@ func NewBitcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationT
 		pushHandler:  pushHandler,
 		RPCMarshaler: JSONMarshalerV2{},
 	}
+	s.MaxResponseBytes = c.RPCMaxResponseBytes
+	s.PoolResponseBuffers = c.RPCPoolResponseBuffers
+	s.allowedMethods = methodSet(c.RPCAllowedMethods)
+	s.deniedMethods = methodSet(c.RPCDeniedMethods)
+	if c.MaxEstimatedFee != "" {
+		// the parser is created later by Initialize, the amounts of the Bitcoin type coins have always 8 decimal places
+		limit, err := (&bchain.BaseParser{AmountDecimalPoint: 8}).AmountToBigInt(c.MaxEstimatedFee)
+		if err != nil || limit.Sign() <= 0 {
+			return nil, errors.Errorf("Invalid max_estimated_fee %v", c.MaxEstimatedFee)
+		}
+		s.MaxEstimatedFee = &limit
+	}
 
 	return s, nil
 }
 
+func methodSet(methods []string) map[string]struct{} {
+	if len(methods) == 0 {
+		return nil
+	}
+	m := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		m[strings.ToLower(method)] = struct{}{}
+	}
+	return m
+}
+
 // Initialize initializes BitcoinRPC instance.
 func (b *BitcoinRPC) Initialize() error {
 	b.ChainConfig.SupportsEstimateFee = false
@@ -134,19 +226,31 @@ func (b *BitcoinRPC) Initialize() error {
 func (b *BitcoinRPC) CreateMempool(chain bchain.BlockChain) (bchain.Mempool, error) {
 	if b.Mempool == nil {
 		b.Mempool = bchain.NewMempoolBitcoinType(chain, b.ChainConfig.MempoolWorkers, b.ChainConfig.MempoolSubWorkers)
+		b.Mempool.MaxTxs = b.ChainConfig.MempoolMaxTxs
+		b.Mempool.MaxAge = time.Duration(b.ChainConfig.MempoolMaxAgeHours) * time.Hour
 	}
 	return b.Mempool, nil
 }
 
 // InitializeMempool creates ZeroMQ subscription and sets AddrDescForOutpointFunc to the Mempool
-func (b *BitcoinRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc) error {
+// the subscription is optional, without message_queue_binding in the config the new blocks and transactions
+// are detected only by the periodic resync of the index and the mempool
+func (b *BitcoinRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, isWatchedAddrDesc bchain.IsWatchedAddrDescFunc) error {
 	if b.Mempool == nil {
 		return errors.New("Mempool not created")
 	}
 	b.Mempool.AddrDescForOutpoint = addrDescForOutpoint
 	b.Mempool.OnNewTxAddr = onNewTxAddr
+	b.Mempool.IsWatchedAddrDesc = isWatchedAddrDesc
+	if b.ChainConfig.MessageQueueBinding == "" {
+		glog.Info("mq: message_queue_binding not configured, using periodic resync")
+		return nil
+	}
 	if b.mq == nil {
-		mq, err := bchain.NewMQ(b.ChainConfig.MessageQueueBinding, b.pushHandler)
+		mq, err := bchain.NewMQWithReconnect(b.ChainConfig.MessageQueueBinding, b.pushHandler, bchain.MQReconnect{
+			Interval:    time.Duration(b.ChainConfig.MessageQueueReconnectInterval) * time.Millisecond,
+			MaxInterval: time.Duration(b.ChainConfig.MessageQueueReconnectMaxInterval) * time.Millisecond,
+		})
 		if err != nil {
 			glog.Error("mq: ", err)
 			return err
@@ -222,13 +326,16 @@ type CmdGetBlockChainInfo struct {
 type ResGetBlockChainInfo struct {
 	Error  *bchain.RPCError `json:"error"`
 	Result struct {
-		Chain         string      `json:"chain"`
-		Blocks        int         `json:"blocks"`
-		Headers       int         `json:"headers"`
-		Bestblockhash string      `json:"bestblockhash"`
-		Difficulty    json.Number `json:"difficulty"`
-		SizeOnDisk    int64       `json:"size_on_disk"`
-		Warnings      string      `json:"warnings"`
+		Chain                string      `json:"chain"`
+		Blocks               int         `json:"blocks"`
+		Headers              int         `json:"headers"`
+		Bestblockhash        string      `json:"bestblockhash"`
+		Difficulty           json.Number `json:"difficulty"`
+		Chainwork            string      `json:"chainwork"`
+		SizeOnDisk           int64       `json:"size_on_disk"`
+		Warnings             string      `json:"warnings"`
+		VerificationProgress float64     `json:"verificationprogress"`
+		InitialBlockDownload bool        `json:"initialblockdownload"`
 	} `json:"result"`
 }
 
@@ -245,6 +352,8 @@ type ResGetNetworkInfo struct {
 		Subversion      json.Number `json:"subversion"`
 		ProtocolVersion json.Number `json:"protocolversion"`
 		Timeoffset      float64     `json:"timeoffset"`
+		RelayFee        json.Number `json:"relayfee"`
+		Connections     int         `json:"connections"`
 		Warnings        string      `json:"warnings"`
 	} `json:"result"`
 }
@@ -386,6 +495,20 @@ type ResGetMempoolEntry struct {
 	Result *bchain.MempoolEntry `json:"result"`
 }
 
+// getrawmempool verbose
+
+type CmdGetMempoolVerbose struct {
+	Method string `json:"method"`
+	Params struct {
+		Verbose bool `json:"verbose"`
+	} `json:"params"`
+}
+
+type ResGetMempoolVerbose struct {
+	Error  *bchain.RPCError                `json:"error"`
+	Result map[string]*bchain.MempoolEntry `json:"result"`
+}
+
 // GetBestBlockHash returns hash of the tip of the best-block-chain.
 func (b *BitcoinRPC) GetBestBlockHash() (string, error) {
 
@@ -421,6 +544,45 @@ func (b *BitcoinRPC) GetBestBlockHeight() (uint32, error) {
 	return res.Result, nil
 }
 
+// ErrCodeMethodNotFound is the JSON-RPC error code returned by the backend for an unknown method
+const ErrCodeMethodNotFound = -32601
+
+// ErrUptimeNotSupported is returned by GetUptime if the backend does not support the uptime RPC method
+var ErrUptimeNotSupported = errors.New("uptime not supported by the backend")
+
+// CmdUptime is the uptime command
+type CmdUptime struct {
+	Method string `json:"method"`
+}
+
+// ResUptime is the response of the uptime command, the number of seconds for which the backend is running
+type ResUptime struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result int64            `json:"result"`
+}
+
+// GetUptime returns the number of seconds for which the backend is running, the support of the uptime method
+// is probed by the first call, ErrUptimeNotSupported is returned without contacting a backend which does not support it
+func (b *BitcoinRPC) GetUptime() (int64, error) {
+	if atomic.LoadInt32(&b.uptimeNotSupported) != 0 {
+		return 0, ErrUptimeNotSupported
+	}
+	glog.V(1).Info("rpc: uptime")
+	res := ResUptime{}
+	if err := b.Call(&CmdUptime{Method: "uptime"}, &res); err != nil {
+		return 0, err
+	}
+	if res.Error != nil {
+		if res.Error.Code == ErrCodeMethodNotFound {
+			glog.Info("rpc: uptime not supported by the backend")
+			atomic.StoreInt32(&b.uptimeNotSupported, 1)
+			return 0, ErrUptimeNotSupported
+		}
+		return 0, res.Error
+	}
+	return res.Result, nil
+}
+
 // GetChainInfo returns information about the connected backend
 func (b *BitcoinRPC) GetChainInfo() (*bchain.ChainInfo, error) {
 	glog.V(1).Info("rpc: getblockchaininfo")
@@ -445,14 +607,19 @@ func (b *BitcoinRPC) GetChainInfo() (*bchain.ChainInfo, error) {
 	}
 
 	rv := &bchain.ChainInfo{
-		Bestblockhash: resCi.Result.Bestblockhash,
-		Blocks:        resCi.Result.Blocks,
-		Chain:         resCi.Result.Chain,
-		Difficulty:    string(resCi.Result.Difficulty),
-		Headers:       resCi.Result.Headers,
-		SizeOnDisk:    resCi.Result.SizeOnDisk,
-		Subversion:    string(resNi.Result.Subversion),
-		Timeoffset:    resNi.Result.Timeoffset,
+		Bestblockhash:        resCi.Result.Bestblockhash,
+		Blocks:               resCi.Result.Blocks,
+		Chain:                resCi.Result.Chain,
+		Difficulty:           string(resCi.Result.Difficulty),
+		Chainwork:            resCi.Result.Chainwork,
+		Headers:              resCi.Result.Headers,
+		VerificationProgress: resCi.Result.VerificationProgress,
+		InitialBlockDownload: resCi.Result.InitialBlockDownload,
+		SizeOnDisk:           resCi.Result.SizeOnDisk,
+		Subversion:           string(resNi.Result.Subversion),
+		Timeoffset:           resNi.Result.Timeoffset,
+		RelayFee:             string(resNi.Result.RelayFee),
+		Connections:          resNi.Result.Connections,
 	}
 	rv.Version = string(resNi.Result.Version)
 	rv.ProtocolVersion = string(resNi.Result.ProtocolVersion)
@@ -462,6 +629,10 @@ func (b *BitcoinRPC) GetChainInfo() (*bchain.ChainInfo, error) {
 	if resCi.Result.Warnings != resNi.Result.Warnings {
 		rv.Warnings += resNi.Result.Warnings
 	}
+	if b.mq != nil {
+		s := b.mq.GetStatus()
+		rv.MessageQueue = &s
+	}
 	return rv, nil
 }
 
@@ -771,7 +942,7 @@ func (b *BitcoinRPC) EstimateSmartFee(blocks int, conservative bool) (big.Int, e
 	if err != nil {
 		return r, err
 	}
-	return r, nil
+	return b.LimitEstimatedFee(r, "estimatesmartfee"), nil
 }
 
 // EstimateFee returns fee estimation.
@@ -799,7 +970,19 @@ func (b *BitcoinRPC) EstimateFee(blocks int) (big.Int, error) {
 	if err != nil {
 		return r, err
 	}
-	return r, nil
+	return b.LimitEstimatedFee(r, "estimatefee"), nil
+}
+
+// LimitEstimatedFee returns the fee rate returned by the estimation method limited by MaxEstimatedFee
+func (b *BitcoinRPC) LimitEstimatedFee(fee big.Int, method string) big.Int {
+	if b.MaxEstimatedFee == nil {
+		return fee
+	}
+	if fee.Cmp(b.MaxEstimatedFee) > 0 {
+		glog.Warning("rpc: ", method, " returned fee rate ", fee.String(), " above the limit ", b.MaxEstimatedFee.String(), ", using the limit")
+		return *b.MaxEstimatedFee
+	}
+	return fee
 }
 
 // SendRawTransaction sends raw transaction
@@ -836,18 +1019,100 @@ func (b *BitcoinRPC) GetMempoolEntry(txid string) (*bchain.MempoolEntry, error)
 	if res.Error != nil {
 		return nil, res.Error
 	}
-	res.Result.FeeSat, err = b.Parser.AmountToBigInt(res.Result.Fee)
-	if err != nil {
+	if err = b.setMempoolEntryFees(res.Result); err != nil {
 		return nil, err
 	}
-	res.Result.ModifiedFeeSat, err = b.Parser.AmountToBigInt(res.Result.ModifiedFee)
+	return res.Result, nil
+}
+
+// GetMempoolEntries returns mempool data of all mempool transactions by txid in one call of the backend
+func (b *BitcoinRPC) GetMempoolEntries() (map[string]*bchain.MempoolEntry, error) {
+	glog.V(1).Info("rpc: getrawmempool verbose")
+
+	res := ResGetMempoolVerbose{}
+	req := CmdGetMempoolVerbose{Method: "getrawmempool"}
+	req.Params.Verbose = true
+	err := b.Call(&req, &res)
 	if err != nil {
 		return nil, err
 	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	for txid, e := range res.Result {
+		if e == nil {
+			delete(res.Result, txid)
+			continue
+		}
+		if err = b.setMempoolEntryFees(e); err != nil {
+			return nil, errors.Annotatef(err, "txid %v", txid)
+		}
+	}
 	return res.Result, nil
 }
 
-func safeDecodeResponse(body io.ReadCloser, res interface{}) (err error) {
+// setMempoolEntryFees converts the fees of the mempool entry to satoshis
+func (b *BitcoinRPC) setMempoolEntryFees(e *bchain.MempoolEntry) error {
+	var err error
+	// the newer backends return the fee only in the fees object, the entry without any fee is returned without it
+	if e.Fee == "" {
+		e.Fee = e.Fees.Base
+	}
+	if e.Fee != "" {
+		if e.FeeSat, err = b.Parser.AmountToBigInt(e.Fee); err != nil {
+			return err
+		}
+	}
+	if e.ModifiedFee == "" {
+		e.ModifiedFee = e.Fees.Modified
+	}
+	if e.ModifiedFee != "" {
+		if e.ModifiedFeeSat, err = b.Parser.AmountToBigInt(e.ModifiedFee); err != nil {
+			return err
+		}
+	}
+	// the old backends return the fees of the ancestors in satoshis, the newer ones in the fees object
+	if e.AncestorFees == 0 && e.Fees.Ancestor != "" {
+		a, err := b.Parser.AmountToBigInt(e.Fees.Ancestor)
+		if err != nil {
+			return err
+		}
+		if a.IsUint64() && a.Uint64() <= math.MaxUint32 {
+			e.AncestorFees = uint32(a.Uint64())
+		}
+	}
+	return nil
+}
+
+// responseBufferPool keeps the buffers to which the responses are read, shared by the concurrent calls,
+// a buffer is used by one call at a time and returned to the pool after the response is unmarshaled
+var responseBufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// maxPooledResponseBuffer is the capacity of a buffer above which it is not returned to the pool,
+// so that an occasional large response (for example of a big block) does not keep the memory allocated
+const maxPooledResponseBuffer = 4 * 1024 * 1024
+
+func getResponseBuffer() *bytes.Buffer {
+	buf := responseBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putResponseBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledResponseBuffer {
+		responseBufferPool.Put(buf)
+	}
+}
+
+// safeDecodeResponse reads the response and unmarshals it to res, if pooled is set the response is read to a pooled buffer,
+// which is safe because json.Unmarshal copies the data to res, including the json.RawMessage values
+func safeDecodeResponse(body io.ReadCloser, res interface{}, maxBytes int64, pooled bool) (err error) {
+	var buf *bytes.Buffer
+	if pooled {
+		buf = getResponseBuffer()
+		// deferred before the recovery from panic so that the buffer is returned after its data are logged
+		defer putResponseBuffer(buf)
+	}
 	var data []byte
 	defer func() {
 		if r := recover(); r != nil {
@@ -860,19 +1125,136 @@ func safeDecodeResponse(body io.ReadCloser, res interface{}) (err error) {
 			}
 		}
 	}()
-	data, err = ioutil.ReadAll(body)
+	var r io.Reader = body
+	if maxBytes > 0 {
+		// read one byte over the limit to detect oversized response without reading all of it
+		r = io.LimitReader(body, maxBytes+1)
+	}
+	if buf != nil {
+		_, err = buf.ReadFrom(r)
+		data = buf.Bytes()
+	} else {
+		data, err = ioutil.ReadAll(r)
+	}
 	if err != nil {
 		return err
 	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return errors.Errorf("Response exceeds the maximum size of %d bytes", maxBytes)
+	}
 	return json.Unmarshal(data, &res)
 }
 
+// rpcMethod returns the value of the field Method of the request
+func rpcMethod(req interface{}) string {
+	v := reflect.ValueOf(req)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	f := v.FieldByName("Method")
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
+
+// checkMethodPermitted checks the RPC method of the request against the configured allowlist and denylist
+func (b *BitcoinRPC) checkMethodPermitted(req interface{}) error {
+	if b.allowedMethods == nil && b.deniedMethods == nil {
+		return nil
+	}
+	method := strings.ToLower(rpcMethod(req))
+	if b.allowedMethods != nil {
+		if _, ok := b.allowedMethods[method]; !ok {
+			return errors.Errorf("RPC method %v not permitted", method)
+		}
+	}
+	if _, ok := b.deniedMethods[method]; ok {
+		return errors.Errorf("RPC method %v not permitted", method)
+	}
+	return nil
+}
+
 // Call calls Backend RPC interface, using RPCMarshaler interface to marshall the request
+// Requests with methods not permitted by the configuration are rejected without contacting the backend
 func (b *BitcoinRPC) Call(req interface{}, res interface{}) error {
+	return b.CallWithMaxResponseBytes(req, res, b.MaxResponseBytes)
+}
+
+// CallWithMaxResponseBytes is Call with the limit of the size of the body of the response given by maxBytes
+// instead of MaxResponseBytes, non-positive maxBytes means no limit
+func (b *BitcoinRPC) CallWithMaxResponseBytes(req interface{}, res interface{}, maxBytes int64) error {
+	if err := b.checkMethodPermitted(req); err != nil {
+		return err
+	}
 	httpData, err := b.RPCMarshaler.Marshal(req)
 	if err != nil {
 		return err
 	}
+	return b.post(httpData, res, maxBytes)
+}
+
+// CallBatch calls Backend RPC interface with the requests reqs in one JSON-RPC batch, the response to reqs[i] is decoded to res[i]
+// the errors of the individual requests are returned in the error fields of their responses, the returned error means that the whole batch failed
+func (b *BitcoinRPC) CallBatch(reqs []interface{}, res []interface{}) error {
+	if len(reqs) != len(res) {
+		return errors.New("CallBatch: the number of requests and responses differ")
+	}
+	batch := make([]map[string]json.RawMessage, len(reqs))
+	for i, req := range reqs {
+		if err := b.checkMethodPermitted(req); err != nil {
+			return err
+		}
+		d, err := b.RPCMarshaler.Marshal(req)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(d, &batch[i]); err != nil {
+			return err
+		}
+		// the responses of a batch can be in any order, they are matched to the requests by the id
+		batch[i]["id"] = json.RawMessage(strconv.Itoa(i))
+	}
+	httpData, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	var responses []json.RawMessage
+	if err = b.post(httpData, &responses, b.MaxResponseBytes); err != nil {
+		return err
+	}
+	decoded := make([]bool, len(res))
+	for _, r := range responses {
+		var id struct {
+			ID *int `json:"id"`
+		}
+		if err = json.Unmarshal(r, &id); err != nil {
+			return err
+		}
+		if id.ID == nil || *id.ID < 0 || *id.ID >= len(res) {
+			return errors.Errorf("CallBatch: unexpected response %v", string(r))
+		}
+		if err = json.Unmarshal(r, res[*id.ID]); err != nil {
+			return err
+		}
+		decoded[*id.ID] = true
+	}
+	for i := range decoded {
+		if !decoded[i] {
+			return errors.Errorf("CallBatch: missing response to %v", rpcMethod(reqs[i]))
+		}
+	}
+	return nil
+}
+
+// post sends the marshalled request httpData to the backend and decodes the response to res
+func (b *BitcoinRPC) post(httpData []byte, res interface{}, maxBytes int64) error {
 	httpReq, err := http.NewRequest("POST", b.rpcURL, bytes.NewBuffer(httpData))
 	if err != nil {
 		return err
@@ -890,11 +1272,11 @@ func (b *BitcoinRPC) Call(req interface{}, res interface{}) error {
 	// if server returns HTTP error code it might not return json with response
 	// handle both cases
 	if httpRes.StatusCode != 200 {
-		err = safeDecodeResponse(httpRes.Body, &res)
+		err = safeDecodeResponse(httpRes.Body, &res, maxBytes, b.PoolResponseBuffers)
 		if err != nil {
 			return errors.Errorf("%v %v", httpRes.Status, err)
 		}
 		return nil
 	}
-	return safeDecodeResponse(httpRes.Body, &res)
+	return safeDecodeResponse(httpRes.Body, &res, maxBytes, b.PoolResponseBuffers)
 }
@@ -0,0 +1,396 @@
+// +build unittest
+
+package btc
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func setupMethodsRPC(t *testing.T, allowed, denied []string) (*BitcoinRPC, *[]string, func()) {
+	var called []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		called = append(called, req.Method)
+		w.Write([]byte(`{"result":"ok","error":null,"id":"1"}`))
+	}))
+	config, err := json.Marshal(map[string]interface{}{
+		"rpc_url":             ts.URL,
+		"rpc_timeout":         5,
+		"rpc_allowed_methods": allowed,
+		"rpc_denied_methods":  denied,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewBitcoinRPC(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.(*BitcoinRPC), &called, ts.Close
+}
+
+type testRPCRequest struct {
+	Method string `json:"method"`
+}
+
+type testRPCResponse struct {
+	Result string `json:"result"`
+}
+
+func TestBitcoinRPC_Call_PermittedMethods(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		method  string
+		wantErr bool
+	}{
+		{
+			name:   "no restriction",
+			method: "stop",
+		},
+		{
+			name:    "allowed",
+			allowed: []string{"getblock", "getrawtransaction"},
+			method:  "getblock",
+		},
+		{
+			name:    "allowed case insensitive",
+			allowed: []string{"GetBlock"},
+			method:  "getblock",
+		},
+		{
+			name:    "not in allowlist",
+			allowed: []string{"getblock", "getrawtransaction"},
+			method:  "stop",
+			wantErr: true,
+		},
+		{
+			name:   "not in denylist",
+			denied: []string{"stop", "dumpprivkey"},
+			method: "getblock",
+		},
+		{
+			name:    "denied",
+			denied:  []string{"stop", "dumpprivkey"},
+			method:  "dumpprivkey",
+			wantErr: true,
+		},
+		{
+			name:    "denied overrides allowed",
+			allowed: []string{"getblock", "stop"},
+			denied:  []string{"stop"},
+			method:  "stop",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, called, closeServer := setupMethodsRPC(t, tt.allowed, tt.denied)
+			defer closeServer()
+			res := testRPCResponse{}
+			err := b.Call(&testRPCRequest{Method: tt.method}, &res)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "not permitted") {
+					t.Errorf("Call() error = %v, want not permitted error", err)
+				}
+				if len(*called) != 0 {
+					t.Errorf("Call() reached the backend with methods %v", *called)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Call() error = %v", err)
+			}
+			if res.Result != "ok" || len(*called) != 1 || (*called)[0] != tt.method {
+				t.Errorf("Call() result %v, backend called with %v", res.Result, *called)
+			}
+		})
+	}
+}
+
+func TestBitcoinRPC_Call_MaxResponseBytes(t *testing.T) {
+	result := strings.Repeat("a", 1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":"` + result + `","error":null,"id":"1"}`))
+	}))
+	defer ts.Close()
+	config, err := json.Marshal(map[string]interface{}{
+		"rpc_url":     ts.URL,
+		"rpc_timeout": 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewBitcoinRPC(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := c.(*BitcoinRPC)
+	if b.MaxResponseBytes != DefaultMaxResponseBytes {
+		t.Errorf("MaxResponseBytes = %d, want default %d", b.MaxResponseBytes, DefaultMaxResponseBytes)
+	}
+	res := testRPCResponse{}
+	if err = b.Call(&testRPCRequest{Method: "getblock"}, &res); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if res.Result != result {
+		t.Errorf("Call() result of length %d, want %d", len(res.Result), len(result))
+	}
+	b.MaxResponseBytes = 500
+	res = testRPCResponse{}
+	err = b.Call(&testRPCRequest{Method: "getblock"}, &res)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the maximum size") {
+		t.Errorf("Call() error = %v, want maximum size error", err)
+	}
+	if res.Result != "" {
+		t.Errorf("Call() of oversized response decoded result of length %d", len(res.Result))
+	}
+}
+
+func TestBitcoinRPC_Transport(t *testing.T) {
+	var newConns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":"ok","error":null,"id":"1"}`))
+	}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&newConns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+	tests := []struct {
+		name            string
+		config          map[string]interface{}
+		wantIdle        int
+		wantIdlePerHost int
+		wantIdleTimeout time.Duration
+	}{
+		{
+			name:            "default",
+			config:          map[string]interface{}{},
+			wantIdle:        DefaultRPCMaxIdleConns,
+			wantIdlePerHost: DefaultRPCMaxIdleConns,
+		},
+		{
+			name: "configured",
+			config: map[string]interface{}{
+				"rpc_max_idle_conns":          20,
+				"rpc_max_idle_conns_per_host": 10,
+				"rpc_idle_conn_timeout":       30,
+			},
+			wantIdle:        20,
+			wantIdlePerHost: 10,
+			wantIdleTimeout: 30 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["rpc_url"] = ts.URL
+			tt.config["rpc_timeout"] = 5
+			config, err := json.Marshal(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := NewBitcoinRPC(config, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := c.(*BitcoinRPC)
+			transport := b.client.Transport.(*http.Transport)
+			defer transport.CloseIdleConnections()
+			if transport.MaxIdleConns != tt.wantIdle || transport.MaxIdleConnsPerHost != tt.wantIdlePerHost || transport.IdleConnTimeout != tt.wantIdleTimeout {
+				t.Errorf("transport MaxIdleConns %v, MaxIdleConnsPerHost %v, IdleConnTimeout %v, want %v, %v, %v",
+					transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout, tt.wantIdle, tt.wantIdlePerHost, tt.wantIdleTimeout)
+			}
+			// sequential calls reuse the keep-alive connection
+			atomic.StoreInt32(&newConns, 0)
+			for i := 0; i < 5; i++ {
+				res := testRPCResponse{}
+				if err = b.Call(&testRPCRequest{Method: "getblockcount"}, &res); err != nil {
+					t.Fatalf("Call() error = %v", err)
+				}
+			}
+			if got := atomic.LoadInt32(&newConns); got != 1 {
+				t.Errorf("5 sequential calls opened %d connections, want 1", got)
+			}
+		})
+	}
+}
+
+func TestBitcoinRPC_GetUptime(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string
+		want      int64
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "supported",
+			response:  `{"result":86523,"error":null,"id":"1"}`,
+			want:      86523,
+			wantCalls: 2,
+		},
+		{
+			name:      "not supported",
+			response:  `{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":"1"}`,
+			wantErr:   ErrUptimeNotSupported,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req testRPCRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal(err)
+				}
+				if req.Method != "uptime" {
+					t.Errorf("method = %v, want uptime", req.Method)
+				}
+				calls++
+				w.Write([]byte(tt.response))
+			}))
+			defer ts.Close()
+			config, err := json.Marshal(map[string]interface{}{
+				"rpc_url":     ts.URL,
+				"rpc_timeout": 5,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := NewBitcoinRPC(config, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := c.(*BitcoinRPC)
+			// the second call is not sent to the backend which does not support the method
+			for i := 0; i < 2; i++ {
+				got, err := b.GetUptime()
+				if err != tt.wantErr {
+					t.Fatalf("GetUptime() error = %v, want %v", err, tt.wantErr)
+				}
+				if got != tt.want {
+					t.Errorf("GetUptime() = %v, want %v", got, tt.want)
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("backend called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// setupEchoRPC returns BitcoinRPC connected to a backend which returns the method of the request repeated in the result
+// and also as a raw json object, so that the responses of concurrent calls differ in content and size
+func setupEchoRPC(t testing.TB, pooled bool) (*BitcoinRPC, func()) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req testRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		w.Write([]byte(`{"result":"` + echoResult(req.Method) + `","raw":{"method":"` + req.Method + `"},"error":null,"id":"1"}`))
+	}))
+	config, err := json.Marshal(map[string]interface{}{
+		"rpc_url":                   ts.URL,
+		"rpc_timeout":               5,
+		"rpc_pool_response_buffers": pooled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewBitcoinRPC(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.(*BitcoinRPC), ts.Close
+}
+
+func echoResult(method string) string {
+	return strings.Repeat(method, len(method)*20)
+}
+
+type testEchoResponse struct {
+	Result string          `json:"result"`
+	Raw    json.RawMessage `json:"raw"`
+}
+
+func TestBitcoinRPC_Call_PoolResponseBuffers(t *testing.T) {
+	b, closeServer := setupEchoRPC(t, true)
+	defer closeServer()
+	if !b.PoolResponseBuffers {
+		t.Fatal("PoolResponseBuffers not set from the configuration")
+	}
+	const goroutines = 20
+	const calls = 50
+	var wg sync.WaitGroup
+	errs := make(chan string, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			var kept []testEchoResponse
+			var methods []string
+			for i := 0; i < calls; i++ {
+				method := "m" + strings.Repeat("x", (g*calls+i)%37)
+				res := testEchoResponse{}
+				if err := b.Call(&testRPCRequest{Method: method}, &res); err != nil {
+					errs <- err.Error()
+					return
+				}
+				kept = append(kept, res)
+				methods = append(methods, method)
+			}
+			// the results of the previous calls must not be overwritten by the reuse of the buffers
+			for i := range kept {
+				if kept[i].Result != echoResult(methods[i]) || string(kept[i].Raw) != `{"method":"`+methods[i]+`"}` {
+					errs <- "call " + methods[i] + " returned " + kept[i].Result + " " + string(kept[i].Raw)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Error(e)
+	}
+}
+
+func BenchmarkBitcoinRPC_Call(b *testing.B) {
+	for _, pooled := range []bool{false, true} {
+		name := "unpooled"
+		if pooled {
+			name = "pooled"
+		}
+		b.Run(name, func(b *testing.B) {
+			r, closeServer := setupEchoRPC(b, pooled)
+			defer closeServer()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				res := testEchoResponse{}
+				if err := r.Call(&testRPCRequest{Method: "getblockchaininfo"}, &res); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,17 +1,79 @@
 package bchain
 
 import (
+	"math/big"
+	"sort"
 	"time"
 
 	"github.com/golang/glog"
 )
 
+// LockTimeThreshold is the boundary of the interpretation of nLockTime,
+// lower values are block heights, higher values are unix timestamps
+const LockTimeThreshold = 500000000
+
+// SequenceFinal is the maximum nSequence value of an input which disables the lock time check
+const SequenceFinal = 0xffffffff
+
+// IsFinalTx checks if the transaction can be included in the block following the tip with given height and median time past.
+// According to BIP113 the time based lock time is compared with the median time past of the tip (the median of the times of the last 11 blocks),
+// not with the time of the tip. For a non-final transaction it returns the earliest block height (height based lock time)
+// or the earliest median time past of the tip (time based lock time) after which the transaction can be included.
+func IsFinalTx(tx *Tx, tipHeight uint32, tipMedianTime int64) (final bool, finalHeight uint32, finalTime int64) {
+	if tx.LockTime == 0 {
+		return true, 0, 0
+	}
+	// the lock time is ignored if all inputs have final sequence
+	nonFinalSequence := false
+	for i := range tx.Vin {
+		if tx.Vin[i].Sequence != SequenceFinal {
+			nonFinalSequence = true
+			break
+		}
+	}
+	if !nonFinalSequence {
+		return true, 0, 0
+	}
+	// the transaction can be included in a block with height or time greater than the lock time
+	if tx.LockTime < LockTimeThreshold {
+		if tx.LockTime < tipHeight+1 {
+			return true, 0, 0
+		}
+		return false, tx.LockTime + 1, 0
+	}
+	if int64(tx.LockTime) < tipMedianTime {
+		return true, 0, 0
+	}
+	return false, 0, int64(tx.LockTime) + 1
+}
+
+// replacedByKeepTime is the time for which the replacements of the mempool transactions are remembered
+const replacedByKeepTime = 24 * time.Hour
+
+// replacement records the confirmed transaction which spent an outpoint of a mempool transaction
+type replacement struct {
+	txid string
+	time int64
+}
+
 // MempoolBitcoinType is mempool handle.
 type MempoolBitcoinType struct {
 	BaseMempool
 	chanTxid            chan string
 	chanAddrIndex       chan txidio
 	AddrDescForOutpoint AddrDescForOutpointFunc
+	// MaxTxs limits the number of tracked transactions, 0 means no limit
+	MaxTxs int
+	// evicted contains fee rates of the transactions evicted from the tracked mempool, they are not fetched again
+	evicted map[string]int64
+	// MaxAge is the maximum age of a tracked transaction measured from its first seen time, 0 means no limit
+	MaxAge time.Duration
+	// expired contains the transactions removed because of their age, they are not fetched again
+	expired map[string]struct{}
+	// now returns the current time, it can be replaced in tests
+	now func() time.Time
+	// replacedBy maps the mempool transactions dropped because of a confirmed conflicting transaction to the conflicting txid
+	replacedBy map[string]replacement
 }
 
 // NewMempoolBitcoinType creates new mempool handler.
@@ -19,12 +81,17 @@ type MempoolBitcoinType struct {
 func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int) *MempoolBitcoinType {
 	m := &MempoolBitcoinType{
 		BaseMempool: BaseMempool{
-			chain:        chain,
-			txEntries:    make(map[string]txEntry),
-			addrDescToTx: make(map[string][]Outpoint),
+			chain:          chain,
+			txEntries:      make(map[string]txEntry),
+			addrDescToTx:   make(map[string][]Outpoint),
+			spentOutpoints: make(map[Outpoint]string),
 		},
 		chanTxid:      make(chan string, 1),
 		chanAddrIndex: make(chan txidio, 1),
+		evicted:       make(map[string]int64),
+		expired:       make(map[string]struct{}),
+		now:           time.Now,
+		replacedBy:    make(map[string]replacement),
 	}
 	for i := 0; i < workers; i++ {
 		go func(i int) {
@@ -39,11 +106,15 @@ func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int) *Mempo
 				}(j)
 			}
 			for txid := range m.chanTxid {
-				io, ok := m.getTxAddrs(txid, chanInput, chanResult)
+				io, inputs, ok := m.getTxAddrs(txid, chanInput, chanResult)
 				if !ok {
 					io = []addrIndex{}
 				}
-				m.chanAddrIndex <- txidio{txid, io}
+				var feePerKB int64
+				if m.MaxTxs > 0 && len(io) > 0 {
+					feePerKB = m.getFeePerKB(txid)
+				}
+				m.chanAddrIndex <- txidio{txid, io, feePerKB, inputs}
 			}
 		}(i)
 	}
@@ -76,11 +147,26 @@ func (m *MempoolBitcoinType) getInputAddress(input Outpoint) *addrIndex {
 
 }
 
-func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, chanResult chan *addrIndex) ([]addrIndex, bool) {
+func (m *MempoolBitcoinType) getFeePerKB(txid string) int64 {
+	e, err := m.chain.GetMempoolEntry(txid)
+	if err != nil {
+		glog.Error("cannot get mempool entry ", txid, ": ", err)
+		return 0
+	}
+	if e.Size == 0 {
+		return 0
+	}
+	var r big.Int
+	r.Mul(&e.FeeSat, big.NewInt(1000))
+	r.Div(&r, big.NewInt(int64(e.Size)))
+	return r.Int64()
+}
+
+func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, chanResult chan *addrIndex) ([]addrIndex, []Outpoint, bool) {
 	tx, err := m.chain.GetTransactionForMempool(txid)
 	if err != nil {
 		glog.Error("cannot get transaction ", txid, ": ", err)
-		return nil, false
+		return nil, nil, false
 	}
 	glog.V(2).Info("mempool: gettxaddrs ", txid, ", ", len(tx.Vin), " inputs")
 	io := make([]addrIndex, 0, len(tx.Vout)+len(tx.Vin))
@@ -98,11 +184,13 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, ch
 		}
 	}
 	dispatched := 0
+	inputs := make([]Outpoint, 0, len(tx.Vin))
 	for _, input := range tx.Vin {
 		if input.Coinbase != "" {
 			continue
 		}
 		o := Outpoint{input.Txid, int32(input.Vout)}
+		inputs = append(inputs, o)
 	loop:
 		for {
 			select {
@@ -125,7 +213,7 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, ch
 			io = append(io, *ai)
 		}
 	}
-	return io, true
+	return io, inputs, true
 }
 
 // Resync gets mempool transactions and maps outputs to transactions.
@@ -146,23 +234,37 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 			for _, si := range entry.addrIndexes {
 				m.addrDescToTx[si.addrDesc] = append(m.addrDescToTx[si.addrDesc], Outpoint{txid, si.n})
 			}
+			for _, o := range entry.inputs {
+				m.spentOutpoints[o] = txid
+			}
 			m.mux.Unlock()
 		}
 	}
 	txsMap := make(map[string]struct{}, len(txs))
 	dispatched := 0
-	txTime := uint32(time.Now().Unix())
+	now := m.now()
+	txTime := uint32(now.Unix())
+	// if there is a room in the tracked mempool, fetch again the evicted transactions with the highest fee rate
+	if room := m.MaxTxs - len(m.txEntries); room > 0 && len(m.evicted) > 0 {
+		m.readmitEvicted(room)
+	}
 	// get transaction in parallel using goroutines created in NewUTXOMempool
 	for _, txid := range txs {
 		txsMap[txid] = struct{}{}
 		_, exists := m.txEntries[txid]
+		if _, evicted := m.evicted[txid]; evicted {
+			exists = true
+		}
+		if _, expired := m.expired[txid]; expired {
+			exists = true
+		}
 		if !exists {
 		loop:
 			for {
 				select {
 				// store as many processed transactions as possible
 				case tio := <-m.chanAddrIndex:
-					onNewEntry(tio.txid, txEntry{tio.io, txTime})
+					onNewEntry(tio.txid, txEntry{tio.io, txTime, tio.feePerKB, tio.inputs})
 					dispatched--
 				// send transaction to be processed
 				case m.chanTxid <- txid:
@@ -174,7 +276,7 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 	}
 	for i := 0; i < dispatched; i++ {
 		tio := <-m.chanAddrIndex
-		onNewEntry(tio.txid, txEntry{tio.io, txTime})
+		onNewEntry(tio.txid, txEntry{tio.io, txTime, tio.feePerKB, tio.inputs})
 	}
 
 	for txid, entry := range m.txEntries {
@@ -184,6 +286,158 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 			m.mux.Unlock()
 		}
 	}
+	// forget evicted and expired transactions which left the mempool (confirmed or dropped by the backend),
+	// if such a transaction returns to the mempool (for example after a reorg), it is tracked again
+	for txid := range m.evicted {
+		if _, exists := txsMap[txid]; !exists {
+			delete(m.evicted, txid)
+		}
+	}
+	for txid := range m.expired {
+		if _, exists := txsMap[txid]; !exists {
+			delete(m.expired, txid)
+		}
+	}
+	if m.MaxAge > 0 {
+		m.expireEntries(now)
+	}
+	m.purgeReplacedBy(now)
+	if m.MaxTxs > 0 && len(m.txEntries) > m.MaxTxs {
+		m.evictEntries()
+	}
 	glog.Info("mempool: resync finished in ", time.Since(start), ", ", len(m.txEntries), " transactions in mempool")
 	return len(m.txEntries), nil
 }
+
+// OnConnectedBlock finds the mempool transactions in conflict with the transactions of the connected block
+// and remembers the conflicting confirmed transactions
+func (m *MempoolBitcoinType) OnConnectedBlock(block *Block) {
+	t := m.now().Unix()
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if len(m.spentOutpoints) == 0 {
+		return
+	}
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		for j := range tx.Vin {
+			vin := &tx.Vin[j]
+			if vin.Coinbase != "" {
+				continue
+			}
+			spender, found := m.spentOutpoints[Outpoint{vin.Txid, int32(vin.Vout)}]
+			if found && spender != tx.Txid {
+				m.replacedBy[spender] = replacement{txid: tx.Txid, time: t}
+				glog.Info("mempool: tx ", spender, " replaced by tx ", tx.Txid, " in block ", block.Height)
+			}
+		}
+	}
+}
+
+// GetReplacedBy returns txid of the confirmed transaction that replaced the mempool transaction
+func (m *MempoolBitcoinType) GetReplacedBy(txid string) string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.replacedBy[txid].txid
+}
+
+// purgeReplacedBy forgets the replacements older than replacedByKeepTime
+func (m *MempoolBitcoinType) purgeReplacedBy(now time.Time) {
+	limit := now.Add(-replacedByKeepTime).Unix()
+	m.mux.Lock()
+	for txid, r := range m.replacedBy {
+		if r.time < limit {
+			delete(m.replacedBy, txid)
+		}
+	}
+	m.mux.Unlock()
+}
+
+// expireEntries removes the transactions first seen before now-MaxAge
+func (m *MempoolBitcoinType) expireEntries(now time.Time) {
+	limit := now.Add(-m.MaxAge).Unix()
+	count := 0
+	m.mux.Lock()
+	for txid, entry := range m.txEntries {
+		if int64(entry.time) < limit {
+			m.removeEntryFromMempool(txid, entry)
+			m.expired[txid] = struct{}{}
+			count++
+		}
+	}
+	m.mux.Unlock()
+	if count > 0 {
+		glog.Info("mempool: expired ", count, " transactions older than ", m.MaxAge)
+	}
+}
+
+type evictionCandidate struct {
+	txid  string
+	entry txEntry
+}
+
+// readmitEvicted removes up to count evicted transactions with the highest fee rate from the evicted list
+func (m *MempoolBitcoinType) readmitEvicted(count int) {
+	candidates := make([]evictionCandidate, 0, len(m.evicted))
+	for txid, feePerKB := range m.evicted {
+		candidates = append(candidates, evictionCandidate{txid: txid, entry: txEntry{feePerKB: feePerKB}})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].entry.feePerKB != candidates[j].entry.feePerKB {
+			return candidates[i].entry.feePerKB > candidates[j].entry.feePerKB
+		}
+		return candidates[i].txid < candidates[j].txid
+	})
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+	for _, c := range candidates[:count] {
+		delete(m.evicted, c.txid)
+	}
+}
+
+func (m *MempoolBitcoinType) isWatchedEntry(entry txEntry) bool {
+	if m.IsWatchedAddrDesc == nil {
+		return false
+	}
+	for _, ai := range entry.addrIndexes {
+		if m.IsWatchedAddrDesc(AddressDescriptor(ai.addrDesc)) {
+			return true
+		}
+	}
+	return false
+}
+
+// evictEntries removes the transactions with the lowest fee rate until the number
+// of tracked transactions is within the limit MaxTxs.
+// Transactions of watched addresses are never evicted.
+func (m *MempoolBitcoinType) evictEntries() {
+	candidates := make([]evictionCandidate, 0, len(m.txEntries))
+	for txid, entry := range m.txEntries {
+		if !m.isWatchedEntry(entry) {
+			candidates = append(candidates, evictionCandidate{txid, entry})
+		}
+	}
+	// the lowest fee rate first, from the transactions with the same fee rate the newest first
+	sort.Slice(candidates, func(i, j int) bool {
+		ci, cj := &candidates[i], &candidates[j]
+		if ci.entry.feePerKB != cj.entry.feePerKB {
+			return ci.entry.feePerKB < cj.entry.feePerKB
+		}
+		if ci.entry.time != cj.entry.time {
+			return ci.entry.time > cj.entry.time
+		}
+		return ci.txid < cj.txid
+	})
+	count := len(m.txEntries) - m.MaxTxs
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+	m.mux.Lock()
+	for _, c := range candidates[:count] {
+		m.removeEntryFromMempool(c.txid, c.entry)
+		m.evicted[c.txid] = c.entry.feePerKB
+	}
+	m.mux.Unlock()
+	glog.Info("mempool: evicted ", count, " transactions, ", len(m.evicted), " evicted transactions in mempool")
+}
@@ -0,0 +1,412 @@
+package bchain
+
+import (
+	"math/big"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestIsFinalTx(t *testing.T) {
+	nonFinalVin := []Vin{{Sequence: 0xffffffff}, {Sequence: 0xfffffffe}}
+	finalVin := []Vin{{Sequence: 0xffffffff}, {Sequence: 0xffffffff}}
+	tests := []struct {
+		name            string
+		tx              Tx
+		tipHeight       uint32
+		tipTime         int64
+		wantFinal       bool
+		wantFinalHeight uint32
+		wantFinalTime   int64
+	}{
+		{
+			name:      "zero locktime",
+			tx:        Tx{LockTime: 0, Vin: nonFinalVin},
+			tipHeight: 1000,
+			tipTime:   1550000000,
+			wantFinal: true,
+		},
+		{
+			name:      "height locktime in the past",
+			tx:        Tx{LockTime: 999, Vin: nonFinalVin},
+			tipHeight: 1000,
+			tipTime:   1550000000,
+			wantFinal: true,
+		},
+		{
+			name:      "height locktime of the next block",
+			tx:        Tx{LockTime: 1000, Vin: nonFinalVin},
+			tipHeight: 1000,
+			tipTime:   1550000000,
+			wantFinal: true,
+		},
+		{
+			name:            "height locktime in the future",
+			tx:              Tx{LockTime: 1001, Vin: nonFinalVin},
+			tipHeight:       1000,
+			tipTime:         1550000000,
+			wantFinal:       false,
+			wantFinalHeight: 1002,
+		},
+		{
+			name:      "height locktime in the future with final sequences",
+			tx:        Tx{LockTime: 1001, Vin: finalVin},
+			tipHeight: 1000,
+			tipTime:   1550000000,
+			wantFinal: true,
+		},
+		{
+			name:      "time locktime in the past",
+			tx:        Tx{LockTime: 1549999999, Vin: nonFinalVin},
+			tipHeight: 1000,
+			tipTime:   1550000000,
+			wantFinal: true,
+		},
+		{
+			name:          "time locktime equal to the tip time",
+			tx:            Tx{LockTime: 1550000000, Vin: nonFinalVin},
+			tipHeight:     1000,
+			tipTime:       1550000000,
+			wantFinal:     false,
+			wantFinalTime: 1550000001,
+		},
+		{
+			name:          "time locktime in the future",
+			tx:            Tx{LockTime: 1560000000, Vin: nonFinalVin},
+			tipHeight:     1000,
+			tipTime:       1550000000,
+			wantFinal:     false,
+			wantFinalTime: 1560000001,
+		},
+		{
+			name:      "time locktime in the future with final sequences",
+			tx:        Tx{LockTime: 1560000000, Vin: finalVin},
+			tipHeight: 1000,
+			tipTime:   1550000000,
+			wantFinal: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			final, finalHeight, finalTime := IsFinalTx(&tt.tx, tt.tipHeight, tt.tipTime)
+			if final != tt.wantFinal || finalHeight != tt.wantFinalHeight || finalTime != tt.wantFinalTime {
+				t.Errorf("IsFinalTx() = %v, %v, %v, want %v, %v, %v", final, finalHeight, finalTime, tt.wantFinal, tt.wantFinalHeight, tt.wantFinalTime)
+			}
+		})
+	}
+}
+
+type testMempoolParser struct {
+	BlockChainParser
+}
+
+func (p *testMempoolParser) GetAddrDescFromVout(output *Vout) (AddressDescriptor, error) {
+	return AddressDescriptor(output.ScriptPubKey.Hex), nil
+}
+
+type testMempoolTx struct {
+	txid   string
+	addr   string
+	fee    int64
+	size   uint32
+	inputs []Outpoint
+}
+
+type testMempoolChain struct {
+	BlockChain
+	parser BlockChainParser
+	txs    []testMempoolTx
+}
+
+func (c *testMempoolChain) GetChainParser() BlockChainParser {
+	return c.parser
+}
+
+func (c *testMempoolChain) GetMempoolTransactions() ([]string, error) {
+	r := make([]string, len(c.txs))
+	for i := range c.txs {
+		r[i] = c.txs[i].txid
+	}
+	return r, nil
+}
+
+func (c *testMempoolChain) find(txid string) *testMempoolTx {
+	for i := range c.txs {
+		if c.txs[i].txid == txid {
+			return &c.txs[i]
+		}
+	}
+	return nil
+}
+
+func (c *testMempoolChain) GetTransactionForMempool(txid string) (*Tx, error) {
+	t := c.find(txid)
+	if t == nil {
+		return nil, ErrTxNotFound
+	}
+	tx := &Tx{
+		Txid: txid,
+		Vout: []Vout{{N: 0, ScriptPubKey: ScriptPubKey{Hex: t.addr}}},
+	}
+	for _, o := range t.inputs {
+		tx.Vin = append(tx.Vin, Vin{Txid: o.Txid, Vout: uint32(o.Vout)})
+	}
+	return tx, nil
+}
+
+func (c *testMempoolChain) GetMempoolEntry(txid string) (*MempoolEntry, error) {
+	t := c.find(txid)
+	if t == nil {
+		return nil, ErrTxNotFound
+	}
+	return &MempoolEntry{Size: t.size, FeeSat: *big.NewInt(t.fee)}, nil
+}
+
+func mempoolTxids(m *MempoolBitcoinType) []string {
+	entries := m.GetAllEntries()
+	r := make([]string, len(entries))
+	for i := range entries {
+		r[i] = entries[i].Txid
+	}
+	sort.Strings(r)
+	return r
+}
+
+func TestMempoolBitcoinType_MaxTxs(t *testing.T) {
+	chain := &testMempoolChain{
+		parser: &testMempoolParser{},
+		txs: []testMempoolTx{
+			{txid: "tx1", addr: "addr1", fee: 1000, size: 250},  // 4000 sat/kB
+			{txid: "tx2", addr: "addr2", fee: 250, size: 250},   // 1000 sat/kB
+			{txid: "tx3", addr: "addr3", fee: 5000, size: 500},  // 10000 sat/kB
+			{txid: "tx4", addr: "watched", fee: 100, size: 500}, // 200 sat/kB, watched
+			{txid: "tx5", addr: "addr5", fee: 500, size: 250},   // 2000 sat/kB
+			{txid: "tx6", addr: "addr6", fee: 3000, size: 1000}, // 3000 sat/kB
+		},
+	}
+	m := NewMempoolBitcoinType(chain, 2, 1)
+	m.MaxTxs = 3
+	m.IsWatchedAddrDesc = func(desc AddressDescriptor) bool {
+		return string(desc) == "watched"
+	}
+
+	count, err := m.Resync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("Resync() = %v, want 3", count)
+	}
+	// tx2, tx5 and tx6 have the lowest fee rate, tx4 is kept because it is watched
+	want := []string{"tx1", "tx3", "tx4"}
+	if got := mempoolTxids(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("after Resync() mempool = %v, want %v", got, want)
+	}
+	outpoints, _ := m.GetAddrDescTransactions(AddressDescriptor("addr2"))
+	if len(outpoints) != 0 {
+		t.Errorf("GetAddrDescTransactions(addr2) = %v, want empty", outpoints)
+	}
+
+	// evicted transactions are not fetched again while the mempool is full
+	chain.txs = append(chain.txs, testMempoolTx{txid: "tx7", addr: "addr7", fee: 250, size: 500}) // 500 sat/kB
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mempoolTxids(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("after 2nd Resync() mempool = %v, want %v", got, want)
+	}
+
+	// tx1 and tx3 are confirmed, the evicted transactions are added again to fill the room
+	chain.txs = []testMempoolTx{chain.txs[1], chain.txs[3], chain.txs[4], chain.txs[5], chain.txs[6]}
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"tx4", "tx5", "tx6"}
+	if got := mempoolTxids(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("after confirmation mempool = %v, want %v", got, want)
+	}
+}
+
+func TestMempoolBitcoinType_MaxAge(t *testing.T) {
+	chain := &testMempoolChain{
+		parser: &testMempoolParser{},
+		txs: []testMempoolTx{
+			{txid: "tx1", addr: "addr1"},
+			{txid: "tx2", addr: "addr2"},
+		},
+	}
+	clock := time.Unix(1550000000, 0)
+	m := NewMempoolBitcoinType(chain, 2, 1)
+	m.MaxAge = 2 * time.Hour
+	m.now = func() time.Time { return clock }
+
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if m.GetTransactionTime("tx1") != uint32(clock.Unix()) {
+		t.Errorf("GetTransactionTime(tx1) = %v, want %v", m.GetTransactionTime("tx1"), clock.Unix())
+	}
+
+	// tx3 is first seen one hour later
+	clock = clock.Add(time.Hour)
+	chain.txs = append(chain.txs, testMempoolTx{txid: "tx3", addr: "addr3"})
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"tx1", "tx2", "tx3"}
+	if got := mempoolTxids(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("after 1h mempool = %v, want %v", got, want)
+	}
+
+	// tx1 and tx2 are older than the max age
+	clock = clock.Add(time.Hour + time.Second)
+	count, err := m.Resync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Resync() = %v, want 1", count)
+	}
+	want = []string{"tx3"}
+	if got := mempoolTxids(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("after 2h mempool = %v, want %v", got, want)
+	}
+	outpoints, _ := m.GetAddrDescTransactions(AddressDescriptor("addr1"))
+	if len(outpoints) != 0 {
+		t.Errorf("GetAddrDescTransactions(addr1) = %v, want empty", outpoints)
+	}
+
+	// expired transactions are not fetched again while they are in the mempool of the backend
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mempoolTxids(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("after 2nd expiration mempool = %v, want %v", got, want)
+	}
+
+	// tx1 leaves the mempool and returns back (for example after a reorg), it is tracked again with a new first seen time
+	chain.txs = chain.txs[1:]
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	chain.txs = append(chain.txs, testMempoolTx{txid: "tx1", addr: "addr1"})
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"tx1", "tx3"}
+	if got := mempoolTxids(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("after return of tx1 mempool = %v, want %v", got, want)
+	}
+	if m.GetTransactionTime("tx1") != uint32(clock.Unix()) {
+		t.Errorf("GetTransactionTime(tx1) = %v, want %v", m.GetTransactionTime("tx1"), clock.Unix())
+	}
+}
+
+func TestMempoolBitcoinType_ReplacedBy(t *testing.T) {
+	chain := &testMempoolChain{
+		parser: &testMempoolParser{},
+		txs: []testMempoolTx{
+			{txid: "tx1", addr: "addr1", inputs: []Outpoint{{"prev1", 0}, {"prev1", 1}}},
+			{txid: "tx2", addr: "addr2", inputs: []Outpoint{{"prev2", 0}}},
+			{txid: "tx3", addr: "addr3", inputs: []Outpoint{{"prev3", 0}}},
+		},
+	}
+	clock := time.Unix(1550000000, 0)
+	m := NewMempoolBitcoinType(chain, 2, 1)
+	m.now = func() time.Time { return clock }
+	m.AddrDescForOutpoint = func(o Outpoint) AddressDescriptor {
+		return AddressDescriptor(o.Txid)
+	}
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// tx2 is confirmed, txA spending the outpoint prev1:1 of tx1 is confirmed, tx3 stays in the mempool
+	m.OnConnectedBlock(&Block{
+		BlockHeader: BlockHeader{Height: 100},
+		Txs: []Tx{
+			{Txid: "coinbase", Vin: []Vin{{Coinbase: "03"}}},
+			{Txid: "tx2", Vin: []Vin{{Txid: "prev2", Vout: 0}}},
+			{Txid: "txA", Vin: []Vin{{Txid: "prev4", Vout: 0}, {Txid: "prev1", Vout: 1}}},
+		},
+	})
+	chain.txs = chain.txs[2:]
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		txid string
+		want string
+	}{
+		{"tx1", "txA"},
+		{"tx2", ""},
+		{"tx3", ""},
+		{"txA", ""},
+	} {
+		if got := m.GetReplacedBy(tt.txid); got != tt.want {
+			t.Errorf("GetReplacedBy(%v) = %v, want %v", tt.txid, got, tt.want)
+		}
+	}
+	if len(m.spentOutpoints) != 1 || m.spentOutpoints[Outpoint{"prev3", 0}] != "tx3" {
+		t.Errorf("spentOutpoints = %v, want only prev3:0 of tx3", m.spentOutpoints)
+	}
+
+	// the replacement is forgotten after replacedByKeepTime
+	clock = clock.Add(replacedByKeepTime + time.Second)
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetReplacedBy("tx1"); got != "" {
+		t.Errorf("GetReplacedBy(tx1) = %v, want empty", got)
+	}
+}
+
+func TestMempoolBitcoinType_GetSpendingTx(t *testing.T) {
+	chain := &testMempoolChain{
+		parser: &testMempoolParser{},
+		txs: []testMempoolTx{
+			{txid: "tx1", addr: "addr1", inputs: []Outpoint{{"prev1", 0}, {"prev1", 1}}},
+			{txid: "tx2", addr: "addr2", inputs: []Outpoint{{"tx1", 0}}},
+		},
+	}
+	m := NewMempoolBitcoinType(chain, 2, 1)
+	m.AddrDescForOutpoint = func(o Outpoint) AddressDescriptor {
+		return AddressDescriptor(o.Txid)
+	}
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		outpoint  Outpoint
+		wantTxid  string
+		wantIndex int
+	}{
+		{Outpoint{"prev1", 0}, "tx1", 0},
+		{Outpoint{"prev1", 1}, "tx1", 1},
+		{Outpoint{"tx1", 0}, "tx2", 0},
+		{Outpoint{"prev1", 2}, "", 0},
+		{Outpoint{"tx2", 0}, "", 0},
+	} {
+		if txid, index := m.GetSpendingTx(tt.outpoint); txid != tt.wantTxid || index != tt.wantIndex {
+			t.Errorf("GetSpendingTx(%v) = %v, %v, want %v, %v", tt.outpoint, txid, index, tt.wantTxid, tt.wantIndex)
+		}
+	}
+	// the spends are forgotten when the spending transaction leaves the mempool
+	chain.txs = chain.txs[1:]
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if txid, _ := m.GetSpendingTx(Outpoint{"prev1", 1}); txid != "" {
+		t.Errorf("GetSpendingTx(prev1:1) = %v after removal of tx1, want empty", txid)
+	}
+	if inputs, found := m.GetTxInputs("tx2"); !found || !reflect.DeepEqual(inputs, []Outpoint{{"tx1", 0}}) {
+		t.Errorf("GetTxInputs(tx2) = %v, %v, want [tx1:0], true", inputs, found)
+	}
+	if _, found := m.GetTxInputs("tx1"); found {
+		t.Error("GetTxInputs(tx1) found the removed transaction")
+	}
+}
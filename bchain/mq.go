@@ -3,6 +3,9 @@ package bchain
 import (
 	"context"
 	"encoding/binary"
+	"fmt"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/golang/glog"
@@ -16,6 +19,48 @@ type MQ struct {
 	isRunning bool
 	finished  chan error
 	binding   string
+	reconnect MQReconnect
+	// recv receives the next message from the socket, it is replaced by the tests to simulate the failures
+	recv func(socket *zmq.Socket) ([][]byte, error)
+	// mux guards the socket, the status and the shutdown, the socket is replaced by the reconnection;
+	// the connection state is reported by the monitor of the socket, which is stopped by closing stopMonitor
+	mux         sync.Mutex
+	status      MQStatus
+	shutdown    bool
+	stop        chan struct{}
+	stopMonitor chan struct{}
+	monitors    int
+}
+
+// MQReconnect configures the reconnection of the subscription, zero values are replaced by the defaults
+type MQReconnect struct {
+	// Interval is the delay before the first attempt to reconnect, it is doubled after each failed attempt
+	// up to MaxInterval; the same backoff is used by ZeroMQ to reconnect the dropped transport of the socket
+	Interval    time.Duration
+	MaxInterval time.Duration
+}
+
+// DefaultMQReconnectInterval and DefaultMQReconnectMaxInterval are the default backoff of the reconnection of the subscription
+const (
+	DefaultMQReconnectInterval    = 100 * time.Millisecond
+	DefaultMQReconnectMaxInterval = 30 * time.Second
+)
+
+// MQStatus is the health of the notification channel
+type MQStatus struct {
+	Connected bool `json:"connected"`
+	// Reconnects is the number of the successful reconnections of the subscription
+	Reconnects int `json:"reconnects"`
+	// LastNotification is the unix time of the last received notification
+	LastNotification int64  `json:"lastNotification,omitempty"`
+	LastError        string `json:"lastError,omitempty"`
+}
+
+// mqMonitorTimeout is the timeout of the receive of the monitor events, after which the monitor checks if it is stopped
+const mqMonitorTimeout = 100 * time.Millisecond
+
+func mqRecv(socket *zmq.Socket) ([][]byte, error) {
+	return socket.RecvMessageBytes(0)
 }
 
 // NotificationType is type of notification
@@ -33,34 +78,207 @@ const (
 // NewMQ creates new Bitcoind ZeroMQ listener
 // callback function receives messages
 func NewMQ(binding string, callback func(NotificationType)) (*MQ, error) {
+	return NewMQWithReconnect(binding, callback, MQReconnect{})
+}
+
+// NewMQWithReconnect creates new Bitcoind ZeroMQ listener which reconnects the subscription with the backoff
+// configured by reconnect if the socket fails, callback function receives messages
+func NewMQWithReconnect(binding string, callback func(NotificationType), reconnect MQReconnect) (*MQ, error) {
+	return newMQ(binding, callback, reconnect, mqRecv)
+}
+
+func newMQ(binding string, callback func(NotificationType), reconnect MQReconnect, recv func(*zmq.Socket) ([][]byte, error)) (*MQ, error) {
+	if reconnect.Interval <= 0 {
+		reconnect.Interval = DefaultMQReconnectInterval
+	}
+	if reconnect.MaxInterval < reconnect.Interval {
+		reconnect.MaxInterval = DefaultMQReconnectMaxInterval
+		if reconnect.MaxInterval < reconnect.Interval {
+			reconnect.MaxInterval = reconnect.Interval
+		}
+	}
 	context, err := zmq.NewContext()
 	if err != nil {
 		return nil, err
 	}
-	socket, err := context.NewSocket(zmq.SUB)
-	if err != nil {
+	mq := &MQ{
+		context:   context,
+		isRunning: true,
+		finished:  make(chan error),
+		binding:   binding,
+		reconnect: reconnect,
+		recv:      recv,
+		stop:      make(chan struct{}),
+	}
+	if mq.socket, mq.stopMonitor, err = mq.connect(); err != nil {
 		return nil, err
 	}
-	err = socket.SetSubscribe("hashblock")
+	glog.Info("MQ listening to ", binding)
+	go mq.run(callback)
+	return mq, nil
+}
+
+// connect creates the socket subscribed to the notifications of the backend together with its monitor,
+// the monitor is stopped by closing the returned channel
+func (mq *MQ) connect() (*zmq.Socket, chan struct{}, error) {
+	socket, err := mq.context.NewSocket(zmq.SUB)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	stopMonitor := make(chan struct{})
+	if err = mq.setupSocket(socket, stopMonitor); err != nil {
+		close(stopMonitor)
+		socket.Close()
+		return nil, nil, err
+	}
+	return socket, stopMonitor, nil
+}
+
+// startMonitor starts the goroutine reporting the connection and disconnection of the socket until stopMonitor is closed
+func (mq *MQ) startMonitor(socket *zmq.Socket, stopMonitor chan struct{}) error {
+	mq.mux.Lock()
+	mq.monitors++
+	addr := fmt.Sprintf("inproc://mq-monitor-%d", mq.monitors)
+	mq.mux.Unlock()
+	if err := socket.Monitor(addr, zmq.EVENT_CONNECTED|zmq.EVENT_DISCONNECTED); err != nil {
+		return err
 	}
-	err = socket.SetSubscribe("hashtx")
+	monitor, err := mq.context.NewSocket(zmq.PAIR)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if err = monitor.SetRcvtimeo(mqMonitorTimeout); err != nil {
+		monitor.Close()
+		return err
+	}
+	if err = monitor.Connect(addr); err != nil {
+		monitor.Close()
+		return err
+	}
+	go mq.monitor(monitor, stopMonitor)
+	return nil
+}
+
+// monitor receives the events of the monitored socket, the monitor socket is closed when stopMonitor is closed
+func (mq *MQ) monitor(monitor *zmq.Socket, stopMonitor chan struct{}) {
+	defer monitor.Close()
+	for {
+		select {
+		case <-stopMonitor:
+			return
+		default:
+		}
+		event, _, _, err := monitor.RecvEvent(0)
+		if err != nil {
+			if isTransientMQError(err) {
+				continue
+			}
+			if zmq.AsErrno(err) != zmq.ETERM {
+				glog.Error("MQ monitor RecvEvent error ", err)
+			}
+			return
+		}
+		mq.mux.Lock()
+		select {
+		case <-stopMonitor:
+			// the event of the replaced socket is not reported
+		default:
+			switch event {
+			case zmq.EVENT_CONNECTED:
+				mq.status.Connected = true
+			case zmq.EVENT_DISCONNECTED:
+				mq.status.Connected = false
+				glog.Warning("MQ disconnected from ", mq.binding)
+			}
+		}
+		mq.mux.Unlock()
+	}
+}
+
+// isTransientMQError returns true for the errors after which the receive on the same socket can be retried,
+// the receive interrupted by a signal or timed out
+func isTransientMQError(err error) bool {
+	e := zmq.AsErrno(err)
+	return e == zmq.Errno(syscall.EINTR) || e == zmq.Errno(syscall.EAGAIN)
+}
+
+func (mq *MQ) setupSocket(socket *zmq.Socket, stopMonitor chan struct{}) error {
+	if err := socket.SetReconnectIvl(mq.reconnect.Interval); err != nil {
+		return err
+	}
+	if err := socket.SetReconnectIvlMax(mq.reconnect.MaxInterval); err != nil {
+		return err
+	}
+	if err := socket.SetSubscribe("hashblock"); err != nil {
+		return err
+	}
+	if err := socket.SetSubscribe("hashtx"); err != nil {
+		return err
 	}
 	// for now do not use raw subscriptions - we would have to handle skipped/lost notifications from zeromq
 	// on each notification we do sync or syncmempool respectively
 	// socket.SetSubscribe("rawblock")
 	// socket.SetSubscribe("rawtx")
-	err = socket.Connect(binding)
-	if err != nil {
-		return nil, err
+	if err := mq.startMonitor(socket, stopMonitor); err != nil {
+		return err
+	}
+	return socket.Connect(mq.binding)
+}
+
+// reconnectSocket replaces the socket failed with a non-transient error by a new one, the attempts are repeated with the exponential backoff
+// until they succeed or the MQ is shut down, returns false if the MQ is shut down
+func (mq *MQ) reconnectSocket(failed *zmq.Socket, cause error) bool {
+	mq.mux.Lock()
+	if mq.shutdown {
+		mq.mux.Unlock()
+		return false
+	}
+	mq.status.Connected = false
+	mq.status.LastError = cause.Error()
+	mq.socket = nil
+	close(mq.stopMonitor)
+	mq.stopMonitor = nil
+	mq.mux.Unlock()
+	failed.Close()
+	delay := mq.reconnect.Interval
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-mq.stop:
+			return false
+		case <-time.After(delay):
+		}
+		glog.Info("MQ reconnecting to ", mq.binding, ", attempt ", attempt)
+		socket, stopMonitor, err := mq.connect()
+		if err != nil {
+			glog.Error("MQ reconnect attempt ", attempt, " error ", err)
+			mq.mux.Lock()
+			mq.status.LastError = err.Error()
+			mq.mux.Unlock()
+			if delay *= 2; delay > mq.reconnect.MaxInterval {
+				delay = mq.reconnect.MaxInterval
+			}
+			continue
+		}
+		mq.mux.Lock()
+		defer mq.mux.Unlock()
+		if mq.shutdown {
+			close(stopMonitor)
+			socket.Close()
+			return false
+		}
+		mq.socket = socket
+		mq.stopMonitor = stopMonitor
+		mq.status.Reconnects++
+		glog.Info("MQ reconnected to ", mq.binding, " after ", attempt, " attempts")
+		return true
 	}
-	glog.Info("MQ listening to ", binding)
-	mq := &MQ{context, socket, true, make(chan error), binding}
-	go mq.run(callback)
-	return mq, nil
+}
+
+// GetStatus returns the health of the notification channel
+func (mq *MQ) GetStatus() MQStatus {
+	mq.mux.Lock()
+	defer mq.mux.Unlock()
+	return mq.status
 }
 
 func (mq *MQ) run(callback func(NotificationType)) {
@@ -73,16 +291,35 @@ func (mq *MQ) run(callback func(NotificationType)) {
 		mq.finished <- nil
 	}()
 	mq.isRunning = true
+	mq.mux.Lock()
+	socket := mq.socket
+	mq.mux.Unlock()
 	for {
-		msg, err := mq.socket.RecvMessageBytes(0)
+		msg, err := mq.recv(socket)
 		if err != nil {
-			if zmq.AsErrno(err) == zmq.Errno(zmq.ETERM) || err.Error() == "Socket is closed" {
+			mq.mux.Lock()
+			shutdown := mq.shutdown
+			mq.mux.Unlock()
+			if shutdown || zmq.AsErrno(err) == zmq.Errno(zmq.ETERM) {
 				break
 			}
+			if isTransientMQError(err) {
+				glog.Warning("MQ RecvMessageBytes error ", err, ", retrying")
+				continue
+			}
 			glog.Error("MQ RecvMessageBytes error ", err, ", ", zmq.AsErrno(err))
-			time.Sleep(100 * time.Millisecond)
+			if !mq.reconnectSocket(socket, err) {
+				break
+			}
+			mq.mux.Lock()
+			socket = mq.socket
+			mq.mux.Unlock()
+			continue
 		}
 		if msg != nil && len(msg) >= 3 {
+			mq.mux.Lock()
+			mq.status.LastNotification = time.Now().Unix()
+			mq.mux.Unlock()
 			var nt NotificationType
 			switch string(msg[0]) {
 			case "hashblock":
@@ -110,24 +347,39 @@ func (mq *MQ) run(callback func(NotificationType)) {
 // Shutdown stops listening to the ZeroMQ and closes the connection
 func (mq *MQ) Shutdown(ctx context.Context) error {
 	glog.Info("MQ server shutdown")
+	mq.mux.Lock()
+	if !mq.shutdown {
+		mq.shutdown = true
+		close(mq.stop)
+	}
+	socket := mq.socket
+	if mq.stopMonitor != nil {
+		close(mq.stopMonitor)
+		mq.stopMonitor = nil
+	}
+	mq.status.Connected = false
+	mq.mux.Unlock()
 	if mq.isRunning {
 		go func() {
 			// if errors in the closing sequence, let it close ungracefully
-			if err := mq.socket.SetUnsubscribe("hashtx"); err != nil {
-				mq.finished <- err
-				return
-			}
-			if err := mq.socket.SetUnsubscribe("hashblock"); err != nil {
-				mq.finished <- err
-				return
-			}
-			if err := mq.socket.Unbind(mq.binding); err != nil {
-				mq.finished <- err
-				return
-			}
-			if err := mq.socket.Close(); err != nil {
-				mq.finished <- err
-				return
+			// the socket is nil if the shutdown interrupted the reconnection
+			if socket != nil {
+				if err := socket.SetUnsubscribe("hashtx"); err != nil {
+					mq.finished <- err
+					return
+				}
+				if err := socket.SetUnsubscribe("hashblock"); err != nil {
+					mq.finished <- err
+					return
+				}
+				if err := socket.Disconnect(mq.binding); err != nil {
+					mq.finished <- err
+					return
+				}
+				if err := socket.Close(); err != nil {
+					mq.finished <- err
+					return
+				}
 			}
 			if err := mq.context.Term(); err != nil {
 				mq.finished <- err
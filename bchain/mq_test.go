@@ -0,0 +1,107 @@
+package bchain
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	zmq "github.com/pebbe/zmq4"
+)
+
+func TestMQ_Reconnect(t *testing.T) {
+	// the receive after drop is set fails as if the socket failed, after interrupt is set it is interrupted by a signal
+	var drop, interrupt int32
+	recv := func(socket *zmq.Socket) ([][]byte, error) {
+		if atomic.CompareAndSwapInt32(&interrupt, 1, 0) {
+			return nil, zmq.Errno(syscall.EINTR)
+		}
+		msg, err := mqRecv(socket)
+		if err == nil && atomic.CompareAndSwapInt32(&drop, 1, 0) {
+			return nil, errors.New("connection dropped")
+		}
+		return msg, err
+	}
+	pub, err := zmq.NewSocket(zmq.PUB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	if err = pub.Bind("tcp://127.0.0.1:*"); err != nil {
+		t.Fatal(err)
+	}
+	binding, err := pub.GetLastEndpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifications := make(chan NotificationType, 16)
+	mq, err := newMQ(binding, func(nt NotificationType) { notifications <- nt }, MQReconnect{Interval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond}, recv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the subscription is established asynchronously, the messages sent before are lost
+	waitNotification := func() {
+		timeout := time.After(5 * time.Second)
+		tick := time.NewTicker(20 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				if _, err := pub.SendMessage("hashblock", []byte{1, 2, 3, 4}, []byte{1, 0, 0, 0}); err != nil {
+					t.Fatal(err)
+				}
+			case nt := <-notifications:
+				if nt != NotificationNewBlock {
+					t.Fatalf("notification %v, want %v", nt, NotificationNewBlock)
+				}
+				return
+			case <-timeout:
+				t.Fatal("notification about new block not received")
+			}
+		}
+	}
+	// the connection is reported by the monitor of the socket
+	waitConnected := func() MQStatus {
+		timeout := time.After(5 * time.Second)
+		for {
+			s := mq.GetStatus()
+			if s.Connected {
+				return s
+			}
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-timeout:
+				t.Fatalf("GetStatus() = %+v, want connected", s)
+			}
+		}
+	}
+	waitNotification()
+	if s := waitConnected(); s.Reconnects != 0 || s.LastNotification == 0 {
+		t.Errorf("GetStatus() = %+v, want connected without reconnects", s)
+	}
+	// the interrupted receive is retried on the same socket
+	atomic.StoreInt32(&interrupt, 1)
+	waitNotification()
+	if s := mq.GetStatus(); s.Reconnects != 0 || s.LastError != "" {
+		t.Errorf("GetStatus() after interrupt = %+v, want no reconnects", s)
+	}
+	atomic.StoreInt32(&drop, 1)
+	// the notifications are received again after the subscription is re-established
+	waitNotification()
+	for atomic.LoadInt32(&drop) != 0 {
+		waitNotification()
+	}
+	if s := waitConnected(); s.Reconnects != 1 || s.LastError != "connection dropped" {
+		t.Errorf("GetStatus() = %+v, want connected after one reconnect", s)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = mq.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := mq.GetStatus(); s.Connected {
+		t.Errorf("GetStatus() after shutdown = %+v, want disconnected", s)
+	}
+}
@@ -114,12 +114,17 @@ type BlockInfo struct {
 	Nonce      json.Number `json:"nonce"`
 	Bits       string      `json:"bits"`
 	Difficulty json.Number `json:"difficulty"`
-	Txids      []string    `json:"tx,omitempty"`
+	Chainwork  string      `json:"chainwork,omitempty"`
+	// Weight is the block weight as defined by BIP141, for the chains without witness data it is 4 times the size
+	Weight int      `json:"weight,omitempty"`
+	Txids  []string `json:"tx,omitempty"`
 }
 
 // MempoolEntry is used to get data about mempool entry
+// the virtual size Vsize is returned only by the backends supporting segwit, the newer backends return the fee only in Fees.Base
 type MempoolEntry struct {
 	Size            uint32 `json:"size"`
+	Vsize           uint32 `json:"vsize"`
 	FeeSat          big.Int
 	Fee             json.Number `json:"fee"`
 	ModifiedFeeSat  big.Int
@@ -133,21 +138,94 @@ type MempoolEntry struct {
 	AncestorSize    uint32      `json:"ancestorsize"`
 	AncestorFees    uint32      `json:"ancestorfees"`
 	Depends         []string    `json:"depends"`
+	Fees            struct {
+		Base     json.Number `json:"base"`
+		Modified json.Number `json:"modified"`
+		Ancestor json.Number `json:"ancestor"`
+	} `json:"fees"`
+}
+
+// FeeRate returns the fee rate of the entry in satoshis per byte of the virtual size or of the size
+// if the virtual size is not known, false if the entry does not contain the fee or the size
+func (e *MempoolEntry) FeeRate() (float64, bool) {
+	size := e.Vsize
+	if size == 0 {
+		size = e.Size
+	}
+	if e.Fee == "" || size == 0 {
+		return 0, false
+	}
+	r, _ := new(big.Float).Quo(new(big.Float).SetInt(&e.FeeSat), big.NewFloat(float64(size))).Float64()
+	return r, true
+}
+
+// MiningFeeRate returns the fee rate in satoshis per byte by which the transaction is selected to a block, i.e. of the modified fee
+// (including the prioritisation) or of the fee of the package with the unconfirmed ancestors if it is lower,
+// false if the entry does not contain the fee or the size
+func (e *MempoolEntry) MiningFeeRate() (float64, bool) {
+	r, ok := e.FeeRate()
+	if !ok {
+		return 0, false
+	}
+	if e.ModifiedFee != "" {
+		size := e.Vsize
+		if size == 0 {
+			size = e.Size
+		}
+		r, _ = new(big.Float).Quo(new(big.Float).SetInt(&e.ModifiedFeeSat), big.NewFloat(float64(size))).Float64()
+	}
+	if e.AncestorCount > 1 && e.AncestorSize > 0 {
+		if ar := float64(e.AncestorFees) / float64(e.AncestorSize); ar < r {
+			r = ar
+		}
+	}
+	return r, true
 }
 
 // ChainInfo is used to get information about blockchain
 type ChainInfo struct {
-	Chain           string  `json:"chain"`
-	Blocks          int     `json:"blocks"`
-	Headers         int     `json:"headers"`
-	Bestblockhash   string  `json:"bestblockhash"`
-	Difficulty      string  `json:"difficulty"`
-	SizeOnDisk      int64   `json:"size_on_disk"`
-	Version         string  `json:"version"`
-	Subversion      string  `json:"subversion"`
-	ProtocolVersion string  `json:"protocolversion"`
-	Timeoffset      float64 `json:"timeoffset"`
-	Warnings        string  `json:"warnings"`
+	Chain         string `json:"chain"`
+	Blocks        int    `json:"blocks"`
+	Headers       int    `json:"headers"`
+	Bestblockhash string `json:"bestblockhash"`
+	Difficulty    string `json:"difficulty"`
+	Chainwork     string `json:"chainwork,omitempty"`
+	// VerificationProgress is the estimated progress (0..1) of the verification of the chain by the backend
+	VerificationProgress float64 `json:"verificationprogress,omitempty"`
+	// InitialBlockDownload is true if the backend is in the initial block download, its chain is not complete
+	InitialBlockDownload bool    `json:"initialblockdownload,omitempty"`
+	SizeOnDisk           int64   `json:"size_on_disk"`
+	Version              string  `json:"version"`
+	Subversion           string  `json:"subversion"`
+	ProtocolVersion      string  `json:"protocolversion"`
+	Timeoffset           float64 `json:"timeoffset"`
+	Warnings             string  `json:"warnings"`
+	// RelayFee is the minimum fee rate in coins per kilobyte of the transactions relayed by the backend
+	RelayFee string `json:"relayfee,omitempty"`
+	// Connections is the number of the peers connected to the backend
+	Connections int `json:"connections,omitempty"`
+	// UnparsedTxs is the number of the transactions skipped by the tolerant parsing of blocks
+	UnparsedTxs int `json:"unparsedTxs,omitempty"`
+	// MedianTime is the median time past of the best block
+	MedianTime int64 `json:"mediantime,omitempty"`
+	// Uptime is the number of seconds for which the backend is running, StartTime is the unix time of its start
+	Uptime    int64 `json:"uptime,omitempty"`
+	StartTime int64 `json:"starttime,omitempty"`
+	// MessageQueue is the health of the subscription to the notifications of the backend, nil if not subscribed
+	MessageQueue *MQStatus `json:"messageQueue,omitempty"`
+}
+
+// ChainStats is the snapshot of the state of the chain and the backend, the values which could not be obtained are nil or empty
+type ChainStats struct {
+	BestHeight   *uint32
+	BestHash     string
+	BestTime     int64
+	MempoolSize  *int
+	MempoolBytes *int64
+	Difficulty   string
+	Connections  *int
+	// Supply is the amount of the coins issued by the block subsidies up to the best block
+	Supply *big.Int
 }
 
 // RPCError defines rpc error returned by backend
@@ -197,9 +275,19 @@ type MempoolTxidEntries []MempoolTxidEntry
 // OnNewBlockFunc is used to send notification about a new block
 type OnNewBlockFunc func(hash string, height uint32)
 
+// OnNewBlocksFunc is used to send a single notification about blocks fromHeight..height connected in quick succession,
+// hash is the hash of the last block
+type OnNewBlocksFunc func(fromHeight uint32, hash string, height uint32)
+
+// OnConnectedBlockFunc is used to send notification about a block connected to the index
+type OnConnectedBlockFunc func(block *Block)
+
 // OnNewTxAddrFunc is used to send notification about a new transaction/address
 type OnNewTxAddrFunc func(tx *Tx, desc AddressDescriptor)
 
+// IsWatchedAddrDescFunc returns true if there are subscribers to the notifications about the address
+type IsWatchedAddrDescFunc func(desc AddressDescriptor) bool
+
 // AddrDescForOutpointFunc defines function that returns address descriptorfor given outpoint or nil if outpoint not found
 type AddrDescForOutpointFunc func(outpoint Outpoint) AddressDescriptor
 
@@ -211,7 +299,7 @@ type BlockChain interface {
 	// create mempool but do not initialize it
 	CreateMempool(BlockChain) (Mempool, error)
 	// initialize mempool, create ZeroMQ (or other) subscription
-	InitializeMempool(AddrDescForOutpointFunc, OnNewTxAddrFunc) error
+	InitializeMempool(AddrDescForOutpointFunc, OnNewTxAddrFunc, IsWatchedAddrDescFunc) error
 	// shutdown mempool, ZeroMQ and block chain connections
 	Shutdown(ctx context.Context) error
 	// chain info
@@ -235,6 +323,9 @@ type BlockChain interface {
 	EstimateFee(blocks int) (big.Int, error)
 	SendRawTransaction(tx string) (string, error)
 	GetMempoolEntry(txid string) (*MempoolEntry, error)
+	GetMempoolEntries() (map[string]*MempoolEntry, error)
+	GetMedianTimePast(hash string) (int64, error)
+	GetChainStats() (*ChainStats, error)
 	// parser
 	GetChainParser() BlockChainParser
 	// EthereumType specific
@@ -273,10 +364,18 @@ type BlockChainParser interface {
 	PackTx(tx *Tx, height uint32, blockTime int64) ([]byte, error)
 	UnpackTx(buf []byte) (*Tx, uint32, error)
 	GetAddrDescForUnknownInput(tx *Tx, input int) AddressDescriptor
+	// DisassembleScript returns the human readable opcode representation (asm) of the script
+	DisassembleScript(script []byte) (string, error)
 	// blocks
 	PackBlockHash(hash string) ([]byte, error)
 	UnpackBlockHash(buf []byte) (string, error)
 	ParseBlock(b []byte) (*Block, error)
+	// IsTolerantBlockParsing returns true if the transactions of a block which cannot be parsed are skipped and not indexed
+	IsTolerantBlockParsing() bool
+	// GetBlockSubsidy returns the subsidy of the block at given height, derived from the halving schedule
+	GetBlockSubsidy(height uint32) (*big.Int, error)
+	// CoinbaseMaturity returns the number of confirmations required before the outputs of a coinbase transaction can be spent
+	CoinbaseMaturity() (uint32, error)
 	// xpub
 	DerivationBasePath(xpub string) (string, error)
 	DeriveAddressDescriptors(xpub string, change uint32, indexes []uint32) ([]AddressDescriptor, error)
@@ -292,4 +391,7 @@ type Mempool interface {
 	GetAddrDescTransactions(addrDesc AddressDescriptor) ([]Outpoint, error)
 	GetAllEntries() MempoolTxidEntries
 	GetTransactionTime(txid string) uint32
+	OnConnectedBlock(block *Block)
+	GetReplacedBy(txid string) string
+	GetSpendingTx(outpoint Outpoint) (string, int)
 }
@@ -16,6 +16,7 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -39,6 +40,11 @@ var (
 	dbPath         = flag.String("datadir", "./data", "path to database directory")
 	dbCache        = flag.Int("dbcache", 1<<29, "size of the rocksdb cache")
 	dbMaxOpenFiles = flag.Int("dbmaxopenfiles", 1<<14, "max open files by rocksdb")
+	dbCompactKeys  = flag.Bool("dbcompactaddrkeys", false, "store P2PKH and P2SH keys of the address index in the compact form")
+	dbDuplicateTx  = flag.String("dbduplicatetx", "overwrite", "handling of coinbase transactions with the txid of an already indexed transaction, overwrite or keep the original")
+	lockTimeIndex  = flag.Bool("locktimeindex", false, "index transactions with non-zero lock time, the index cannot be disabled later (only Bitcoin type coins)")
+	dailyTxsIndex  = flag.Bool("dailytxsindex", false, "index the transaction counts per UTC day, the index cannot be disabled later (only Bitcoin type coins)")
+	noAddressIndex = flag.Bool("noaddressindex", false, "index only blocks and transactions without the address index, the address queries are disabled (only Bitcoin type coins)")
 
 	blockFrom      = flag.Int("blockheight", -1, "height of the starting block")
 	blockUntil     = flag.Int("blockuntil", -1, "height of the final block")
@@ -52,6 +58,8 @@ var (
 	syncWorkers = flag.Int("workers", 8, "number of workers to process blocks in bulk mode")
 	dryRun      = flag.Bool("dryrun", false, "do not index blocks, only download")
 
+	syncCheckpoint = flag.Int("synccheckpoint", 0, "in bulk mode store consistent checkpoint of the index each n blocks so that interrupted import resumes from the last checkpoint (default 0, no checkpoints, interrupted import leaves inconsistent db)")
+
 	debugMode = flag.Bool("debug", false, "debug mode, return more verbose errors, reload templates on each request")
 
 	internalBinding = flag.String("internal", "", "internal http server binding [address]:port, (default no internal server)")
@@ -64,14 +72,46 @@ var (
 
 	noTxCache = flag.Bool("notxcache", false, "disable tx cache")
 
+	addressLabelsFile = flag.String("addresslabels", "", "path to json file with labels of known addresses in the form {\"address\": \"label\"} (default no labels)")
+
+	balancesWorkers = flag.Int("balancesworkers", api.DefaultBalancesConcurrency, "number of addresses of a bulk balances request resolved in parallel")
+
+	feeStatsBlocks = flag.Int("feestatsblocks", api.DefaultFeeStatsBlocks, "default number of the last blocks from which the fee rate percentiles are computed (maximum 24)")
+
+	stuckTxPercentile = flag.Int("stucktxpercentile", api.DefaultStuckTxPercentile, "percentile of the fee rates of the last blocks below which a mempool transaction is reported as likely stuck")
+
+	xpubMaxAddresses = flag.Int("xpubmaxaddresses", api.DefaultXpubMaxAddresses, "maximum number of addresses derived from one xpub on both chains together")
+
+	utxoLimit = flag.Int("utxolimit", 0, "max number of utxos returned by the utxo API, larger sets are truncated (default 0, no limit)")
+
+	apiCacheSize       = flag.Int("apicachesize", 0, "max number of cached responses of the read-only API endpoints (default 0, API cache disabled)")
+	apiCacheBlockTTL   = flag.Int("apicacheblockttl", int(server.DefaultAPICacheBlockTTL/time.Second), "time to live in seconds of the cached responses of the block endpoint, 0 disables their caching")
+	apiCacheTxTTL      = flag.Int("apicachetxttl", int(server.DefaultAPICacheTxTTL/time.Second), "time to live in seconds of the cached responses of the transaction endpoint, 0 disables their caching")
+	apiCacheAddressTTL = flag.Int("apicacheaddressttl", int(server.DefaultAPICacheAddressTTL/time.Second), "time to live in seconds of the cached responses of the address endpoint, 0 disables their caching")
+
 	computeColumnStats = flag.Bool("computedbstats", false, "compute column stats and exit")
 	dbStatsPeriodHours = flag.Int("dbstatsperiod", 24, "period of db stats collection in hours, 0 disables stats collection")
 
+	dbCompactHour        = flag.Int("dbcompacthour", -1, "hour of the day (local time) in which the manual compaction of the db runs, the synchronization of the index waits for it, -1 disables the time window")
+	dbCompactIdleMinutes = flag.Int("dbcompactidle", 0, "time in minutes without public requests after which the manual compaction of the db runs, 0 disables the idle detection")
+	dbCompactPeriodHours = flag.Int("dbcompactperiod", 24, "minimal period between manual compactions of the db in hours")
+
 	// resync index at least each resyncIndexPeriodMs (could be more often if invoked by message from ZeroMQ)
 	resyncIndexPeriodMs = flag.Int("resyncindexperiod", 935093, "resync index period in milliseconds")
 
 	// resync mempool at least each resyncMempoolPeriodMs (could be more often if invoked by message from ZeroMQ)
 	resyncMempoolPeriodMs = flag.Int("resyncmempoolperiod", 60017, "resync mempool period in milliseconds")
+
+	blockNotifyWindowMs = flag.Int("blocknotifywindow", 0, "window in milliseconds in which the notifications about new blocks connected in quick succession are coalesced to a single notification (default 0, notification for each block)")
+
+	addrNotifyWindowMs = flag.Int("addrnotifywindow", 0, "window in milliseconds in which the websocket address notifications to one connection are batched to a single message (default 0, notification sent immediately)")
+
+	confirmationPolicy   = flag.String("confirmationpolicy", "", "risk curve of the confirmations required for the USD value of an output in the format <min USD value>:<confirmations>,..., for example 0:1,1000:3,10000:6")
+	defaultConfirmations = flag.Int("defaultconfirmations", api.DefaultRequiredConfirmations, "confirmations required for an output if its USD value is not known")
+
+	ibdRefuseQueries = flag.Bool("ibdrefusequeries", false, "refuse the address queries while the backend is in the initial block download and its data is incomplete")
+
+	wsMaxConnections = flag.Int("wsmaxconnections", 0, "maximum number of the open websocket connections, the further connections are rejected (default 0, no limit)")
 )
 
 var (
@@ -87,9 +127,15 @@ var (
 	txCache                    *db.TxCache
 	metrics                    *common.Metrics
 	syncWorker                 *db.SyncWorker
+	addressLabels              *api.AddressLabels
 	internalState              *common.InternalState
-	callbacksOnNewBlock        []bchain.OnNewBlockFunc
+	compactionScheduler        *common.CompactionScheduler
+	// syncCompactionMux makes the synchronization of the index and the manual compaction of the db mutually exclusive
+	syncCompactionMux          sync.Mutex
+	callbacksOnNewBlocks       []bchain.OnNewBlocksFunc
+	blockNotifier              *bchain.BlockNotificationCoalescer
 	callbacksOnNewTxAddr       []bchain.OnNewTxAddrFunc
+	callbacksIsWatchedAddrDesc []bchain.IsWatchedAddrDescFunc
 	chanOsSignal               chan os.Signal
 	inShutdown                 int32
 )
@@ -155,6 +201,16 @@ func main() {
 		return
 	}
 	defer index.Close()
+	index.SetBulkCheckpointInterval(*syncCheckpoint)
+	switch *dbDuplicateTx {
+	case "overwrite":
+		index.SetDuplicateTxPolicy(db.DuplicateTxOverwrite)
+	case "keep":
+		index.SetDuplicateTxPolicy(db.DuplicateTxKeep)
+	default:
+		glog.Error("dbduplicatetx: invalid value ", *dbDuplicateTx)
+		return
+	}
 
 	internalState, err = newInternalState(coin, coinShortcut, coinLabel, index)
 	if err != nil {
@@ -169,6 +225,76 @@ func main() {
 		}
 		glog.Warning("internalState: database was left in open state, possibly previous ungraceful shutdown")
 	}
+	if *dbCompactKeys != internalState.CompactAddressKeys {
+		if *dbCompactKeys && chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+			glog.Error("dbcompactaddrkeys: supported only for Bitcoin type coins")
+			return
+		}
+		// the db contains the keys of only one form, the mode can be changed only for an empty db
+		if internalState.BestHeight > 0 {
+			glog.Error("internalState: database was indexed with the flag -dbcompactaddrkeys=", internalState.CompactAddressKeys, ", it is necessary to rebuild the index to change it")
+			return
+		}
+		internalState.CompactAddressKeys = *dbCompactKeys
+	}
+	index.SetCompactAddressKeys(internalState.CompactAddressKeys)
+	if tolerant := chain.GetChainParser().IsTolerantBlockParsing(); tolerant != internalState.TolerantBlockParsing {
+		// the skipped transactions are missing in the index, the mode can be changed only for an empty db
+		if internalState.BestHeight > 0 {
+			glog.Error("internalState: database was indexed with tolerant_block_parsing=", internalState.TolerantBlockParsing, ", it is necessary to rebuild the index to change it")
+			return
+		}
+		internalState.TolerantBlockParsing = tolerant
+	}
+	if *noAddressIndex {
+		if chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+			glog.Error("noaddressindex: supported only for Bitcoin type coins")
+			return
+		}
+		if !internalState.NoAddressIndex && internalState.BestHeight > 0 {
+			glog.Warning("internalState: database with the address index is continued without it, the address index will not be complete")
+		}
+		// record the mode permanently, the address index of the database is from now on not complete
+		internalState.NoAddressIndex = true
+	} else if internalState.NoAddressIndex {
+		glog.Error("internalState: database was indexed without the address index (-noaddressindex), it is necessary to rebuild the index")
+		return
+	}
+	index.SetNoAddressIndex(*noAddressIndex)
+	if *lockTimeIndex {
+		if chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+			glog.Error("locktimeindex: supported only for Bitcoin type coins")
+			return
+		}
+		if !internalState.LockTimeIndex {
+			if internalState.BestHeight > 0 {
+				internalState.LockTimeIndexFrom = internalState.BestHeight + 1
+				glog.Warning("internalState: lock time index enabled on existing database, transactions are indexed from height ", internalState.LockTimeIndexFrom)
+			}
+			internalState.LockTimeIndex = true
+		}
+	} else if internalState.LockTimeIndex {
+		glog.Error("internalState: database was indexed with the lock time index (-locktimeindex), the index would not be complete")
+		return
+	}
+	index.SetLockTimeIndex(*lockTimeIndex)
+	if *dailyTxsIndex {
+		if chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+			glog.Error("dailytxsindex: supported only for Bitcoin type coins")
+			return
+		}
+		if !internalState.DailyTxsIndex {
+			if internalState.BestHeight > 0 {
+				internalState.DailyTxsIndexFrom = internalState.BestHeight + 1
+				glog.Warning("internalState: daily transactions index enabled on existing database, transactions are counted from height ", internalState.DailyTxsIndexFrom)
+			}
+			internalState.DailyTxsIndex = true
+		}
+	} else if internalState.DailyTxsIndex {
+		glog.Error("internalState: database was indexed with the daily transactions index (-dailytxsindex), the index would not be complete")
+		return
+	}
+	index.SetDailyTxsIndex(*dailyTxsIndex)
 
 	if *computeColumnStats {
 		internalState.DbState = common.DbStateOpen
@@ -180,11 +306,12 @@ func main() {
 		return
 	}
 
-	syncWorker, err = db.NewSyncWorker(index, chain, *syncWorkers, *syncChunk, *blockFrom, *dryRun, chanOsSignal, metrics, internalState)
+	syncWorker, err = db.NewSyncWorker(index, chain, *syncWorkers, *syncChunk, *blockFrom, *dryRun, chanOsSignal, metrics, internalState, mempool.OnConnectedBlock)
 	if err != nil {
 		glog.Errorf("NewSyncWorker %v", err)
 		return
 	}
+	blockNotifier = bchain.NewBlockNotificationCoalescer(time.Duration(*blockNotifyWindowMs)*time.Millisecond, onNewBlocks)
 
 	// set the DbState to open at this moment, after all important workers are initialized
 	internalState.DbState = common.DbStateOpen
@@ -209,6 +336,23 @@ func main() {
 		glog.Error("blockbookAppInfoMetric ", err)
 	}
 
+	addressLabels, err = api.NewAddressLabels(chain.GetChainParser(), index)
+	if err != nil {
+		glog.Error("addressLabels ", err)
+		return
+	}
+	if *addressLabelsFile != "" {
+		if err = addressLabels.LoadFile(*addressLabelsFile); err != nil {
+			glog.Error("addressLabels ", err)
+			return
+		}
+	}
+
+	if *dbCompactHour >= 0 || *dbCompactIdleMinutes > 0 {
+		compactionScheduler = common.NewCompactionScheduler(*dbCompactHour, time.Duration(*dbCompactIdleMinutes)*time.Minute,
+			time.Duration(*dbCompactPeriodHours)*time.Hour, internalState.GetLastCompaction())
+	}
+
 	var internalServer *server.InternalServer
 	if *internalBinding != "" {
 		internalServer, err = startInternalServer()
@@ -239,7 +383,7 @@ func main() {
 		if chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType {
 			addrDescForOutpoint = index.AddrDescForOutpoint
 		}
-		err = chain.InitializeMempool(addrDescForOutpoint, onNewTxAddr)
+		err = chain.InitializeMempool(addrDescForOutpoint, onNewTxAddr, isWatchedAddrDesc)
 		if err != nil {
 			glog.Error("initializeMempool ", err)
 			return
@@ -255,11 +399,17 @@ func main() {
 		internalState.InitialSync = false
 	}
 	go storeInternalStateLoop()
+	// the loop is stopped on every return, it waits for the running compaction before the deferred close of the database
+	defer func() {
+		close(chanStoreInternalState)
+		<-chanStoreInternalStateDone
+	}()
 
 	if publicServer != nil {
 		// start full public interface
-		callbacksOnNewBlock = append(callbacksOnNewBlock, publicServer.OnNewBlock)
+		callbacksOnNewBlocks = append(callbacksOnNewBlocks, publicServer.OnNewBlocks)
 		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, publicServer.OnNewTxAddr)
+		callbacksIsWatchedAddrDesc = append(callbacksIsWatchedAddrDesc, publicServer.IsWatchedAddrDesc)
 		publicServer.ConnectFullPublicInterface()
 	}
 
@@ -285,10 +435,8 @@ func main() {
 	if *synchronize {
 		close(chanSyncIndex)
 		close(chanSyncMempool)
-		close(chanStoreInternalState)
 		<-chanSyncIndexDone
 		<-chanSyncMempoolDone
-		<-chanStoreInternalStateDone
 	}
 }
 
@@ -321,6 +469,7 @@ func startInternalServer() (*server.InternalServer, error) {
 	if err != nil {
 		return nil, err
 	}
+	internalServer.SetAddressLabels(addressLabels)
 	go func() {
 		err = internalServer.Run()
 		if err != nil {
@@ -337,10 +486,37 @@ func startInternalServer() (*server.InternalServer, error) {
 
 func startPublicServer() (*server.PublicServer, error) {
 	// start public server in limited functionality, extend it after sync is finished by calling ConnectFullPublicInterface
-	publicServer, err := server.NewPublicServer(*publicBinding, *certFiles, index, chain, mempool, txCache, *explorerURL, metrics, internalState, *debugMode)
+	publicServer, err := server.NewPublicServer(*publicBinding, *certFiles, index, chain, mempool, txCache, *explorerURL, metrics, internalState, *debugMode, *apiCacheSize)
 	if err != nil {
 		return nil, err
 	}
+	publicServer.SetAddressLabels(addressLabels)
+	publicServer.SetBalancesConcurrency(*balancesWorkers)
+	if *apiCacheBlockTTL < 0 || *apiCacheTxTTL < 0 || *apiCacheAddressTTL < 0 {
+		return nil, errors.Errorf("apicacheblockttl, apicachetxttl, apicacheaddressttl: invalid value %d, %d, %d", *apiCacheBlockTTL, *apiCacheTxTTL, *apiCacheAddressTTL)
+	}
+	publicServer.SetAPICacheTTLs(time.Duration(*apiCacheBlockTTL)*time.Second, time.Duration(*apiCacheTxTTL)*time.Second, time.Duration(*apiCacheAddressTTL)*time.Second)
+	if *feeStatsBlocks > api.MaxFeeStatsBlocks {
+		return nil, errors.Errorf("feestatsblocks: invalid value %d, maximum is %d", *feeStatsBlocks, api.MaxFeeStatsBlocks)
+	}
+	publicServer.SetFeeStatsBlocks(*feeStatsBlocks)
+	publicServer.SetStuckTxPercentile(*stuckTxPercentile)
+	publicServer.SetXpubMaxAddresses(*xpubMaxAddresses)
+	if *defaultConfirmations < 0 {
+		return nil, errors.Errorf("defaultconfirmations: invalid value %d", *defaultConfirmations)
+	}
+	policy, err := api.ParseConfirmationPolicy(*confirmationPolicy, uint32(*defaultConfirmations))
+	if err != nil {
+		return nil, err
+	}
+	publicServer.SetConfirmationPolicy(policy)
+	publicServer.SetUtxoLimit(*utxoLimit)
+	publicServer.SetAddressNotificationWindow(time.Duration(*addrNotifyWindowMs) * time.Millisecond)
+	publicServer.SetWebsocketMaxConnections(*wsMaxConnections)
+	publicServer.SetRefuseQueriesInInitialBlockDownload(*ibdRefuseQueries)
+	if compactionScheduler != nil {
+		publicServer.SetCompactionScheduler(compactionScheduler)
+	}
 	go func() {
 		err = publicServer.Run()
 		if err != nil {
@@ -463,16 +639,25 @@ func syncIndexLoop() {
 	glog.Info("syncIndexLoop starting")
 	// resync index about every 15 minutes if there are no chanSyncIndex requests, with debounce 1 second
 	tickAndDebounce(time.Duration(*resyncIndexPeriodMs)*time.Millisecond, debounceResyncIndexMs*time.Millisecond, chanSyncIndex, func() {
-		if err := syncWorker.ResyncIndex(onNewBlockHash, false); err != nil {
+		syncCompactionMux.Lock()
+		err := syncWorker.ResyncIndex(onNewBlockHash, false)
+		syncCompactionMux.Unlock()
+		if err != nil {
 			glog.Error("syncIndexLoop ", errors.ErrorStack(err))
 		}
+		// the index is at the tip, do not wait with the notification of the last blocks
+		blockNotifier.Flush()
 	})
 	glog.Info("syncIndexLoop stopped")
 }
 
 func onNewBlockHash(hash string, height uint32) {
-	for _, c := range callbacksOnNewBlock {
-		c(hash, height)
+	blockNotifier.OnNewBlock(hash, height)
+}
+
+func onNewBlocks(fromHeight uint32, hash string, height uint32) {
+	for _, c := range callbacksOnNewBlocks {
+		c(fromHeight, hash, height)
 	}
 }
 
@@ -494,11 +679,15 @@ func syncMempoolLoop() {
 
 func storeInternalStateLoop() {
 	stopCompute := make(chan os.Signal)
+	// the running compaction cannot be interrupted, it is waited for so that the database is not closed under it
+	var compaction sync.WaitGroup
 	defer func() {
 		close(stopCompute)
+		compaction.Wait()
 		close(chanStoreInternalStateDone)
 	}()
 	var computeRunning bool
+	var compactionRunning int32
 	lastCompute := time.Now()
 	lastAppInfo := time.Now()
 	logAppInfoPeriod := 15 * time.Minute
@@ -521,9 +710,45 @@ func storeInternalStateLoop() {
 				computeRunning = false
 			}()
 		}
+		if compactionScheduler != nil && atomic.LoadInt32(&compactionRunning) == 0 {
+			synced, _, _ := internalState.GetSyncState()
+			if compactionScheduler.ShouldCompact(time.Now(), internalState.InitialSync || !synced) {
+				atomic.StoreInt32(&compactionRunning, 1)
+				compaction.Add(1)
+				go func() {
+					defer compaction.Done()
+					defer atomic.StoreInt32(&compactionRunning, 0)
+					// the sync loop waits for the compaction, the sync state is checked again under the lock,
+					// the sync could have started after the check above
+					syncCompactionMux.Lock()
+					defer syncCompactionMux.Unlock()
+					synced, _, lastSync := internalState.GetSyncState()
+					if internalState.InitialSync || !synced {
+						glog.Info("db compaction skipped, synchronization of the index is running")
+						return
+					}
+					index.CompactDatabase()
+					// the compaction is not recorded if the sync state changed during it, it is scheduled again
+					if syncedAfter, _, lastSyncAfter := internalState.GetSyncState(); internalState.InitialSync || !syncedAfter || !lastSyncAfter.Equal(lastSync) {
+						glog.Warning("db compaction overlapped with synchronization of the index")
+						return
+					}
+					now := time.Now()
+					compactionScheduler.Compacted(now)
+					internalState.SetLastCompaction(now)
+				}()
+			}
+		}
 		if err := index.StoreInternalState(internalState); err != nil {
 			glog.Error("storeInternalStateLoop ", errors.ErrorStack(err))
 		}
+		// sample the progress of the backend for the estimation of its time to full sync
+		if ci, err := chain.GetChainInfo(); err == nil {
+			internalState.SetBackendInitialBlockDownload(ci.InitialBlockDownload)
+			if ci.VerificationProgress > 0 {
+				internalState.AddBackendSyncProgress(ci.VerificationProgress)
+			}
+		}
 		if lastAppInfo.Add(logAppInfoPeriod).Before(time.Now()) {
 			glog.Info(index.GetMemoryStats())
 			if err := blockbookAppInfoMetric(index, chain, txCache, internalState, metrics); err != nil {
@@ -541,6 +766,15 @@ func onNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
 	}
 }
 
+func isWatchedAddrDesc(desc bchain.AddressDescriptor) bool {
+	for _, c := range callbacksIsWatchedAddrDesc {
+		if c(desc) {
+			return true
+		}
+	}
+	return false
+}
+
 func pushSynchronizationHandler(nt bchain.NotificationType) {
 	glog.V(1).Info("MQ: notification ", nt)
 	if atomic.LoadInt32(&inShutdown) != 0 {
@@ -0,0 +1,58 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// CompactionScheduler decides when to run the manual compaction of the database, the compaction runs in the configured
+// hour of the day or when the server did not serve any request for the idle time, never during the synchronization
+type CompactionScheduler struct {
+	mux sync.Mutex
+	// hour is the hour of the day (local time) in which the compaction runs, negative value disables the time window
+	hour int
+	// idle is the time without requests after which the compaction runs, zero disables the idle detection
+	idle time.Duration
+	// interval is the minimal time between two compactions
+	interval       time.Duration
+	lastActivity   time.Time
+	lastCompaction time.Time
+}
+
+// NewCompactionScheduler returns new CompactionScheduler, the time of the last compaction is taken from the internal state
+func NewCompactionScheduler(hour int, idle time.Duration, interval time.Duration, lastCompaction time.Time) *CompactionScheduler {
+	return &CompactionScheduler{
+		hour:           hour,
+		idle:           idle,
+		interval:       interval,
+		lastCompaction: lastCompaction,
+		lastActivity:   time.Now(),
+	}
+}
+
+// OnActivity records a request served at time t
+func (c *CompactionScheduler) OnActivity(t time.Time) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.lastActivity = t
+}
+
+// ShouldCompact returns true if the compaction should run at time now, syncing signals a running synchronization of the index
+func (c *CompactionScheduler) ShouldCompact(now time.Time, syncing bool) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if syncing || now.Sub(c.lastCompaction) < c.interval {
+		return false
+	}
+	if c.hour >= 0 && now.Hour() == c.hour {
+		return true
+	}
+	return c.idle > 0 && now.Sub(c.lastActivity) >= c.idle
+}
+
+// Compacted records the compaction finished at time t
+func (c *CompactionScheduler) Compacted(t time.Time) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.lastCompaction = t
+}
@@ -0,0 +1,63 @@
+// +build unittest
+
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompactionScheduler_Idle(t *testing.T) {
+	start := time.Date(2019, 3, 1, 12, 0, 0, 0, time.Local)
+	c := NewCompactionScheduler(-1, 10*time.Minute, 24*time.Hour, time.Time{})
+	c.OnActivity(start)
+	tests := []struct {
+		name    string
+		now     time.Time
+		syncing bool
+		want    bool
+	}{
+		{name: "active", now: start.Add(5 * time.Minute), want: false},
+		{name: "idle during sync", now: start.Add(15 * time.Minute), syncing: true, want: false},
+		{name: "idle", now: start.Add(15 * time.Minute), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.ShouldCompact(tt.now, tt.syncing); got != tt.want {
+				t.Errorf("ShouldCompact() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	c.Compacted(start.Add(20 * time.Minute))
+	// idle, but the interval from the last compaction did not pass
+	if c.ShouldCompact(start.Add(2*time.Hour), false) {
+		t.Error("ShouldCompact() = true before the interval from the last compaction")
+	}
+	if !c.ShouldCompact(start.Add(25*time.Hour), false) {
+		t.Error("ShouldCompact() = false after the interval from the last compaction")
+	}
+	// a new request resets the idle time
+	c.OnActivity(start.Add(25 * time.Hour))
+	if c.ShouldCompact(start.Add(25*time.Hour+time.Minute), false) {
+		t.Error("ShouldCompact() = true after a request")
+	}
+}
+
+func TestCompactionScheduler_Hour(t *testing.T) {
+	day := time.Date(2019, 3, 1, 0, 0, 0, 0, time.Local)
+	c := NewCompactionScheduler(3, 0, 12*time.Hour, day.Add(-24*time.Hour))
+	// requests are served all the time, the idle detection is disabled
+	c.OnActivity(day.Add(4 * time.Hour))
+	if c.ShouldCompact(day.Add(2*time.Hour+59*time.Minute), false) {
+		t.Error("ShouldCompact() = true before the time window")
+	}
+	if c.ShouldCompact(day.Add(3*time.Hour+30*time.Minute), true) {
+		t.Error("ShouldCompact() = true during sync")
+	}
+	if !c.ShouldCompact(day.Add(3*time.Hour+30*time.Minute), false) {
+		t.Error("ShouldCompact() = false in the time window")
+	}
+	if c.ShouldCompact(day.Add(4*time.Hour), false) {
+		t.Error("ShouldCompact() = true after the time window")
+	}
+}
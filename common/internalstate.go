@@ -51,8 +51,40 @@ type InternalState struct {
 	LastMempoolSync       time.Time `json:"lastMempoolSync"`
 
 	DbColumns []InternalStateColumn `json:"dbColumns"`
+
+	LastCompaction time.Time `json:"lastCompaction"`
+
+	// true if the keys of the address index are stored in the compact form (flag -dbcompactaddrkeys)
+	CompactAddressKeys bool `json:"compactAddressKeys"`
+
+	// true if blocks were indexed with the tolerant parsing (tolerant_block_parsing in the coin configuration),
+	// the transactions which could not be parsed are not indexed
+	TolerantBlockParsing bool `json:"tolerantBlockParsing"`
+
+	// true if blocks were indexed without the address index (flag -noaddressindex), the address index is not complete
+	NoAddressIndex bool `json:"noAddressIndex"`
+
+	// true if transactions with non-zero lock time are indexed (flag -locktimeindex) from the height LockTimeIndexFrom
+	LockTimeIndex     bool   `json:"lockTimeIndex"`
+	LockTimeIndexFrom uint32 `json:"lockTimeIndexFrom"`
+
+	// true if the transaction counts per day are indexed (flag -dailytxsindex) from the height DailyTxsIndexFrom
+	DailyTxsIndex     bool   `json:"dailyTxsIndex"`
+	DailyTxsIndexFrom uint32 `json:"dailyTxsIndexFrom"`
+
+	// backendSyncProgress estimates the time to full synchronization of the backend, it is not stored
+	backendSyncProgress *SyncProgressEstimator
+
+	// websocketConnections is the number of the open websocket connections, it is not stored
+	websocketConnections int
+
+	// backendInitialBlockDownload is true if the backend was in the initial block download when last checked, it is not stored
+	backendInitialBlockDownload bool
 }
 
+// backendSyncProgressWindow is the window over which the rate of the synchronization of the backend is computed
+const backendSyncProgressWindow = time.Hour
+
 // StartedSync signals start of synchronization
 func (is *InternalState) StartedSync() {
 	is.mux.Lock()
@@ -114,6 +146,83 @@ func (is *InternalState) GetMempoolSyncState() (bool, time.Time, int) {
 	return is.IsMempoolSynchronized, is.LastMempoolSync, is.MempoolSize
 }
 
+// SetLastCompaction sets the time of the last manual compaction of the database
+func (is *InternalState) SetLastCompaction(t time.Time) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	is.LastCompaction = t
+}
+
+// GetLastCompaction returns the time of the last manual compaction of the database, zero time if it never ran
+func (is *InternalState) GetLastCompaction() time.Time {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	return is.LastCompaction
+}
+
+// AddWebsocketConnections changes the number of the open websocket connections by delta and returns the new number
+func (is *InternalState) AddWebsocketConnections(delta int) int {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	is.websocketConnections += delta
+	return is.websocketConnections
+}
+
+// AddWebsocketConnectionWithLimit increments the number of the open websocket connections if it is below max,
+// non-positive max means no limit; the check and the increment are done under one lock, false is returned if the limit is reached
+func (is *InternalState) AddWebsocketConnectionWithLimit(max int) bool {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	if max > 0 && is.websocketConnections >= max {
+		return false
+	}
+	is.websocketConnections++
+	return true
+}
+
+// GetWebsocketConnections returns the number of the open websocket connections
+func (is *InternalState) GetWebsocketConnections() int {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	return is.websocketConnections
+}
+
+// SetBackendInitialBlockDownload records whether the backend is in the initial block download
+func (is *InternalState) SetBackendInitialBlockDownload(ibd bool) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	is.backendInitialBlockDownload = ibd
+}
+
+// IsBackendInitialBlockDownload returns true if the backend was in the initial block download when last checked
+func (is *InternalState) IsBackendInitialBlockDownload() bool {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	return is.backendInitialBlockDownload
+}
+
+// AddBackendSyncProgress adds current verification progress of the backend
+func (is *InternalState) AddBackendSyncProgress(progress float64) {
+	is.mux.Lock()
+	if is.backendSyncProgress == nil {
+		is.backendSyncProgress = NewSyncProgressEstimator(backendSyncProgressWindow)
+	}
+	e := is.backendSyncProgress
+	is.mux.Unlock()
+	e.AddSample(progress, time.Now())
+}
+
+// GetBackendSyncETA returns the estimated time to full synchronization of the backend, false if it is not known
+func (is *InternalState) GetBackendSyncETA() (time.Duration, bool) {
+	is.mux.Lock()
+	e := is.backendSyncProgress
+	is.mux.Unlock()
+	if e == nil {
+		return 0, false
+	}
+	return e.ETA()
+}
+
 // AddDBColumnStats adds differences in column statistics to column stats
 func (is *InternalState) AddDBColumnStats(c int, rowsDiff int64, keyBytesDiff int64, valueBytesDiff int64) {
 	is.mux.Lock()
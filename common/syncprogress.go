@@ -0,0 +1,73 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// syncedProgress is the verification progress considered as fully synchronized
+const syncedProgress = 0.9999
+
+type progressSample struct {
+	time     time.Time
+	progress float64
+}
+
+// SyncProgressEstimator estimates the time to full synchronization of the backend
+// from the samples of its verification progress in a sliding time window
+type SyncProgressEstimator struct {
+	mux     sync.Mutex
+	window  time.Duration
+	samples []progressSample
+}
+
+// NewSyncProgressEstimator returns new SyncProgressEstimator computing the rate of progress over the given window
+func NewSyncProgressEstimator(window time.Duration) *SyncProgressEstimator {
+	return &SyncProgressEstimator{window: window}
+}
+
+// AddSample adds the verification progress (0..1) of the backend at time t, the samples older than the window are discarded
+func (e *SyncProgressEstimator) AddSample(progress float64, t time.Time) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	// the progress can drop for example after restart of the backend, the older samples are not relevant anymore
+	if l := len(e.samples); l > 0 && (progress < e.samples[l-1].progress || t.Before(e.samples[l-1].time)) {
+		e.samples = e.samples[:0]
+	}
+	e.samples = append(e.samples, progressSample{time: t, progress: progress})
+	i := 0
+	for i < len(e.samples)-1 && t.Sub(e.samples[i].time) > e.window {
+		i++
+	}
+	e.samples = e.samples[i:]
+}
+
+// ETA returns the estimated time to full synchronization, false if the time cannot be estimated
+// because there are not enough samples or the progress stalled
+func (e *SyncProgressEstimator) ETA() (time.Duration, bool) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	l := len(e.samples)
+	if l == 0 {
+		return 0, false
+	}
+	last := e.samples[l-1]
+	if last.progress >= syncedProgress {
+		return 0, true
+	}
+	if l < 2 {
+		return 0, false
+	}
+	first := e.samples[0]
+	elapsed := last.time.Sub(first.time)
+	done := last.progress - first.progress
+	if elapsed <= 0 || done <= 0 {
+		return 0, false
+	}
+	eta := time.Duration(float64(elapsed) * (1 - last.progress) / done)
+	// eta overflow, the progress is too slow to be meaningful
+	if eta < 0 {
+		return 0, false
+	}
+	return eta, true
+}
@@ -0,0 +1,73 @@
+// +build unittest
+
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncProgressEstimator(t *testing.T) {
+	start := time.Unix(1550000000, 0)
+	type sample struct {
+		offset   time.Duration
+		progress float64
+	}
+	tests := []struct {
+		name    string
+		samples []sample
+		wantETA time.Duration
+		wantOK  bool
+	}{
+		{
+			name: "no samples",
+		},
+		{
+			name:    "single sample",
+			samples: []sample{{0, 0.5}},
+		},
+		{
+			name:    "synchronized",
+			samples: []sample{{0, 0.99995}},
+			wantOK:  true,
+		},
+		{
+			name:    "constant rate",
+			samples: []sample{{0, 0.1}, {time.Minute, 0.11}, {2 * time.Minute, 0.12}},
+			// 0.01 per minute, 0.88 remaining
+			wantETA: 88 * time.Minute,
+			wantOK:  true,
+		},
+		{
+			name:    "rate over the window",
+			samples: []sample{{0, 0.1}, {30 * time.Minute, 0.5}, {90 * time.Minute, 0.6}, {120 * time.Minute, 0.7}},
+			// the first two samples are out of the window, 0.1 in 30 minutes with 0.3 remaining
+			wantETA: 90 * time.Minute,
+			wantOK:  true,
+		},
+		{
+			name:    "stalled",
+			samples: []sample{{0, 0.3}, {time.Minute, 0.3}, {2 * time.Minute, 0.3}},
+		},
+		{
+			name:    "progress dropped",
+			samples: []sample{{0, 0.3}, {time.Minute, 0.4}, {2 * time.Minute, 0.2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewSyncProgressEstimator(time.Hour)
+			for _, s := range tt.samples {
+				e.AddSample(s.progress, start.Add(s.offset))
+			}
+			eta, ok := e.ETA()
+			if ok != tt.wantOK {
+				t.Fatalf("ETA() ok = %v, want %v", ok, tt.wantOK)
+			}
+			// allow rounding errors of the floating point progress
+			if d := eta - tt.wantETA; d < -time.Second || d > time.Second {
+				t.Errorf("ETA() = %v, want %v", eta, tt.wantETA)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ import (
 type bulkAddresses struct {
 	bi        BlockInfo
 	addresses addressesMap
+	lockTimes []byte
 }
 
 // BulkConnect is used to connect blocks in bulk, faster but if interrupted inconsistent way
@@ -29,6 +30,9 @@ type BulkConnect struct {
 	balances           map[string]*AddrBalance
 	addressContracts   map[string]*AddrContracts
 	height             uint32
+	// checkpointInterval > 0 means that the data are stored only in consistent checkpoints
+	checkpointInterval    int
+	blocksSinceCheckpoint int
 }
 
 const (
@@ -42,6 +46,7 @@ const (
 )
 
 // InitBulkConnect initializes bulk connect and switches DB to inconsistent state
+// if the bulk checkpoints are set, the DB is kept in open state as it is consistent at the last checkpoint
 func (d *RocksDB) InitBulkConnect() (*BulkConnect, error) {
 	b := &BulkConnect{
 		d:                d,
@@ -50,6 +55,11 @@ func (d *RocksDB) InitBulkConnect() (*BulkConnect, error) {
 		balances:         make(map[string]*AddrBalance),
 		addressContracts: make(map[string]*AddrContracts),
 	}
+	if b.chainType == bchain.ChainBitcoinType && d.bulkCheckpointInterval > 0 {
+		b.checkpointInterval = d.bulkCheckpointInterval
+		glog.Info("rocksdb: bulk connect init, checkpoint each ", b.checkpointInterval, " blocks")
+		return b, nil
+	}
 	if err := d.SetInconsistentState(true); err != nil {
 		return nil, err
 	}
@@ -156,24 +166,105 @@ func (b *BulkConnect) parallelStoreBalances(c chan error, all bool) {
 }
 
 func (b *BulkConnect) storeBulkAddresses(wb *gorocksdb.WriteBatch) error {
+	dailyTxs := make(map[uint32]int64)
 	for _, ba := range b.bulkAddresses {
+		dailyTxs[blockDay(ba.bi.Time)] += int64(ba.bi.Txs)
 		if err := b.d.storeAddresses(wb, ba.bi.Height, ba.addresses); err != nil {
 			return err
 		}
 		if err := b.d.writeHeight(wb, ba.bi.Height, &ba.bi, opInsert); err != nil {
 			return err
 		}
+		b.d.storeLockTimes(wb, ba.bi.Height, ba.lockTimes)
+	}
+	if b.chainType == bchain.ChainBitcoinType {
+		if err := b.d.updateDailyTxs(wb, dailyTxs); err != nil {
+			return err
+		}
 	}
 	b.bulkAddressesCount = 0
 	b.bulkAddresses = b.bulkAddresses[:0]
 	return nil
 }
 
+// storeCheckpoint stores all cached data in one write batch, after the write the db is consistent at the current height
+func (b *BulkConnect) storeCheckpoint(wb *gorocksdb.WriteBatch) error {
+	start := time.Now()
+	bac := b.bulkAddressesCount
+	txs, _, err := b.storeTxAddresses(wb, true)
+	if err != nil {
+		return err
+	}
+	bal, err := b.storeBalances(wb, true)
+	if err != nil {
+		return err
+	}
+	if err := b.storeBulkAddresses(wb); err != nil {
+		return err
+	}
+	if err := b.d.db.Write(b.d.wo, wb); err != nil {
+		return err
+	}
+	b.blocksSinceCheckpoint = 0
+	glog.Info("rocksdb: height ", b.height, ", checkpoint stored ", bac, " addresses, ", txs, " txAddresses, ", bal, " balances, done in ", time.Since(start))
+	return nil
+}
+
+func (b *BulkConnect) connectBlockBitcoinTypeCheckpoints(block *bchain.Block, addresses addressesMap, lockTimes []byte, storeBlockTxs bool) error {
+	b.bulkAddresses = append(b.bulkAddresses, bulkAddresses{
+		bi: BlockInfo{
+			Hash:   block.Hash,
+			Time:   block.Time,
+			Txs:    uint32(len(block.Txs)),
+			Size:   uint32(block.Size),
+			Height: block.Height,
+		},
+		addresses: addresses,
+		lockTimes: lockTimes,
+	})
+	b.bulkAddressesCount += len(addresses)
+	b.blocksSinceCheckpoint++
+	// the partial stores would break the consistency of the db, store the checkpoint before the cache limits are exceeded
+	if b.blocksSinceCheckpoint >= b.checkpointInterval || len(b.txAddressesMap) > maxBulkTxAddresses ||
+		len(b.balances) > maxBulkBalances || b.bulkAddressesCount > maxBulkAddresses {
+		wb := gorocksdb.NewWriteBatch()
+		defer wb.Destroy()
+		if storeBlockTxs {
+			if err := b.d.storeAndCleanupBlockTxs(wb, block); err != nil {
+				return err
+			}
+		}
+		return b.storeCheckpoint(wb)
+	}
+	// blockTxs of blocks above the checkpoint are overwritten when the blocks are connected again after interruption
+	if storeBlockTxs {
+		wb := gorocksdb.NewWriteBatch()
+		defer wb.Destroy()
+		if err := b.d.storeAndCleanupBlockTxs(wb, block); err != nil {
+			return err
+		}
+		if err := b.d.db.Write(b.d.wo, wb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs bool) error {
 	addresses := make(addressesMap)
 	if err := b.d.processAddressesBitcoinType(block, addresses, b.txAddressesMap, b.balances); err != nil {
 		return err
 	}
+	var lockTimes []byte
+	if b.d.lockTimeIndex {
+		var err error
+		if lockTimes, err = b.d.packBlockLockTimes(block); err != nil {
+			return err
+		}
+	}
+	if b.checkpointInterval > 0 {
+		return b.connectBlockBitcoinTypeCheckpoints(block, addresses, lockTimes, storeBlockTxs)
+	}
 	var storeAddressesChan, storeBalancesChan chan error
 	var sa bool
 	if len(b.txAddressesMap) > maxBulkTxAddresses || len(b.balances) > maxBulkBalances {
@@ -196,6 +287,7 @@ func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs
 			Height: block.Height,
 		},
 		addresses: addresses,
+		lockTimes: lockTimes,
 	})
 	b.bulkAddressesCount += len(addresses)
 	// open WriteBatch only if going to write
@@ -345,6 +437,16 @@ func (b *BulkConnect) ConnectBlock(block *bchain.Block, storeBlockTxs bool) erro
 // after Close, the BulkConnect cannot be used
 func (b *BulkConnect) Close() error {
 	glog.Info("rocksdb: bulk connect closing")
+	if b.checkpointInterval > 0 {
+		wb := gorocksdb.NewWriteBatch()
+		defer wb.Destroy()
+		if err := b.storeCheckpoint(wb); err != nil {
+			return err
+		}
+		glog.Info("rocksdb: bulk connect closed")
+		b.d = nil
+		return nil
+	}
 	start := time.Now()
 	var storeTxAddressesChan, storeBalancesChan, storeAddressContractsChan chan error
 	if b.chainType == bchain.ChainBitcoinType {
@@ -4,13 +4,16 @@ import (
 	"blockbook/bchain"
 	"blockbook/common"
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"math/big"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	vlq "github.com/bsm/go-vlq"
@@ -55,7 +58,31 @@ type RocksDB struct {
 	cache        *gorocksdb.Cache
 	maxOpenFiles int
 	cbs          connectBlockStats
-}
+	// store P2PKH/P2SH address keys in the compact form, see compactAddrDesc
+	compactAddrKeys bool
+	// number of blocks between consistent checkpoints of the bulk connect, see SetBulkCheckpointInterval
+	bulkCheckpointInterval int
+	// handling of coinbase transactions with the txid of an already indexed transaction, see SetDuplicateTxPolicy
+	duplicateTxPolicy DuplicateTxPolicy
+	// blocks are connected without the address index, see SetNoAddressIndex
+	noAddressIndex bool
+	// transactions with non-zero lock time are indexed, see SetLockTimeIndex
+	lockTimeIndex bool
+	// the transaction counts per day are indexed, see SetDailyTxsIndex
+	dailyTxsIndex bool
+}
+
+// DuplicateTxPolicy specifies how the index handles a coinbase transaction with the txid of an already indexed transaction,
+// such transactions exist in the old blocks of some chains (see BIP30)
+type DuplicateTxPolicy int
+
+const (
+	// DuplicateTxOverwrite replaces the indexed transaction by the new one,
+	// the outputs of the original transaction stay in the balances of the addresses
+	DuplicateTxOverwrite DuplicateTxPolicy = iota
+	// DuplicateTxKeep keeps the indexed transaction and does not index the new one
+	DuplicateTxKeep
+)
 
 const (
 	cfDefault = iota
@@ -66,6 +93,10 @@ const (
 	// BitcoinType
 	cfAddressBalance
 	cfTxAddresses
+	cfScriptHashes
+	cfDailyTxs
+	cfLockTimes
+	cfLockTimeBlocks
 	// EthereumType
 	cfAddressContracts = cfAddressBalance
 )
@@ -74,7 +105,7 @@ const (
 var cfNames = []string{"default", "height", "addresses", "blockTxs", "transactions"}
 
 // type specific columns
-var cfNamesBitcoinType = []string{"addressBalance", "txAddresses"}
+var cfNamesBitcoinType = []string{"addressBalance", "txAddresses", "scriptHashes", "dailyTxs", "lockTimes", "lockTimeBlocks"}
 var cfNamesEthereumType = []string{"addressContracts"}
 
 func openDB(path string, c *gorocksdb.Cache, openFiles int) (*gorocksdb.DB, []*gorocksdb.ColumnFamilyHandle, error) {
@@ -118,7 +149,7 @@ func NewRocksDB(path string, cacheSize, maxOpenFiles int, parser bchain.BlockCha
 	}
 	wo := gorocksdb.NewDefaultWriteOptions()
 	ro := gorocksdb.NewDefaultReadOptions()
-	return &RocksDB{path, db, wo, ro, cfh, parser, nil, metrics, c, maxOpenFiles, connectBlockStats{}}, nil
+	return &RocksDB{path, db, wo, ro, cfh, parser, nil, metrics, c, maxOpenFiles, connectBlockStats{}, false, 0, DuplicateTxOverwrite, false, false, false}, nil
 }
 
 func (d *RocksDB) closeDB() error {
@@ -164,6 +195,68 @@ func (d *RocksDB) Reopen() error {
 	return nil
 }
 
+// SetCompactAddressKeys sets if the keys of the addresses column are stored in the compact form
+// The setting applies to both writing and reading, the db must contain the keys of only one form,
+// therefore the mode is recorded in the internal state and cannot be changed for an existing db
+func (d *RocksDB) SetCompactAddressKeys(compact bool) {
+	d.compactAddrKeys = compact
+}
+
+// SetBulkCheckpointInterval sets the number of blocks after which the bulk connect stores all cached data
+// to the db in one write batch so that the db is consistent at the checkpoint height
+// and an interrupted bulk import can resume from the last checkpoint
+// 0 disables the checkpoints, an interrupted bulk import then leaves the db in inconsistent state
+// the setting applies only to Bitcoin type coins, the blocks connected in normal mode are always stored one by one
+func (d *RocksDB) SetBulkCheckpointInterval(blocks int) {
+	d.bulkCheckpointInterval = blocks
+}
+
+// SetNoAddressIndex sets if the blocks are connected without the address index, i.e. without the columns addresses and addressBalance,
+// the transactions are indexed as usual; applicable only to Bitcoin type coins
+func (d *RocksDB) SetNoAddressIndex(noAddressIndex bool) {
+	d.noAddressIndex = noAddressIndex
+}
+
+// SetLockTimeIndex sets if the transactions with non-zero lock time are indexed in the columns lockTimes and lockTimeBlocks,
+// the index is optional as only few applications need it; applicable only to Bitcoin type coins
+func (d *RocksDB) SetLockTimeIndex(lockTimeIndex bool) {
+	d.lockTimeIndex = lockTimeIndex
+}
+
+// SetDailyTxsIndex sets if the transaction counts per UTC day are indexed in the column dailyTxs,
+// the total count of transactions is maintained regardless of the setting; applicable only to Bitcoin type coins
+func (d *RocksDB) SetDailyTxsIndex(dailyTxsIndex bool) {
+	d.dailyTxsIndex = dailyTxsIndex
+}
+
+// SetDuplicateTxPolicy sets the handling of coinbase transactions with the txid of an already indexed transaction
+// the setting applies only to Bitcoin type coins
+func (d *RocksDB) SetDuplicateTxPolicy(policy DuplicateTxPolicy) {
+	d.duplicateTxPolicy = policy
+}
+
+// isDuplicateTx checks if the coinbase transaction with btxID is already indexed, either in the db or in txAddressesMap
+// of the blocks being connected; it logs the duplicate and returns true if the transaction should be skipped
+func (d *RocksDB) isDuplicateTx(block *bchain.Block, tx *bchain.Tx, btxID []byte, txAddressesMap map[string]*TxAddresses) (bool, error) {
+	ta, found := txAddressesMap[string(btxID)]
+	if !found {
+		var err error
+		ta, err = d.getTxAddresses(btxID)
+		if err != nil {
+			return false, err
+		}
+		if ta == nil {
+			return false, nil
+		}
+	}
+	if d.duplicateTxPolicy == DuplicateTxKeep {
+		glog.Warningf("rocksdb: height %d, tx %v is duplicate of tx in block %d, keeping the original tx", block.Height, tx.Txid, ta.Height)
+		return true, nil
+	}
+	glog.Warningf("rocksdb: height %d, tx %v is duplicate of tx in block %d, overwriting the original tx", block.Height, tx.Txid, ta.Height)
+	return false, nil
+}
+
 func atoi(s string) int {
 	i, err := strconv.Atoi(s)
 	if err != nil {
@@ -228,25 +321,67 @@ func (d *RocksDB) GetTransactions(address string, lower uint32, higher uint32, f
 // GetAddrDescTransactions finds all input/output transactions for address descriptor
 // Transaction are passed to callback function in the order from newest block to the oldest
 func (d *RocksDB) GetAddrDescTransactions(addrDesc bchain.AddressDescriptor, lower uint32, higher uint32, fn GetTransactionsCallback) (err error) {
+	return d.getAddrDescTransactions(addrDesc, lower, higher, false, fn)
+}
+
+// GetAddrDescTransactionsAscending finds all input/output transactions for address descriptor
+// Transaction are passed to callback function in the order from oldest block to the newest,
+// the transactions in one block in the order of the block
+func (d *RocksDB) GetAddrDescTransactionsAscending(addrDesc bchain.AddressDescriptor, lower uint32, higher uint32, fn GetTransactionsCallback) (err error) {
+	return d.getAddrDescTransactions(addrDesc, lower, higher, true, fn)
+}
+
+// GetAddrDescFirstFundedHeight returns the height of the block with the first output paying to the address descriptor,
+// false if no output paying to the address descriptor is in the index
+func (d *RocksDB) GetAddrDescFirstFundedHeight(addrDesc bchain.AddressDescriptor) (uint32, bool, error) {
+	var height uint32
+	var found bool
+	err := d.GetAddrDescTransactionsAscending(addrDesc, 0, ^uint32(0), func(txid string, h uint32, indexes []int32) error {
+		for _, index := range indexes {
+			// the inputs have negative indexes
+			if index >= 0 {
+				height, found = h, true
+				return &StopIteration{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return height, found, nil
+}
+
+func (d *RocksDB) getAddrDescTransactions(addrDesc bchain.AddressDescriptor, lower uint32, higher uint32, ascending bool, fn GetTransactionsCallback) (err error) {
 	txidUnpackedLen := d.chainParser.PackedTxidLen()
-	startKey := packAddressKey(addrDesc, higher)
-	stopKey := packAddressKey(addrDesc, lower)
-	indexes := make([]int32, 0, 16)
 	it := d.db.NewIteratorCF(d.ro, d.cfh[cfAddresses])
 	defer it.Close()
-	for it.Seek(startKey); it.Valid(); it.Next() {
-		key := it.Key().Data()
-		val := it.Value().Data()
-		if bytes.Compare(key, stopKey) > 0 {
+	var ai *addressKeyIterator
+	// the packed height is in binary complement, the keys of the newer blocks are lower
+	if ascending {
+		it.SeekForPrev(d.packAddressKey(addrDesc, lower))
+		ai = &addressKeyIterator{it: it, stopKey: d.packAddressKey(addrDesc, higher), ascending: true}
+	} else {
+		it.Seek(d.packAddressKey(addrDesc, higher))
+		ai = &addressKeyIterator{it: it, stopKey: d.packAddressKey(addrDesc, lower)}
+	}
+	indexes := make([]int32, 0, 16)
+	var ascendingTxs []txidIndexes
+	for ; ; ai.next() {
+		key, ok := ai.key()
+		if !ok {
 			break
 		}
+		val := ai.it.Value().Data()
 		if glog.V(2) {
 			glog.Infof("rocksdb: addresses %s: %s", hex.EncodeToString(key), hex.EncodeToString(val))
 		}
-		_, height, err := unpackAddressKey(key)
+		_, height, err := d.unpackAddressKey(key)
 		if err != nil {
 			return err
 		}
+		// the txs of one block are stored from the newest to the oldest, in the ascending order they must be reversed
+		ascendingTxs = ascendingTxs[:0]
 		for len(val) > txidUnpackedLen {
 			tx, err := d.chainParser.UnpackTxid(val[:txidUnpackedLen])
 			if err != nil {
@@ -265,6 +400,10 @@ func (d *RocksDB) GetAddrDescTransactions(addrDesc bchain.AddressDescriptor, low
 					break
 				}
 			}
+			if ascending {
+				ascendingTxs = append(ascendingTxs, txidIndexes{tx, append([]int32(nil), indexes...)})
+				continue
+			}
 			if err := fn(tx, height, indexes); err != nil {
 				if _, ok := err.(*StopIteration); ok {
 					return nil
@@ -275,10 +414,52 @@ func (d *RocksDB) GetAddrDescTransactions(addrDesc bchain.AddressDescriptor, low
 		if len(val) != 0 {
 			glog.Warningf("rocksdb: addresses contain incorrect data %s: %s", hex.EncodeToString(key), hex.EncodeToString(val))
 		}
+		for i := len(ascendingTxs) - 1; i >= 0; i-- {
+			if err := fn(ascendingTxs[i].txid, height, ascendingTxs[i].indexes); err != nil {
+				if _, ok := err.(*StopIteration); ok {
+					return nil
+				}
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+type txidIndexes struct {
+	txid    string
+	indexes []int32
+}
+
+// addressKeyIterator iterates over the keys of an address in the addresses column
+type addressKeyIterator struct {
+	it      *gorocksdb.Iterator
+	stopKey []byte
+	// ascending iterator goes from the oldest block to the newest, i.e. in the reverse order of the keys
+	ascending bool
+}
+
+// key returns the current key of the iterator or false if the iterator is out of range
+func (a *addressKeyIterator) key() ([]byte, bool) {
+	if !a.it.Valid() {
+		return nil, false
+	}
+	key := a.it.Key().Data()
+	c := bytes.Compare(key, a.stopKey)
+	if (c > 0 && !a.ascending) || (c < 0 && a.ascending) {
+		return nil, false
+	}
+	return key, true
+}
+
+func (a *addressKeyIterator) next() {
+	if a.ascending {
+		a.it.Prev()
+	} else {
+		a.it.Next()
+	}
+}
+
 const (
 	opInsert = 0
 	opDelete = 1
@@ -314,6 +495,16 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 		if err := d.storeAndCleanupBlockTxs(wb, block); err != nil {
 			return err
 		}
+		if err := d.updateDailyTxs(wb, map[uint32]int64{blockDay(block.Time): int64(len(block.Txs))}); err != nil {
+			return err
+		}
+		if d.lockTimeIndex {
+			lockTimes, err := d.packBlockLockTimes(block)
+			if err != nil {
+				return err
+			}
+			d.storeLockTimes(wb, block.Height, lockTimes)
+		}
 	} else if chainType == bchain.ChainEthereumType {
 		addressContracts := make(map[string]*AddrContracts)
 		blockTxs, err := d.processAddressesEthereumType(block, addresses, addressContracts)
@@ -381,8 +572,14 @@ type TxAddresses struct {
 	Height  uint32
 	Inputs  []TxInput
 	Outputs []TxOutput
+	// Coinbase is set for the coinbase transaction of the block
+	Coinbase bool
 }
 
+// txAddressesFlagCoinbase is the flag of the coinbase transaction stored after the outputs of packed TxAddresses
+// the flags are stored only if some is set, TxAddresses packed by older versions do not contain them
+const txAddressesFlagCoinbase = 1
+
 // AddrBalance stores number of transactions and balances of an address
 type AddrBalance struct {
 	Txs        uint32
@@ -431,6 +628,17 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 		}
 		blockTxIDs[txi] = btxID
 		ta := TxAddresses{Height: block.Height}
+		ta.Coinbase = len(tx.Vin) > 0 && tx.Vin[0].Coinbase != ""
+		// only coinbase transactions can have duplicate txids, the check of other transactions is not necessary
+		if ta.Coinbase {
+			skip, err := d.isDuplicateTx(block, tx, btxID, txAddressesMap)
+			if err != nil {
+				return err
+			}
+			if skip {
+				continue
+			}
+		}
 		ta.Outputs = make([]TxOutput, len(tx.Vout))
 		txAddressesMap[string(btxID)] = &ta
 		blockTxAddresses[txi] = &ta
@@ -450,6 +658,9 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 				continue
 			}
 			tao.AddrDesc = addrDesc
+			if d.noAddressIndex {
+				continue
+			}
 			strAddrDesc := string(addrDesc)
 			ab, e := balances[strAddrDesc]
 			if !e {
@@ -472,11 +683,20 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 			}
 		}
 	}
+	// the outputs of this block are already in txAddressesMap, load the other spent transactions at once
+	prefetched, err := d.prefetchTxAddresses(block, txAddressesMap)
+	if err != nil {
+		return err
+	}
 	// process inputs
 	for txi := range block.Txs {
 		tx := &block.Txs[txi]
 		spendingTxid := blockTxIDs[txi]
 		ta := blockTxAddresses[txi]
+		// skipped duplicate transaction
+		if ta == nil {
+			continue
+		}
 		ta.Inputs = make([]TxInput, len(tx.Vin))
 		logged := false
 		for i, input := range tx.Vin {
@@ -503,6 +723,10 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 				}
 				txAddressesMap[stxID] = ita
 				d.cbs.txAddressesMiss++
+			} else if _, p := prefetched[stxID]; p {
+				// the transaction was loaded from the db by the prefetch
+				delete(prefetched, stxID)
+				d.cbs.txAddressesMiss++
 			} else {
 				d.cbs.txAddressesHit++
 			}
@@ -525,6 +749,9 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 				}
 				continue
 			}
+			if d.noAddressIndex {
+				continue
+			}
 			strAddrDesc := string(ot.AddrDesc)
 			ab, e := balances[strAddrDesc]
 			if !e {
@@ -578,15 +805,61 @@ func addToAddressesMap(addresses addressesMap, strAddrDesc string, btxID []byte,
 }
 
 func (d *RocksDB) storeAddresses(wb *gorocksdb.WriteBatch, height uint32, addresses addressesMap) error {
+	bitcoinType := d.chainParser.GetChainType() == bchain.ChainBitcoinType
 	for addrDesc, txi := range addresses {
 		ba := bchain.AddressDescriptor(addrDesc)
-		key := packAddressKey(ba, height)
+		key := d.packAddressKey(ba, height)
 		val := d.packTxIndexes(txi)
 		wb.PutCF(d.cfh[cfAddresses], key, val)
+		if bitcoinType && d.isScriptHashIndexed(ba) {
+			h := sha256.Sum256(ba)
+			wb.PutCF(d.cfh[cfScriptHashes], h[:], ba)
+		}
 	}
 	return nil
 }
 
+// isScriptHashIndexed returns true for output scripts which cannot be searched by an address,
+// for example bare multisig or nonstandard scripts, such scripts can be found by their sha256 hash
+// the script is classified only by its structure, the address descriptor is not decoded
+func (d *RocksDB) isScriptHashIndexed(addrDesc bchain.AddressDescriptor) bool {
+	// P2PKH and P2SH
+	if compactAddrDescType(addrDesc) != compactAddrDescFull {
+		return false
+	}
+	switch {
+	case len(addrDesc) == 0:
+		return false
+	// OP_RETURN outputs cannot be spent
+	case addrDesc[0] == 0x6a:
+		return false
+	// P2WPKH - OP_0 <20 bytes>
+	case len(addrDesc) == 22 && addrDesc[0] == 0x00 && addrDesc[1] == 0x14:
+		return false
+	// P2WSH - OP_0 <32 bytes>
+	case len(addrDesc) == 34 && addrDesc[0] == 0x00 && addrDesc[1] == 0x20:
+		return false
+	}
+	return true
+}
+
+// GetAddrDescByScriptHash returns the output script (address descriptor) with the given sha256 hash
+// only scripts not searchable by an address are indexed, returns nil if the hash is not found
+func (d *RocksDB) GetAddrDescByScriptHash(hash []byte) (bchain.AddressDescriptor, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil, nil
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfScriptHashes], hash)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	if val.Size() == 0 {
+		return nil, nil
+	}
+	return append(bchain.AddressDescriptor(nil), val.Data()...), nil
+}
+
 func (d *RocksDB) storeTxAddresses(wb *gorocksdb.WriteBatch, am map[string]*TxAddresses) error {
 	varBuf := make([]byte, maxPackedBigintBytes)
 	buf := make([]byte, 1024)
@@ -703,6 +976,28 @@ func (d *RocksDB) getBlockTxs(height uint32) ([]blockTxs, error) {
 	return bt, nil
 }
 
+// GetBlockTxids returns the txids of the block at given height in the order of the block,
+// the txids are kept only for the last blocks, nil is returned for older blocks
+func (d *RocksDB) GetBlockTxids(height uint32) ([]string, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil, nil
+	}
+	bt, err := d.getBlockTxs(height)
+	if err != nil {
+		return nil, err
+	}
+	if len(bt) == 0 {
+		return nil, nil
+	}
+	txids := make([]string, len(bt))
+	for i := range bt {
+		if txids[i], err = d.chainParser.UnpackTxid(bt[i].btxID); err != nil {
+			return nil, err
+		}
+	}
+	return txids, nil
+}
+
 // GetAddrDescBalance returns AddrBalance for given addrDesc
 func (d *RocksDB) GetAddrDescBalance(addrDesc bchain.AddressDescriptor) (*AddrBalance, error) {
 	val, err := d.db.GetCF(d.ro, d.cfh[cfAddressBalance], addrDesc)
@@ -734,6 +1029,60 @@ func (d *RocksDB) GetAddressBalance(address string) (*AddrBalance, error) {
 	return d.GetAddrDescBalance(addrDesc)
 }
 
+// prefetchTxAddresses loads to txAddressesMap the TxAddresses of all transactions spent by the inputs of the block
+// which are not yet in the map, the outputs of the transactions of the block must be already in the map, they are not looked up;
+// the keys are deduplicated and sorted and loaded by one MultiGet so that the lookups go through the column in order
+// the transactions which are not found are not added to the map, they are handled as unknown inputs later
+// the returned set contains the loaded transactions, their first lookup from the map is counted as a miss of the cache
+func (d *RocksDB) prefetchTxAddresses(block *bchain.Block, txAddressesMap map[string]*TxAddresses) (map[string]struct{}, error) {
+	unique := make(map[string]struct{})
+	for txi := range block.Txs {
+		for _, input := range block.Txs[txi].Vin {
+			btxID, err := d.chainParser.PackTxid(input.Txid)
+			if err != nil {
+				// the inputs without txid and the invalid txids are handled by the processing of the inputs
+				continue
+			}
+			stxID := string(btxID)
+			if _, found := txAddressesMap[stxID]; !found {
+				unique[stxID] = struct{}{}
+			}
+		}
+	}
+	if len(unique) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(unique))
+	for k := range unique {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	bkeys := make([][]byte, len(keys))
+	for i := range keys {
+		bkeys[i] = []byte(keys[i])
+	}
+	vals, err := d.db.MultiGetCF(d.ro, d.cfh[cfTxAddresses], bkeys...)
+	if err != nil {
+		return nil, err
+	}
+	defer vals.Destroy()
+	loaded := make(map[string]struct{}, len(keys))
+	for i, k := range keys {
+		buf := vals[i].Data()
+		// see getTxAddresses for the minimal length
+		if len(buf) < 3 {
+			continue
+		}
+		ta, err := unpackTxAddresses(buf)
+		if err != nil {
+			return nil, err
+		}
+		txAddressesMap[k] = ta
+		loaded[k] = struct{}{}
+	}
+	return loaded, nil
+}
+
 func (d *RocksDB) getTxAddresses(btxID []byte) (*TxAddresses, error) {
 	val, err := d.db.GetCF(d.ro, d.cfh[cfTxAddresses], btxID)
 	if err != nil {
@@ -790,6 +1139,9 @@ func packTxAddresses(ta *TxAddresses, buf []byte, varBuf []byte) []byte {
 	for i := range ta.Outputs {
 		buf = appendTxOutput(&ta.Outputs[i], buf, varBuf)
 	}
+	if ta.Coinbase {
+		buf = append(buf, txAddressesFlagCoinbase)
+	}
 	return buf
 }
 
@@ -832,6 +1184,9 @@ func unpackTxAddresses(buf []byte) (*TxAddresses, error) {
 	for i := uint(0); i < outputs; i++ {
 		l += unpackTxOutput(&ta.Outputs[i], buf[l:])
 	}
+	if l < len(buf) {
+		ta.Coinbase = buf[l]&txAddressesFlagCoinbase != 0
+	}
 	return &ta, nil
 }
 
@@ -985,6 +1340,34 @@ func (d *RocksDB) GetBlockHash(height uint32) (string, error) {
 	return info.Hash, nil
 }
 
+// FindBlocksByHashPrefix returns in ascending order the heights of at most limit blocks whose hash starts with the (lowercase hex) prefix,
+// only the lastBlocks blocks at the tip of the column height are scanned
+func (d *RocksDB) FindBlocksByHashPrefix(prefix string, lastBlocks int, limit int) ([]uint32, error) {
+	var heights []uint32
+	pl := d.chainParser.PackedTxidLen()
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfHeight])
+	defer it.Close()
+	scanned := 0
+	for it.SeekToLast(); it.Valid() && len(heights) < limit && scanned < lastBlocks; it.Prev() {
+		scanned++
+		val := it.Value().Data()
+		if len(val) < pl {
+			continue
+		}
+		hash, err := d.chainParser.UnpackBlockHash(val[:pl])
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(hash, prefix) {
+			heights = append(heights, unpackUint(it.Key().Data()))
+		}
+	}
+	for i, j := 0, len(heights)-1; i < j; i, j = i+1, j-1 {
+		heights[i], heights[j] = heights[j], heights[i]
+	}
+	return heights, nil
+}
+
 // GetBlockInfo returns block info stored in db
 func (d *RocksDB) GetBlockInfo(height uint32) (*BlockInfo, error) {
 	key := packUint(height)
@@ -1028,6 +1411,306 @@ func (d *RocksDB) writeHeight(wb *gorocksdb.WriteBatch, height uint32, bi *Block
 	return nil
 }
 
+// Daily transactions index
+
+const secondsPerDay = 24 * 60 * 60
+
+// DailyTxs holds the number of transactions in the blocks with the time in one UTC day
+type DailyTxs struct {
+	Day uint32 // Day is the number of days since the unix epoch
+	Txs uint64
+}
+
+// blockDay returns the UTC day of the block time t as the number of days since the unix epoch
+func blockDay(t int64) uint32 {
+	return uint32(t / secondsPerDay)
+}
+
+// updateDailyTxs adds the deltas to the transaction counts of the days in column dailyTxs (if the index is enabled)
+// and to the total count of transactions, the days with no transactions are removed from the column
+func (d *RocksDB) updateDailyTxs(wb *gorocksdb.WriteBatch, deltas map[uint32]int64) error {
+	varBuf := make([]byte, vlq.MaxLen64)
+	var total int64
+	for day, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		total += delta
+		if !d.dailyTxsIndex {
+			continue
+		}
+		key := packUint(day)
+		val, err := d.db.GetCF(d.ro, d.cfh[cfDailyTxs], key)
+		if err != nil {
+			return err
+		}
+		var txs int64
+		if val.Size() > 0 {
+			c, _ := unpackVaruint(val.Data())
+			txs = int64(c)
+		}
+		val.Free()
+		txs += delta
+		if txs <= 0 {
+			wb.DeleteCF(d.cfh[cfDailyTxs], key)
+		} else {
+			l := packVaruint(uint(txs), varBuf)
+			wb.PutCF(d.cfh[cfDailyTxs], key, varBuf[:l])
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	txs, err := d.GetTotalTxs()
+	if err != nil {
+		return err
+	}
+	if total < 0 && uint64(-total) > txs {
+		txs = 0
+	} else {
+		txs = uint64(int64(txs) + total)
+	}
+	l := packVaruint(uint(txs), varBuf)
+	wb.PutCF(d.cfh[cfDefault], []byte(totalTxsKey), varBuf[:l])
+	return nil
+}
+
+// totalTxsKey is the key of the total count of the indexed transactions in the default column
+const totalTxsKey = "totalTxs"
+
+// GetTotalTxs returns the total count of the transactions in the indexed blocks,
+// if it is not stored (the db was indexed before it was maintained), it is computed from the blocks
+func (d *RocksDB) GetTotalTxs() (uint64, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfDefault], []byte(totalTxsKey))
+	if err != nil {
+		return 0, err
+	}
+	defer val.Free()
+	if val.Size() > 0 {
+		txs, _ := unpackVaruint(val.Data())
+		return uint64(txs), nil
+	}
+	return d.countBlockTxs()
+}
+
+// countBlockTxs sums the counts of the transactions of all blocks in column height
+func (d *RocksDB) countBlockTxs() (uint64, error) {
+	var txs uint64
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfHeight])
+	defer it.Close()
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		bi, err := d.unpackBlockInfo(it.Value().Data())
+		if err != nil {
+			return 0, err
+		}
+		if bi != nil {
+			txs += uint64(bi.Txs)
+		}
+	}
+	return txs, nil
+}
+
+// storeTotalTxs stores the total count of the transactions if it is not stored yet, so that it is not computed repeatedly
+func (d *RocksDB) storeTotalTxs() error {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfDefault], []byte(totalTxsKey))
+	if err != nil {
+		return err
+	}
+	stored := val.Size() > 0
+	val.Free()
+	if stored {
+		return nil
+	}
+	txs, err := d.countBlockTxs()
+	if err != nil {
+		return err
+	}
+	varBuf := make([]byte, vlq.MaxLen64)
+	l := packVaruint(uint(txs), varBuf)
+	return d.db.PutCF(d.wo, d.cfh[cfDefault], []byte(totalTxsKey), varBuf[:l])
+}
+
+// addressLabelsKey is the key of the labels of known addresses in the default column
+const addressLabelsKey = "addressLabels"
+
+// GetAddressLabels returns the stored labels of known addresses mapped by the address descriptors
+func (d *RocksDB) GetAddressLabels() (map[string]string, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfDefault], []byte(addressLabelsKey))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	labels := make(map[string]string)
+	for len(buf) > 0 {
+		addrDesc, l, ok := unpackLengthPrefixed(buf)
+		if !ok {
+			return nil, errors.New("Inconsistent data in address labels")
+		}
+		buf = buf[l:]
+		label, l, ok := unpackLengthPrefixed(buf)
+		if !ok {
+			return nil, errors.New("Inconsistent data in address labels")
+		}
+		buf = buf[l:]
+		labels[string(addrDesc)] = string(label)
+	}
+	return labels, nil
+}
+
+// StoreAddressLabels replaces the stored labels of known addresses by labels mapped by the address descriptors
+func (d *RocksDB) StoreAddressLabels(labels map[string]string) error {
+	if len(labels) == 0 {
+		return d.db.DeleteCF(d.wo, d.cfh[cfDefault], []byte(addressLabelsKey))
+	}
+	varBuf := make([]byte, vlq.MaxLen32)
+	buf := make([]byte, 0, 64*len(labels))
+	for addrDesc, label := range labels {
+		l := packVaruint(uint(len(addrDesc)), varBuf)
+		buf = append(buf, varBuf[:l]...)
+		buf = append(buf, addrDesc...)
+		l = packVaruint(uint(len(label)), varBuf)
+		buf = append(buf, varBuf[:l]...)
+		buf = append(buf, label...)
+	}
+	return d.db.PutCF(d.wo, d.cfh[cfDefault], []byte(addressLabelsKey), buf)
+}
+
+// unpackLengthPrefixed returns the data prefixed by their varuint packed length and the number of consumed bytes
+func unpackLengthPrefixed(buf []byte) ([]byte, int, bool) {
+	n, l := unpackVaruint(buf)
+	if l <= 0 || len(buf)-l < int(n) {
+		return nil, 0, false
+	}
+	return buf[l : l+int(n)], l + int(n), true
+}
+
+// GetDailyTxs returns the number of transactions in the UTC days fromDay..toDay (days since the unix epoch)
+// ordered by the day, the days without any transaction are not returned
+func (d *RocksDB) GetDailyTxs(fromDay, toDay uint32) ([]DailyTxs, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil, nil
+	}
+	var r []DailyTxs
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfDailyTxs])
+	defer it.Close()
+	for it.Seek(packUint(fromDay)); it.Valid(); it.Next() {
+		day := unpackUint(it.Key().Data())
+		if day > toDay {
+			break
+		}
+		txs, _ := unpackVaruint(it.Value().Data())
+		r = append(r, DailyTxs{Day: day, Txs: uint64(txs)})
+	}
+	return r, nil
+}
+
+// Lock time index
+
+// LockTimeTx is a transaction with non-zero lock time
+type LockTimeTx struct {
+	LockTime uint32
+	Height   uint32
+	Txid     string
+}
+
+// packBlockLockTimes packs the lock times and txids of the transactions of the block with non-zero lock time,
+// the result is the value of the lockTimeBlocks column, nil if there is no such transaction
+func (d *RocksDB) packBlockLockTimes(block *bchain.Block) ([]byte, error) {
+	var buf []byte
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		if tx.LockTime == 0 {
+			continue
+		}
+		btxID, err := d.chainParser.PackTxid(tx.Txid)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, packUint(tx.LockTime)...)
+		buf = append(buf, btxID...)
+	}
+	return buf, nil
+}
+
+// storeLockTimes stores the lock times of the block packed by packBlockLockTimes to the columns lockTimes and lockTimeBlocks,
+// the key of lockTimes is lock time+height+txid so that the transactions can be iterated in the order of the lock time
+func (d *RocksDB) storeLockTimes(wb *gorocksdb.WriteBatch, height uint32, lockTimes []byte) {
+	if len(lockTimes) == 0 {
+		return
+	}
+	key := packUint(height)
+	l := 4 + d.chainParser.PackedTxidLen()
+	for i := 0; i+l <= len(lockTimes); i += l {
+		wb.PutCF(d.cfh[cfLockTimes], lockTimeKey(lockTimes[i:i+l], key), []byte{})
+	}
+	wb.PutCF(d.cfh[cfLockTimeBlocks], key, lockTimes)
+}
+
+// disconnectLockTimes removes the lock times of the block at height from the columns lockTimes and lockTimeBlocks
+func (d *RocksDB) disconnectLockTimes(wb *gorocksdb.WriteBatch, height uint32) error {
+	key := packUint(height)
+	val, err := d.db.GetCF(d.ro, d.cfh[cfLockTimeBlocks], key)
+	if err != nil {
+		return err
+	}
+	defer val.Free()
+	lockTimes := val.Data()
+	if len(lockTimes) == 0 {
+		return nil
+	}
+	l := 4 + d.chainParser.PackedTxidLen()
+	for i := 0; i+l <= len(lockTimes); i += l {
+		wb.DeleteCF(d.cfh[cfLockTimes], lockTimeKey(lockTimes[i:i+l], key))
+	}
+	wb.DeleteCF(d.cfh[cfLockTimeBlocks], key)
+	return nil
+}
+
+// lockTimeKey creates the key of the lockTimes column from the entry lock time+txid and the packed height
+func lockTimeKey(entry []byte, height []byte) []byte {
+	key := make([]byte, 0, len(entry)+4)
+	key = append(key, entry[:4]...)
+	key = append(key, height...)
+	return append(key, entry[4:]...)
+}
+
+// GetLockTimeTxs returns at most limit transactions with the lock time in the range from..to (both inclusive)
+// ordered by the lock time and height, the transactions are returned only if the lock time index is built;
+// if after is set, only the transactions following it in the order are returned and from is ignored
+func (d *RocksDB) GetLockTimeTxs(from, to uint32, after *LockTimeTx, limit int) ([]LockTimeTx, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil, nil
+	}
+	seek := packUint(from)
+	if after != nil {
+		btxID, err := d.chainParser.PackTxid(after.Txid)
+		if err != nil {
+			return nil, err
+		}
+		seek = lockTimeKey(append(packUint(after.LockTime), btxID...), packUint(after.Height))
+	}
+	var r []LockTimeTx
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfLockTimes])
+	defer it.Close()
+	for it.Seek(seek); it.Valid() && len(r) < limit; it.Next() {
+		key := it.Key().Data()
+		if len(key) < 8 || after != nil && bytes.Equal(key, seek) {
+			continue
+		}
+		lockTime := unpackUint(key)
+		if lockTime > to {
+			break
+		}
+		txid, err := d.chainParser.UnpackTxid(key[8:])
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, LockTimeTx{LockTime: lockTime, Height: unpackUint(key[4:]), Txid: txid})
+	}
+	return r, nil
+}
+
 // Disconnect blocks
 
 func (d *RocksDB) disconnectTxAddresses(wb *gorocksdb.WriteBatch, height uint32, txid string, inputs []outpoint, txa *TxAddresses,
@@ -1053,24 +1736,27 @@ func (d *RocksDB) disconnectTxAddresses(wb *gorocksdb.WriteBatch, height uint32,
 			if !exist {
 				addresses[s] = struct{}{}
 			}
-			b, err := getAddressBalance(t.AddrDesc)
-			if err != nil {
-				return err
-			}
-			if b != nil {
-				// subtract number of txs only once
-				if !exist {
-					b.Txs--
+			if !d.noAddressIndex {
+				b, err := getAddressBalance(t.AddrDesc)
+				if err != nil {
+					return err
 				}
-				b.SentSat.Sub(&b.SentSat, &t.ValueSat)
-				if b.SentSat.Sign() < 0 {
-					d.resetValueSatToZero(&b.SentSat, t.AddrDesc, "sent amount")
+				if b != nil {
+					// subtract number of txs only once
+					if !exist {
+						b.Txs--
+					}
+					b.SentSat.Sub(&b.SentSat, &t.ValueSat)
+					if b.SentSat.Sign() < 0 {
+						d.resetValueSatToZero(&b.SentSat, t.AddrDesc, "sent amount")
+					}
+					b.BalanceSat.Add(&b.BalanceSat, &t.ValueSat)
+				} else {
+					ad, _, _ := d.chainParser.GetAddressesFromAddrDesc(t.AddrDesc)
+					glog.Warningf("Balance for address %s (%s) not found", ad, t.AddrDesc)
 				}
-				b.BalanceSat.Add(&b.BalanceSat, &t.ValueSat)
-			} else {
-				ad, _, _ := d.chainParser.GetAddressesFromAddrDesc(t.AddrDesc)
-				glog.Warningf("Balance for address %s (%s) not found", ad, t.AddrDesc)
 			}
+			var err error
 			s = string(inputs[i].btxID)
 			sa, exist := txAddressesToUpdate[s]
 			if !exist {
@@ -1088,7 +1774,7 @@ func (d *RocksDB) disconnectTxAddresses(wb *gorocksdb.WriteBatch, height uint32,
 		}
 	}
 	for _, t := range txa.Outputs {
-		if len(t.AddrDesc) > 0 {
+		if len(t.AddrDesc) > 0 && !d.noAddressIndex {
 			s := string(t.AddrDesc)
 			_, exist := addresses[s]
 			if !exist {
@@ -1114,8 +1800,13 @@ func (d *RocksDB) disconnectTxAddresses(wb *gorocksdb.WriteBatch, height uint32,
 		}
 	}
 	for a := range addresses {
-		key := packAddressKey([]byte(a), height)
+		key := d.packAddressKey([]byte(a), height)
 		wb.DeleteCF(d.cfh[cfAddresses], key)
+		// the script hash is removed together with the last transaction of the script
+		if b := balances[a]; b != nil && b.Txs <= 0 && d.isScriptHashIndexed(bchain.AddressDescriptor(a)) {
+			h := sha256.Sum256([]byte(a))
+			wb.DeleteCF(d.cfh[cfScriptHashes], h[:])
+		}
 	}
 	return nil
 }
@@ -1139,8 +1830,20 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 	txAddressesToUpdate := make(map[string]*TxAddresses)
 	txsToDelete := make(map[string]struct{})
 	balances := make(map[string]*AddrBalance)
+	dailyTxs := make(map[uint32]int64)
 	for height := higher; height >= lower; height-- {
 		blockTxs := blocks[height-lower]
+		bi, err := d.GetBlockInfo(height)
+		if err != nil {
+			return err
+		}
+		if bi != nil {
+			dailyTxs[blockDay(bi.Time)] -= int64(bi.Txs)
+		}
+		// the lock times are removed even if the index is not enabled now, they could be stored before
+		if err := d.disconnectLockTimes(wb, height); err != nil {
+			return err
+		}
 		glog.Info("Disconnecting block ", height, " containing ", len(blockTxs), " transactions")
 		// go backwards to avoid interim negative balance
 		// when connecting block, amount is first in tx on the output side, then in another tx on the input side
@@ -1148,7 +1851,6 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 		for i := len(blockTxs) - 1; i >= 0; i-- {
 			txid := blockTxs[i].btxID
 			s := string(txid)
-			txsToDelete[s] = struct{}{}
 			txa, err := d.getTxAddresses(txid)
 			if err != nil {
 				return err
@@ -1156,8 +1858,16 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 			if txa == nil {
 				ut, _ := d.chainParser.UnpackTxid(txid)
 				glog.Warning("TxAddress for txid ", ut, " not found")
+				txsToDelete[s] = struct{}{}
+				continue
+			}
+			// the duplicate transaction kept from an older block is not disconnected
+			if txa.Height != height {
+				ut, _ := d.chainParser.UnpackTxid(txid)
+				glog.Warning("TxAddress for txid ", ut, " belongs to block ", txa.Height, ", skipping duplicate tx")
 				continue
 			}
+			txsToDelete[s] = struct{}{}
 			if err := d.disconnectTxAddresses(wb, height, s, blockTxs[i].inputs, txa, txAddressesToUpdate, balances); err != nil {
 				return err
 			}
@@ -1168,6 +1878,9 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 	}
 	d.storeTxAddresses(wb, txAddressesToUpdate)
 	d.storeBalances(wb, balances)
+	if err := d.updateDailyTxs(wb, dailyTxs); err != nil {
+		return err
+	}
 	for s := range txsToDelete {
 		b := []byte(s)
 		wb.DeleteCF(d.cfh[cfTransactions], b)
@@ -1193,6 +1906,17 @@ func dirSize(path string) (int64, error) {
 	return size, err
 }
 
+// CompactDatabase runs the manual compaction of all columns of the database
+func (d *RocksDB) CompactDatabase() {
+	start := time.Now()
+	glog.Info("rocksdb: compaction start")
+	for i, h := range d.cfh {
+		d.db.CompactRangeCF(h, gorocksdb.Range{})
+		glog.Info("rocksdb: compacted column ", cfNames[i], ", elapsed ", time.Since(start))
+	}
+	glog.Info("rocksdb: compaction finished in ", time.Since(start))
+}
+
 // DatabaseSizeOnDisk returns size of the database in bytes
 func (d *RocksDB) DatabaseSizeOnDisk() int64 {
 	size, err := dirSize(d.path)
@@ -1313,6 +2037,11 @@ func (d *RocksDB) LoadInternalState(rpcCoin string) (*common.InternalState, erro
 		}
 	}
 	is.DbColumns = nc
+	if d.chainParser.GetChainType() == bchain.ChainBitcoinType {
+		if err := d.storeTotalTxs(); err != nil {
+			return nil, err
+		}
+	}
 	// after load, reset the synchronization data
 	is.IsSynchronized = false
 	is.IsMempoolSynchronized = false
@@ -1418,6 +2147,14 @@ func (d *RocksDB) ComputeInternalStateColumnStats(stopCompute chan os.Signal) er
 
 // Helpers
 
+// packAddressKey packs the key of the addresses column in the form set by SetCompactAddressKeys
+func (d *RocksDB) packAddressKey(addrDesc bchain.AddressDescriptor, height uint32) []byte {
+	if d.compactAddrKeys {
+		return packAddressKey(compactAddrDesc(addrDesc), height)
+	}
+	return packAddressKey(addrDesc, height)
+}
+
 func packAddressKey(addrDesc bchain.AddressDescriptor, height uint32) []byte {
 	buf := make([]byte, len(addrDesc)+packedHeightBytes)
 	copy(buf, addrDesc)
@@ -1426,6 +2163,19 @@ func packAddressKey(addrDesc bchain.AddressDescriptor, height uint32) []byte {
 	return buf
 }
 
+// unpackAddressKey unpacks the key of the addresses column in the form set by SetCompactAddressKeys
+func (d *RocksDB) unpackAddressKey(key []byte) ([]byte, uint32, error) {
+	addrDesc, height, err := unpackAddressKey(key)
+	if err != nil || !d.compactAddrKeys {
+		return addrDesc, height, err
+	}
+	addrDesc, err = expandAddrDesc(addrDesc)
+	if err != nil {
+		return nil, 0, err
+	}
+	return addrDesc, height, nil
+}
+
 func unpackAddressKey(key []byte) ([]byte, uint32, error) {
 	i := len(key) - packedHeightBytes
 	if i <= 0 {
@@ -1435,6 +2185,68 @@ func unpackAddressKey(key []byte) ([]byte, uint32, error) {
 	return key[:i], ^unpackUint(key[i : i+packedHeightBytes]), nil
 }
 
+// Compact form of address descriptor
+// In the compact form, the address descriptor is prefixed by one byte of its type. P2PKH (OP_DUP OP_HASH160 <20 bytes>
+// OP_EQUALVERIFY OP_CHECKSIG) and P2SH (OP_HASH160 <20 bytes> OP_EQUAL) output scripts are stored as the type followed
+// by the 20 byte hash, all other descriptors as the type compactAddrDescFull followed by the whole descriptor.
+// Any byte sequence can be an output script, the form is therefore determined only by the type byte
+// and the compact forms of two different descriptors never clash.
+// As the mapping is a fixed prefix per address, the ordering of the keys of one address by height is preserved.
+const (
+	compactAddrDescFull  = 0x00
+	compactAddrDescP2PKH = 0x01
+	compactAddrDescP2SH  = 0x02
+)
+
+func compactAddrDescType(addrDesc bchain.AddressDescriptor) byte {
+	if len(addrDesc) == 25 && addrDesc[0] == 0x76 && addrDesc[1] == 0xa9 && addrDesc[2] == 0x14 && addrDesc[23] == 0x88 && addrDesc[24] == 0xac {
+		return compactAddrDescP2PKH
+	}
+	if len(addrDesc) == 23 && addrDesc[0] == 0xa9 && addrDesc[1] == 0x14 && addrDesc[22] == 0x87 {
+		return compactAddrDescP2SH
+	}
+	return compactAddrDescFull
+}
+
+func compactAddrDesc(addrDesc bchain.AddressDescriptor) []byte {
+	t := compactAddrDescType(addrDesc)
+	switch t {
+	case compactAddrDescP2PKH:
+		addrDesc = addrDesc[3:23]
+	case compactAddrDescP2SH:
+		addrDesc = addrDesc[2:22]
+	}
+	buf := make([]byte, 1+len(addrDesc))
+	buf[0] = t
+	copy(buf[1:], addrDesc)
+	return buf
+}
+
+func expandAddrDesc(cad []byte) (bchain.AddressDescriptor, error) {
+	if len(cad) == 0 {
+		return nil, errors.New("Invalid compact address descriptor")
+	}
+	switch cad[0] {
+	case compactAddrDescFull:
+		return bchain.AddressDescriptor(cad[1:]), nil
+	case compactAddrDescP2PKH:
+		if len(cad) == 21 {
+			buf := make([]byte, 0, 25)
+			buf = append(buf, 0x76, 0xa9, 0x14)
+			buf = append(buf, cad[1:]...)
+			return append(buf, 0x88, 0xac), nil
+		}
+	case compactAddrDescP2SH:
+		if len(cad) == 21 {
+			buf := make([]byte, 0, 23)
+			buf = append(buf, 0xa9, 0x14)
+			buf = append(buf, cad[1:]...)
+			return append(buf, 0x87), nil
+		}
+	}
+	return nil, errors.New("Invalid compact address descriptor")
+}
+
 func packUint(i uint32) []byte {
 	buf := make([]byte, 4)
 	binary.BigEndian.PutUint32(buf, i)
@@ -4,11 +4,15 @@ package db
 
 import (
 	"blockbook/bchain"
+	"blockbook/bchain/coins/bch"
 	"blockbook/bchain/coins/btc"
 	"blockbook/common"
 	"blockbook/tests/dbtestdata"
+	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"os"
@@ -250,6 +254,18 @@ func verifyAfterBitcoinTypeBlock1(t *testing.T, d *RocksDB, afterDisconnect bool
 			t.Fatal(err)
 		}
 	}
+	// both blocks are from the day 2018-08-21, 17764 days since the unix epoch
+	dailyTxsKp := []keyPair{}
+	if d.dailyTxsIndex {
+		dailyTxsKp = []keyPair{
+			{"00004564", varuintToHex(2), nil},
+		}
+	}
+	if err := checkColumn(d, cfDailyTxs, dailyTxsKp); err != nil {
+		{
+			t.Fatal(err)
+		}
+	}
 }
 
 func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
@@ -346,7 +362,8 @@ func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
 				"01" + inputAddressToPubKeyHexWithLength("", t, d) + bigintToHex(dbtestdata.SatZero) +
 				"02" +
 				addressToPubKeyHexWithLength(dbtestdata.AddrA, t, d) + bigintToHex(dbtestdata.SatB2T4AA) +
-				addressToPubKeyHexWithLength("", t, d) + bigintToHex(dbtestdata.SatZero),
+				addressToPubKeyHexWithLength("", t, d) + bigintToHex(dbtestdata.SatZero) +
+				"01",
 			nil,
 		},
 	}); err != nil {
@@ -384,6 +401,17 @@ func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
 			t.Fatal(err)
 		}
 	}
+	dailyTxsKp := []keyPair{}
+	if d.dailyTxsIndex {
+		dailyTxsKp = []keyPair{
+			{"00004564", varuintToHex(6), nil},
+		}
+	}
+	if err := checkColumn(d, cfDailyTxs, dailyTxsKp); err != nil {
+		{
+			t.Fatal(err)
+		}
+	}
 }
 
 type txidIndex struct {
@@ -409,6 +437,71 @@ func verifyGetTransactions(t *testing.T, d *RocksDB, addr string, low, high uint
 	}
 }
 
+func verifyGetTransactionsAscending(t *testing.T, d *RocksDB, addr string, low, high uint32, wantTxids []txidIndex) {
+	addrDesc, err := d.chainParser.GetAddrDescFromAddress(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotTxids := make([]txidIndex, 0)
+	addToTxids := func(txid string, height uint32, indexes []int32) error {
+		for _, index := range indexes {
+			gotTxids = append(gotTxids, txidIndex{txid, index})
+		}
+		return nil
+	}
+	if err := d.GetAddrDescTransactionsAscending(addrDesc, low, high, addToTxids); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotTxids, wantTxids) {
+		t.Errorf("GetAddrDescTransactionsAscending() = %v, want %v", gotTxids, wantTxids)
+	}
+}
+
+// verifyAddressTotals checks that the total received and total sent amounts stored in the address balance
+// match the sums of the outputs and inputs of the address transactions and that received - sent == balance
+func verifyAddressTotals(t *testing.T, d *RocksDB, addr string) {
+	addrDesc, err := d.chainParser.GetAddrDescFromAddress(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab, err := d.GetAddrDescBalance(addrDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ab == nil {
+		ab = &AddrBalance{}
+	}
+	var received, sent big.Int
+	err = d.GetAddrDescTransactions(addrDesc, 0, ^uint32(0), func(txid string, height uint32, indexes []int32) error {
+		ta, err := d.GetTxAddresses(txid)
+		if err != nil {
+			return err
+		}
+		for _, index := range indexes {
+			if index < 0 {
+				sent.Add(&sent, &ta.Inputs[^index].ValueSat)
+			} else {
+				received.Add(&received, &ta.Outputs[index].ValueSat)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ab.ReceivedSat().Cmp(&received) != 0 {
+		t.Errorf("%v: ReceivedSat() = %v, want %v", addr, ab.ReceivedSat(), received.String())
+	}
+	if ab.SentSat.Cmp(&sent) != 0 {
+		t.Errorf("%v: SentSat = %v, want %v", addr, ab.SentSat.String(), sent.String())
+	}
+	var balance big.Int
+	balance.Sub(&received, &sent)
+	if ab.BalanceSat.Cmp(&balance) != 0 {
+		t.Errorf("%v: received - sent = %v, balance %v", addr, balance.String(), ab.BalanceSat.String())
+	}
+}
+
 // override PackTx and UnpackTx to default BaseParser functionality
 // BitcoinParser uses tx hex which is not available for the test transactions
 func (p *testBitcoinParser) PackTx(tx *bchain.Tx, height uint32, blockTime int64) ([]byte, error) {
@@ -454,6 +547,7 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 		BitcoinParser: bitcoinTestnetParser(),
 	})
 	defer closeAndDestroyRocksDB(t, d)
+	d.SetDailyTxsIndex(true)
 
 	// connect 1st block - will log warnings about missing UTXO transactions in txAddresses column
 	block1 := dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)
@@ -489,6 +583,27 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 		{dbtestdata.TxidB2T1, 0},
 	}, nil)
 	verifyGetTransactions(t, d, "mtGXQvBowMkBpnhLckhxhbwYK44Gs9eBad", 500000, 1000000, []txidIndex{}, errors.New("checksum mismatch"))
+	verifyGetTransactionsAscending(t, d, dbtestdata.Addr2, 0, 1000000, []txidIndex{
+		{dbtestdata.TxidB1T1, 1},
+		{dbtestdata.TxidB2T1, ^1},
+	})
+	verifyGetTransactionsAscending(t, d, dbtestdata.Addr2, 225493, 225493, []txidIndex{
+		{dbtestdata.TxidB1T1, 1},
+	})
+	verifyGetTransactionsAscending(t, d, dbtestdata.Addr2, 225494, 1000000, []txidIndex{
+		{dbtestdata.TxidB2T1, ^1},
+	})
+	verifyGetTransactionsAscending(t, d, dbtestdata.Addr2, 500000, 1000000, []txidIndex{})
+	verifyGetTransactionsAscending(t, d, dbtestdata.Addr6, 0, 1000000, []txidIndex{
+		{dbtestdata.TxidB2T1, 0},
+		{dbtestdata.TxidB2T2, ^0},
+	})
+	for _, addr := range []string{
+		dbtestdata.Addr1, dbtestdata.Addr2, dbtestdata.Addr3, dbtestdata.Addr4, dbtestdata.Addr5,
+		dbtestdata.Addr6, dbtestdata.Addr7, dbtestdata.Addr8, dbtestdata.Addr9,
+	} {
+		verifyAddressTotals(t, d, addr)
+	}
 
 	// GetBestBlock
 	height, hash, err := d.GetBestBlock()
@@ -644,6 +759,7 @@ func Test_BulkConnect_BitcoinType(t *testing.T) {
 		BitcoinParser: bitcoinTestnetParser(),
 	})
 	defer closeAndDestroyRocksDB(t, d)
+	d.SetDailyTxsIndex(true)
 
 	bc, err := d.InitBulkConnect()
 	if err != nil {
@@ -678,6 +794,206 @@ func Test_BulkConnect_BitcoinType(t *testing.T) {
 	verifyAfterBitcoinTypeBlock2(t, d)
 }
 
+func Test_BulkConnect_BitcoinType_Checkpoints(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	// checkpoint after each block
+	d.SetBulkCheckpointInterval(1)
+	bc, err := d.InitBulkConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.is.DbState == common.DbStateInconsistent {
+		t.Fatal("DB in DbStateInconsistent")
+	}
+	if err := bc.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser), true); err != nil {
+		t.Fatal(err)
+	}
+	// simulate crash, bc is abandoned without Close, the db must be consistent at the checkpoint
+	verifyAfterBitcoinTypeBlock1(t, d, false)
+
+	// the 2nd block does not reach the checkpoint interval, after crash the db must stay at the last checkpoint
+	d.SetBulkCheckpointInterval(2)
+	bc, err = d.InitBulkConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser), false); err != nil {
+		t.Fatal(err)
+	}
+	if d.is.DbState == common.DbStateInconsistent {
+		t.Fatal("DB in DbStateInconsistent")
+	}
+	verifyAfterBitcoinTypeBlock1(t, d, false)
+
+	// resume from the last checkpoint
+	height, _, err := d.GetBestBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 225493 {
+		t.Fatalf("GetBestBlock: got height %v, expected %v", height, 225493)
+	}
+	bc, err = d.InitBulkConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser), true); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if d.is.DbState == common.DbStateInconsistent {
+		t.Fatal("DB in DbStateInconsistent")
+	}
+	verifyAfterBitcoinTypeBlock2(t, d)
+}
+
+func TestRocksDB_ScriptHashIndex(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	// bare 1 of 2 multisig
+	multisig := "51" +
+		"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798" +
+		"2102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5" +
+		"52ae"
+	// bare 1 of 1 multisig
+	multisig2 := "51" +
+		"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798" +
+		"51ae"
+	script2, _ := hex.DecodeString(multisig2)
+	txid1 := "7e05c2b8bc3576b3b8e541a6e1e2fa8a6f9b1d5e5a3cd1de0f7b0e35d5b1d7c1"
+	txid2 := "b7c9a1d3b42a6ab3f1f53fba7e7f4c2a6b1d9b30ab0de1bb6d0f30d2c7b3e1a2"
+	block1 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 225493, Hash: "0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997", Time: 1534858021},
+		Txs: []bchain.Tx{
+			{
+				Txid: txid1,
+				Vout: []bchain.Vout{
+					{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: multisig}, ValueSat: *big.NewInt(5000)},
+					{N: 1, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, d.chainParser)}, ValueSat: *big.NewInt(7000)},
+				},
+			},
+		},
+	}
+	if err := d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+
+	script, _ := hex.DecodeString(multisig)
+	h0 := sha256.Sum256(script)
+	addrDesc, err := d.GetAddrDescByScriptHash(h0[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(addrDesc, script) {
+		t.Fatalf("GetAddrDescByScriptHash() = %v, want %v", hex.EncodeToString(addrDesc), multisig)
+	}
+	// scripts searchable by an address are not in the index
+	p2pkh, _ := hex.DecodeString(dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, d.chainParser))
+	h := sha256.Sum256(p2pkh)
+	if ad, err := d.GetAddrDescByScriptHash(h[:]); err != nil || ad != nil {
+		t.Errorf("GetAddrDescByScriptHash(P2PKH) = %v, %v, want nil, nil", ad, err)
+	}
+
+	verifyGetTransactions(t, d, dbtestdata.Addr1, 0, 1000000, []txidIndex{{txid1, 1}}, nil)
+	ab, err := d.GetAddrDescBalance(addrDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ab == nil || ab.Txs != 1 || ab.BalanceSat.Cmp(big.NewInt(5000)) != 0 {
+		t.Fatalf("GetAddrDescBalance() = %+v, want 1 tx with balance 5000", ab)
+	}
+
+	// spend the multisig output
+	block2 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 225494, Hash: "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6", Time: 1534859123},
+		Txs: []bchain.Tx{
+			{
+				Txid: txid2,
+				Vin:  []bchain.Vin{{Txid: txid1, Vout: 0}},
+				Vout: []bchain.Vout{
+					{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr2, d.chainParser)}, ValueSat: *big.NewInt(4000)},
+					{N: 1, ScriptPubKey: bchain.ScriptPubKey{Hex: multisig2}, ValueSat: *big.NewInt(500)},
+				},
+			},
+		},
+	}
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	var got []txidIndex
+	if err := d.GetAddrDescTransactions(addrDesc, 0, ^uint32(0), func(txid string, height uint32, indexes []int32) error {
+		for _, index := range indexes {
+			got = append(got, txidIndex{txid, index})
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []txidIndex{{txid2, ^0}, {txid1, 0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAddrDescTransactions() = %v, want %v", got, want)
+	}
+	ab, err = d.GetAddrDescBalance(addrDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ab == nil || ab.Txs != 2 || ab.BalanceSat.Sign() != 0 || ab.SentSat.Cmp(big.NewInt(5000)) != 0 {
+		t.Errorf("GetAddrDescBalance() = %+v, want 2 txs, balance 0 and sent 5000", ab)
+	}
+
+	h2 := sha256.Sum256(script2)
+	if ad, err := d.GetAddrDescByScriptHash(h2[:]); err != nil || !bytes.Equal(ad, script2) {
+		t.Fatalf("GetAddrDescByScriptHash() = %v, %v, want %v", hex.EncodeToString(ad), err, multisig2)
+	}
+
+	// disconnect block2, the first script still has a transaction in block1 and stays in the index,
+	// the second script has no transaction left and is removed from the index
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	if ad, err := d.GetAddrDescByScriptHash(h0[:]); err != nil || !bytes.Equal(ad, script) {
+		t.Errorf("GetAddrDescByScriptHash() after disconnect = %v, %v, want %v", hex.EncodeToString(ad), err, multisig)
+	}
+	if ad, err := d.GetAddrDescByScriptHash(h2[:]); err != nil || ad != nil {
+		t.Errorf("GetAddrDescByScriptHash() after disconnect = %v, %v, want nil, nil", hex.EncodeToString(ad), err)
+	}
+}
+
+func Test_isScriptHashIndexed(t *testing.T) {
+	d := &RocksDB{}
+	tests := []struct {
+		name   string
+		script string
+		want   bool
+	}{
+		{"P2PKH", "76a914" + "0123456789abcdef0123456789abcdef01234567" + "88ac", false},
+		{"P2SH", "a914" + "0123456789abcdef0123456789abcdef01234567" + "87", false},
+		{"P2WPKH", "0014" + "0123456789abcdef0123456789abcdef01234567", false},
+		{"P2WSH", "0020" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", false},
+		{"OP_RETURN", "6a0461626364", false},
+		{"empty", "", false},
+		{"P2PK", "210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac", true},
+		{"bare multisig", "51210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179851ae", true},
+		{"nonstandard", "0014" + "0123", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := hex.DecodeString(tt.script)
+			if got := d.isScriptHashIndexed(s); got != tt.want {
+				t.Errorf("isScriptHashIndexed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_packBigint_unpackBigint(t *testing.T) {
 	bigbig1, _ := big.NewInt(0).SetString("123456789123456789012345", 10)
 	bigbig2, _ := big.NewInt(0).SetString("12345678912345678901234512389012345123456789123456789012345123456789123456789012345", 10)
@@ -877,6 +1193,30 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "coinbase",
+			hex:  "baef9a1501000002000204d20002162e01",
+			data: &TxAddresses{
+				Height: 123456789,
+				Inputs: []TxInput{
+					{
+						AddrDesc: []byte(nil),
+						ValueSat: *big.NewInt(0),
+					},
+				},
+				Outputs: []TxOutput{
+					{
+						AddrDesc: []byte(nil),
+						ValueSat: *big.NewInt(1234),
+					},
+					{
+						AddrDesc: []byte(nil),
+						ValueSat: *big.NewInt(5678),
+					},
+				},
+				Coinbase: true,
+			},
+		},
 		{
 			name: "empty",
 			hex:  "000000",
@@ -906,3 +1246,971 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 		})
 	}
 }
+
+func Test_compactAddrDesc_expandAddrDesc(t *testing.T) {
+	tests := []struct {
+		name    string
+		desc    string
+		compact string
+	}{
+		{
+			name:    "P2PKH",
+			desc:    "76a914010d39800f86122416e28f485029acf77507169288ac",
+			compact: "01010d39800f86122416e28f485029acf775071692",
+		},
+		{
+			name:    "P2SH",
+			desc:    "a91452724c5178682f70e0ba31c6ec0633755a3b41d987",
+			compact: "0252724c5178682f70e0ba31c6ec0633755a3b41d9",
+		},
+		{
+			name:    "P2WPKH",
+			desc:    "00145fd6b4a7ddaf1327e0bd4f4c8848c43637184d49",
+			compact: "0000145fd6b4a7ddaf1327e0bd4f4c8848c43637184d49",
+		},
+		{
+			name:    "P2PK",
+			desc:    "2102f7351beeb7bc2e1824964c6e558adb81f0baf6f9fc53af2c0ec8fb63711c4e0bac",
+			compact: "002102f7351beeb7bc2e1824964c6e558adb81f0baf6f9fc53af2c0ec8fb63711c4e0bac",
+		},
+		{
+			name:    "truncated P2PKH",
+			desc:    "76a914010d39800f86122416e28f485029acf77507169288",
+			compact: "0076a914010d39800f86122416e28f485029acf77507169288",
+		},
+		{
+			name:    "nonstandard script with the bytes of a compact P2PKH",
+			desc:    "01010d39800f86122416e28f485029acf775071692",
+			compact: "0001010d39800f86122416e28f485029acf775071692",
+		},
+	}
+	d := &RocksDB{compactAddrKeys: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc, _ := hex.DecodeString(tt.desc)
+			got := compactAddrDesc(desc)
+			if h := hex.EncodeToString(got); h != tt.compact {
+				t.Errorf("compactAddrDesc() = %v, want %v", h, tt.compact)
+			}
+			expanded, err := expandAddrDesc(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := hex.EncodeToString(expanded); h != tt.desc {
+				t.Errorf("expandAddrDesc() = %v, want %v", h, tt.desc)
+			}
+			key := d.packAddressKey(desc, 123456)
+			ad, height, err := d.unpackAddressKey(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := hex.EncodeToString(ad); h != tt.desc || height != 123456 {
+				t.Errorf("unpackAddressKey() = %v, %v, want %v, %v", h, height, tt.desc, 123456)
+			}
+		})
+	}
+}
+
+func Test_packAddressKey_compactOrdering(t *testing.T) {
+	d := &RocksDB{compactAddrKeys: true}
+	descs := []string{
+		"76a914010d39800f86122416e28f485029acf77507169288ac",
+		"76a9148bdf0aa3c567aa5975c2e61321b8bebbe7293df688ac",
+		"a91452724c5178682f70e0ba31c6ec0633755a3b41d987",
+		"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87",
+	}
+	heights := []uint32{0, 1, 225493, 225494, 1 << 24}
+	var keys []string
+	for _, s := range descs {
+		desc, _ := hex.DecodeString(s)
+		// keys of one address, expected order is from the newest to the oldest block
+		for i := len(heights) - 1; i >= 0; i-- {
+			key := d.packAddressKey(desc, heights[i])
+			if len(key) != 1+20+packedHeightBytes {
+				t.Fatalf("packAddressKey(%v) length %v, want %v", s, len(key), 1+20+packedHeightBytes)
+			}
+			keys = append(keys, string(key))
+		}
+	}
+	if !sort.StringsAreSorted(keys) {
+		t.Errorf("compact address keys are not sorted by address and from the newest to the oldest block")
+	}
+}
+
+func TestRocksDB_GetAddrDescFirstFundedHeight(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	// the address with only a spending transaction in the index, its funding transaction is not indexed
+	sendOnly, _ := hex.DecodeString("76a914" + strings.Repeat("ab", 20) + "88ac")
+	unknown, _ := hex.DecodeString("76a914" + strings.Repeat("cd", 20) + "88ac")
+	btxID, err := d.chainParser.PackTxid(dbtestdata.TxidB2T4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.db.PutCF(d.wo, d.cfh[cfAddresses], d.packAddressKey(sendOnly, 225494), d.packTxIndexes([]txIndexes{{btxID: btxID, indexes: []int32{^0}}})); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		addrDesc   bchain.AddressDescriptor
+		wantHeight uint32
+		wantFound  bool
+	}{
+		{name: "funded in block 1 spent in block 2", addrDesc: addressToAddrDesc(dbtestdata.Addr2, d.chainParser), wantHeight: 225493, wantFound: true},
+		{name: "funded in block 2", addrDesc: addressToAddrDesc(dbtestdata.Addr6, d.chainParser), wantHeight: 225494, wantFound: true},
+		{name: "send only", addrDesc: sendOnly, wantHeight: 0, wantFound: false},
+		{name: "unknown", addrDesc: unknown, wantHeight: 0, wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			height, found, err := d.GetAddrDescFirstFundedHeight(tt.addrDesc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if height != tt.wantHeight || found != tt.wantFound {
+				t.Errorf("GetAddrDescFirstFundedHeight() = %v, %v, want %v, %v", height, found, tt.wantHeight, tt.wantFound)
+			}
+		})
+	}
+}
+
+// TestRocksDB_Index_BitcoinType_CompactAddressKeys connects the blocks with the compact address keys
+// and checks that the keys are read and disconnected correctly and that they do not clash with other scripts
+func TestRocksDB_Index_BitcoinType_CompactAddressKeys(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	d.SetCompactAddressKeys(true)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	addr6 := addressToAddrDesc(dbtestdata.Addr6, d.chainParser)
+	key := packAddressKey(compactAddrDesc(addr6), 225494)
+	val, err := d.db.GetCF(d.ro, d.cfh[cfAddresses], key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(val.Data()) == 0 {
+		t.Fatal("compact address key of the 2nd block not found")
+	}
+	val.Free()
+	val, err = d.db.GetCF(d.ro, d.cfh[cfAddresses], packAddressKey(addr6, 225494))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(val.Data()) != 0 {
+		t.Fatal("full address key stored in the compact mode")
+	}
+	val.Free()
+
+	verifyGetTransactions(t, d, dbtestdata.Addr2, 0, 1000000, []txidIndex{
+		{dbtestdata.TxidB2T1, ^1},
+		{dbtestdata.TxidB1T1, 1},
+	}, nil)
+	verifyGetTransactions(t, d, dbtestdata.Addr2, 225493, 225493, []txidIndex{
+		{dbtestdata.TxidB1T1, 1},
+	}, nil)
+	verifyGetTransactions(t, d, dbtestdata.Addr6, 0, 1000000, []txidIndex{
+		{dbtestdata.TxidB2T2, ^0},
+		{dbtestdata.TxidB2T1, 0},
+	}, nil)
+	verifyGetTransactionsAscending(t, d, dbtestdata.Addr2, 0, 1000000, []txidIndex{
+		{dbtestdata.TxidB1T1, 1},
+		{dbtestdata.TxidB2T1, ^1},
+	})
+
+	// a nonstandard script consisting of the bytes of the compact form of Addr6 must not find the transactions of Addr6
+	collision := append(bchain.AddressDescriptor{compactAddrDescP2PKH}, addr6[3:23]...)
+	if bytes.Equal(d.packAddressKey(collision, 225494), key) {
+		t.Fatal("compact address key of a nonstandard script clashes with the P2PKH key")
+	}
+	found := false
+	if err := d.GetAddrDescTransactions(collision, 0, 1000000, func(txid string, height uint32, indexes []int32) error {
+		found = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("transactions of a P2PKH address found by a nonstandard script")
+	}
+
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	verifyGetTransactions(t, d, dbtestdata.Addr2, 0, 1000000, []txidIndex{
+		{dbtestdata.TxidB1T1, 1},
+	}, nil)
+	verifyGetTransactions(t, d, dbtestdata.Addr6, 0, 1000000, []txidIndex{}, nil)
+}
+
+// TestRocksDB_DuplicateCoinbaseTx connects two blocks with coinbase transactions of the same txid
+// and checks the handling of the duplicate by both policies
+func TestRocksDB_DuplicateCoinbaseTx(t *testing.T) {
+	const txid = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	coinbaseBlock := func(height uint32, hash string, addr string, value int64, p bchain.BlockChainParser) *bchain.Block {
+		return &bchain.Block{
+			BlockHeader: bchain.BlockHeader{Height: height, Hash: hash},
+			Txs: []bchain.Tx{
+				{
+					Txid: txid,
+					Vin:  []bchain.Vin{{Coinbase: "04ffff001d0104"}},
+					Vout: []bchain.Vout{
+						{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(addr, p)}, ValueSat: *big.NewInt(value)},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name       string
+		policy     DuplicateTxPolicy
+		wantHeight uint32
+		wantAddr   string
+		wantAddr3  string
+	}{
+		{name: "overwrite", policy: DuplicateTxOverwrite, wantHeight: 225495, wantAddr: dbtestdata.Addr3, wantAddr3: "2500"},
+		{name: "keep", policy: DuplicateTxKeep, wantHeight: 225494, wantAddr: dbtestdata.Addr1, wantAddr3: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := setupRocksDB(t, &testBitcoinParser{
+				BitcoinParser: bitcoinTestnetParser(),
+			})
+			defer closeAndDestroyRocksDB(t, d)
+			d.SetDuplicateTxPolicy(tt.policy)
+
+			if err := d.ConnectBlock(coinbaseBlock(225494, "00000000000000000000000000000000000000000000000000000000000000a1", dbtestdata.Addr1, 5000, d.chainParser)); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.ConnectBlock(coinbaseBlock(225495, "00000000000000000000000000000000000000000000000000000000000000a2", dbtestdata.Addr3, 2500, d.chainParser)); err != nil {
+				t.Fatal(err)
+			}
+			ta, err := d.GetTxAddresses(txid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ta == nil {
+				t.Fatal("GetTxAddresses() returned nil")
+			}
+			if ta.Height != tt.wantHeight {
+				t.Errorf("GetTxAddresses() height = %v, want %v", ta.Height, tt.wantHeight)
+			}
+			if got, want := hex.EncodeToString(ta.Outputs[0].AddrDesc), dbtestdata.AddressToPubKeyHex(tt.wantAddr, d.chainParser); got != want {
+				t.Errorf("GetTxAddresses() output addrDesc = %v, want %v", got, want)
+			}
+			// the balance of the original output is kept by both policies
+			ab, err := d.GetAddrDescBalance(addressToAddrDesc(dbtestdata.Addr1, d.chainParser))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ab == nil || ab.BalanceSat.String() != "5000" {
+				t.Errorf("GetAddrDescBalance(Addr1) = %+v, want balance 5000", ab)
+			}
+			ab, err = d.GetAddrDescBalance(addressToAddrDesc(dbtestdata.Addr3, d.chainParser))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got3 := "0"
+			if ab != nil {
+				got3 = ab.BalanceSat.String()
+			}
+			if got3 != tt.wantAddr3 {
+				t.Errorf("GetAddrDescBalance(Addr3) = %v, want %v", got3, tt.wantAddr3)
+			}
+
+			if err := d.DisconnectBlockRangeBitcoinType(225495, 225495); err != nil {
+				t.Fatal(err)
+			}
+			ta, err = d.GetTxAddresses(txid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.policy == DuplicateTxKeep {
+				// the kept original transaction is not removed by the disconnect of the duplicate
+				if ta == nil || ta.Height != 225494 {
+					t.Errorf("GetTxAddresses() after disconnect = %+v, want tx of block 225494", ta)
+				}
+			} else if ta != nil {
+				// the overwritten original transaction cannot be restored
+				t.Errorf("GetTxAddresses() after disconnect = %+v, want nil", ta)
+			}
+		})
+	}
+}
+
+func TestRocksDB_FindBlocksByHashPrefix(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	hashes := []string{
+		"00000000aaaa1111000000000000000000000000000000000000000000000000",
+		"00000000aaaa2222000000000000000000000000000000000000000000000000",
+		"00000000bbbb1111000000000000000000000000000000000000000000000000",
+	}
+	for i, h := range hashes {
+		height := uint32(225493 + i)
+		b := &bchain.Block{
+			BlockHeader: bchain.BlockHeader{Height: height, Hash: h, Time: 1534858022 + int64(i)},
+			Txs: []bchain.Tx{{
+				Txid: fmt.Sprintf("%064x", height),
+				Vin:  []bchain.Vin{{Coinbase: "04ffff001d0104"}},
+				Vout: []bchain.Vout{
+					{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, d.chainParser)}, ValueSat: *big.NewInt(1000)},
+				},
+			}},
+		}
+		if err := d.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name       string
+		prefix     string
+		lastBlocks int
+		limit      int
+		want       []uint32
+	}{
+		{name: "unique", prefix: "00000000aaaa2", lastBlocks: 10, limit: 2, want: []uint32{225494}},
+		{name: "ambiguous", prefix: "00000000aaaa", lastBlocks: 10, limit: 2, want: []uint32{225493, 225494}},
+		{name: "limit", prefix: "00000000", lastBlocks: 10, limit: 2, want: []uint32{225494, 225495}},
+		{name: "full hash", prefix: hashes[2], lastBlocks: 10, limit: 2, want: []uint32{225495}},
+		{name: "no match", prefix: "00000000cccc", lastBlocks: 10, limit: 2},
+		{name: "older than last blocks", prefix: "00000000aaaa1", lastBlocks: 2, limit: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.FindBlocksByHashPrefix(tt.prefix, tt.lastBlocks, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindBlocksByHashPrefix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRocksDB_AddressLabels(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	got, err := d.GetAddressLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("GetAddressLabels() = %v, want no labels", got)
+	}
+	want := map[string]string{
+		string(addressToAddrDesc(dbtestdata.Addr1, d.chainParser)): "Exchange",
+		string(addressToAddrDesc(dbtestdata.Addr2, d.chainParser)): "",
+		string(addressToAddrDesc(dbtestdata.Addr3, d.chainParser)): "Mining pool",
+	}
+	if err = d.StoreAddressLabels(want); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = d.GetAddressLabels(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAddressLabels() = %v, want %v", got, want)
+	}
+	if err = d.StoreAddressLabels(map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = d.GetAddressLabels(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("GetAddressLabels() = %v, want no labels", got)
+	}
+}
+
+func TestRocksDB_TotalTxs(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer func() { closeAndDestroyRocksDB(t, d) }()
+
+	block := func(height uint32, txs int) *bchain.Block {
+		b := &bchain.Block{
+			BlockHeader: bchain.BlockHeader{Height: height, Hash: fmt.Sprintf("%064x", height), Time: 1534858022 + int64(height)},
+		}
+		for i := 0; i < txs; i++ {
+			b.Txs = append(b.Txs, bchain.Tx{
+				Txid: fmt.Sprintf("%056x%08x", height, i),
+				Vin:  []bchain.Vin{{Coinbase: "04ffff001d0104"}},
+				Vout: []bchain.Vout{
+					{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, d.chainParser)}, ValueSat: *big.NewInt(1000)},
+				},
+			})
+		}
+		return b
+	}
+	check := func(phase string, want uint64) {
+		got, err := d.GetTotalTxs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: GetTotalTxs() = %v, want %v", phase, got, want)
+		}
+	}
+	reopen := func() {
+		path := d.path
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if d, err = NewRocksDB(path, 100000, -1, &testBitcoinParser{BitcoinParser: bitcoinTestnetParser()}, nil); err != nil {
+			t.Fatal(err)
+		}
+		is, err := d.LoadInternalState("coin-unittest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.SetInternalState(is)
+	}
+
+	check("empty", 0)
+	for i, txs := range []int{2, 1, 3, 4} {
+		if err := d.ConnectBlock(block(225493+uint32(i), txs)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check("connected", 10)
+
+	// reorg of the last block
+	if err := d.DisconnectBlockRangeBitcoinType(225496, 225496); err != nil {
+		t.Fatal(err)
+	}
+	check("disconnected", 6)
+	if err := d.ConnectBlock(block(225496, 5)); err != nil {
+		t.Fatal(err)
+	}
+	check("reorged", 11)
+
+	reopen()
+	check("reopened", 11)
+
+	// the db indexed before the total was maintained, the total is computed on load and maintained since then
+	if err := d.db.DeleteCF(d.wo, d.cfh[cfDefault], []byte(totalTxsKey)); err != nil {
+		t.Fatal(err)
+	}
+	reopen()
+	check("computed", 11)
+	if err := d.ConnectBlock(block(225497, 2)); err != nil {
+		t.Fatal(err)
+	}
+	check("connected after computed", 13)
+}
+
+func TestRocksDB_DailyTxs(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	d.SetDailyTxsIndex(true)
+
+	// 2018-08-21 is 17764 days since the unix epoch
+	const day = 17764
+	block := func(height uint32, time int64, txs int) *bchain.Block {
+		b := &bchain.Block{
+			BlockHeader: bchain.BlockHeader{Height: height, Hash: fmt.Sprintf("%064x", height), Time: time},
+		}
+		for i := 0; i < txs; i++ {
+			b.Txs = append(b.Txs, bchain.Tx{
+				Txid: fmt.Sprintf("%056x%08x", height, i),
+				Vin:  []bchain.Vin{{Coinbase: "04ffff001d0104"}},
+				Vout: []bchain.Vout{
+					{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, d.chainParser)}, ValueSat: *big.NewInt(1000)},
+				},
+			})
+		}
+		return b
+	}
+	blocks := []*bchain.Block{
+		// the last second of 2018-08-21
+		block(225493, (day+1)*secondsPerDay-1, 2),
+		// the first second of 2018-08-22
+		block(225494, (day+1)*secondsPerDay, 1),
+		block(225495, (day+1)*secondsPerDay+3600, 3),
+		// 2018-08-24, no block on 2018-08-23
+		block(225496, (day+3)*secondsPerDay+7200, 4),
+	}
+	for _, b := range blocks {
+		if err := d.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name     string
+		from, to uint32
+		want     []DailyTxs
+	}{
+		{name: "all", from: 0, to: day + 10, want: []DailyTxs{{day, 2}, {day + 1, 4}, {day + 3, 4}}},
+		{name: "one day", from: day + 1, to: day + 1, want: []DailyTxs{{day + 1, 4}}},
+		{name: "day without blocks", from: day + 2, to: day + 2, want: nil},
+		{name: "range", from: day + 1, to: day + 3, want: []DailyTxs{{day + 1, 4}, {day + 3, 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.GetDailyTxs(tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDailyTxs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// only the last block can be disconnected, the day without any remaining transaction is removed
+	if err := d.DisconnectBlockRangeBitcoinType(225496, 225496); err != nil {
+		t.Fatal(err)
+	}
+	want := []DailyTxs{{day, 2}, {day + 1, 4}}
+	got, err := d.GetDailyTxs(0, day+10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDailyTxs() after disconnect = %+v, want %+v", got, want)
+	}
+	// connect and disconnect another block from 2018-08-22, the count of the day returns to the previous value
+	if err := d.ConnectBlock(block(225496, (day+2)*secondsPerDay-1, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = d.GetDailyTxs(day+1, day+1); err != nil {
+		t.Fatal(err)
+	}
+	if want := []DailyTxs{{day + 1, 9}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDailyTxs() = %+v, want %+v", got, want)
+	}
+	if err := d.DisconnectBlockRangeBitcoinType(225496, 225496); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = d.GetDailyTxs(0, day+10); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDailyTxs() after disconnect = %+v, want %+v", got, want)
+	}
+}
+
+func TestRocksDB_LockTimeIndex(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	d.SetLockTimeIndex(true)
+
+	block := func(height uint32, lockTimes ...uint32) *bchain.Block {
+		b := &bchain.Block{
+			BlockHeader: bchain.BlockHeader{Height: height, Hash: fmt.Sprintf("%064x", height), Time: 1534858022},
+		}
+		for i, lt := range lockTimes {
+			b.Txs = append(b.Txs, bchain.Tx{
+				Txid:     fmt.Sprintf("%056x%08x", height, i),
+				LockTime: lt,
+				Vin:      []bchain.Vin{{Coinbase: "04ffff001d0104"}},
+				Vout: []bchain.Vout{
+					{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, d.chainParser)}, ValueSat: *big.NewInt(1000)},
+				},
+			})
+		}
+		return b
+	}
+	txid := func(height uint32, i int) string {
+		return fmt.Sprintf("%056x%08x", height, i)
+	}
+	// lock times below 500000000 are heights, the others unix times
+	blocks := []*bchain.Block{
+		block(225493, 0, 225000, 1534850000),
+		block(225494, 225000, 0, 225490),
+		block(225495, 1534850000, 1),
+	}
+	for _, b := range blocks {
+		if err := d.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name     string
+		from, to uint32
+		after    *LockTimeTx
+		limit    int
+		want     []LockTimeTx
+	}{
+		{
+			name: "heights",
+			from: 1, to: bchain.LockTimeThreshold - 1, limit: 10,
+			want: []LockTimeTx{
+				{LockTime: 1, Height: 225495, Txid: txid(225495, 1)},
+				{LockTime: 225000, Height: 225493, Txid: txid(225493, 1)},
+				{LockTime: 225000, Height: 225494, Txid: txid(225494, 0)},
+				{LockTime: 225490, Height: 225494, Txid: txid(225494, 2)},
+			},
+		},
+		{
+			name: "range",
+			from: 225000, to: 225000, limit: 10,
+			want: []LockTimeTx{
+				{LockTime: 225000, Height: 225493, Txid: txid(225493, 1)},
+				{LockTime: 225000, Height: 225494, Txid: txid(225494, 0)},
+			},
+		},
+		{
+			name: "limit",
+			from: 0, to: 225000, limit: 2,
+			want: []LockTimeTx{
+				{LockTime: 1, Height: 225495, Txid: txid(225495, 1)},
+				{LockTime: 225000, Height: 225493, Txid: txid(225493, 1)},
+			},
+		},
+		{
+			name: "times",
+			from: bchain.LockTimeThreshold, to: 0xffffffff, limit: 10,
+			want: []LockTimeTx{
+				{LockTime: 1534850000, Height: 225493, Txid: txid(225493, 2)},
+				{LockTime: 1534850000, Height: 225495, Txid: txid(225495, 0)},
+			},
+		},
+		{
+			name: "after",
+			from: 0, to: 225490, after: &LockTimeTx{LockTime: 225000, Height: 225493, Txid: txid(225493, 1)}, limit: 10,
+			want: []LockTimeTx{
+				{LockTime: 225000, Height: 225494, Txid: txid(225494, 0)},
+				{LockTime: 225490, Height: 225494, Txid: txid(225494, 2)},
+			},
+		},
+		{name: "empty", from: 225491, to: 1534849999, limit: 10, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.GetLockTimeTxs(tt.from, tt.to, tt.after, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetLockTimeTxs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// the disconnected block is removed from the index
+	if err := d.DisconnectBlockRangeBitcoinType(225495, 225495); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.GetLockTimeTxs(0, 0xffffffff, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LockTimeTx{
+		{LockTime: 225000, Height: 225493, Txid: txid(225493, 1)},
+		{LockTime: 225000, Height: 225494, Txid: txid(225494, 0)},
+		{LockTime: 225490, Height: 225494, Txid: txid(225494, 2)},
+		{LockTime: 1534850000, Height: 225493, Txid: txid(225493, 2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetLockTimeTxs() after disconnect = %+v, want %+v", got, want)
+	}
+	if err := checkColumn(d, cfLockTimeBlocks, []keyPair{
+		{"000370d5", "00036ee8" + "000000000000000000000000000000000000000000000000000370d500000001" + "5b7bf3d0" + "000000000000000000000000000000000000000000000000000370d500000002", nil},
+		{"000370d6", "00036ee8" + "000000000000000000000000000000000000000000000000000370d600000000" + "000370d2" + "000000000000000000000000000000000000000000000000000370d600000002", nil},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// without the index the lock times are not stored
+	d.SetLockTimeIndex(false)
+	if err := d.ConnectBlock(block(225495, 225000)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = d.GetLockTimeTxs(225000, 225000, nil, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("GetLockTimeTxs() without index = %+v, want 2 transactions", got)
+	}
+}
+
+// prefetchTestBlocks returns a block with a transaction with many outputs and a block spending them,
+// the last transaction of the second block spends also an output of a transaction of the same block
+func prefetchTestBlocks(d *RocksDB, outputs int) (*bchain.Block, *bchain.Block) {
+	addr := dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, d.chainParser)
+	b1 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 225493, Hash: fmt.Sprintf("%064x", 225493), Time: 1534858022},
+		Txs:         []bchain.Tx{{Txid: fmt.Sprintf("%064x", 1), Vin: []bchain.Vin{{Coinbase: "04ffff001d0104"}}}},
+	}
+	for i := 0; i < outputs; i++ {
+		b1.Txs[0].Vout = append(b1.Txs[0].Vout, bchain.Vout{N: uint32(i), ScriptPubKey: bchain.ScriptPubKey{Hex: addr}, ValueSat: *big.NewInt(int64(1000 + i))})
+	}
+	b2 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 225494, Hash: fmt.Sprintf("%064x", 225494), Time: 1534859022},
+		Txs:         []bchain.Tx{{Txid: fmt.Sprintf("%064x", 2), Vin: []bchain.Vin{{Coinbase: "04ffff001d0105"}}}},
+	}
+	for i := 0; i < outputs; i++ {
+		b2.Txs = append(b2.Txs, bchain.Tx{
+			Txid: fmt.Sprintf("%064x", 3+i),
+			Vin:  []bchain.Vin{{Txid: b1.Txs[0].Txid, Vout: uint32(i)}},
+			Vout: []bchain.Vout{{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: addr}, ValueSat: *big.NewInt(int64(900 + i))}},
+		})
+	}
+	// spend the output created in this block
+	last := &b2.Txs[len(b2.Txs)-1]
+	b2.Txs = append(b2.Txs, bchain.Tx{
+		Txid: fmt.Sprintf("%064x", 3+outputs),
+		Vin:  []bchain.Vin{{Txid: last.Txid, Vout: 0}},
+		Vout: []bchain.Vout{{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: addr}, ValueSat: *big.NewInt(100)}},
+	})
+	return b1, b2
+}
+
+func TestRocksDB_PrefetchTxAddresses(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	b1, b2 := prefetchTestBlocks(d, 20)
+	if err := d.ConnectBlock(b1); err != nil {
+		t.Fatal(err)
+	}
+	// only the transaction of the previous block is loaded, the transactions of the block are not in the db yet
+	txAddressesMap := make(map[string]*TxAddresses)
+	prefetched, err := d.prefetchTxAddresses(b2, txAddressesMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	btxID, _ := d.chainParser.PackTxid(b1.Txs[0].Txid)
+	if len(txAddressesMap) != 1 || txAddressesMap[string(btxID)] == nil {
+		t.Fatalf("prefetchTxAddresses() = %+v, want only tx %v", txAddressesMap, b1.Txs[0].Txid)
+	}
+	if len(txAddressesMap[string(btxID)].Outputs) != 20 {
+		t.Errorf("prefetchTxAddresses() outputs = %v, want 20", len(txAddressesMap[string(btxID)].Outputs))
+	}
+	if _, found := prefetched[string(btxID)]; len(prefetched) != 1 || !found {
+		t.Errorf("prefetchTxAddresses() loaded = %v, want only tx %v", prefetched, b1.Txs[0].Txid)
+	}
+
+	d.cbs = connectBlockStats{}
+	if err := d.ConnectBlock(b2); err != nil {
+		t.Fatal(err)
+	}
+	// each input is counted once, the prefetched transaction is loaded from the db once and then found in the map
+	if d.cbs.txAddressesMiss != 1 || d.cbs.txAddressesHit != 20 {
+		t.Errorf("ConnectBlock() txAddresses stats = %+v, want 1 miss and 20 hits", d.cbs)
+	}
+	ta, err := d.GetTxAddresses(b1.Txs[0].Txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range ta.Outputs {
+		if !ta.Outputs[i].Spent {
+			t.Errorf("output %d of %v not spent", i, b1.Txs[0].Txid)
+		}
+	}
+	for i := 1; i < len(b2.Txs)-1; i++ {
+		ta, err := d.GetTxAddresses(b2.Txs[i].Txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ta.Inputs) != 1 || ta.Inputs[0].ValueSat.Int64() != int64(1000+i-1) {
+			t.Errorf("GetTxAddresses(%v) inputs = %+v, want value %d", b2.Txs[i].Txid, ta.Inputs, 1000+i-1)
+		}
+	}
+	// the intra block spend is resolved from the outputs of the block
+	last := b2.Txs[len(b2.Txs)-1]
+	ta, err = d.GetTxAddresses(last.Txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ta.Inputs) != 1 || ta.Inputs[0].ValueSat.Int64() != 919 || !bytes.Equal(ta.Inputs[0].AddrDesc, ta.Outputs[0].AddrDesc) {
+		t.Errorf("GetTxAddresses(%v) inputs = %+v, want value 919", last.Txid, ta.Inputs)
+	}
+	ta, err = d.GetTxAddresses(last.Vin[0].Txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ta.Outputs[0].Spent {
+		t.Errorf("output of %v spent in the same block not spent", last.Vin[0].Txid)
+	}
+}
+
+func BenchmarkRocksDB_ProcessAddressesBitcoinType(b *testing.B) {
+	tmp, err := ioutil.TempDir("", "testdb")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	d, err := NewRocksDB(tmp, 100000, -1, &testBitcoinParser{BitcoinParser: bitcoinTestnetParser()}, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer d.Close()
+	is, err := d.LoadInternalState("coin-unittest")
+	if err != nil {
+		b.Fatal(err)
+	}
+	d.SetInternalState(is)
+	b1, b2 := prefetchTestBlocks(d, 2000)
+	if err := d.ConnectBlock(b1); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := d.processAddressesBitcoinType(b2, make(addressesMap), make(map[string]*TxAddresses), make(map[string]*AddrBalance)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestRocksDB_SameHashP2PKHAndP2SH checks that the P2PKH and P2SH addresses with the same hash have separate balances
+func TestRocksDB_SameHashP2PKHAndP2SH(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compact keys %v", compact), func(t *testing.T) {
+			parser, err := bch.NewBCashParser(bch.GetChainParams("main"), &btc.Configuration{AddressFormat: "cashaddr"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := setupRocksDB(t, parser)
+			defer closeAndDestroyRocksDB(t, d)
+			d.SetCompactAddressKeys(compact)
+
+			// P2PKH and P2SH output scripts with the hash 88f772450c830a30eddfdc08a93d5f2ae1a30e17
+			var descs []bchain.AddressDescriptor
+			for _, script := range []string{"76a91488f772450c830a30eddfdc08a93d5f2ae1a30e1788ac", "a91488f772450c830a30eddfdc08a93d5f2ae1a30e1787"} {
+				ad, err := hex.DecodeString(script)
+				if err != nil {
+					t.Fatal(err)
+				}
+				descs = append(descs, ad)
+			}
+			block := &bchain.Block{
+				BlockHeader: bchain.BlockHeader{Height: 225493, Hash: fmt.Sprintf("%064x", 225493), Time: 1534858022},
+				Txs: []bchain.Tx{{
+					Txid: fmt.Sprintf("%064x", 1),
+					Vin:  []bchain.Vin{{Coinbase: "04ffff001d0104"}},
+					Vout: []bchain.Vout{
+						{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(descs[0])}, ValueSat: *big.NewInt(1000)},
+						{N: 1, ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(descs[1])}, ValueSat: *big.NewInt(2000)},
+						{N: 2, ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(descs[1])}, ValueSat: *big.NewInt(3000)},
+					},
+				}},
+			}
+			if err := d.ConnectBlock(block); err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range []struct {
+				balance int64
+				outputs []int32
+			}{{1000, []int32{0}}, {5000, []int32{1, 2}}} {
+				ab, err := d.GetAddrDescBalance(descs[i])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ab == nil || ab.BalanceSat.Int64() != want.balance || ab.Txs != 1 {
+					t.Errorf("GetAddrDescBalance(%v) = %+v, want balance %v", descs[i], ab, want.balance)
+				}
+				var outputs []int32
+				if err := d.GetAddrDescTransactions(descs[i], 0, 1000000, func(txid string, height uint32, indexes []int32) error {
+					outputs = append(outputs, indexes...)
+					return nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+				sort.Slice(outputs, func(a, b int) bool { return outputs[a] < outputs[b] })
+				if !reflect.DeepEqual(outputs, want.outputs) {
+					t.Errorf("GetAddrDescTransactions(%v) outputs = %v, want %v", descs[i], outputs, want.outputs)
+				}
+			}
+		})
+	}
+}
+
+func TestRocksDB_NoAddressIndex(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	d.SetNoAddressIndex(true)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	// the address index is empty
+	if err := checkColumn(d, cfAddresses, []keyPair{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkColumn(d, cfAddressBalance, []keyPair{}); err != nil {
+		t.Fatal(err)
+	}
+	// the blocks and transactions are indexed
+	bi, err := d.GetBlockInfo(225494)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bi == nil || bi.Hash != "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6" || bi.Txs != 4 {
+		t.Fatalf("GetBlockInfo(225494) = %+v", bi)
+	}
+	ta, err := d.GetTxAddresses(dbtestdata.TxidB2T2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ta == nil || len(ta.Inputs) != 2 || ta.Inputs[0].ValueSat.String() != "317283951061" {
+		t.Fatalf("GetTxAddresses(TxidB2T2) = %+v", ta)
+	}
+	if got, want := hex.EncodeToString(ta.Inputs[0].AddrDesc), dbtestdata.AddressToPubKeyHex(dbtestdata.Addr6, d.chainParser); got != want {
+		t.Errorf("GetTxAddresses(TxidB2T2) input addrDesc = %v, want %v", got, want)
+	}
+	spent := func() bool {
+		ta, err := d.GetTxAddresses(dbtestdata.TxidB1T2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ta.Outputs[0].Spent
+	}
+	if !spent() {
+		t.Error("output 0 of TxidB1T2 not spent")
+	}
+
+	// the disconnect of the block does not need the address index
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	if ta, err = d.GetTxAddresses(dbtestdata.TxidB2T2); err != nil {
+		t.Fatal(err)
+	}
+	if ta != nil {
+		t.Errorf("GetTxAddresses(TxidB2T2) after disconnect = %+v, want nil", ta)
+	}
+	if spent() {
+		t.Error("output 0 of TxidB1T2 spent after disconnect")
+	}
+	if err := checkColumn(d, cfAddressBalance, []keyPair{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -23,23 +23,26 @@ type SyncWorker struct {
 	chanOsSignal           chan os.Signal
 	metrics                *common.Metrics
 	is                     *common.InternalState
+	onConnectedBlock       bchain.OnConnectedBlockFunc
 }
 
 // NewSyncWorker creates new SyncWorker and returns its handle
-func NewSyncWorker(db *RocksDB, chain bchain.BlockChain, syncWorkers, syncChunk int, minStartHeight int, dryRun bool, chanOsSignal chan os.Signal, metrics *common.Metrics, is *common.InternalState) (*SyncWorker, error) {
+// onConnectedBlock (if not nil) is called with the content of each block connected one by one (not in initial parallel sync)
+func NewSyncWorker(db *RocksDB, chain bchain.BlockChain, syncWorkers, syncChunk int, minStartHeight int, dryRun bool, chanOsSignal chan os.Signal, metrics *common.Metrics, is *common.InternalState, onConnectedBlock bchain.OnConnectedBlockFunc) (*SyncWorker, error) {
 	if minStartHeight < 0 {
 		minStartHeight = 0
 	}
 	return &SyncWorker{
-		db:           db,
-		chain:        chain,
-		syncWorkers:  syncWorkers,
-		syncChunk:    syncChunk,
-		dryRun:       dryRun,
-		startHeight:  uint32(minStartHeight),
-		chanOsSignal: chanOsSignal,
-		metrics:      metrics,
-		is:           is,
+		db:               db,
+		chain:            chain,
+		syncWorkers:      syncWorkers,
+		syncChunk:        syncChunk,
+		dryRun:           dryRun,
+		startHeight:      uint32(minStartHeight),
+		chanOsSignal:     chanOsSignal,
+		metrics:          metrics,
+		is:               is,
+		onConnectedBlock: onConnectedBlock,
 	}, nil
 }
 
@@ -143,32 +146,92 @@ func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 
 func (w *SyncWorker) handleFork(localBestHeight uint32, localBestHash string, onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
 	// find forked blocks, disconnect them and then synchronize again
-	var height uint32
+	forkHeight, err := findForkHeight(localBestHeight, w.isCommonBlock)
+	if err != nil {
+		return err
+	}
+	glog.Info("resync: fork point at height ", forkHeight)
 	hashes := []string{localBestHash}
-	for height = localBestHeight - 1; height >= 0; height-- {
-		local, err := w.db.GetBlockHash(height)
+	for height := int64(localBestHeight) - 1; height > forkHeight; height-- {
+		local, err := w.db.GetBlockHash(uint32(height))
 		if err != nil {
 			return err
 		}
-		if local == "" {
-			break
-		}
-		remote, err := w.chain.GetBlockHash(height)
-		// for some coins (eth) remote can be at lower best height after rollback
-		if err != nil && err != bchain.ErrBlockNotFound {
-			return err
-		}
-		if local == remote {
-			break
-		}
 		hashes = append(hashes, local)
 	}
-	if err := w.DisconnectBlocks(height+1, localBestHeight, hashes); err != nil {
+	if err := w.DisconnectBlocks(uint32(forkHeight+1), localBestHeight, hashes); err != nil {
 		return err
 	}
 	return w.resyncIndex(onNewBlock, initialSync)
 }
 
+// isCommonBlock returns true if the indexed block at the height is the same as the block in the backend
+// or if the block at the height is not indexed (the index cannot be disconnected below this height)
+func (w *SyncWorker) isCommonBlock(height uint32) (bool, error) {
+	local, err := w.db.GetBlockHash(height)
+	if err != nil {
+		return false, err
+	}
+	if local == "" {
+		return true, nil
+	}
+	remote, err := w.chain.GetBlockHash(height)
+	// for some coins (eth) remote can be at lower best height after rollback
+	if err != nil && err != bchain.ErrBlockNotFound {
+		return false, err
+	}
+	return local == remote, nil
+}
+
+// blockLocatorHeights returns exponentially spaced heights starting at the height top and ending at 0,
+// the first heights are consecutive because the fork point is usually recent
+func blockLocatorHeights(top uint32) []uint32 {
+	heights := make([]uint32, 0, 32)
+	step := uint32(1)
+	for h := int64(top); h > 0; h -= int64(step) {
+		heights = append(heights, uint32(h))
+		if len(heights) >= 10 {
+			step *= 2
+		}
+	}
+	return append(heights, 0)
+}
+
+// findForkHeight returns the height of the highest common block of the index and the backend,
+// -1 if there is no common block. The block at localBestHeight must not be common.
+// The block locator heights are checked first, the fork point is then located by bisection between
+// the highest common locator height and the lowest checked height above it.
+func findForkHeight(localBestHeight uint32, isCommon func(height uint32) (bool, error)) (int64, error) {
+	lower, upper := int64(-1), int64(localBestHeight)
+	if localBestHeight > 0 {
+		for _, h := range blockLocatorHeights(localBestHeight - 1) {
+			common, err := isCommon(h)
+			if err != nil {
+				return 0, err
+			}
+			if common {
+				lower = int64(h)
+				break
+			}
+			upper = int64(h)
+		}
+	}
+	// the chain is common below any common block, the fork point is between lower and upper
+	for upper-lower > 1 {
+		mid := (lower + upper) / 2
+		common, err := isCommon(uint32(mid))
+		if err != nil {
+			return 0, err
+		}
+		if common {
+			lower = mid
+		} else {
+			upper = mid
+		}
+	}
+	return lower, nil
+}
+
 func (w *SyncWorker) connectBlocks(onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
 	bch := make(chan blockResult, 8)
 	done := make(chan struct{})
@@ -187,6 +250,9 @@ func (w *SyncWorker) connectBlocks(onNewBlock bchain.OnNewBlockFunc, initialSync
 		if err != nil {
 			return err
 		}
+		if w.onConnectedBlock != nil {
+			w.onConnectedBlock(res.block)
+		}
 		if onNewBlock != nil {
 			onNewBlock(res.block.Hash, res.block.Height)
 		}
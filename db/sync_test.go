@@ -0,0 +1,110 @@
+// +build unittest
+
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_blockLocatorHeights(t *testing.T) {
+	tests := []struct {
+		top  uint32
+		want []uint32
+	}{
+		{0, []uint32{0}},
+		{1, []uint32{1, 0}},
+		{5, []uint32{5, 4, 3, 2, 1, 0}},
+		{30, []uint32{30, 29, 28, 27, 26, 25, 24, 23, 22, 21, 19, 15, 7, 0}},
+		{1000, []uint32{1000, 999, 998, 997, 996, 995, 994, 993, 992, 991, 989, 985, 977, 961, 929, 865, 737, 481, 0}},
+	}
+	for _, tt := range tests {
+		if got := blockLocatorHeights(tt.top); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("blockLocatorHeights(%v) = %v, want %v", tt.top, got, tt.want)
+		}
+	}
+}
+
+func Test_findForkHeight(t *testing.T) {
+	tests := []struct {
+		name            string
+		localBestHeight uint32
+		forkHeight      int64
+		// the lowest indexed height, the blocks below are not in the index
+		indexedFrom uint32
+		maxCalls    int
+	}{
+		{
+			name:            "one block reorg",
+			localBestHeight: 500000,
+			forkHeight:      499999,
+			maxCalls:        1,
+		},
+		{
+			name:            "three blocks reorg",
+			localBestHeight: 500000,
+			forkHeight:      499997,
+			maxCalls:        3,
+		},
+		{
+			name:            "deep reorg",
+			localBestHeight: 500000,
+			forkHeight:      463819,
+			maxCalls:        50,
+		},
+		{
+			name:            "fork at genesis",
+			localBestHeight: 500000,
+			forkHeight:      0,
+			maxCalls:        50,
+		},
+		{
+			name:            "no common block",
+			localBestHeight: 500000,
+			forkHeight:      -1,
+			maxCalls:        50,
+		},
+		{
+			name:            "fork below indexed blocks",
+			localBestHeight: 500000,
+			forkHeight:      399999,
+			indexedFrom:     400000,
+			maxCalls:        50,
+		},
+		{
+			name:            "best block at genesis",
+			localBestHeight: 0,
+			forkHeight:      -1,
+			maxCalls:        0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			isCommon := func(height uint32) (bool, error) {
+				calls++
+				if height >= tt.localBestHeight {
+					t.Fatalf("isCommon called with height %v above the local best height", height)
+				}
+				if tt.indexedFrom > 0 && height < tt.indexedFrom {
+					return true, nil
+				}
+				return int64(height) <= tt.forkHeight, nil
+			}
+			got, err := findForkHeight(tt.localBestHeight, isCommon)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.forkHeight
+			if tt.indexedFrom > 0 && want < int64(tt.indexedFrom)-1 {
+				want = int64(tt.indexedFrom) - 1
+			}
+			if got != want {
+				t.Errorf("findForkHeight() = %v, want %v", got, want)
+			}
+			if calls > tt.maxCalls {
+				t.Errorf("findForkHeight() called isCommon %v times, want at most %v", calls, tt.maxCalls)
+			}
+		})
+	}
+}
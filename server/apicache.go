@@ -0,0 +1,114 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// default time to live of the cached responses of the individual API endpoints
+const (
+	DefaultAPICacheBlockTTL   = 10 * time.Minute
+	DefaultAPICacheTxTTL      = 5 * time.Minute
+	DefaultAPICacheAddressTTL = 30 * time.Second
+)
+
+// apiCacheTTLs is the time to live of the cached responses of the individual API endpoints, zero disables the caching of the endpoint
+type apiCacheTTLs struct {
+	block   time.Duration
+	tx      time.Duration
+	address time.Duration
+}
+
+type apiCacheEntry struct {
+	data    interface{}
+	expires time.Time
+	tag     string
+}
+
+// apiCache is an in-memory cache of the responses of read-only API endpoints
+// all entries are dropped when a new block is connected, entries can be also dropped by tag,
+// for example when a mempool transaction touches an address
+// nil apiCache does not cache anything
+type apiCache struct {
+	mux        sync.Mutex
+	entries    map[string]apiCacheEntry
+	maxEntries int
+	now        func() time.Time
+}
+
+// newAPICache returns cache of at most maxEntries responses or nil if maxEntries is not positive
+func newAPICache(maxEntries int) *apiCache {
+	if maxEntries <= 0 {
+		return nil
+	}
+	return &apiCache{
+		entries:    make(map[string]apiCacheEntry),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+func (c *apiCache) get(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	e, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.data, true
+}
+
+func (c *apiCache) set(key string, data interface{}, tag string, ttl time.Duration) {
+	if c == nil || ttl <= 0 {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	now := c.now()
+	if _, found := c.entries[key]; !found && len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+	c.entries[key] = apiCacheEntry{
+		data:    data,
+		expires: now.Add(ttl),
+		tag:     tag,
+	}
+}
+
+// invalidateTag drops entries with the given tag
+func (c *apiCache) invalidateTag(tag string) {
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for k, e := range c.entries {
+		if e.tag == tag {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// onNewBlock drops all entries, the confirmations and possibly the content of blocks, transactions and addresses change
+func (c *apiCache) onNewBlock() {
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	c.entries = make(map[string]apiCacheEntry)
+	c.mux.Unlock()
+}
@@ -0,0 +1,114 @@
+// +build unittest
+
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestAPICache(maxEntries int) (*apiCache, *time.Time) {
+	now := time.Unix(1554700000, 0)
+	c := newAPICache(maxEntries)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func Test_apiCache_Hit(t *testing.T) {
+	c, _ := newTestAPICache(10)
+	if _, found := c.get("/api/v2/block/1"); found {
+		t.Fatal("unexpected hit in empty cache")
+	}
+	c.set("/api/v2/block/1", "block 1", "", DefaultAPICacheBlockTTL)
+	data, found := c.get("/api/v2/block/1")
+	if !found || data != "block 1" {
+		t.Errorf("get() = %v, %v, want block 1, true", data, found)
+	}
+	if _, found := c.get("/api/v2/block/2"); found {
+		t.Error("unexpected hit of other key")
+	}
+}
+
+func Test_apiCache_TTL(t *testing.T) {
+	c, now := newTestAPICache(10)
+	c.set("/api/v2/address/a", "address a", "a", DefaultAPICacheAddressTTL)
+	c.set("/api/v2/tx/t", "tx t", "", DefaultAPICacheTxTTL)
+	*now = now.Add(DefaultAPICacheAddressTTL - time.Second)
+	if _, found := c.get("/api/v2/address/a"); !found {
+		t.Error("address expired before its TTL")
+	}
+	*now = now.Add(time.Second)
+	if _, found := c.get("/api/v2/address/a"); found {
+		t.Error("address not expired after its TTL")
+	}
+	if _, found := c.get("/api/v2/tx/t"); !found {
+		t.Error("tx expired before its TTL")
+	}
+	if len(c.entries) != 1 {
+		t.Errorf("len(entries) = %v, want 1", len(c.entries))
+	}
+	// zero TTL disables the caching
+	c.set("/api/v2/block/1", "block 1", "", 0)
+	if _, found := c.get("/api/v2/block/1"); found {
+		t.Error("response with zero TTL cached")
+	}
+}
+
+func Test_apiCache_OnNewBlock(t *testing.T) {
+	c, _ := newTestAPICache(10)
+	c.set("/api/v2/block/1", "block 1", "", DefaultAPICacheBlockTTL)
+	c.set("/api/v2/tx/t", "tx t", "", DefaultAPICacheTxTTL)
+	c.set("/api/v2/address/a", "address a", "a", DefaultAPICacheAddressTTL)
+	c.onNewBlock()
+	for _, k := range []string{"/api/v2/block/1", "/api/v2/tx/t", "/api/v2/address/a"} {
+		if _, found := c.get(k); found {
+			t.Errorf("%v not invalidated by new block", k)
+		}
+	}
+}
+
+func Test_apiCache_InvalidateTag(t *testing.T) {
+	c, _ := newTestAPICache(10)
+	c.set("/api/v2/address/a", "address a", "a", DefaultAPICacheAddressTTL)
+	c.set("/api/v2/address/a?page=2", "address a page 2", "a", DefaultAPICacheAddressTTL)
+	c.set("/api/v2/address/b", "address b", "b", DefaultAPICacheAddressTTL)
+	c.invalidateTag("a")
+	if _, found := c.get("/api/v2/address/a"); found {
+		t.Error("address a not invalidated")
+	}
+	if _, found := c.get("/api/v2/address/a?page=2"); found {
+		t.Error("address a page 2 not invalidated")
+	}
+	if _, found := c.get("/api/v2/address/b"); !found {
+		t.Error("address b invalidated")
+	}
+}
+
+func Test_apiCache_MaxEntries(t *testing.T) {
+	c, now := newTestAPICache(2)
+	c.set("/api/v2/address/a", "address a", "a", DefaultAPICacheAddressTTL)
+	c.set("/api/v2/block/1", "block 1", "", DefaultAPICacheBlockTTL)
+	c.set("/api/v2/block/2", "block 2", "", DefaultAPICacheBlockTTL)
+	if _, found := c.get("/api/v2/block/2"); found {
+		t.Error("entry stored over the limit")
+	}
+	// the expired entry makes space for a new one
+	*now = now.Add(DefaultAPICacheAddressTTL)
+	c.set("/api/v2/block/2", "block 2", "", DefaultAPICacheBlockTTL)
+	if _, found := c.get("/api/v2/block/2"); !found {
+		t.Error("entry not stored after expiry of other entry")
+	}
+}
+
+func Test_apiCache_Disabled(t *testing.T) {
+	c := newAPICache(0)
+	if c != nil {
+		t.Fatal("newAPICache(0) is not nil")
+	}
+	c.set("/api/v2/block/1", "block 1", "", DefaultAPICacheBlockTTL)
+	if _, found := c.get("/api/v2/block/1"); found {
+		t.Error("disabled cache returned an entry")
+	}
+	c.invalidateTag("a")
+	c.onNewBlock()
+}
@@ -26,6 +26,7 @@ type InternalServer struct {
 	mempool     bchain.Mempool
 	is          *common.InternalState
 	api         *api.Worker
+	labels      *api.AddressLabels
 }
 
 // NewInternalServer creates new internal http interface to blockbook and returns its handle
@@ -55,11 +56,18 @@ func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.B
 
 	serveMux.Handle(path+"favicon.ico", http.FileServer(http.Dir("./static/")))
 	serveMux.HandleFunc(path+"metrics", promhttp.Handler().ServeHTTP)
+	serveMux.HandleFunc(path+"labels", s.addressLabels)
 	serveMux.HandleFunc(path, s.index)
 
 	return s, nil
 }
 
+// SetAddressLabels sets the store of address labels managed by the labels endpoint
+func (s *InternalServer) SetAddressLabels(labels *api.AddressLabels) {
+	s.labels = labels
+	s.api.SetAddressLabels(labels)
+}
+
 // Run starts the server
 func (s *InternalServer) Run() error {
 	if s.certFiles == "" {
@@ -98,3 +106,35 @@ func (s *InternalServer) index(w http.ResponseWriter, r *http.Request) {
 
 	w.Write(buf)
 }
+
+// addressLabels returns all address labels, POST request with json body {"address": "...", "label": "..."}
+// sets the label of the address, empty label removes it
+func (s *InternalServer) addressLabels(w http.ResponseWriter, r *http.Request) {
+	if s.labels == nil {
+		http.Error(w, "Address labels not enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodPost {
+		var req struct {
+			Address string `json:"address"`
+			Label   string `json:"label"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request, "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.labels.Set(req.Address, req.Label); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		glog.Info("address label of ", req.Address, " set to '", req.Label, "'")
+	}
+	buf, err := json.MarshalIndent(s.labels.All(), "", "    ")
+	if err != nil {
+		glog.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(buf)
+}
@@ -10,8 +10,10 @@ import (
 	"fmt"
 	"html/template"
 	"io/ioutil"
+	"math"
 	"math/big"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"reflect"
 	"regexp"
@@ -28,6 +30,7 @@ const txsOnPage = 25
 const blocksOnPage = 50
 const mempoolTxsOnPage = 50
 const txsInAPI = 1000
+const blocksInAPIRange = 10
 
 const (
 	_ = iota
@@ -42,6 +45,7 @@ type PublicServer struct {
 	socketio         *SocketIoServer
 	websocket        *WebsocketServer
 	https            *http.Server
+	serveMux         *http.ServeMux
 	db               *db.RocksDB
 	txCache          *db.TxCache
 	chain            bchain.BlockChain
@@ -54,11 +58,15 @@ type PublicServer struct {
 	is               *common.InternalState
 	templates        []*template.Template
 	debug            bool
+	apiCache         *apiCache
+	apiCacheTTL      apiCacheTTLs
+	compaction       *common.CompactionScheduler
 }
 
 // NewPublicServer creates new public server http interface to blockbook and returns its handle
 // only basic functionality is mapped, to map all functions, call
-func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, explorerURL string, metrics *common.Metrics, is *common.InternalState, debugMode bool) (*PublicServer, error) {
+// apiCacheSize is the max number of cached responses of the read-only API endpoints, 0 disables the cache
+func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, explorerURL string, metrics *common.Metrics, is *common.InternalState, debugMode bool, apiCacheSize int) (*PublicServer, error) {
 
 	api, err := api.NewWorker(db, chain, mempool, txCache, is)
 	if err != nil {
@@ -86,6 +94,7 @@ func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bch
 		binding:          binding,
 		certFiles:        certFiles,
 		https:            https,
+		serveMux:         serveMux,
 		api:              api,
 		socketio:         socketio,
 		websocket:        websocket,
@@ -99,8 +108,21 @@ func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bch
 		metrics:          metrics,
 		is:               is,
 		debug:            debugMode,
+		apiCache:         newAPICache(apiCacheSize),
+		apiCacheTTL: apiCacheTTLs{
+			block:   DefaultAPICacheBlockTTL,
+			tx:      DefaultAPICacheTxTTL,
+			address: DefaultAPICacheAddressTTL,
+		},
 	}
 	s.templates = s.parseTemplates()
+	// record the served requests for the detection of idle time
+	https.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.compaction != nil {
+			s.compaction.OnActivity(time.Now())
+		}
+		serveMux.ServeHTTP(w, r)
+	})
 
 	// map only basic functions, the rest is enabled by method MapFullPublicInterface
 	serveMux.Handle(path+"favicon.ico", http.FileServer(http.Dir("./static/")))
@@ -125,7 +147,7 @@ func (s *PublicServer) Run() error {
 
 // ConnectFullPublicInterface enables complete public functionality
 func (s *PublicServer) ConnectFullPublicInterface() {
-	serveMux := s.https.Handler.(*http.ServeMux)
+	serveMux := s.serveMux
 	_, path := splitBinding(s.binding)
 	// support for test pages
 	serveMux.Handle(path+"test-socketio.html", http.FileServer(http.Dir("./static/")))
@@ -169,21 +191,53 @@ func (s *PublicServer) ConnectFullPublicInterface() {
 	serveMux.HandleFunc(path+"api/block-index/", s.jsonHandler(s.apiBlockIndex, apiDefault))
 	serveMux.HandleFunc(path+"api/tx-specific/", s.jsonHandler(s.apiTxSpecific, apiDefault))
 	serveMux.HandleFunc(path+"api/tx/", s.jsonHandler(s.apiTx, apiDefault))
+	serveMux.HandleFunc(path+"api/tx-status/", s.jsonHandler(s.apiTxStatus, apiDefault))
+	serveMux.HandleFunc(path+"api/tx-meta/", s.jsonHandler(s.apiTxMetadata, apiDefault))
+	serveMux.HandleFunc(path+"api/tx-spends/", s.jsonHandler(s.apiTxSpendStatus, apiDefault))
 	serveMux.HandleFunc(path+"api/address/", s.jsonHandler(s.apiAddress, apiDefault))
 	serveMux.HandleFunc(path+"api/xpub/", s.jsonHandler(s.apiXpub, apiDefault))
 	serveMux.HandleFunc(path+"api/utxo/", s.jsonHandler(s.apiUtxo, apiDefault))
+	serveMux.HandleFunc(path+"api/scripthash/", s.jsonHandler(s.apiScriptHash, apiDefault))
+	serveMux.HandleFunc(path+"api/scripthash-utxo/", s.jsonHandler(s.apiScriptHashUtxo, apiDefault))
 	serveMux.HandleFunc(path+"api/block/", s.jsonHandler(s.apiBlock, apiDefault))
+	serveMux.HandleFunc(path+"api/block-merkleroot/", s.jsonHandler(s.apiBlockMerkleRoot, apiDefault))
+	serveMux.HandleFunc(path+"api/block-range/", s.jsonHandler(s.apiBlockRange, apiDefault))
 	serveMux.HandleFunc(path+"api/sendtx/", s.jsonHandler(s.apiSendTx, apiDefault))
 	serveMux.HandleFunc(path+"api/estimatefee/", s.jsonHandler(s.apiEstimateFee, apiDefault))
 	// v2 format
 	serveMux.HandleFunc(path+"api/v2/block-index/", s.jsonHandler(s.apiBlockIndex, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tx-specific/", s.jsonHandler(s.apiTxSpecific, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tx/", s.jsonHandler(s.apiTx, apiV2))
+	serveMux.HandleFunc(path+"api/v2/tx-status/", s.jsonHandler(s.apiTxStatus, apiV2))
+	serveMux.HandleFunc(path+"api/v2/tx-meta/", s.jsonHandler(s.apiTxMetadata, apiV2))
+	serveMux.HandleFunc(path+"api/v2/tx-spends/", s.jsonHandler(s.apiTxSpendStatus, apiV2))
+	serveMux.HandleFunc(path+"api/v2/outpoint/", s.jsonHandler(s.apiOutpoint, apiV2))
 	serveMux.HandleFunc(path+"api/v2/address/", s.jsonHandler(s.apiAddress, apiV2))
+	serveMux.HandleFunc(path+"api/v2/balances/", s.jsonHandler(s.apiBalances, apiV2))
+	serveMux.HandleFunc(path+"api/v2/address-time/", s.jsonHandler(s.apiAddressTimeRange, apiV2))
+	serveMux.HandleFunc(path+"api/v2/address-block/", s.jsonHandler(s.apiAddressBlockTxs, apiV2))
+	serveMux.HandleFunc(path+"api/v2/balance-delta/", s.jsonHandler(s.apiAddressBalanceDelta, apiV2))
+	serveMux.HandleFunc(path+"api/v2/address-fees/", s.jsonHandler(s.apiAddressFees, apiV2))
+	serveMux.HandleFunc(path+"api/v2/tx-address-value/", s.jsonHandler(s.apiTxAddressValue, apiV2))
+	serveMux.HandleFunc(path+"api/v2/balance-at-heights/", s.jsonHandler(s.apiAddressBalanceAtHeights, apiV2))
+	serveMux.HandleFunc(path+"api/v2/daily-txs/", s.jsonHandler(s.apiDailyTxs, apiV2))
+	serveMux.HandleFunc(path+"api/v2/locktime-txs/", s.jsonHandler(s.apiLockTimeTxs, apiV2))
+	serveMux.HandleFunc(path+"api/v2/block-value-txs/", s.jsonHandler(s.apiBlockValueTxs, apiV2))
+	serveMux.HandleFunc(path+"api/v2/chain-stats", s.jsonHandler(s.apiChainStats, apiV2))
+	serveMux.HandleFunc(path+"api/v2/mempool-stuck", s.jsonHandler(s.apiStuckMempoolTxs, apiV2))
+	serveMux.HandleFunc(path+"api/v2/feerates/", s.jsonHandler(s.apiFeeRates, apiV2))
+	serveMux.HandleFunc(path+"api/v2/feerate-median", s.jsonHandler(s.apiMedianFeeRate, apiV2))
+	serveMux.HandleFunc(path+"api/v2/required-confirmations/", s.jsonHandler(s.apiRequiredConfirmations, apiV2))
 	serveMux.HandleFunc(path+"api/v2/xpub/", s.jsonHandler(s.apiXpub, apiV2))
 	serveMux.HandleFunc(path+"api/v2/utxo/", s.jsonHandler(s.apiUtxo, apiV2))
+	serveMux.HandleFunc(path+"api/v2/scripthash/", s.jsonHandler(s.apiScriptHash, apiV2))
+	serveMux.HandleFunc(path+"api/v2/scripthash-utxo/", s.jsonHandler(s.apiScriptHashUtxo, apiV2))
 	serveMux.HandleFunc(path+"api/v2/block/", s.jsonHandler(s.apiBlock, apiV2))
+	serveMux.HandleFunc(path+"api/v2/block-header/", s.jsonHandler(s.apiBlockRawHeader, apiV2))
+	serveMux.HandleFunc(path+"api/v2/block-merkleroot/", s.jsonHandler(s.apiBlockMerkleRoot, apiV2))
+	serveMux.HandleFunc(path+"api/v2/block-range/", s.jsonHandler(s.apiBlockRange, apiV2))
 	serveMux.HandleFunc(path+"api/v2/sendtx/", s.jsonHandler(s.apiSendTx, apiV2))
+	serveMux.HandleFunc(path+"api/v2/sendtxs/", s.jsonHandler(s.apiSendTxs, apiV2))
 	serveMux.HandleFunc(path+"api/v2/estimatefee/", s.jsonHandler(s.apiEstimateFee, apiV2))
 	// socket.io interface
 	serveMux.Handle(path+"socket.io/", s.socketio.GetHandler())
@@ -203,18 +257,105 @@ func (s *PublicServer) Shutdown(ctx context.Context) error {
 	return s.https.Shutdown(ctx)
 }
 
+// SetAddressLabels sets the store of labels attached to the returned addresses
+// the cached responses of an address are dropped when its label changes
+func (s *PublicServer) SetAddressLabels(labels *api.AddressLabels) {
+	s.api.SetAddressLabels(labels)
+	s.websocket.api.SetAddressLabels(labels)
+	if labels != nil {
+		labels.OnChange(func(addrDesc bchain.AddressDescriptor) {
+			s.apiCache.invalidateTag(string(addrDesc))
+		})
+	}
+}
+
+// SetBalancesConcurrency sets the number of addresses of a bulk balances request resolved in parallel
+func (s *PublicServer) SetBalancesConcurrency(n int) {
+	s.api.SetBalancesConcurrency(n)
+}
+
+// SetStuckTxPercentile sets the percentile of the fee rates of the last blocks below which a mempool transaction is likely stuck
+func (s *PublicServer) SetStuckTxPercentile(p int) {
+	s.api.SetStuckTxPercentile(p)
+}
+
+// SetAPICacheTTLs sets the time to live of the cached responses of the block, transaction and address endpoints,
+// zero disables the caching of the endpoint
+func (s *PublicServer) SetAPICacheTTLs(block, tx, address time.Duration) {
+	s.apiCacheTTL = apiCacheTTLs{block: block, tx: tx, address: address}
+}
+
+// SetFeeStatsBlocks sets the default number of the last blocks from which the fee rate percentiles are computed
+func (s *PublicServer) SetFeeStatsBlocks(n int) {
+	s.api.SetFeeStatsBlocks(n)
+}
+
+// SetConfirmationPolicy sets the policy of the confirmations required for the value of an output
+func (s *PublicServer) SetConfirmationPolicy(p *api.ConfirmationPolicy) {
+	s.api.SetConfirmationPolicy(p)
+}
+
+// SetXpubMaxAddresses sets the maximum number of addresses derived from one xpub on both chains together
+func (s *PublicServer) SetXpubMaxAddresses(n int) {
+	s.api.SetXpubMaxAddresses(n)
+	s.websocket.api.SetXpubMaxAddresses(n)
+}
+
+// SetUtxoLimit sets the maximum number of utxos returned by the utxo API, the larger sets are truncated,
+// zero means no limit
+func (s *PublicServer) SetUtxoLimit(n int) {
+	s.api.SetUtxoLimit(n)
+	s.websocket.api.SetUtxoLimit(n)
+}
+
+// SetAddressNotificationWindow sets the window in which the websocket address notifications to one connection
+// are batched to a single message, zero means that each notification is sent immediately
+func (s *PublicServer) SetAddressNotificationWindow(window time.Duration) {
+	s.websocket.SetAddressNotificationWindow(window)
+}
+
+// SetRefuseQueriesInInitialBlockDownload sets whether the address queries are refused while the backend is in the initial block download
+func (s *PublicServer) SetRefuseQueriesInInitialBlockDownload(refuse bool) {
+	s.api.SetRefuseQueriesInInitialBlockDownload(refuse)
+	s.websocket.api.SetRefuseQueriesInInitialBlockDownload(refuse)
+}
+
+// SetWebsocketMaxConnections sets the maximum number of the open websocket connections, zero means no limit
+func (s *PublicServer) SetWebsocketMaxConnections(n int) {
+	s.websocket.SetMaxConnections(n)
+}
+
+// SetCompactionScheduler sets the scheduler of the database compaction notified about the served requests,
+// it must be set before the server is started
+func (s *PublicServer) SetCompactionScheduler(c *common.CompactionScheduler) {
+	s.compaction = c
+}
+
 // OnNewBlock notifies users subscribed to bitcoind/hashblock about new block
 func (s *PublicServer) OnNewBlock(hash string, height uint32) {
+	s.OnNewBlocks(height, hash, height)
+}
+
+// OnNewBlocks notifies subscribed users about blocks fromHeight..height connected in quick succession by a single notification,
+// socket.io subscribers get only the hash of the last block
+func (s *PublicServer) OnNewBlocks(fromHeight uint32, hash string, height uint32) {
+	s.apiCache.onNewBlock()
 	s.socketio.OnNewBlockHash(hash)
-	s.websocket.OnNewBlock(hash, height)
+	s.websocket.OnNewBlocks(fromHeight, hash, height)
 }
 
 // OnNewTxAddr notifies users subscribed to bitcoind/addresstxid about new block
 func (s *PublicServer) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
+	s.apiCache.invalidateTag(string(desc))
 	s.socketio.OnNewTxAddr(tx.Txid, desc)
 	s.websocket.OnNewTxAddr(tx, desc)
 }
 
+// IsWatchedAddrDesc returns true if there are subscribers to notifications about given address
+func (s *PublicServer) IsWatchedAddrDesc(desc bchain.AddressDescriptor) bool {
+	return s.socketio.IsWatchedAddrDesc(desc) || s.websocket.IsWatchedAddrDesc(desc)
+}
+
 func (s *PublicServer) txRedirect(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, joinURL(s.explorerURL, r.URL.Path), 302)
 	s.metrics.ExplorerViews.With(common.Labels{"action": "tx-redirect"}).Inc()
@@ -548,6 +689,12 @@ func (s *PublicServer) explorerTx(w http.ResponseWriter, r *http.Request) (tpl,
 		if err != nil {
 			return errorTpl, nil, err
 		}
+		// the scripts are shown only if the parser supports their disassembly
+		if s.chainParser.GetChainType() == bchain.ChainBitcoinType {
+			if err = s.api.SetTxAsm(tx); err != nil && glog.V(1) {
+				glog.Infof("SetTxAsm %v: %v", txid, err)
+			}
+		}
 	}
 	data := s.newTemplateData()
 	data.Tx = tx
@@ -631,14 +778,33 @@ func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api
 	if ec != nil {
 		gap = 0
 	}
+	coinbaseFilter := api.CoinbaseFilterOff
+	switch r.URL.Query().Get("coinbase") {
+	case "only":
+		coinbaseFilter = api.CoinbaseFilterOnly
+	case "exclude":
+		coinbaseFilter = api.CoinbaseFilterExclude
+	}
 	return page, pageSize, accountDetails, &api.AddressFilter{
-		Vout:           voutFilter,
-		TokensToReturn: tokensToReturn,
-		FromHeight:     uint32(from),
-		ToHeight:       uint32(to),
+		Vout:             voutFilter,
+		TokensToReturn:   tokensToReturn,
+		FromHeight:       uint32(from),
+		ToHeight:         uint32(to),
+		Ascending:        r.URL.Query().Get("order") == "asc",
+		Coinbase:         coinbaseFilter,
+		MinConfirmations: minConfirmations(r),
 	}, filterParam, gap
 }
 
+// minConfirmations returns the value of the query parameter minConfirmations, 0 if not specified or invalid
+func minConfirmations(r *http.Request) int {
+	m, err := strconv.Atoi(r.URL.Query().Get("minConfirmations"))
+	if err != nil || m < 0 {
+		return 0
+	}
+	return m
+}
+
 func (s *PublicServer) explorerAddress(w http.ResponseWriter, r *http.Request) (tpl, *TemplateData, error) {
 	var addressParam string
 	i := strings.LastIndexByte(r.URL.Path, '/')
@@ -923,6 +1089,10 @@ func (s *PublicServer) apiTx(r *http.Request, apiVersion int) (interface{}, erro
 	var tx *api.Tx
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx"}).Inc()
+	cacheKey := r.URL.RequestURI()
+	if data, found := s.apiCache.get(cacheKey); found {
+		return data, nil
+	}
 	spendingTxs := false
 	p := r.URL.Query().Get("spending")
 	if len(p) > 0 {
@@ -932,10 +1102,284 @@ func (s *PublicServer) apiTx(r *http.Request, apiVersion int) (interface{}, erro
 		}
 	}
 	tx, err = s.api.GetTransaction(txid, spendingTxs, false)
-	if err == nil && apiVersion == apiV1 {
-		return s.api.TxToV1(tx), nil
+	if err != nil {
+		return nil, err
 	}
-	return tx, err
+	// the index in an older block not kept in the index requires the download of the block, it is done only on request
+	indexFromBackend := false
+	if p := r.URL.Query().Get("indexInBlock"); len(p) > 0 {
+		indexFromBackend, err = strconv.ParseBool(p)
+		if err != nil {
+			return nil, api.NewAPIError("Parameter 'indexInBlock' cannot be converted to boolean", true)
+		}
+	}
+	s.api.SetTxIndexInBlock(tx, indexFromBackend)
+	// the hex is returned if known by default, hex=true makes sure it is present, hex=false omits it
+	if p := r.URL.Query().Get("hex"); len(p) > 0 {
+		withHex, err := strconv.ParseBool(p)
+		if err != nil {
+			return nil, api.NewAPIError("Parameter 'hex' cannot be converted to boolean", true)
+		}
+		if !withHex {
+			tx.Hex = ""
+		} else if err = s.api.SetTxHex(tx); err != nil {
+			return nil, err
+		}
+	}
+	if p := r.URL.Query().Get("asm"); len(p) > 0 {
+		withAsm, err := strconv.ParseBool(p)
+		if err != nil {
+			return nil, api.NewAPIError("Parameter 'asm' cannot be converted to boolean", true)
+		}
+		if withAsm {
+			if err = s.api.SetTxAsm(tx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var data interface{} = tx
+	if apiVersion == apiV1 {
+		data = s.api.TxToV1(tx)
+	}
+	// unconfirmed transactions are not cached, they can be replaced or confirmed anytime
+	if tx.Confirmations > 0 {
+		s.apiCache.set(cacheKey, data, "", s.apiCacheTTL.tx)
+	}
+	return data, nil
+}
+
+func (s *PublicServer) apiTxStatus(r *http.Request, apiVersion int) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		txid = r.URL.Path[i+1:]
+	}
+	if len(txid) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx-status"}).Inc()
+	return s.api.HasTransaction(txid)
+}
+
+func (s *PublicServer) apiTxMetadata(r *http.Request, apiVersion int) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		txid = r.URL.Path[i+1:]
+	}
+	if len(txid) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx-meta"}).Inc()
+	return s.api.GetTxMetadata(txid)
+}
+
+func (s *PublicServer) apiTxSpendStatus(r *http.Request, apiVersion int) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		txid = r.URL.Path[i+1:]
+	}
+	if len(txid) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx-spends"}).Inc()
+	return s.api.GetTxSpendStatus(txid)
+}
+
+// apiOutpoint returns the output of a transaction with the context of the transaction, the url is in the form outpoint/<txid>/<vout>
+func (s *PublicServer) apiOutpoint(r *http.Request, apiVersion int) (interface{}, error) {
+	var params []string
+	if i := strings.Index(r.URL.Path, "outpoint/"); i >= 0 {
+		params = strings.Split(r.URL.Path[i+len("outpoint/"):], "/")
+	}
+	if len(params) < 1 || len(params[0]) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	if len(params) < 2 {
+		return nil, api.NewAPIError("Missing vout", true)
+	}
+	vout, err := strconv.Atoi(params[1])
+	if err != nil {
+		return nil, api.NewAPIError(fmt.Sprintf("Invalid vout '%v'", params[1]), true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-outpoint"}).Inc()
+	return s.api.GetOutpointInfo(params[0], vout)
+}
+
+// apiAddressBlockTxs returns the transactions of an address in one block, the url is in the form address-block/<address>/<block height or hash>
+func (s *PublicServer) apiAddressBlockTxs(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-block"}).Inc()
+	var params []string
+	if i := strings.Index(r.URL.Path, "address-block/"); i >= 0 {
+		params = strings.Split(r.URL.Path[i+len("address-block/"):], "/")
+	}
+	if len(params) < 1 || len(params[0]) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	if len(params) < 2 || len(params[1]) == 0 {
+		return nil, api.NewAPIError("Missing block height or hash", true)
+	}
+	return s.api.GetAddressBlockTxs(params[0], params[1])
+}
+
+func (s *PublicServer) apiAddressTimeRange(r *http.Request, apiVersion int) (interface{}, error) {
+	var address string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		address = r.URL.Path[i+1:]
+	}
+	if len(address) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-time"}).Inc()
+	from, ec := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if ec != nil || from < 0 {
+		from = 0
+	}
+	to, ec := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if ec != nil || to < 0 {
+		to = math.MaxInt64
+	}
+	// the from and to parameters are times, getAddressQueryParams reads them as heights, which are replaced
+	page, pageSize, details, filter, _, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	return s.api.GetAddressTimeRange(address, page, pageSize, details, filter, from, to)
+}
+
+func (s *PublicServer) apiAddressBalanceDelta(r *http.Request, apiVersion int) (interface{}, error) {
+	var address string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		address = r.URL.Path[i+1:]
+	}
+	if len(address) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-balance-delta"}).Inc()
+	from, ec := strconv.Atoi(r.URL.Query().Get("from"))
+	if ec != nil || from < 0 {
+		from = 0
+	}
+	to, ec := strconv.Atoi(r.URL.Query().Get("to"))
+	if ec != nil || to < 0 {
+		to = 0
+	}
+	return s.api.GetAddressBalanceDelta(address, uint32(from), uint32(to))
+}
+
+func (s *PublicServer) apiAddressFees(r *http.Request, apiVersion int) (interface{}, error) {
+	var address string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		address = r.URL.Path[i+1:]
+	}
+	if len(address) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-fees"}).Inc()
+	return s.api.GetAddressFees(address)
+}
+
+func (s *PublicServer) apiTxAddressValue(r *http.Request, apiVersion int) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		txid = r.URL.Path[i+1:]
+	}
+	if len(txid) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	address := r.URL.Query().Get("address")
+	if len(address) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx-address-value"}).Inc()
+	return s.api.GetTxAddressValue(txid, address)
+}
+
+func (s *PublicServer) apiAddressBalanceAtHeights(r *http.Request, apiVersion int) (interface{}, error) {
+	var address string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		address = r.URL.Path[i+1:]
+	}
+	if len(address) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-balance-at-heights"}).Inc()
+	var heights [2]uint32
+	for i, p := range []string{"from", "to"} {
+		h, err := strconv.ParseUint(r.URL.Query().Get(p), 10, 32)
+		if err != nil {
+			return nil, api.NewAPIError("Missing or invalid block height '"+p+"'", true)
+		}
+		heights[i] = uint32(h)
+	}
+	return s.api.GetAddressBalanceAtHeights(address, heights[0], heights[1])
+}
+
+// apiDailyTxs returns the number of transactions per UTC day in the range of days given by the query parameters from and to
+func (s *PublicServer) apiDailyTxs(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-daily-txs"}).Inc()
+	return s.api.GetDailyTxs(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+}
+
+// apiLockTimeTxs returns the transactions with the lock time in the range given by the query parameters from and to
+func (s *PublicServer) apiLockTimeTxs(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-locktime-txs"}).Inc()
+	return s.api.GetLockTimeTxs(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+}
+
+// apiBlockValueTxs returns the transactions of the block with the value exceeding the threshold given by the query parameter,
+// the query parameter sort=value orders them by the value
+func (s *PublicServer) apiBlockValueTxs(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block-value-txs"}).Inc()
+	var block string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		block = r.URL.Path[i+1:]
+	}
+	if len(block) == 0 {
+		return nil, api.NewAPIError("Missing block height or hash", true)
+	}
+	byValue := false
+	switch o := r.URL.Query().Get("sort"); o {
+	case "":
+	case "value":
+		byValue = true
+	default:
+		return nil, api.NewAPIError(fmt.Sprintf("Invalid sort '%s'", o), true)
+	}
+	return s.api.GetBlockValueTxs(block, r.URL.Query().Get("threshold"), byValue)
+}
+
+// apiChainStats returns the snapshot of the state of the chain
+func (s *PublicServer) apiChainStats(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-chain-stats"}).Inc()
+	return s.api.GetChainStats()
+}
+
+// apiBalances returns balances of multiple addresses, passed either comma separated in the url
+// or as a json array in the body of POST request
+func (s *PublicServer) apiBalances(r *http.Request, apiVersion int) (interface{}, error) {
+	var addresses []string
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-balances"}).Inc()
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&addresses); err != nil {
+			return nil, api.NewAPIError("Invalid list of addresses", true)
+		}
+	} else if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 && len(r.URL.Path[i+1:]) > 0 {
+		addresses = strings.Split(r.URL.Path[i+1:], ",")
+	}
+	balances, err := s.api.GetAddressesBalances(r.Context(), addresses, minConfirmations(r))
+	if err != nil {
+		return nil, err
+	}
+	if withDenominations(r) {
+		for _, a := range balances {
+			s.api.SetAddressDenominations(a)
+		}
+	}
+	return balances, nil
 }
 
 func (s *PublicServer) apiTxSpecific(r *http.Request, apiVersion int) (interface{}, error) {
@@ -954,6 +1398,12 @@ func (s *PublicServer) apiTxSpecific(r *http.Request, apiVersion int) (interface
 	return tx, err
 }
 
+// withDenominations returns true if the request asks for balances in multiple denominations
+func withDenominations(r *http.Request) bool {
+	d, err := strconv.ParseBool(r.URL.Query().Get("denominations"))
+	return err == nil && d
+}
+
 func (s *PublicServer) apiAddress(r *http.Request, apiVersion int) (interface{}, error) {
 	var addressParam string
 	i := strings.LastIndexByte(r.URL.Path, '/')
@@ -966,28 +1416,60 @@ func (s *PublicServer) apiAddress(r *http.Request, apiVersion int) (interface{},
 	var address *api.Address
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address"}).Inc()
+	cacheKey := r.URL.RequestURI()
+	if data, found := s.apiCache.get(cacheKey); found {
+		return data, nil
+	}
 	page, pageSize, details, filter, _, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	if apiVersion == apiV2 {
+		if filter.Fields, err = api.ParseAddressFields(r.URL.Query().Get("fields")); err != nil {
+			return nil, err
+		}
+	}
 	address, err = s.api.GetAddress(addressParam, page, pageSize, details, filter)
-	if err == nil && apiVersion == apiV1 {
-		return s.api.AddressToV1(address), nil
+	if err != nil {
+		return nil, err
 	}
-	return address, err
+	if apiVersion == apiV2 && withDenominations(r) && filter.Fields.Has("denominations") {
+		s.api.SetAddressDenominations(address)
+	}
+	var data interface{} = address
+	if apiVersion == apiV1 {
+		data = s.api.AddressToV1(address)
+	} else if data, err = filter.Fields.Project(address); err != nil {
+		return nil, err
+	}
+	// addresses with unconfirmed transactions are not cached, the others are invalidated by a new mempool transaction
+	if address.UnconfirmedTxs == 0 {
+		if addrDesc, err := s.chainParser.GetAddrDescFromAddress(addressParam); err == nil {
+			s.apiCache.set(cacheKey, data, string(addrDesc), s.apiCacheTTL.address)
+		}
+	}
+	return data, nil
 }
 
 func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, error) {
 	var xpub string
-	i := strings.LastIndexByte(r.URL.Path, '/')
+	var err error
+	// the xpub can be passed as an output descriptor containing escaped '/' and '#'
+	p := r.URL.EscapedPath()
+	i := strings.LastIndexByte(p, '/')
 	if i > 0 {
-		xpub = r.URL.Path[i+1:]
+		xpub, err = url.PathUnescape(p[i+1:])
+		if err != nil {
+			return nil, api.NewAPIError("Invalid xpub", true)
+		}
 	}
 	if len(xpub) == 0 {
 		return nil, api.NewAPIError("Missing xpub", true)
 	}
 	var address *api.Address
-	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub"}).Inc()
 	page, pageSize, details, filter, _, gap := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
 	address, err = s.api.GetXpubAddress(xpub, page, pageSize, details, filter, gap)
+	if err == nil && apiVersion == apiV2 && withDenominations(r) {
+		s.api.SetAddressDenominations(address)
+	}
 	if err == nil && apiVersion == apiV1 {
 		return s.api.AddressToV1(address), nil
 	}
@@ -995,31 +1477,91 @@ func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, er
 }
 
 func (s *PublicServer) apiUtxo(r *http.Request, apiVersion int) (interface{}, error) {
-	var utxo []api.Utxo
+	var utxo *api.LimitedUtxos
 	var err error
 	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
-		onlyConfirmed := false
+		// the utxos with fewer than minConfirmations confirmations are not returned
+		filter := &api.UtxoFilter{MinConfirmations: minConfirmations(r)}
 		c := r.URL.Query().Get("confirmed")
 		if len(c) > 0 {
-			onlyConfirmed, err = strconv.ParseBool(c)
+			filter.OnlyConfirmed, err = strconv.ParseBool(c)
 			if err != nil {
 				return nil, api.NewAPIError("Parameter 'confirmed' cannot be converted to boolean", true)
 			}
 		}
+		// the response with the summary is returned only if the limit is requested, the server limit truncates both responses
+		limited := false
+		if l := r.URL.Query().Get("limit"); len(l) > 0 {
+			filter.Limit, err = strconv.Atoi(l)
+			if err != nil || filter.Limit <= 0 {
+				return nil, api.NewAPIError(fmt.Sprintf("Invalid limit '%s'", l), true)
+			}
+			limited = true
+		}
 		gap, ec := strconv.Atoi(r.URL.Query().Get("gap"))
 		if ec != nil {
 			gap = 0
 		}
-		utxo, err = s.api.GetXpubUtxo(r.URL.Path[i+1:], onlyConfirmed, gap)
+		utxo, err = s.api.GetXpubLimitedUtxo(r.URL.Path[i+1:], filter, gap)
 		if err == nil {
 			s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub-utxo"}).Inc()
 		} else {
-			utxo, err = s.api.GetAddressUtxo(r.URL.Path[i+1:], onlyConfirmed)
+			utxo, err = s.api.GetAddressLimitedUtxo(r.URL.Path[i+1:], filter)
 			s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-utxo"}).Inc()
 		}
-		if err == nil && apiVersion == apiV1 {
-			return s.api.AddressUtxoToV1(utxo), nil
+		if err != nil {
+			return nil, err
+		}
+		if apiVersion == apiV1 {
+			return s.api.AddressUtxoToV1(utxo.Utxos), nil
+		}
+		if limited {
+			return utxo, nil
 		}
+		return utxo.Utxos, nil
+	}
+	return []api.Utxo{}, nil
+}
+
+func (s *PublicServer) apiScriptHash(r *http.Request, apiVersion int) (interface{}, error) {
+	var scriptHash string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		scriptHash = r.URL.Path[i+1:]
+	}
+	if len(scriptHash) == 0 {
+		return nil, api.NewAPIError("Missing script hash", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-scripthash"}).Inc()
+	page, pageSize, details, filter, _, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	address, err := s.api.GetScriptHashAddress(scriptHash, page, pageSize, details, filter)
+	if err == nil && apiVersion == apiV1 {
+		return s.api.AddressToV1(address), nil
+	}
+	return address, err
+}
+
+func (s *PublicServer) apiScriptHashUtxo(r *http.Request, apiVersion int) (interface{}, error) {
+	var scriptHash string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		scriptHash = r.URL.Path[i+1:]
+	}
+	if len(scriptHash) == 0 {
+		return nil, api.NewAPIError("Missing script hash", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-scripthash-utxo"}).Inc()
+	onlyConfirmed := false
+	if c := r.URL.Query().Get("confirmed"); len(c) > 0 {
+		var err error
+		onlyConfirmed, err = strconv.ParseBool(c)
+		if err != nil {
+			return nil, api.NewAPIError("Parameter 'confirmed' cannot be converted to boolean", true)
+		}
+	}
+	utxo, err := s.api.GetScriptHashUtxo(scriptHash, onlyConfirmed)
+	if err == nil && apiVersion == apiV1 {
+		return s.api.AddressUtxoToV1(utxo), nil
 	}
 	return utxo, err
 }
@@ -1028,19 +1570,65 @@ func (s *PublicServer) apiBlock(r *http.Request, apiVersion int) (interface{}, e
 	var block *api.Block
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block"}).Inc()
+	cacheKey := r.URL.RequestURI()
+	if data, found := s.apiCache.get(cacheKey); found {
+		return data, nil
+	}
 	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
 		page, ec := strconv.Atoi(r.URL.Query().Get("page"))
 		if ec != nil {
 			page = 0
 		}
 		block, err = s.api.GetBlock(r.URL.Path[i+1:], page, txsInAPI)
-		if err == nil && apiVersion == apiV1 {
-			return s.api.BlockToV1(block), nil
+		if err != nil {
+			return nil, err
+		}
+		var data interface{} = block
+		if apiVersion == apiV1 {
+			data = s.api.BlockToV1(block)
 		}
+		s.apiCache.set(cacheKey, data, "", s.apiCacheTTL.block)
+		return data, nil
 	}
 	return block, err
 }
 
+func (s *PublicServer) apiBlockMerkleRoot(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block-merkleroot"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		if bid := r.URL.Path[i+1:]; len(bid) > 0 {
+			return s.api.GetBlockMerkleRoot(bid)
+		}
+	}
+	return nil, api.NewAPIError("Missing block height or hash", true)
+}
+
+func (s *PublicServer) apiBlockRawHeader(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block-header"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		if bid := r.URL.Path[i+1:]; len(bid) > 0 {
+			return s.api.GetBlockRawHeader(bid)
+		}
+	}
+	return nil, api.NewAPIError("Missing block height or hash", true)
+}
+
+func (s *PublicServer) apiBlockRange(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block-range"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		from, ec := strconv.Atoi(r.URL.Path[i+1:])
+		if ec != nil {
+			return nil, api.NewAPIError("Invalid block height", true)
+		}
+		count, ec := strconv.Atoi(r.URL.Query().Get("count"))
+		if ec != nil {
+			count = blocksInAPIRange
+		}
+		return s.api.GetBlockRange(from, count)
+	}
+	return nil, api.NewAPIError("Missing block height", true)
+}
+
 type resultSendTransaction struct {
 	Result string `json:"result"`
 }
@@ -1071,6 +1659,53 @@ func (s *PublicServer) apiSendTx(r *http.Request, apiVersion int) (interface{},
 	return nil, api.NewAPIError("Missing tx blob", true)
 }
 
+func (s *PublicServer) apiSendTxs(r *http.Request, apiVersion int) (interface{}, error) {
+	var txs []string
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-sendtxs"}).Inc()
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Use POST request with the list of transactions", true)
+	}
+	if err := json.NewDecoder(r.Body).Decode(&txs); err != nil {
+		return nil, api.NewAPIError("Invalid list of transactions", true)
+	}
+	return s.api.SendRawTransactions(txs)
+}
+
+func (s *PublicServer) apiStuckMempoolTxs(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-mempool-stuck"}).Inc()
+	return s.api.GetStuckMempoolTxs()
+}
+
+func (s *PublicServer) apiFeeRates(r *http.Request, apiVersion int) (interface{}, error) {
+	var blocks int
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-feerates"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 && len(r.URL.Path[i+1:]) > 0 {
+		var err error
+		blocks, err = strconv.Atoi(r.URL.Path[i+1:])
+		if err != nil || blocks <= 0 {
+			return nil, api.NewAPIError("Parameter 'number of blocks' is not a positive number", true)
+		}
+	}
+	return s.api.GetFeeRatePercentiles(blocks)
+}
+
+func (s *PublicServer) apiMedianFeeRate(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-feerate-median"}).Inc()
+	return s.api.GetMedianFeeRate()
+}
+
+func (s *PublicServer) apiRequiredConfirmations(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-required-confirmations"}).Inc()
+	var value string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		value = r.URL.Path[i+1:]
+	}
+	if len(value) == 0 {
+		return nil, api.NewAPIError("Missing parameter 'value'", true)
+	}
+	return s.api.GetRequiredConfirmations(value)
+}
+
 type resultEstimateFeeAsString struct {
 	Result string `json:"result"`
 }
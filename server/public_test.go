@@ -3,21 +3,30 @@
 package server
 
 import (
+	"blockbook/api"
 	"blockbook/bchain"
+	"blockbook/bchain/coins/bch"
 	"blockbook/bchain/coins/btc"
 	"blockbook/common"
 	"blockbook/db"
 	"blockbook/tests/dbtestdata"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/gorilla/websocket"
 	"github.com/martinboehm/btcutil/chaincfg"
 	gosocketio "github.com/martinboehm/golang-socketio"
 	"github.com/martinboehm/golang-socketio/transport"
@@ -33,7 +42,7 @@ func TestMain(m *testing.M) {
 	os.Exit(c)
 }
 
-func setupRocksDB(t *testing.T, parser bchain.BlockChainParser) (*db.RocksDB, *common.InternalState, string) {
+func setupRocksDB(t *testing.T, parser bchain.BlockChainParser, noAddressIndex bool) (*db.RocksDB, *common.InternalState, string) {
 	tmp, err := ioutil.TempDir("", "testdb")
 	if err != nil {
 		t.Fatal(err)
@@ -47,6 +56,10 @@ func setupRocksDB(t *testing.T, parser bchain.BlockChainParser) (*db.RocksDB, *c
 		t.Fatal(err)
 	}
 	d.SetInternalState(is)
+	d.SetNoAddressIndex(noAddressIndex)
+	is.NoAddressIndex = noAddressIndex
+	d.SetDailyTxsIndex(true)
+	is.DailyTxsIndex = true
 	// import data
 	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(parser)); err != nil {
 		t.Fatal(err)
@@ -57,7 +70,32 @@ func setupRocksDB(t *testing.T, parser bchain.BlockChainParser) (*db.RocksDB, *c
 	return d, is, tmp
 }
 
-func setupPublicHTTPServer(t *testing.T) (*PublicServer, string) {
+// mempoolTxid is reported as being in the mempool by testMempool
+const mempoolTxid = "a8b1a272836f9ccb2bc2a5a93c35bd8b662ade8f533c12c2c398ea2f2fa5bfe1"
+
+// testMempool wraps the mempool and adds a fixed transaction to it
+type testMempool struct {
+	bchain.Mempool
+}
+
+func (m *testMempool) GetTransactionTime(txid string) uint32 {
+	if txid == mempoolTxid {
+		return 1554700000
+	}
+	return m.Mempool.GetTransactionTime(txid)
+}
+
+// GetSpendingTx reports the second output of TxidB2T1 as spent by the mempool transaction mempoolTxid
+func (m *testMempool) GetSpendingTx(outpoint bchain.Outpoint) (string, int) {
+	if outpoint.Txid == dbtestdata.TxidB2T1 && outpoint.Vout == 1 {
+		return mempoolTxid, 1
+	}
+	return m.Mempool.GetSpendingTx(outpoint)
+}
+
+var testMetrics *common.Metrics
+
+func setupPublicHTTPServer(t *testing.T, noAddressIndex bool) (*PublicServer, string) {
 	parser := btc.NewBitcoinParser(
 		btc.GetChainParams("test"),
 		&btc.Configuration{
@@ -68,17 +106,21 @@ func setupPublicHTTPServer(t *testing.T) (*PublicServer, string) {
 			Slip44:                1,
 		})
 
-	d, is, path := setupRocksDB(t, parser)
+	d, is, path := setupRocksDB(t, parser, noAddressIndex)
 	// setup internal state and match BestHeight to test data
 	is.Coin = "Fakecoin"
 	is.CoinLabel = "Fake Coin"
 	is.CoinShortcut = "FAKE"
 	is.BestHeight = 225494
 
-	metrics, err := common.GetMetrics("Fakecoin")
-	if err != nil {
-		glog.Fatal("metrics: ", err)
+	// the metrics can be registered only once, they are shared by the test servers
+	if testMetrics == nil {
+		var err error
+		if testMetrics, err = common.GetMetrics("Fakecoin"); err != nil {
+			glog.Fatal("metrics: ", err)
+		}
 	}
+	metrics := testMetrics
 
 	chain, err := dbtestdata.NewFakeBlockChain(parser)
 	if err != nil {
@@ -89,6 +131,7 @@ func setupPublicHTTPServer(t *testing.T) (*PublicServer, string) {
 	if err != nil {
 		glog.Fatal("mempool: ", err)
 	}
+	mempool = &testMempool{Mempool: mempool}
 
 	// caching is switched off because test transactions do not have hex data
 	txCache, err := db.NewTxCache(d, chain, metrics, is, false)
@@ -97,7 +140,7 @@ func setupPublicHTTPServer(t *testing.T) (*PublicServer, string) {
 	}
 
 	// s.Run is never called, binding can be to any port
-	s, err := NewPublicServer("localhost:12345", "", d, chain, mempool, txCache, "", metrics, is, false)
+	s, err := NewPublicServer("localhost:12345", "", d, chain, mempool, txCache, "", metrics, is, false, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -273,6 +316,16 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 				`</html>`,
 			},
 		},
+		{
+			name:        "explorerSearch block hash prefix",
+			r:           newGetRequest(ts.URL + "/search?q=00000000eb0443fd7dc4"),
+			status:      http.StatusOK,
+			contentType: "text/html; charset=utf-8",
+			body: []string{
+				`<h1>Block 225494</h1>`,
+				`<span class="data">00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6</span>`,
+			},
+		},
 		{
 			name:        "explorerSearch block hash",
 			r:           newGetRequest(ts.URL + "/search?q=00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6"),
@@ -389,6 +442,7 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"blockbook":{"coin":"Fakecoin"`,
 				`"bestHeight":225494`,
 				`"decimals":8`,
+				`"totalTxs":6`,
 				`"backend":{"chain":"fakecoin","blocks":2,"headers":2,"bestblockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6"`,
 				`"version":"001001","subversion":"/Fakecoin:0.0.1/"`,
 			},
@@ -426,7 +480,70 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"n":0,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"value":"9876"}],"vout":[{"value":"9000","n":0,"hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"confirmations":1,"blocktime":22549400002,"value":"9000","valueIn":"9876","fees":"876"}`,
+				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"n":0,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"value":"9876"}],"fundingAddresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"vout":[{"value":"9000","n":0,"hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"txIndexInBlock":2,"confirmations":1,"blocktime":22549400002,"block":{"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"time":1534859123},"value":"9000","valueIn":"9876","fees":"876"}`,
+			},
+		},
+		{
+			name:        "apiTx v2 inputs resolved",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/" + dbtestdata.TxidB2T2),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vin":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","n":0,"addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"],"value":"317283951061"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":1,"n":1,"addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"value":"1"}],"fundingAddresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX","2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"vout":[{"value":"118641975500","n":0,"hex":"a91495e9fbe306449c991d314afe3c3567d5bf78efd287","addresses":["2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu"]},{"value":"198641975500","n":1,"hex":"76a9143f8ba3fda3ba7b69f5818086e12223c6dd25e3c888ac","addresses":["mmJx9Y8ayz9h14yd9fgCW1bUKoEpkBAquP"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"txIndexInBlock":1,"confirmations":1,"blocktime":22549400001,"block":{"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"time":1534859123},"value":"317283951000","valueIn":"317283951062","fees":"62"}`,
+			},
+		},
+		{
+			name:        "apiTx v2 hex not available",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/" + dbtestdata.TxidB2T2 + "?hex=true"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Raw data of transaction 3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71 not available"}`,
+			},
+		},
+		{
+			name:        "apiTx v2 invalid hex parameter",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/" + dbtestdata.TxidB2T2 + "?hex=raw"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Parameter 'hex' cannot be converted to boolean"}`,
+			},
+		},
+		{
+			name:        "apiTx v2 index first in block",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/" + dbtestdata.TxidB1T1 + "?indexInBlock=true"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"txIndexInBlock":0,`,
+			},
+		},
+		{
+			name:        "apiTx v2 index second in block",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/" + dbtestdata.TxidB1T2 + "?indexInBlock=true"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"txIndexInBlock":1,`,
+			},
+		},
+		{
+			name:        "apiTx v2 index in block not kept in index",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/" + dbtestdata.TxidB1T2),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"blockheight":225493,"confirmations":2,`,
+			},
+		},
+		{
+			name:        "apiTx v2 index last in block",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/" + dbtestdata.TxidB2T4),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"txIndexInBlock":3,`,
 			},
 		},
 		{
@@ -462,7 +579,7 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}`,
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493,"txids":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}`,
 			},
 		},
 		{
@@ -471,7 +588,52 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2}`,
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 order=desc page 1",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?pageSize=1&page=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":2,"itemsOnPage":1,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493,"txids":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 order=desc page 2",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?pageSize=1&page=2"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":2,"totalPages":2,"itemsOnPage":1,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493,"txids":["effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 order=asc page 1",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?pageSize=1&page=1&order=asc"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":2,"itemsOnPage":1,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493,"txids":["effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 order=asc page 2",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?pageSize=1&page=2&order=asc"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":2,"totalPages":2,"itemsOnPage":1,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493,"txids":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 denominations",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?details=basic&denominations=true"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493,"denominations":{"decimals":8,"balance":{"sat":"0","value":"0.00000000"},"totalReceived":{"sat":"1234567890123","value":"12345.67890123"},"totalSent":{"sat":"1234567890123","value":"12345.67890123"},"unconfirmedBalance":{"sat":"0","value":"0.00000000"}}}`,
 			},
 		},
 		{
@@ -480,7 +642,34 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"transactions":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","n":0,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"value":"1234567890123"},{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":1,"n":1,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"value":"12345"}],"vout":[{"value":"317283951061","n":0,"spent":true,"hex":"76a914ccaaaf374e1b06cb83118453d102587b4273d09588ac","addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"]},{"value":"917283951061","n":1,"hex":"76a9148d802c045445df49613f6a70ddd2e48526f3701f88ac","addresses":["mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"confirmations":1,"blocktime":22549400000,"value":"1234567902122","valueIn":"1234567902468","fees":"346"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"hex":"76a914a08eae93007f22668ab5e4a9c83c8cd1c325e3e088ac","addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"]},{"value":"1","n":1,"spent":true,"hex":"a91452724c5178682f70e0ba31c6ec0633755a3b41d987","addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"]},{"value":"9876","n":2,"spent":true,"hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"]}],"blockhash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockheight":225493,"confirmations":2,"blocktime":22549300001,"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493,"transactions":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","n":0,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"value":"1234567890123"},{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":1,"n":1,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"value":"12345"}],"fundingAddresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"vout":[{"value":"317283951061","n":0,"spent":true,"hex":"76a914ccaaaf374e1b06cb83118453d102587b4273d09588ac","addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"]},{"value":"917283951061","n":1,"hex":"76a9148d802c045445df49613f6a70ddd2e48526f3701f88ac","addresses":["mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"confirmations":1,"blocktime":22549400000,"block":{"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"time":1534859123},"value":"1234567902122","valueIn":"1234567902468","fees":"346"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"hex":"76a914a08eae93007f22668ab5e4a9c83c8cd1c325e3e088ac","addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"]},{"value":"1","n":1,"spent":true,"hex":"a91452724c5178682f70e0ba31c6ec0633755a3b41d987","addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"]},{"value":"9876","n":2,"spent":true,"hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"]}],"blockhash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockheight":225493,"confirmations":2,"blocktime":22549300001,"block":{"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","height":225493,"time":1534858021},"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 fields=balance",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?fields=balance"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"balance":"0"}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 fields=totalReceived,txids",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?fields=totalReceived,txids&pageSize=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"totalReceived":"1234567890123","txids":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 fields unknown",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?fields=balance,foo"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Unknown field 'foo'"}`,
 			},
 		},
 		{
@@ -552,7 +741,34 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"page":1,"totalPages":1,"itemsOnPage":3,"address":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q","balance":"118641975500","totalReceived":"118641975501","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"transactions":[{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vin":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","n":0,"addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"],"value":"317283951061"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":1,"n":1,"addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"value":"1"}],"vout":[{"value":"118641975500","n":0,"hex":"a91495e9fbe306449c991d314afe3c3567d5bf78efd287","addresses":["2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu"]},{"value":"198641975500","n":1,"hex":"76a9143f8ba3fda3ba7b69f5818086e12223c6dd25e3c888ac","addresses":["mmJx9Y8ayz9h14yd9fgCW1bUKoEpkBAquP"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"confirmations":1,"blocktime":22549400001,"value":"317283951000","valueIn":"317283951062","fees":"62"}],"totalTokens":2,"tokens":[{"type":"XPUBAddress","name":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","path":"m/49'/1'/33'/0/0","transfers":2,"decimals":8,"balance":"0","totalReceived":"1","totalSent":"1"},{"type":"XPUBAddress","name":"2MsYfbi6ZdVXLDNrYAQ11ja9Sd3otMk4Pmj","path":"m/49'/1'/33'/0/1","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MuAZNAjLSo6RLFad2fvHSfgqBD7BoEVy4T","path":"m/49'/1'/33'/0/2","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2NEqKzw3BosGnBE9by5uaDy5QgwjHac4Zbg","path":"m/49'/1'/33'/0/3","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2Mw7vJNC8zUK6VNN4CEjtoTYmuNPLewxZzV","path":"m/49'/1'/33'/0/4","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N1kvo97NFASPXiwephZUxE9PRXunjTxEc4","path":"m/49'/1'/33'/0/5","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MzSBtRWHbBjeUcu3H5VRDqkvz5sfmDxJKo","path":"m/49'/1'/33'/1/0","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MtShtAJYb1afWduUTwF1SixJjan7urZKke","path":"m/49'/1'/33'/1/1","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N3cP668SeqyBEr9gnB4yQEmU3VyxeRYith","path":"m/49'/1'/33'/1/2","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","path":"m/49'/1'/33'/1/3","transfers":1,"decimals":8,"balance":"118641975500","totalReceived":"118641975500","totalSent":"0"},{"type":"XPUBAddress","name":"2NEzatauNhf9kPTwwj6ZfYKjUdy52j4hVUL","path":"m/49'/1'/33'/1/4","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N4RjsDp4LBpkNqyF91aNjgpF9CwDwBkJZq","path":"m/49'/1'/33'/1/5","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N8XygTmQc4NoBBPEy3yybnfCYhsxFtzPDY","path":"m/49'/1'/33'/1/6","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N5BjBomZvb48sccK2vwLMiQ5ETKp1fdPVn","path":"m/49'/1'/33'/1/7","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MybMwbZRPCGU3SMWPwQCpDkbcQFw5Hbwen","path":"m/49'/1'/33'/1/8","transfers":0,"decimals":8}]}`,
+				`{"page":1,"totalPages":1,"itemsOnPage":3,"address":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q","balance":"118641975500","totalReceived":"118641975501","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"transactions":[{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vin":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","n":0,"addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"],"value":"317283951061"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":1,"n":1,"addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"value":"1"}],"fundingAddresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX","2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"vout":[{"value":"118641975500","n":0,"hex":"a91495e9fbe306449c991d314afe3c3567d5bf78efd287","addresses":["2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu"]},{"value":"198641975500","n":1,"hex":"76a9143f8ba3fda3ba7b69f5818086e12223c6dd25e3c888ac","addresses":["mmJx9Y8ayz9h14yd9fgCW1bUKoEpkBAquP"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"confirmations":1,"blocktime":22549400001,"block":{"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"time":1534859123},"value":"317283951000","valueIn":"317283951062","fees":"62"}],"totalTokens":2,"tokens":[{"type":"XPUBAddress","name":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","path":"m/49'/1'/33'/0/0","transfers":2,"decimals":8,"balance":"0","totalReceived":"1","totalSent":"1"},{"type":"XPUBAddress","name":"2MsYfbi6ZdVXLDNrYAQ11ja9Sd3otMk4Pmj","path":"m/49'/1'/33'/0/1","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MuAZNAjLSo6RLFad2fvHSfgqBD7BoEVy4T","path":"m/49'/1'/33'/0/2","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2NEqKzw3BosGnBE9by5uaDy5QgwjHac4Zbg","path":"m/49'/1'/33'/0/3","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2Mw7vJNC8zUK6VNN4CEjtoTYmuNPLewxZzV","path":"m/49'/1'/33'/0/4","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N1kvo97NFASPXiwephZUxE9PRXunjTxEc4","path":"m/49'/1'/33'/0/5","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MzSBtRWHbBjeUcu3H5VRDqkvz5sfmDxJKo","path":"m/49'/1'/33'/1/0","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MtShtAJYb1afWduUTwF1SixJjan7urZKke","path":"m/49'/1'/33'/1/1","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N3cP668SeqyBEr9gnB4yQEmU3VyxeRYith","path":"m/49'/1'/33'/1/2","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","path":"m/49'/1'/33'/1/3","transfers":1,"decimals":8,"balance":"118641975500","totalReceived":"118641975500","totalSent":"0"},{"type":"XPUBAddress","name":"2NEzatauNhf9kPTwwj6ZfYKjUdy52j4hVUL","path":"m/49'/1'/33'/1/4","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N4RjsDp4LBpkNqyF91aNjgpF9CwDwBkJZq","path":"m/49'/1'/33'/1/5","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N8XygTmQc4NoBBPEy3yybnfCYhsxFtzPDY","path":"m/49'/1'/33'/1/6","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N5BjBomZvb48sccK2vwLMiQ5ETKp1fdPVn","path":"m/49'/1'/33'/1/7","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MybMwbZRPCGU3SMWPwQCpDkbcQFw5Hbwen","path":"m/49'/1'/33'/1/8","transfers":0,"decimals":8}]}`,
+			},
+		},
+		{
+			name:        "apiXpub v2 descriptor details=basic",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + url.PathEscape("sh(wpkh([5c9e228d/49'/1'/33']upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q/0/*))#205um5ds") + "?details=basic"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"sh(wpkh([5c9e228d/49'/1'/33']upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q/0/*))#205um5ds","balance":"118641975500","totalReceived":"118641975501","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":3,"totalTokens":2}`,
+			},
+		},
+		{
+			name:        "apiXpub v2 descriptor missing checksum",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + url.PathEscape("sh(wpkh([5c9e228d/49'/1'/33']upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q/0/*))") + "?details=basic"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing descriptor checksum"}`,
+			},
+		},
+		{
+			name:        "apiXpub v2 descriptor wrong checksum",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + url.PathEscape("sh(wpkh([5c9e228d/49'/1'/33']upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q/0/*))#205um5dx") + "?details=basic"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Descriptor checksum '205um5dx' does not match computed checksum '205um5ds'"}`,
 			},
 		},
 		{
@@ -564,6 +780,24 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"error":"Missing xpub"}`,
 			},
 		},
+		{
+			name:        "apiTx v2 asm not supported",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25?asm=true"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Script disassembly not supported"}`,
+			},
+		},
+		{
+			name:        "apiTx v2 invalid asm",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25?asm=x"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Parameter 'asm' cannot be converted to boolean"}`,
+			},
+		},
 		{
 			name:        "apiUtxo v1",
 			r:           newGetRequest(ts.URL + "/api/v1/utxo/mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"),
@@ -592,140 +826,898 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 			},
 		},
 		{
-			name:        "apiSendTx",
-			r:           newGetRequest(ts.URL + "/api/v2/sendtx/1234567890"),
-			status:      http.StatusBadRequest,
+			name:        "apiUtxo v2 confirmed minConfirmations=2",
+			r:           newGetRequest(ts.URL + "/api/v2/utxo/mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL?confirmed=true&minConfirmations=2"),
+			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"error":"Invalid data"}`,
+				`[]`,
 			},
 		},
 		{
-			name:        "apiSendTx POST",
-			r:           newPostRequest(ts.URL+"/api/v2/sendtx/", "123456"),
+			name:        "apiUtxo v2 minConfirmations=2 without confirmed",
+			r:           newGetRequest(ts.URL + "/api/v2/utxo/mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL?minConfirmations=2"),
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"result":"9876"}`,
+				`[]`,
 			},
 		},
 		{
-			name:        "apiSendTx POST empty",
-			r:           newPostRequest(ts.URL+"/api/v2/sendtx", ""),
-			status:      http.StatusBadRequest,
+			name:        "apiUtxo v2 minConfirmations=2 older output without confirmed",
+			r:           newGetRequest(ts.URL + "/api/v2/utxo/" + dbtestdata.Addr1 + "?minConfirmations=2"),
+			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"error":"Missing tx blob"}`,
+				`[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":0,"value":"100000000","height":225493,"confirmations":2}]`,
 			},
 		},
 		{
-			name:        "apiEstimateFee",
-			r:           newGetRequest(ts.URL + "/api/estimatefee/123?conservative=false"),
+			name:        "apiUtxo v2 confirmed minConfirmations=2 older output",
+			r:           newGetRequest(ts.URL + "/api/v2/utxo/" + dbtestdata.Addr1 + "?confirmed=true&minConfirmations=2"),
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"result":"0.00012299"}`,
+				`[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":0,"value":"100000000","height":225493,"confirmations":2}]`,
 			},
 		},
 		{
-			name:        "apiGetBlock",
-			r:           newGetRequest(ts.URL + "/api/v2/block/225493"),
+			name:        "apiUtxo v2 limit",
+			r:           newGetRequest(ts.URL + "/api/v2/utxo/" + dbtestdata.Xpub + "?limit=1"),
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"page":1,"totalPages":1,"itemsOnPage":1000,"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","previousblockhash":"","nextblockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225493,"confirmations":2,"size":1234567,"time":1534858021,"version":0,"merkleroot":"","nonce":"","bits":"","difficulty":"","txCount":2,"txs":[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vin":[],"vout":[{"value":"100000000","n":0,"addresses":["mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti"]},{"value":"12345","n":1,"spent":true,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"]}],"blockhash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockheight":225493,"confirmations":2,"blocktime":1534858021,"value":"100012345","valueIn":"0","fees":"0"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"]},{"value":"1","n":1,"spent":true,"addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"]},{"value":"9876","n":2,"spent":true,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"]}],"blockhash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockheight":225493,"confirmations":2,"blocktime":1534858021,"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
+				`{"utxos":[{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vout":0,"value":"118641975500","height":225494,"confirmations":1,"address":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","path":"m/49'/1'/33'/1/3"}],"truncated":false,"totalUtxos":1,"totalValue":"118641975500"}`,
 			},
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			resp, err := http.DefaultClient.Do(tt.r)
-			if err != nil {
-				t.Fatal(err)
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode != tt.status {
-				t.Errorf("StatusCode = %v, want %v", resp.StatusCode, tt.status)
-			}
-			if resp.Header["Content-Type"][0] != tt.contentType {
-				t.Errorf("Content-Type = %v, want %v", resp.Header["Content-Type"][0], tt.contentType)
-			}
-			bb, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				t.Fatal(err)
-			}
-			b := string(bb)
-			for _, c := range tt.body {
-				if !strings.Contains(b, c) {
-					t.Errorf("got %v, want to contain %v", b, c)
-					break
-				}
-			}
-		})
-	}
-}
-
-func socketioTests_BitcoinType(t *testing.T, ts *httptest.Server) {
-	type socketioReq struct {
-		Method string        `json:"method"`
-		Params []interface{} `json:"params"`
-	}
-
-	url := strings.Replace(ts.URL, "http://", "ws://", 1) + "/socket.io/"
-	s, err := gosocketio.Dial(url, transport.GetDefaultWebsocketTransport())
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s.Close()
-
-	tests := []struct {
-		name string
-		req  socketioReq
-		want string
-	}{
 		{
-			name: "getInfo",
-			req:  socketioReq{"getInfo", []interface{}{}},
-			want: `{"result":{"blocks":225494,"testnet":true,"network":"fakecoin","subversion":"/Fakecoin:0.0.1/","coin_name":"Fakecoin","about":"Blockbook - blockchain indexer for TREZOR wallet https://trezor.io/. Do not use for any other purpose."}}`,
+			name:        "apiUtxo v2 invalid limit",
+			r:           newGetRequest(ts.URL + "/api/v2/utxo/" + dbtestdata.Addr1 + "?limit=0"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid limit '0'"}`,
+			},
 		},
 		{
-			name: "estimateFee",
-			req:  socketioReq{"estimateFee", []interface{}{17}},
-			want: `{"result":0.000034}`,
+			name:        "apiAddress v2 never funded",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mnnAKPTSrWjgoi3uEYaQkHA1QEC5btFeBr?details=basic"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mnnAKPTSrWjgoi3uEYaQkHA1QEC5btFeBr","balance":"0","totalReceived":"0","totalSent":"0","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":0,"firstFundedHeight":-1}`,
+			},
 		},
 		{
-			name: "estimateSmartFee",
-			req:  socketioReq{"estimateSmartFee", []interface{}{19, true}},
-			want: `{"result":0.000019}`,
+			name:        "apiAddress v2 minConfirmations=1",
+			r:           newGetRequest(ts.URL + "/api/v2/address/" + dbtestdata.Addr5 + "?details=basic&minConfirmations=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"9000","totalReceived":"18876","totalSent":"9876","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493}`,
+			},
 		},
 		{
-			name: "getAddressTxids",
-			req: socketioReq{"getAddressTxids", []interface{}{
-				[]string{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"},
-				map[string]interface{}{
-					"start":        2000000,
-					"end":          0,
-					"queryMempool": false,
-				},
-			}},
-			want: `{"result":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840"]}`,
+			name:        "apiAddress v2 minConfirmations=2",
+			r:           newGetRequest(ts.URL + "/api/v2/address/" + dbtestdata.Addr5 + "?details=basic&minConfirmations=2"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"0","totalReceived":"18876","totalSent":"9876","unconfirmedBalance":"9000","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493}`,
+			},
 		},
 		{
-			name: "getAddressTxids limited range",
-			req: socketioReq{"getAddressTxids", []interface{}{
-				[]string{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"},
-				map[string]interface{}{
-					"start":        225494,
-					"end":          225494,
-					"queryMempool": false,
-				},
-			}},
-			want: `{"result":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"]}`,
+			name:        "apiAddress v2 minConfirmations=2 older output",
+			r:           newGetRequest(ts.URL + "/api/v2/address/" + dbtestdata.Addr1 + "?details=basic&minConfirmations=2"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti","balance":"100000000","totalReceived":"100000000","totalSent":"0","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":1,"firstFundedHeight":225493}`,
+			},
 		},
 		{
-			name: "getAddressHistory",
-			req: socketioReq{"getAddressHistory", []interface{}{
+			name:        "apiAddress v2 minConfirmations=3 older output",
+			r:           newGetRequest(ts.URL + "/api/v2/address/" + dbtestdata.Addr1 + "?details=basic&minConfirmations=3"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti","balance":"0","totalReceived":"100000000","totalSent":"0","unconfirmedBalance":"100000000","unconfirmedTxs":0,"txs":1,"firstFundedHeight":225493}`,
+			},
+		},
+		{
+			name:        "apiSendTx",
+			r:           newGetRequest(ts.URL + "/api/v2/sendtx/1234567890"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid data"}`,
+			},
+		},
+		{
+			name:        "apiSendTx POST",
+			r:           newPostRequest(ts.URL+"/api/v2/sendtx/", "123456"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"result":"9876"}`,
+			},
+		},
+		{
+			name:        "apiSendTx POST empty",
+			r:           newPostRequest(ts.URL+"/api/v2/sendtx", ""),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing tx blob"}`,
+			},
+		},
+		{
+			name:        "apiSendTxs POST",
+			r:           newPostRequest(ts.URL+"/api/v2/sendtxs/", `["123456","1234567890","`+dbtestdata.TxKnownHex+`","123456"]`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"txid":"9876"},{"error":"Invalid data"},{"txid":"` + dbtestdata.TxKnownTxid + `"},{"txid":"9876"}]`,
+			},
+		},
+		{
+			name:        "apiSendTxs POST empty",
+			r:           newPostRequest(ts.URL+"/api/v2/sendtxs/", `[]`),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing tx blob"}`,
+			},
+		},
+		{
+			name:        "apiSendTxs POST invalid",
+			r:           newPostRequest(ts.URL+"/api/v2/sendtxs/", "123456"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid list of transactions"}`,
+			},
+		},
+		{
+			name:        "apiFeeRates",
+			r:           newGetRequest(ts.URL + "/api/v2/feerates/"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"fromHeight":225493,"toHeight":225494,"txs":3,"percentiles":[{"percentile":10,"feeRate":"392"},{"percentile":25,"feeRate":"392"},{"percentile":50,"feeRate":"2162"},{"percentile":75,"feeRate":"2162"},{"percentile":90,"feeRate":"10554"}]}`,
+			},
+		},
+		{
+			name:        "apiStuckMempoolTxs",
+			r:           newGetRequest(ts.URL + "/api/v2/mempool-stuck"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"fromHeight":225493,"toHeight":225494,"percentile":10,"feeRate":"392","mempoolSize":0,"txs":[]}`,
+			},
+		},
+		{
+			name:        "apiFeeRates 1 block",
+			r:           newGetRequest(ts.URL + "/api/v2/feerates/1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"fromHeight":225494,"toHeight":225494,"txs":3,"percentiles":[{"percentile":10,"feeRate":"392"},{"percentile":25,"feeRate":"392"},{"percentile":50,"feeRate":"2162"},{"percentile":75,"feeRate":"2162"},{"percentile":90,"feeRate":"10554"}]}`,
+			},
+		},
+		{
+			name:        "apiBlockValueTxs",
+			r:           newGetRequest(ts.URL + "/api/v2/block-value-txs/225494?threshold=100000000"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"threshold":"100000000","txs":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","value":"1234567902122"},{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","value":"317283951000"},{"txid":"fdd824a780cbb718eeb766eb05d83fdefc793a27082cd5e67f856d69798cf7db","value":"1360030331"}]}`,
+			},
+		},
+		{
+			name:        "apiBlockValueTxs sort by value",
+			r:           newGetRequest(ts.URL + "/api/v2/block-value-txs/225494?threshold=317283951000&sort=value"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"threshold":"317283951000","txs":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","value":"1234567902122"}]}`,
+			},
+		},
+		{
+			name:        "apiBlockValueTxs invalid threshold",
+			r:           newGetRequest(ts.URL + "/api/v2/block-value-txs/225494?threshold=-1"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid threshold '-1'"}`,
+			},
+		},
+		{
+			name:        "apiRequiredConfirmations without rates",
+			r:           newGetRequest(ts.URL + "/api/v2/required-confirmations/123456789"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"value":"123456789","confirmations":6,"defaultUsed":true}`,
+			},
+		},
+		{
+			name:        "apiRequiredConfirmations invalid",
+			r:           newGetRequest(ts.URL + "/api/v2/required-confirmations/1.5"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid value '1.5'"}`,
+			},
+		},
+		{
+			name:        "apiMedianFeeRate",
+			r:           newGetRequest(ts.URL + "/api/v2/feerate-median"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"height":225494,"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","txs":3,"feeRate":"2162"}`,
+			},
+		},
+		{
+			name:        "apiFeeRates invalid",
+			r:           newGetRequest(ts.URL + "/api/v2/feerates/x"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Parameter 'number of blocks' is not a positive number"}`,
+			},
+		},
+		{
+			name:        "apiEstimateFee",
+			r:           newGetRequest(ts.URL + "/api/estimatefee/123?conservative=false"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"result":"0.00012299"}`,
+			},
+		},
+		{
+			name:        "apiGetBlock",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225493"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","previousblockhash":"","nextblockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225493,"confirmations":2,"size":1234567,"time":1534858021,"version":0,"merkleroot":"","nonce":"","bits":"","difficulty":"","inBestChain":true,"txCount":2,"txs":[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vin":[],"vout":[{"value":"100000000","n":0,"addresses":["mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti"]},{"value":"12345","n":1,"spent":true,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"]}],"blockhash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockheight":225493,"confirmations":2,"blocktime":1534858021,"block":{"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","height":225493,"time":1534858021},"value":"100012345","valueIn":"0","fees":"0"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"]},{"value":"1","n":1,"spent":true,"addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"]},{"value":"9876","n":2,"spent":true,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"]}],"blockhash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockheight":225493,"confirmations":2,"blocktime":1534858021,"block":{"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","height":225493,"time":1534858021},"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
+			},
+		},
+		{
+			name:        "apiGetBlock tip",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225494?pageSize=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","previousblockhash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","nextblockhash":"","height":225494`,
+			},
+		},
+		{
+			name:        "apiBlockRange",
+			r:           newGetRequest(ts.URL + "/api/v2/block-range/225493?count=5"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"from":225493,"blocks":[{"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","previousblockhash":"","nextblockhash":"","height":225493,"confirmations":2,"size":1234567,"time":1534858021,"txCount":2},{"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","previousblockhash":"","nextblockhash":"","height":225494,"confirmations":1,"size":2345678,"time":1534859123,"txCount":4}]}`,
+			},
+		},
+		{
+			name:        "apiBlockRange out of range",
+			r:           newGetRequest(ts.URL + "/api/v2/block-range/225495"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Block not found"}`,
+			},
+		},
+		{
+			name:        "apiTxMetadata",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-meta/" + dbtestdata.TxidB2T1),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","blockheight":225494,"confirmations":1,"blocktime":1534859123,"vinCount":2,"voutCount":2,"value":"1234567902122"}`,
+			},
+		},
+		{
+			name:        "apiBalances",
+			r:           newGetRequest(ts.URL + "/api/v2/balances/" + dbtestdata.Addr6 + "," + dbtestdata.Addr5),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"address":"mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX","balance":"0","totalReceived":"317283951061","totalSent":"317283951061","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225494},{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"9000","totalReceived":"18876","totalSent":"9876","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493}]`,
+			},
+		},
+		{
+			name:        "apiBalances POST",
+			r:           newPostRequest(ts.URL+"/api/v2/balances/", `["`+dbtestdata.Addr5+`","`+dbtestdata.Addr6+`"]`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"9000","totalReceived":"18876","totalSent":"9876","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493},{"address":"mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX","balance":"0","totalReceived":"317283951061","totalSent":"317283951061","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225494}]`,
+			},
+		},
+		{
+			name:        "apiBalances invalid address",
+			r:           newGetRequest(ts.URL + "/api/v2/balances/" + dbtestdata.Addr5 + ",1234"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid address, decoded address is of unknown format"}`,
+			},
+		},
+		{
+			name:        "apiTxSpendStatus spent",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-spends/" + dbtestdata.TxidB1T2),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"n":0,"spent":true,"spentTxId":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","spentHeight":225494},{"n":1,"spent":true,"spentTxId":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","spentIndex":1,"spentHeight":225494},{"n":2,"spent":true,"spentTxId":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","spentHeight":225494}]`,
+			},
+		},
+		{
+			name:        "apiTxSpendStatus spent in mempool",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-spends/" + dbtestdata.TxidB2T1),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"n":0,"spent":true,"spentTxId":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","spentHeight":225494},{"n":1,"spent":true,"spentTxId":"a8b1a272836f9ccb2bc2a5a93c35bd8b662ade8f533c12c2c398ea2f2fa5bfe1","spentIndex":1}]`,
+			},
+		},
+		{
+			name:        "apiTxSpendStatus unspent",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-spends/" + dbtestdata.TxidB2T2),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"n":0,"spent":false},{"n":1,"spent":false}]`,
+			},
+		},
+		{
+			name:        "apiBlock hash prefix",
+			r:           newGetRequest(ts.URL + "/api/v2/block/0000000076FBBED90FD7"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997"`,
+				`"height":225493`,
+			},
+		},
+		{
+			name:        "apiBlock hash prefix not found",
+			r:           newGetRequest(ts.URL + "/api/v2/block/00000000ffffffff"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Block hash prefix '00000000ffffffff' not found in the last 10000 blocks, use the full block hash"}`,
+			},
+		},
+		{
+			name:        "apiBlock hash prefix of decimal digits",
+			r:           newGetRequest(ts.URL + "/api/v2/block/0000000000225493"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Block hash prefix '0000000000225493' not found in the last 10000 blocks, use the full block hash"}`,
+			},
+		},
+		{
+			name:        "apiBlock hash prefix too short",
+			r:           newGetRequest(ts.URL + "/api/v2/block/00000000eb04"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Block hash prefix must have at least 16 characters"}`,
+			},
+		},
+		{
+			name:        "apiOutpoint spent",
+			r:           newGetRequest(ts.URL + "/api/v2/outpoint/" + dbtestdata.TxidB1T2 + "/0"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"found":true,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true,"value":"1234567890123","blockheight":225493,"confirmations":2,"blocktime":1534858021,"spent":true,"spentTxId":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","spentHeight":225494}`,
+			},
+		},
+		{
+			name:        "apiOutpoint unspent",
+			r:           newGetRequest(ts.URL + "/api/v2/outpoint/" + dbtestdata.TxidB2T2 + "/1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vout":1,"found":true,"addresses":["mmJx9Y8ayz9h14yd9fgCW1bUKoEpkBAquP"],"isAddress":true,"value":"198641975500","blockheight":225494,"confirmations":1,"blocktime":1534859123,"spent":false}`,
+			},
+		},
+		{
+			name:        "apiOutpoint nonexistent vout",
+			r:           newGetRequest(ts.URL + "/api/v2/outpoint/" + dbtestdata.TxidB2T2 + "/2"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"` + dbtestdata.TxidB2T2 + `","vout":2,"found":false,"spent":false}`,
+			},
+		},
+		{
+			name:        "apiOutpoint nonexistent tx",
+			r:           newGetRequest(ts.URL + "/api/v2/outpoint/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/0"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","vout":0,"found":false,"spent":false}`,
+			},
+		},
+		{
+			name:        "apiOutpoint invalid vout",
+			r:           newGetRequest(ts.URL + "/api/v2/outpoint/" + dbtestdata.TxidB2T2 + "/x"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid vout 'x'"}`,
+			},
+		},
+		{
+			name:        "apiAddressBlockTxs active",
+			r:           newGetRequest(ts.URL + "/api/v2/address-block/" + dbtestdata.Addr3 + "/225494"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"txs":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vin":[{"n":0,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"value":"1234567890123"},{"n":1,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"value":"12345"}],"fundingAddresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"vout":[{"value":"317283951061","n":0,"spent":true,"addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"]},{"value":"917283951061","n":1,"addresses":["mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"confirmations":1,"blocktime":1534859123,"block":{"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"time":1534859123},"value":"1234567902122","valueIn":"1234567902468","fees":"346"}]}`,
+			},
+		},
+		{
+			name:        "apiAddressBlockTxs active by hash",
+			r:           newGetRequest(ts.URL + "/api/v2/address-block/" + dbtestdata.Addr1 + "/0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti","hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","height":225493,"txs":[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vin":[],"vout":[{"value":"100000000","n":0,"addresses":["mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti"]},{"value":"12345","n":1,"spent":true,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"]}],"blockhash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockheight":225493,"confirmations":2,"blocktime":1534858021,"block":{"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","height":225493,"time":1534858021},"value":"100012345","valueIn":"0","fees":"0"}]}`,
+			},
+		},
+		{
+			name:        "apiAddressBlockTxs inactive",
+			r:           newGetRequest(ts.URL + "/api/v2/address-block/" + dbtestdata.Addr1 + "/225494"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti","hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"txs":[]}`,
+			},
+		},
+		{
+			name:        "apiAddressBlockTxs missing block",
+			r:           newGetRequest(ts.URL + "/api/v2/address-block/" + dbtestdata.Addr1),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing block height or hash"}`,
+			},
+		},
+		{
+			name:        "apiAddressTimeRange fully inside",
+			r:           newGetRequest(ts.URL + "/api/v2/address-time/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=1534859000&to=1534859200"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493,"txids":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"]}`,
+			},
+		},
+		{
+			name:        "apiAddressTimeRange partially before",
+			r:           newGetRequest(ts.URL + "/api/v2/address-time/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=1000000000&to=1534858021"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493,"txids":["effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}`,
+			},
+		},
+		{
+			name:        "apiAddressTimeRange partially after",
+			r:           newGetRequest(ts.URL + "/api/v2/address-time/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=1534858022"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493,"txids":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"]}`,
+			},
+		},
+		{
+			name:        "apiAddressTimeRange fully outside",
+			r:           newGetRequest(ts.URL + "/api/v2/address-time/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=1600000000&to=1700000000"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493}`,
+			},
+		},
+		{
+			name:        "apiAddressTimeRange invalid range",
+			r:           newGetRequest(ts.URL + "/api/v2/address-time/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=1534859123&to=1534858021"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid time range 1534859123-1534858021"}`,
+			},
+		},
+		{
+			name:        "apiAddressFees spending",
+			r:           newGetRequest(ts.URL + "/api/v2/address-fees/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","fees":"346","txs":1}`,
+			},
+		},
+		{
+			name:        "apiAddressFees only receiving",
+			r:           newGetRequest(ts.URL + "/api/v2/address-fees/" + dbtestdata.Addr1),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti","fees":"0","txs":0}`,
+			},
+		},
+		{
+			name:        "apiTxAddressValue self-send",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-address-value/" + dbtestdata.TxidB2T3 + "?address=" + dbtestdata.Addr5),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","blockHeight":225494,"confirmations":1,"received":"9000","sent":"9876","value":"-876"}`,
+			},
+		},
+		{
+			name:        "apiTxAddressValue receiving",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-address-value/" + dbtestdata.TxidB2T2 + "?address=" + dbtestdata.Addr8),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"received":"118641975500","sent":"0","value":"118641975500"}`,
+			},
+		},
+		{
+			name:        "apiTxAddressValue address not in transaction",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-address-value/" + dbtestdata.TxidB2T2 + "?address=" + dbtestdata.Addr1),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"received":"0","sent":"0","value":"0"}`,
+			},
+		},
+		{
+			name:        "apiTxAddressValue missing address",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-address-value/" + dbtestdata.TxidB2T2),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing address"}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceDelta to tip",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-delta/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","fromHeight":0,"toHeight":225494,"received":"1234567890123","sent":"1234567890123","delta":"0","txs":2}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceDelta only receive",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-delta/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=225493&to=225493"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","fromHeight":225493,"toHeight":225493,"received":"1234567890123","sent":"0","delta":"1234567890123","txs":1}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceDelta only send",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-delta/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=225494"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","fromHeight":225494,"toHeight":225494,"received":"0","sent":"1234567890123","delta":"-1234567890123","txs":1}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceDelta no activity",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-delta/" + dbtestdata.Addr1 + "?from=225494&to=225494"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti","fromHeight":225494,"toHeight":225494,"received":"0","sent":"0","delta":"0","txs":0}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceDelta beyond tip",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-delta/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=225495"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid block range"}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceAtHeights",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-at-heights/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=225493&to=225494"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","fromHeight":225493,"toHeight":225494,"fromBalance":"1234567890123","toBalance":"0","delta":"-1234567890123"}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceAtHeights before first tx",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-at-heights/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=0&to=225493"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","fromHeight":0,"toHeight":225493,"fromBalance":"0","toBalance":"1234567890123","delta":"1234567890123"}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceAtHeights same height",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-at-heights/" + dbtestdata.Addr5 + "?from=225494&to=225494"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","fromHeight":225494,"toHeight":225494,"fromBalance":"9000","toBalance":"9000","delta":"0"}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceAtHeights not ordered",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-at-heights/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=225494&to=225493"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid block range"}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceAtHeights beyond tip",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-at-heights/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=225493&to=225495"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Block height 225495 is higher than the best block 225494"}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceAtHeights missing height",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-at-heights/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=225493"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing or invalid block height 'to'"}`,
+			},
+		},
+		{
+			name:        "apiAddressBalanceDelta missing address",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-delta/"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing address"}`,
+			},
+		},
+		{
+			name:        "apiDailyTxs",
+			r:           newGetRequest(ts.URL + "/api/v2/daily-txs/?from=2018-08-20&to=2018-08-22"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"from":"2018-08-20","to":"2018-08-22","days":[{"date":"2018-08-20","txs":0},{"date":"2018-08-21","txs":6},{"date":"2018-08-22","txs":0}]}`,
+			},
+		},
+		{
+			name:        "apiDailyTxs default range",
+			r:           newGetRequest(ts.URL + "/api/v2/daily-txs/"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"from":"2018-07-23","to":"2018-08-21","days":[{"date":"2018-07-23","txs":0},`,
+				`{"date":"2018-08-20","txs":0},{"date":"2018-08-21","txs":6}]}`,
+			},
+		},
+		{
+			name:        "apiDailyTxs invalid range",
+			r:           newGetRequest(ts.URL + "/api/v2/daily-txs/?from=2018-08-22&to=2018-08-21"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid date range"}`,
+			},
+		},
+		{
+			name:        "apiDailyTxs invalid date",
+			r:           newGetRequest(ts.URL + "/api/v2/daily-txs/?from=21.8.2018"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid date '21.8.2018', expected format YYYY-MM-DD"}`,
+			},
+		},
+		{
+			name:        "apiLockTimeTxs index disabled",
+			r:           newGetRequest(ts.URL + "/api/v2/locktime-txs/?from=225000"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Lock time index disabled"}`,
+			},
+		},
+		{
+			name:        "apiLockTimeTxs mixed range",
+			r:           newGetRequest(ts.URL + "/api/v2/locktime-txs/?from=225000&to=1534850000"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Lock time range mixes block heights and times"}`,
+			},
+		},
+		{
+			name:        "apiLockTimeTxs invalid continuation key",
+			r:           newGetRequest(ts.URL + "/api/v2/locktime-txs/?from=225000:225494"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid continuation key '225000:225494'"}`,
+			},
+		},
+		{
+			name:        "apiLockTimeTxs invalid lock time",
+			r:           newGetRequest(ts.URL + "/api/v2/locktime-txs/?from=-1"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid lock time '-1'"}`,
+			},
+		},
+		{
+			name:        "apiTxStatus confirmed",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-status/" + dbtestdata.TxidB1T1),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","status":"confirmed","height":225493,"confirmations":2}`,
+			},
+		},
+		{
+			name:        "apiTxStatus unconfirmed",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-status/" + mempoolTxid),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"a8b1a272836f9ccb2bc2a5a93c35bd8b662ade8f533c12c2c398ea2f2fa5bfe1","status":"unconfirmed"}`,
+			},
+		},
+		{
+			name:        "apiTxStatus unknown",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-status/1111111111111111111111111111111111111111111111111111111111111111"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"1111111111111111111111111111111111111111111111111111111111111111","status":"unknown"}`,
+			},
+		},
+		{
+			name:        "apiBlockRawHeader incomplete header",
+			r:           newGetRequest(ts.URL + "/api/v2/block-header/225493"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Cannot serialize block header, version`,
+			},
+		},
+		{
+			name:        "apiBlockRawHeader missing block",
+			r:           newGetRequest(ts.URL + "/api/v2/block-header/"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing block height or hash"}`,
+			},
+		},
+		{
+			name:        "apiBlockMerkleRoot",
+			r:           newGetRequest(ts.URL + "/api/v2/block-merkleroot/225493"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","height":225493,"txCount":2,"merkleRoot":"","computedMerkleRoot":"4b9b8d2d53bd6d364a8a7b7040cc524e89f0ddc599c35507c0412881d9770679","match":false}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.DefaultClient.Do(tt.r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("StatusCode = %v, want %v", resp.StatusCode, tt.status)
+			}
+			if resp.Header["Content-Type"][0] != tt.contentType {
+				t.Errorf("Content-Type = %v, want %v", resp.Header["Content-Type"][0], tt.contentType)
+			}
+			bb, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := string(bb)
+			for _, c := range tt.body {
+				if !strings.Contains(b, c) {
+					t.Errorf("got %v, want to contain %v", b, c)
+					break
+				}
+			}
+		})
+	}
+}
+
+func socketioTests_BitcoinType(t *testing.T, ts *httptest.Server) {
+	type socketioReq struct {
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	}
+
+	url := strings.Replace(ts.URL, "http://", "ws://", 1) + "/socket.io/"
+	s, err := gosocketio.Dial(url, transport.GetDefaultWebsocketTransport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	tests := []struct {
+		name string
+		req  socketioReq
+		want string
+	}{
+		{
+			name: "getInfo",
+			req:  socketioReq{"getInfo", []interface{}{}},
+			want: `{"result":{"blocks":225494,"testnet":true,"network":"fakecoin","subversion":"/Fakecoin:0.0.1/","coin_name":"Fakecoin","about":"Blockbook - blockchain indexer for TREZOR wallet https://trezor.io/. Do not use for any other purpose."}}`,
+		},
+		{
+			name: "estimateFee",
+			req:  socketioReq{"estimateFee", []interface{}{17}},
+			want: `{"result":0.000034}`,
+		},
+		{
+			name: "estimateSmartFee",
+			req:  socketioReq{"estimateSmartFee", []interface{}{19, true}},
+			want: `{"result":0.000019}`,
+		},
+		{
+			name: "getAddressTxids",
+			req: socketioReq{"getAddressTxids", []interface{}{
+				[]string{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"},
+				map[string]interface{}{
+					"start":        2000000,
+					"end":          0,
+					"queryMempool": false,
+				},
+			}},
+			want: `{"result":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840"]}`,
+		},
+		{
+			name: "getAddressTxids limited range",
+			req: socketioReq{"getAddressTxids", []interface{}{
+				[]string{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"},
+				map[string]interface{}{
+					"start":        225494,
+					"end":          225494,
+					"queryMempool": false,
+				},
+			}},
+			want: `{"result":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"]}`,
+		},
+		{
+			name: "getAddressHistory",
+			req: socketioReq{"getAddressHistory", []interface{}{
 				[]string{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"},
 				map[string]interface{}{
 					"start":        2000000,
@@ -734,47 +1726,1061 @@ func socketioTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 					"from":         0,
 					"to":           5,
 				},
-			}},
-			want: `{"result":{"totalCount":2,"items":[{"addresses":{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz":{"inputIndexes":[1],"outputIndexes":[]}},"satoshis":-12345,"confirmations":1,"tx":{"hex":"","height":225494,"blockTimestamp":22549400000,"version":0,"hash":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","inputs":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","outputIndex":0,"script":"","sequence":0,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","satoshis":1234567890123},{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","outputIndex":1,"script":"","sequence":0,"address":"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz","satoshis":12345}],"inputSatoshis":1234567902468,"outputs":[{"satoshis":317283951061,"script":"76a914ccaaaf374e1b06cb83118453d102587b4273d09588ac","address":"mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"},{"satoshis":917283951061,"script":"76a9148d802c045445df49613f6a70ddd2e48526f3701f88ac","address":"mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"}],"outputSatoshis":1234567902122,"feeSatoshis":346}},{"addresses":{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz":{"inputIndexes":[],"outputIndexes":[1]}},"satoshis":12345,"confirmations":2,"tx":{"hex":"","height":225493,"blockTimestamp":22549300000,"version":0,"hash":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","inputs":[],"outputs":[{"satoshis":100000000,"script":"76a914010d39800f86122416e28f485029acf77507169288ac","address":"mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti"},{"satoshis":12345,"script":"76a9148bdf0aa3c567aa5975c2e61321b8bebbe7293df688ac","address":"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"}],"outputSatoshis":100012345}}]}}`,
+			}},
+			want: `{"result":{"totalCount":2,"items":[{"addresses":{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz":{"inputIndexes":[1],"outputIndexes":[]}},"satoshis":-12345,"confirmations":1,"tx":{"hex":"","height":225494,"blockTimestamp":22549400000,"version":0,"hash":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","inputs":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","outputIndex":0,"script":"","sequence":0,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","satoshis":1234567890123},{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","outputIndex":1,"script":"","sequence":0,"address":"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz","satoshis":12345}],"inputSatoshis":1234567902468,"outputs":[{"satoshis":317283951061,"script":"76a914ccaaaf374e1b06cb83118453d102587b4273d09588ac","address":"mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"},{"satoshis":917283951061,"script":"76a9148d802c045445df49613f6a70ddd2e48526f3701f88ac","address":"mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"}],"outputSatoshis":1234567902122,"feeSatoshis":346}},{"addresses":{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz":{"inputIndexes":[],"outputIndexes":[1]}},"satoshis":12345,"confirmations":2,"tx":{"hex":"","height":225493,"blockTimestamp":22549300000,"version":0,"hash":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","inputs":[],"outputs":[{"satoshis":100000000,"script":"76a914010d39800f86122416e28f485029acf77507169288ac","address":"mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti"},{"satoshis":12345,"script":"76a9148bdf0aa3c567aa5975c2e61321b8bebbe7293df688ac","address":"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"}],"outputSatoshis":100012345}}]}}`,
+		},
+		{
+			name: "getBlockHeader",
+			req:  socketioReq{"getBlockHeader", []interface{}{225493}},
+			want: `{"result":{"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","version":0,"confirmations":0,"height":0,"chainWork":"","nextHash":"","merkleRoot":"","time":0,"medianTime":0,"nonce":0,"bits":"","difficulty":0}}`,
+		},
+		{
+			name: "getDetailedTransaction",
+			req:  socketioReq{"getDetailedTransaction", []interface{}{"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"}},
+			want: `{"result":{"hex":"","height":225494,"blockTimestamp":22549400001,"version":0,"hash":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","inputs":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","outputIndex":0,"script":"","sequence":0,"address":"mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX","satoshis":317283951061},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","outputIndex":1,"script":"","sequence":0,"address":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","satoshis":1}],"inputSatoshis":317283951062,"outputs":[{"satoshis":118641975500,"script":"a91495e9fbe306449c991d314afe3c3567d5bf78efd287","address":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu"},{"satoshis":198641975500,"script":"76a9143f8ba3fda3ba7b69f5818086e12223c6dd25e3c888ac","address":"mmJx9Y8ayz9h14yd9fgCW1bUKoEpkBAquP"}],"outputSatoshis":317283951000,"feeSatoshis":62}}`,
+		},
+		{
+			name: "sendTransaction",
+			req:  socketioReq{"sendTransaction", []interface{}{"010000000001019d64f0c72a0d206001decbffaa722eb1044534c"}},
+			want: `{"error":{"message":"Invalid data"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Ack("message", tt.req, time.Second*3)
+			if err != nil {
+				t.Errorf("Socketio error %v", err)
+			}
+			if resp != tt.want {
+				t.Errorf("got %v, want %v", resp, tt.want)
+			}
+		})
+	}
+}
+
+func Test_PublicServer_BitcoinType(t *testing.T) {
+	s, dbpath := setupPublicHTTPServer(t, false)
+	defer closeAndDestroyPublicServer(t, s, dbpath)
+	s.ConnectFullPublicInterface()
+	// take the handler of the public server and pass it to the test server
+	ts := httptest.NewServer(s.https.Handler)
+	defer ts.Close()
+
+	httpTests_BitcoinType(t, ts)
+	utxoSpendableTests_BitcoinType(t, s)
+	stuckTxsTests_BitcoinType(t, s)
+	socketioTests_BitcoinType(t, ts)
+	txMetadataTests_BitcoinType(t, s)
+	txBlockTests_BitcoinType(t, s)
+	addressLabelsTests_BitcoinType(t, ts, s)
+	xpubGapTests_BitcoinType(t, s)
+	coinbaseFilterTests_BitcoinType(t, s)
+	blockLinksTests_BitcoinType(t, s)
+	txConfirmationTests_BitcoinType(t, ts, s)
+	orphanedBlockTests_BitcoinType(t, s)
+	txHexTests_BitcoinType(t, s)
+	txAsmTests_BitcoinType(t, s)
+	addressNotificationBatchTests_BitcoinType(t, ts, s)
+	websocketMaxConnectionsTests_BitcoinType(t, ts, s)
+	initialBlockDownloadTests_BitcoinType(t, ts, s)
+}
+
+// Test_PublicServer_BitcoinType_NoAddressIndex checks that the blocks and transactions are served by the index built without the address index
+// and that the address queries are rejected
+func Test_PublicServer_BitcoinType_NoAddressIndex(t *testing.T) {
+	s, dbpath := setupPublicHTTPServer(t, true)
+	defer closeAndDestroyPublicServer(t, s, dbpath)
+	s.ConnectFullPublicInterface()
+	ts := httptest.NewServer(s.https.Handler)
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		r      *http.Request
+		status int
+		body   []string
+	}{
+		{
+			name:   "apiIndex",
+			r:      newGetRequest(ts.URL + "/api"),
+			status: http.StatusOK,
+			body:   []string{`"bestHeight":225494`, `"noAddressIndex":true`},
+		},
+		{
+			name:   "apiGetBlock",
+			r:      newGetRequest(ts.URL + "/api/v2/block/225494"),
+			status: http.StatusOK,
+			body:   []string{`"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6"`, `"txCount":4`, `"inBestChain":true`},
+		},
+		{
+			name:   "apiTx inputs resolved",
+			r:      newGetRequest(ts.URL + "/api/v2/tx/" + dbtestdata.TxidB2T2),
+			status: http.StatusOK,
+			body:   []string{`"vin":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","n":0,"addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"],"value":"317283951061"}`, `"fees":"62"`},
+		},
+		{
+			name:   "apiAddress",
+			r:      newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"),
+			status: http.StatusBadRequest,
+			body:   []string{`{"error":"Address index disabled"}`},
+		},
+		{
+			name:   "apiUtxo",
+			r:      newGetRequest(ts.URL + "/api/v2/utxo/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"),
+			status: http.StatusBadRequest,
+			body:   []string{`{"error":"Address index disabled"}`},
+		},
+		{
+			name:   "apiBalanceDelta",
+			r:      newGetRequest(ts.URL + "/api/v2/balance-delta/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"),
+			status: http.StatusBadRequest,
+			body:   []string{`{"error":"Address index disabled"}`},
+		},
+		{
+			name:   "apiTx spending",
+			r:      newGetRequest(ts.URL + "/api/v2/tx/" + dbtestdata.TxidB1T2 + "?spending=true"),
+			status: http.StatusBadRequest,
+			body:   []string{`{"error":"Address index disabled"}`},
+		},
+		{
+			name:   "apiTxSpendStatus",
+			r:      newGetRequest(ts.URL + "/api/v2/tx-spends/" + dbtestdata.TxidB1T2),
+			status: http.StatusBadRequest,
+			body:   []string{`{"error":"Address index disabled"}`},
+		},
+		{
+			name:   "apiOutpoint spent without spending tx",
+			r:      newGetRequest(ts.URL + "/api/v2/outpoint/" + dbtestdata.TxidB1T2 + "/0"),
+			status: http.StatusOK,
+			body:   []string{`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"found":true,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true,"value":"1234567890123","blockheight":225493,"confirmations":2,"blocktime":1534858021,"spent":true}`},
+		},
+		{
+			name:   "apiOutpoint unspent",
+			r:      newGetRequest(ts.URL + "/api/v2/outpoint/" + dbtestdata.TxidB2T2 + "/1"),
+			status: http.StatusOK,
+			body:   []string{`"found":true`, `"spent":false}`},
+		},
+		{
+			name:   "apiXpub",
+			r:      newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub),
+			status: http.StatusBadRequest,
+			body:   []string{`{"error":"Address index disabled"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.DefaultClient.Do(tt.r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("StatusCode = %v, want %v", resp.StatusCode, tt.status)
+			}
+			bb, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := string(bb)
+			for _, c := range tt.body {
+				if !strings.Contains(b, c) {
+					t.Errorf("got %v, want to contain %v", b, c)
+					break
+				}
+			}
+		})
+	}
+}
+
+// addressLabelsTests_BitcoinType checks that the label is returned only for labeled addresses
+func addressLabelsTests_BitcoinType(t *testing.T, ts *httptest.Server, s *PublicServer) {
+	labels, err := api.NewAddressLabels(s.chainParser, s.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := labels.Set(dbtestdata.Addr5, "Exchange"); err != nil {
+		t.Fatal(err)
+	}
+	s.SetAddressLabels(labels)
+	defer func() {
+		s.SetAddressLabels(nil)
+		if err := labels.Set(dbtestdata.Addr5, ""); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	getAddress := func(t *testing.T, address string) string {
+		resp, err := http.Get(ts.URL + "/api/v2/address/" + address + "?details=basic")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(b))
+	}
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{
+			name:    "labeled address",
+			address: dbtestdata.Addr5,
+			want:    `{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","label":"Exchange","balance":"9000","totalReceived":"18876","totalSent":"9876","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225493}`,
+		},
+		{
+			name:    "unlabeled address",
+			address: dbtestdata.Addr6,
+			want:    `{"address":"mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX","balance":"0","totalReceived":"317283951061","totalSent":"317283951061","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"firstFundedHeight":225494}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getAddress(t, tt.address); got != tt.want {
+				t.Errorf("body = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("persisted labels", func(t *testing.T) {
+		reloaded, err := api.NewAddressLabels(s.chainParser, s.db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{dbtestdata.Addr5: "Exchange"}
+		if got := reloaded.All(); !reflect.DeepEqual(got, want) {
+			t.Errorf("All() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("cached response invalidated by label change", func(t *testing.T) {
+		apiCache := s.apiCache
+		s.apiCache = newAPICache(100)
+		defer func() { s.apiCache = apiCache }()
+		if got := getAddress(t, dbtestdata.Addr5); !strings.Contains(got, `"label":"Exchange"`) {
+			t.Fatalf("body = %v, want label Exchange", got)
+		}
+		if err := labels.Set(dbtestdata.Addr5, "Pool"); err != nil {
+			t.Fatal(err)
+		}
+		if got := getAddress(t, dbtestdata.Addr5); !strings.Contains(got, `"label":"Pool"`) {
+			t.Errorf("body = %v, want label Pool", got)
+		}
+	})
+}
+
+// txMetadataTests_BitcoinType checks that the compact metadata from the index match the full transactions
+func txMetadataTests_BitcoinType(t *testing.T, s *PublicServer) {
+	for _, txid := range []string{
+		dbtestdata.TxidB1T1, dbtestdata.TxidB1T2,
+		dbtestdata.TxidB2T1, dbtestdata.TxidB2T2, dbtestdata.TxidB2T3, dbtestdata.TxidB2T4,
+	} {
+		t.Run("txMetadata "+txid, func(t *testing.T) {
+			m, err := s.api.GetTxMetadata(txid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx, err := s.api.GetTransaction(txid, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.VinCount != len(tx.Vin) || m.VoutCount != len(tx.Vout) {
+				t.Errorf("GetTxMetadata() vin/vout count = %v/%v, full tx %v/%v", m.VinCount, m.VoutCount, len(tx.Vin), len(tx.Vout))
+			}
+			if m.ValueOutSat.String() != tx.ValueOutSat.String() {
+				t.Errorf("GetTxMetadata() value = %v, full tx %v", m.ValueOutSat, tx.ValueOutSat)
+			}
+			// blocktime is not compared, the times of the test transactions do not match the times of the test blocks
+			if m.Blockheight != tx.Blockheight || m.Confirmations != tx.Confirmations {
+				t.Errorf("GetTxMetadata() = %+v, full tx height %v, confirmations %v", m, tx.Blockheight, tx.Confirmations)
+			}
+		})
+	}
+}
+
+// txBlockTests_BitcoinType checks that the block details are returned for a confirmed transaction and omitted for a mempool transaction
+func txBlockTests_BitcoinType(t *testing.T, s *PublicServer) {
+	t.Run("txBlock confirmed", func(t *testing.T) {
+		tx, err := s.api.GetTransaction(dbtestdata.TxidB2T1, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the time of the block is taken from the index, the backend transaction of the test data has a different blocktime
+		want := &api.TxBlock{Hash: "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6", Height: 225494, Time: 1534859123}
+		if !reflect.DeepEqual(tx.Block, want) {
+			t.Errorf("GetTransaction() block = %+v, want %+v", tx.Block, want)
+		}
+	})
+	t.Run("txBlock mempool", func(t *testing.T) {
+		bchainTx := dbtestdata.GetTestBitcoinTypeBlock2(s.chainParser).Txs[0]
+		bchainTx.Confirmations = 0
+		tx, err := s.api.GetTransactionFromBchainTx(&bchainTx, 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Block != nil {
+			t.Errorf("GetTransactionFromBchainTx() block = %+v, want nil", tx.Block)
+		}
+		b, err := json.Marshal(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), `"block":`) {
+			t.Errorf("mempool transaction contains block details %s", b)
+		}
+		// the transaction is not in the mempool of the backend, the fee rate is computed from the fee and the size of the transaction
+		if tx.FeeRate == nil || *tx.FeeRate != 2.1625 {
+			t.Errorf("GetTransactionFromBchainTx() feeRate = %v, fees %v, %s", tx.FeeRate, tx.FeesSat.DecimalString(8), b)
+		}
+	})
+	t.Run("txBlock mempool entry", func(t *testing.T) {
+		bchainTx := dbtestdata.GetTestBitcoinTypeBlock2(s.chainParser).Txs[1]
+		bchainTx.Confirmations = 0
+		tx, err := s.api.GetTransactionFromBchainTx(&bchainTx, 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the fee rate is taken from the mempool entry, the modified fee rate 10 is lowered to the rate 7.5 of the package with the ancestor
+		if tx.FeeRate == nil || *tx.FeeRate != 7.5 {
+			t.Errorf("GetTransactionFromBchainTx() feeRate = %v, want 7.5", tx.FeeRate)
+		}
+	})
+}
+
+// xpubGapTests_BitcoinType connects a new block to the db, it must run after the other tests
+func xpubGapTests_BitcoinType(t *testing.T, s *PublicServer) {
+	// fund addresses beyond the default gap, receive address m/49'/1'/33'/0/25 and change address m/49'/1'/33'/1/40
+	receive, err := s.chainParser.DeriveAddressDescriptorsFromTo(dbtestdata.Xpub, 0, 25, 26)
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, err := s.chainParser.DeriveAddressDescriptorsFromTo(dbtestdata.Xpub, 1, 40, 41)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block3 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: 225495,
+			Hash:   "000000000056e4e3e0b5e8e9e079d6b9ba7c71fa0fa8bb0254ff1b0c0b8e2b3b",
+			Time:   1534859988,
+		},
+		Txs: []bchain.Tx{
+			{
+				Txid: "f4d1d5f2c516e9d8e6b0a27d1da35ce0d2b8f0b0f0f5f5e7c5c6d1d4b2e3a4f5",
+				Vout: []bchain.Vout{
+					{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(receive[0])}, ValueSat: *big.NewInt(1000)},
+					{N: 1, ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(change[0])}, ValueSat: *big.NewInt(2000)},
+				},
+			},
 		},
-		{
-			name: "getBlockHeader",
-			req:  socketioReq{"getBlockHeader", []interface{}{225493}},
-			want: `{"result":{"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","version":0,"confirmations":0,"height":0,"chainWork":"","nextHash":"","merkleRoot":"","time":0,"medianTime":0,"nonce":0,"bits":"","difficulty":0}}`,
+	}
+	if err := s.db.ConnectBlock(block3); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		gap  int
+		want string
+	}{
+		// the addresses beyond the default gap are not found
+		{name: "default gap", gap: 0, want: "118641975500"},
+		// the change chain has its own gap counter, the change address is not found
+		{name: "gap 30", gap: 30, want: "118641976500"},
+		{name: "gap 40", gap: 40, want: "118641978500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := s.api.GetXpubAddress(dbtestdata.Xpub, 0, 1, api.AccountDetailsBasic, &api.AddressFilter{Vout: api.AddressFilterVoutOff}, tt.gap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.BalanceSat.String(); got != tt.want {
+				t.Errorf("GetXpubAddress() balance = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// with the gap 40 the xpub needs 66 receive and 81 change addresses
+	s.api.SetXpubMaxAddresses(100)
+	defer s.api.SetXpubMaxAddresses(0)
+	t.Run("max addresses gap 30", func(t *testing.T) {
+		a, err := s.api.GetXpubAddress(dbtestdata.Xpub, 0, 1, api.AccountDetailsBasic, &api.AddressFilter{Vout: api.AddressFilterVoutOff}, 30)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.BalanceSat.String(); got != "118641976500" {
+			t.Errorf("GetXpubAddress() balance = %v, want %v", got, "118641976500")
+		}
+	})
+	t.Run("max addresses exceeded", func(t *testing.T) {
+		_, err := s.api.GetXpubAddress(dbtestdata.Xpub, 0, 1, api.AccountDetailsBasic, &api.AddressFilter{Vout: api.AddressFilterVoutOff}, 40)
+		want := "Too many addresses derived from xpub, the limit is 100 addresses"
+		if err == nil || err.Error() != want {
+			t.Errorf("GetXpubAddress() error = %v, want %v", err, want)
+		}
+	})
+}
+
+// coinbaseFilterTests_BitcoinType connects a block with a coinbase and a regular transaction of AddrA,
+// it must run after xpubGapTests_BitcoinType
+func coinbaseFilterTests_BitcoinType(t *testing.T, s *PublicServer) {
+	const (
+		txidCoinbase = "0d1f2e3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+		txidSpend    = "1e2f3d4c5b6a798897a6b5c4d3e2f1001f2e3d4c5b6a798897a6b5c4d3e2f101"
+	)
+	addrA := dbtestdata.AddressToPubKeyHex(dbtestdata.AddrA, s.chainParser)
+	block4 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: 225496,
+			Hash:   "00000000009a8f1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e7f8",
+			Time:   1534860500,
 		},
-		{
-			name: "getDetailedTransaction",
-			req:  socketioReq{"getDetailedTransaction", []interface{}{"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"}},
-			want: `{"result":{"hex":"","height":225494,"blockTimestamp":22549400001,"version":0,"hash":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","inputs":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","outputIndex":0,"script":"","sequence":0,"address":"mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX","satoshis":317283951061},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","outputIndex":1,"script":"","sequence":0,"address":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","satoshis":1}],"inputSatoshis":317283951062,"outputs":[{"satoshis":118641975500,"script":"a91495e9fbe306449c991d314afe3c3567d5bf78efd287","address":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu"},{"satoshis":198641975500,"script":"76a9143f8ba3fda3ba7b69f5818086e12223c6dd25e3c888ac","address":"mmJx9Y8ayz9h14yd9fgCW1bUKoEpkBAquP"}],"outputSatoshis":317283951000,"feeSatoshis":62}}`,
+		Txs: []bchain.Tx{
+			{
+				Txid: txidCoinbase,
+				Vin:  []bchain.Vin{{Coinbase: "03c0710300"}},
+				Vout: []bchain.Vout{
+					{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: addrA}, ValueSat: *big.NewInt(1250000000)},
+				},
+			},
+			{
+				Txid: txidSpend,
+				Vin:  []bchain.Vin{{Txid: dbtestdata.TxidB2T4, Vout: 0}},
+				Vout: []bchain.Vout{
+					{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: addrA}, ValueSat: *big.NewInt(1000)},
+				},
+			},
 		},
-		{
-			name: "sendTransaction",
-			req:  socketioReq{"sendTransaction", []interface{}{"010000000001019d64f0c72a0d206001decbffaa722eb1044534c"}},
-			want: `{"error":{"message":"Invalid data"}}`,
+	}
+	if err := s.db.ConnectBlock(block4); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		coinbase api.CoinbaseFilter
+		page     int
+		pageSize int
+		want     []string
+	}{
+		{name: "off", coinbase: api.CoinbaseFilterOff, page: 1, pageSize: 10, want: []string{txidSpend, txidCoinbase, dbtestdata.TxidB2T4}},
+		{name: "only", coinbase: api.CoinbaseFilterOnly, page: 1, pageSize: 10, want: []string{txidCoinbase, dbtestdata.TxidB2T4}},
+		{name: "exclude", coinbase: api.CoinbaseFilterExclude, page: 1, pageSize: 10, want: []string{txidSpend}},
+		{name: "only first page", coinbase: api.CoinbaseFilterOnly, page: 1, pageSize: 1, want: []string{txidCoinbase}},
+		{name: "only second page", coinbase: api.CoinbaseFilterOnly, page: 2, pageSize: 1, want: []string{dbtestdata.TxidB2T4}},
+	}
+	for _, tt := range tests {
+		t.Run("coinbase filter "+tt.name, func(t *testing.T) {
+			a, err := s.api.GetAddress(dbtestdata.AddrA, tt.page, tt.pageSize, api.AccountDetailsTxidHistory, &api.AddressFilter{Vout: api.AddressFilterVoutOff, Coinbase: tt.coinbase})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.coinbase == api.CoinbaseFilterOff {
+				// the order of the transactions in the same block is not significant
+				sort.Strings(a.Txids[:2])
+				sort.Strings(tt.want[:2])
+			}
+			if !reflect.DeepEqual(a.Txids, tt.want) {
+				t.Errorf("GetAddress() txids = %v, want %v", a.Txids, tt.want)
+			}
+		})
+	}
+}
+
+// blockLinksTests_BitcoinType checks the links of a block which is not at the tip of the index,
+// it must run after the tests connecting blocks 225495 and 225496
+func blockLinksTests_BitcoinType(t *testing.T, s *PublicServer) {
+	b, err := s.api.GetBlock("225494", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997"; b.Prev != want {
+		t.Errorf("GetBlock() previousblockhash = %v, want %v", b.Prev, want)
+	}
+	if want := "000000000056e4e3e0b5e8e9e079d6b9ba7c71fa0fa8bb0254ff1b0c0b8e2b3b"; b.Next != want {
+		t.Errorf("GetBlock() nextblockhash = %v, want %v", b.Next, want)
+	}
+}
+
+// txConfirmationTests_BitcoinType subscribes to confirmations of transactions over the websocket interface,
+// it connects and disconnects block 225497 and must run after blockLinksTests_BitcoinType
+func txConfirmationTests_BitcoinType(t *testing.T, ts *httptest.Server, s *PublicServer) {
+	const txid5 = "2f3e4d5c6b7a8998a7b6c5d4e3f2011f2e3d4c5b6a798897a6b5c4d3e2f10203"
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/websocket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	request := func(id, method string, params interface{}) {
+		p, err := json.Marshal(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.WriteJSON(&websocketReq{ID: id, Method: method, Params: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(id, want string) {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var res struct {
+			ID   string          `json:"id"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := conn.ReadJSON(&res); err != nil {
+			t.Fatal(err)
+		}
+		if res.ID != id || string(res.Data) != want {
+			t.Errorf("got id %v, data %v, want id %v, data %v", res.ID, string(res.Data), id, want)
+		}
+	}
+	type params struct {
+		Txid          string `json:"txid"`
+		Confirmations uint32 `json:"confirmations"`
+	}
+	// the tip is 225496, TxidB2T4 has 3 confirmations
+	request("1", "subscribeTxConfirmation", &params{Txid: dbtestdata.TxidB2T4, Confirmations: 2})
+	expect("1", `{"subscribed":false,"txid":"`+dbtestdata.TxidB2T4+`","confirmations":3}`)
+	request("2", "subscribeTxConfirmation", &params{Txid: dbtestdata.TxidB2T4, Confirmations: 4})
+	expect("2", `{"subscribed":true,"txid":"`+dbtestdata.TxidB2T4+`","confirmations":3}`)
+	request("3", "subscribeTxConfirmation", &params{Txid: txid5, Confirmations: 2})
+	expect("3", `{"error":{"message":"Transaction not found"}}`)
+	request("4", "subscribeTxConfirmation", &params{Txid: dbtestdata.TxidB2T4, Confirmations: 0})
+	expect("4", `{"error":{"message":"Invalid confirmations"}}`)
+
+	block5 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: 225497,
+			Hash:   "0000000000b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e7f8091",
+			Time:   1534861000,
+		},
+		Txs: []bchain.Tx{
+			{
+				Txid: txid5,
+				Vin:  []bchain.Vin{{Coinbase: "03c1710300"}},
+				Vout: []bchain.Vout{
+					{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.AddrA, s.chainParser)}, ValueSat: *big.NewInt(1250000000)},
+				},
+			},
+		},
+	}
+	if err := s.db.ConnectBlock(block5); err != nil {
+		t.Fatal(err)
+	}
+	request("5", "subscribeTxConfirmation", &params{Txid: txid5, Confirmations: 2})
+	expect("5", `{"subscribed":true,"txid":"`+txid5+`","confirmations":1}`)
+	// TxidB2T4 reaches the target depth, txid5 does not
+	s.websocket.OnNewBlocks(225497, block5.Hash, 225497)
+	expect("2", `{"txid":"`+dbtestdata.TxidB2T4+`","confirmations":4,"event":"confirmed"}`)
+
+	// the block is disconnected, txid5 is removed from the chain
+	if err := s.db.DisconnectBlockRangeBitcoinType(225497, 225497); err != nil {
+		t.Fatal(err)
+	}
+	s.websocket.OnNewBlocks(225496, "00000000009a8f1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e7f8", 225496)
+	expect("5", `{"txid":"`+txid5+`","confirmations":0,"event":"reorged"}`)
+
+	// the number of the subscriptions of one connection is limited, the repeated subscription is not counted
+	for i := 0; i < maxTxConfirmationSubscriptions; i++ {
+		request("6", "subscribeTxConfirmation", &params{Txid: dbtestdata.TxidB2T4, Confirmations: uint32(4 + i)})
+		expect("6", `{"subscribed":true,"txid":"`+dbtestdata.TxidB2T4+`","confirmations":3}`)
+	}
+	request("7", "subscribeTxConfirmation", &params{Txid: dbtestdata.TxidB2T4, Confirmations: 4})
+	expect("7", `{"subscribed":true,"txid":"`+dbtestdata.TxidB2T4+`","confirmations":3}`)
+	request("8", "subscribeTxConfirmation", &params{Txid: dbtestdata.TxidB2T4, Confirmations: 4 + maxTxConfirmationSubscriptions})
+	expect("8", `{"error":{"message":"Too many transaction confirmation subscriptions"}}`)
+	request("9", "unsubscribeTxConfirmations", nil)
+	expect("9", `{"subscribed":false}`)
+
+	s.websocket.txConfirmationSubscriptionsLock.Lock()
+	n := len(s.websocket.txConfirmationSubscriptions)
+	s.websocket.txConfirmationSubscriptionsLock.Unlock()
+	if n != 0 {
+		t.Errorf("txConfirmationSubscriptions has %d entries after the notifications, want 0", n)
+	}
+}
+
+// reorgChain returns from the backend also the blocks which are not in the test chain, including the orphaned ones
+type reorgChain struct {
+	bchain.BlockChain
+	blocks map[string]*bchain.BlockInfo
+}
+
+func (c *reorgChain) GetBlockInfo(hash string) (*bchain.BlockInfo, error) {
+	if bi, found := c.blocks[hash]; found {
+		// the caller modifies the returned block info
+		r := *bi
+		return &r, nil
+	}
+	return c.BlockChain.GetBlockInfo(hash)
+}
+
+// orphanedBlockTests_BitcoinType replaces block 225497 by a reorg and checks that the original block is flagged as orphaned
+func orphanedBlockTests_BitcoinType(t *testing.T, s *PublicServer) {
+	coinbaseBlock := func(hash, txid string, confirmations int) (*bchain.Block, *bchain.BlockInfo) {
+		b := &bchain.Block{
+			BlockHeader: bchain.BlockHeader{
+				Height:        225497,
+				Hash:          hash,
+				Prev:          "00000000009a8f1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e7f8",
+				Time:          1534862000,
+				Confirmations: confirmations,
+			},
+			Txs: []bchain.Tx{
+				{
+					Txid: txid,
+					Vin:  []bchain.Vin{{Coinbase: "03d9710300"}},
+					Vout: []bchain.Vout{
+						{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.AddrA, s.chainParser)}, ValueSat: *big.NewInt(1250000000)},
+					},
+				},
+			},
+		}
+		return b, &bchain.BlockInfo{BlockHeader: b.BlockHeader, Txids: []string{txid}}
+	}
+	orphaned, orphanedInfo := coinbaseBlock("0000000000c0ffee00000000000000000000000000000000000000000000a001", "1111111111111111111111111111111111111111111111111111111111110001", -1)
+	best, bestInfo := coinbaseBlock("0000000000c0ffee00000000000000000000000000000000000000000000b001", "1111111111111111111111111111111111111111111111111111111111110002", 1)
+	chain := &reorgChain{
+		BlockChain: s.chain,
+		blocks:     map[string]*bchain.BlockInfo{orphaned.Hash: orphanedInfo, best.Hash: bestInfo},
+	}
+	w, err := api.NewWorker(s.db, chain, s.mempool, s.txCache, s.is)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.db.ConnectBlock(orphaned); err != nil {
+		t.Fatal(err)
+	}
+	b, err := w.GetBlock(orphaned.Hash, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.InBestChain {
+		t.Errorf("GetBlock(%v) inBestChain = false before the reorg", orphaned.Hash)
+	}
+
+	// reorg, the block is replaced by another block at the same height
+	if err := s.db.DisconnectBlockRangeBitcoinType(225497, 225497); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.ConnectBlock(best); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := s.db.DisconnectBlockRangeBitcoinType(225497, 225497); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	b, err = w.GetBlock(orphaned.Hash, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.InBestChain {
+		t.Errorf("GetBlock(%v) inBestChain = true after the reorg", orphaned.Hash)
+	}
+	if b.Hash != orphaned.Hash || b.Height != 225497 || b.TxCount != 1 {
+		t.Errorf("GetBlock(%v) returned hash %v, height %v, txCount %v", orphaned.Hash, b.Hash, b.Height, b.TxCount)
+	}
+	if b.Next != "" {
+		t.Errorf("GetBlock(%v) nextblockhash = %v, want empty", orphaned.Hash, b.Next)
+	}
+	b, err = w.GetBlock("225497", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Hash != best.Hash || !b.InBestChain {
+		t.Errorf("GetBlock(225497) returned hash %v, inBestChain %v, want %v, true", b.Hash, b.InBestChain, best.Hash)
+	}
+}
+
+// rawTxChain returns from the backend the transactions with the raw hex
+type rawTxChain struct {
+	bchain.BlockChain
+	txs map[string]string
+}
+
+func (c *rawTxChain) GetTransaction(txid string) (*bchain.Tx, error) {
+	if h, found := c.txs[txid]; found {
+		return &bchain.Tx{Txid: txid, Hex: h}, nil
+	}
+	return c.BlockChain.GetTransaction(txid)
+}
+
+// entriesMempool returns the fixed entries as the content of the mempool
+type entriesMempool struct {
+	bchain.Mempool
+	entries bchain.MempoolTxidEntries
+}
+
+func (m *entriesMempool) GetAllEntries() bchain.MempoolTxidEntries {
+	return m.entries
+}
+
+// stuckTxsTests_BitcoinType checks that only the mempool transactions with the fee rate below the percentile of the last blocks are reported
+func stuckTxsTests_BitcoinType(t *testing.T, s *PublicServer) {
+	mempool := &entriesMempool{
+		Mempool: s.mempool,
+		entries: bchain.MempoolTxidEntries{
+			{Txid: dbtestdata.TxidB2T2, Time: 1554700000},
+			{Txid: dbtestdata.TxidMempoolLowFee, Time: 1554700100},
+			// the transaction without the mempool entry is skipped
+			{Txid: mempoolTxid, Time: 1554700200},
+		},
+	}
+	w, err := api.NewWorker(s.db, s.chain, mempool, s.txCache, s.is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := w.GetStuckMempoolTxs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &api.StuckMempoolTxs{
+		FromHeight:  225493,
+		ToHeight:    225494,
+		Percentile:  api.DefaultStuckTxPercentile,
+		FeeRate:     (*api.Amount)(big.NewInt(392)),
+		MempoolSize: 3,
+		Txs: []api.StuckMempoolTx{
+			{Txid: dbtestdata.TxidMempoolLowFee, Time: 1554700100, FeeRate: (*api.Amount)(big.NewInt(200))},
 		},
 	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("GetStuckMempoolTxs() = %s, want %s", gotJSON, wantJSON)
+	}
+}
 
+// txHexTests_BitcoinType checks that the raw hex of a transaction is downloaded from the backend if missing and that it hashes to the txid
+func txHexTests_BitcoinType(t *testing.T, s *PublicServer) {
+	const txid = "056e3d82e5ffd0e915fb9b62797d76263508c34fe3e5dbed30dd3e943930f204"
+	const txHex = "01000000017f9a22c9cbf54bd902400df746f138f37bcf5b4d93eb755820e974ba43ed5f42040000006a4730440220037f4ed5427cde81d55b9b6a2fd08c8a25090c2c2fff3a75c1a57625ca8a7118022076c702fe55969fa08137f71afd4851c48e31082dd3c40c919c92cdbc826758d30121029f6da5623c9f9b68a9baf9c1bc7511df88fa34c6c2f71f7c62f2f03ff48dca80feffffff019c9700000000000017a9146144d57c8aff48492c9dfb914e120b20bad72d6f8773d00700"
+	// the backend returns for TxidB2T1 the hex of another transaction
+	chain := &rawTxChain{BlockChain: s.chain, txs: map[string]string{txid: txHex, dbtestdata.TxidB2T1: txHex}}
+	w, err := api.NewWorker(s.db, chain, s.mempool, s.txCache, s.is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		tx      api.Tx
+		wantErr bool
+	}{
+		{name: "downloaded", tx: api.Tx{Txid: txid}},
+		{name: "known", tx: api.Tx{Txid: txid, Hex: txHex}},
+		{name: "hex of another tx", tx: api.Tx{Txid: dbtestdata.TxidB2T1}, wantErr: true},
+		{name: "invalid hex", tx: api.Tx{Txid: txid, Hex: txHex[:100]}, wantErr: true},
+	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp, err := s.Ack("message", tt.req, time.Second*3)
-			if err != nil {
-				t.Errorf("Socketio error %v", err)
+			tx := tt.tx
+			err := w.SetTxHex(&tx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetTxHex() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if resp != tt.want {
-				t.Errorf("got %v, want %v", resp, tt.want)
+			if err == nil && tx.Hex != txHex {
+				t.Errorf("SetTxHex() hex = %v, want %v", tx.Hex, txHex)
 			}
 		})
 	}
 }
 
-func Test_PublicServer_BitcoinType(t *testing.T) {
-	s, dbpath := setupPublicHTTPServer(t)
-	defer closeAndDestroyPublicServer(t, s, dbpath)
-	s.ConnectFullPublicInterface()
-	// take the handler of the public server and pass it to the test server
-	ts := httptest.NewServer(s.https.Handler)
-	defer ts.Close()
+// asmChain returns the parser of the chain which disassembles the scripts by the parser of Bitcoin Cash
+type asmChain struct {
+	bchain.BlockChain
+	parser bchain.BlockChainParser
+}
 
-	httpTests_BitcoinType(t, ts)
-	socketioTests_BitcoinType(t, ts)
+func (c *asmChain) GetChainParser() bchain.BlockChainParser {
+	return c.parser
+}
+
+type asmParser struct {
+	bchain.BlockChainParser
+	bch *bch.BCashParser
+}
+
+func (p *asmParser) DisassembleScript(script []byte) (string, error) {
+	return p.bch.DisassembleScript(script)
+}
+
+// txAsmTests_BitcoinType checks that the scripts of the inputs and outputs of a transaction are disassembled
+func txAsmTests_BitcoinType(t *testing.T, s *PublicServer) {
+	bp, err := bch.NewBCashParser(bch.GetChainParams("test"), &btc.Configuration{AddressFormat: "legacy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := &asmChain{BlockChain: s.chain, parser: &asmParser{BlockChainParser: s.chainParser, bch: bp}}
+	w, err := api.NewWorker(s.db, chain, s.mempool, s.txCache, s.is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := w.GetTransaction(dbtestdata.TxidB2T1, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.SetTxAsm(tx); err != nil {
+		t.Fatal(err)
+	}
+	want := "OP_DUP OP_HASH160 8d802c045445df49613f6a70ddd2e48526f3701f OP_EQUALVERIFY OP_CHECKSIG"
+	if len(tx.Vout) < 2 || tx.Vout[1].Asm != want {
+		t.Errorf("SetTxAsm() vout asm = %+v, want %v", tx.Vout, want)
+	}
+	for i := range tx.Vin {
+		if tx.Vin[i].Hex != "" && tx.Vin[i].Asm == "" {
+			t.Errorf("SetTxAsm() vin %d without asm", i)
+		}
+	}
+}
+
+type maturityParser struct {
+	bchain.BlockChainParser
+	maturity uint32
+}
+
+func (p *maturityParser) CoinbaseMaturity() (uint32, error) {
+	return p.maturity, nil
+}
+
+// utxoSpendableTests_BitcoinType checks the spendability of the utxos of a coinbase transaction
+// with one confirmation and of a normal transaction
+func utxoSpendableTests_BitcoinType(t *testing.T, s *PublicServer) {
+	utxo := func(t *testing.T, maturity uint32, address, txid string) api.Utxo {
+		chain := &asmChain{BlockChain: s.chain, parser: &maturityParser{BlockChainParser: s.chainParser, maturity: maturity}}
+		w, err := api.NewWorker(s.db, chain, s.mempool, s.txCache, s.is)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := w.GetAddressUtxo(address, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range u {
+			if u[i].Txid == txid && u[i].Spendable != nil {
+				return u[i]
+			}
+		}
+		t.Fatalf("GetAddressUtxo() = %+v, want utxo of %v with spendable", u, txid)
+		return api.Utxo{}
+	}
+	tests := []struct {
+		name     string
+		maturity uint32
+		address  string
+		txid     string
+		want     bool
+	}{
+		{name: "immature coinbase", maturity: 2, address: dbtestdata.AddrA, txid: dbtestdata.TxidB2T4, want: false},
+		{name: "mature coinbase", maturity: 1, address: dbtestdata.AddrA, txid: dbtestdata.TxidB2T4, want: true},
+		{name: "normal", maturity: 2, address: dbtestdata.Addr5, txid: dbtestdata.TxidB2T3, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := utxo(t, tt.maturity, tt.address, tt.txid)
+			if *u.Spendable != tt.want {
+				t.Errorf("GetAddressUtxo() spendable = %v, want %v", *u.Spendable, tt.want)
+			}
+		})
+	}
+	// the spendability is not reported for the coins without known coinbase maturity
+	u, err := s.api.GetAddressUtxo(dbtestdata.AddrA, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range u {
+		if u[i].Spendable != nil {
+			t.Errorf("GetAddressUtxo() = %+v, want utxos without spendable", u)
+		}
+	}
+}
+
+// addressNotificationBatchTests_BitcoinType checks that the websocket address notifications sent within the batching window
+// are coalesced to a single message and that without the window they are sent one by one
+func addressNotificationBatchTests_BitcoinType(t *testing.T, ts *httptest.Server, s *PublicServer) {
+	defer s.SetAddressNotificationWindow(0)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/websocket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	type notification struct {
+		Address string `json:"address"`
+		Tx      struct {
+			Txid string `json:"txid"`
+		} `json:"tx"`
+	}
+	read := func(data interface{}) string {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var res struct {
+			ID   string          `json:"id"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := conn.ReadJSON(&res); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(res.Data, data); err != nil {
+			t.Fatal(err)
+		}
+		return res.ID
+	}
+	p, err := json.Marshal(map[string][]string{"addresses": {dbtestdata.AddrA, dbtestdata.Addr5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(&websocketReq{ID: "1", Method: "subscribeAddresses", Params: p}); err != nil {
+		t.Fatal(err)
+	}
+	var subscribed subscriptionResponse
+	if read(&subscribed); !subscribed.Subscribed {
+		t.Fatal("subscribeAddresses not subscribed")
+	}
+	newTx := func(txid, addr string) *bchain.Tx {
+		return &bchain.Tx{
+			Txid: txid,
+			Vin:  []bchain.Vin{{Coinbase: "03c1710300"}},
+			Vout: []bchain.Vout{
+				{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(addr, s.chainParser)}, ValueSat: *big.NewInt(1000)},
+			},
+		}
+	}
+	notify := func(txid, addr string) {
+		addrDesc, err := s.chainParser.GetAddrDescFromAddress(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.websocket.OnNewTxAddr(newTx(txid, addr), addrDesc)
+	}
+	want := []notification{
+		{Address: dbtestdata.AddrA},
+		{Address: dbtestdata.Addr5},
+		{Address: dbtestdata.AddrA},
+	}
+	for i := range want {
+		want[i].Tx.Txid = fmt.Sprintf("%064x", i+1)
+	}
+
+	s.SetAddressNotificationWindow(200 * time.Millisecond)
+	for _, n := range want {
+		notify(n.Tx.Txid, n.Address)
+	}
+	var batch []notification
+	if id := read(&batch); id != "1" || !reflect.DeepEqual(batch, want) {
+		t.Errorf("batched notification id %v, data %+v, want id 1, data %+v", id, batch, want)
+	}
+
+	s.SetAddressNotificationWindow(0)
+	for _, n := range want {
+		notify(n.Tx.Txid, n.Address)
+	}
+	for _, w := range want {
+		var n notification
+		if id := read(&n); id != "1" || n != w {
+			t.Errorf("notification id %v, data %+v, want id 1, data %+v", id, n, w)
+		}
+	}
+
+	// the batch flushed by the timer after the channel was closed must not be sent to the closed out channel
+	c := &websocketChannel{out: make(chan *websocketRes, outChannelSize), addrBatch: []interface{}{&want[0]}, addrBatchID: "1"}
+	close(c.out)
+	c.addrBatchLock.Lock()
+	c.flushAddressNotifications()
+	c.addrBatchLock.Unlock()
+	if c.addrBatch != nil {
+		t.Errorf("addrBatch = %+v after the flush, want nil", c.addrBatch)
+	}
+}
+
+// websocketMaxConnectionsTests_BitcoinType checks that the websocket connections beyond the limit are closed with the reason
+// and that the number of the open connections is reported in the status
+func websocketMaxConnectionsTests_BitcoinType(t *testing.T, ts *httptest.Server, s *PublicServer) {
+	defer s.SetWebsocketMaxConnections(0)
+	// the server notices the connections closed by the previous tests asynchronously
+	for i := 0; s.is.GetWebsocketConnections() > 0; i++ {
+		if i == 100 {
+			t.Fatalf("%d websocket connections left open", s.is.GetWebsocketConnections())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	s.SetWebsocketMaxConnections(1)
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/websocket"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	rejected, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rejected.Close()
+	rejected.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = rejected.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) || err.(*websocket.CloseError).Text != tooManyConnections {
+		t.Errorf("rejected connection error = %v, want close %v %v", err, websocket.CloseTryAgainLater, tooManyConnections)
+	}
+	si, err := s.api.GetSystemInfo(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if si.Blockbook.WebsocketConnections != 1 {
+		t.Errorf("GetSystemInfo() websocketConnections = %v, want 1", si.Blockbook.WebsocketConnections)
+	}
+	// the request which is not upgraded is not counted
+	if r, err := http.Get(ts.URL + "/websocket"); err != nil {
+		t.Fatal(err)
+	} else {
+		r.Body.Close()
+	}
+	if n := s.is.GetWebsocketConnections(); n != 1 {
+		t.Errorf("websocket connections after the failed upgrade = %v, want 1", n)
+	}
+	// the accepted connection is served
+	if err := conn.WriteJSON(&websocketReq{ID: "1", Method: "getInfo"}); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var res websocketRes
+	if err := conn.ReadJSON(&res); err != nil || res.ID != "1" {
+		t.Errorf("getInfo = %+v, %v", res, err)
+	}
+}
+
+// initialBlockDownloadTests_BitcoinType checks that the address queries are refused while the backend is in the initial block download
+// and that the status refreshes the state of the backend
+func initialBlockDownloadTests_BitcoinType(t *testing.T, ts *httptest.Server, s *PublicServer) {
+	defer s.SetRefuseQueriesInInitialBlockDownload(false)
+	s.SetRefuseQueriesInInitialBlockDownload(true)
+	s.is.SetBackendInitialBlockDownload(true)
+	get := func(url string) (int, string) {
+		resp, err := http.Get(ts.URL + url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+	if status, body := get("/api/v2/address/mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"); status != http.StatusBadRequest ||
+		!strings.Contains(body, `{"error":"Backend is in initial block download, the address data is incomplete"}`) {
+		t.Errorf("address in initial block download = %v %v", status, body)
+	}
+	// the blocks and transactions are served
+	if status, body := get("/api/v2/block/225494"); status != http.StatusOK {
+		t.Errorf("block in initial block download = %v %v", status, body)
+	}
+	// the fake backend is not in the initial block download, the false value is omitted from the status
+	if status, body := get("/api"); status != http.StatusOK || strings.Contains(body, `"initialblockdownload"`) {
+		t.Errorf("status = %v %v", status, body)
+	}
+	if status, body := get("/api/v2/address/mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"); status != http.StatusOK {
+		t.Errorf("address after initial block download = %v %v", status, body)
+	}
 }
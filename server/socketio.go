@@ -9,7 +9,6 @@ import (
 	"math/big"
 	"net/http"
 	"runtime/debug"
-	"strconv"
 	"strings"
 	"time"
 
@@ -214,6 +213,10 @@ type resultAddressTxids struct {
 }
 
 func (s *SocketIoServer) getAddressTxids(addr []string, opts *addrOpts) (res resultAddressTxids, err error) {
+	if s.is.NoAddressIndex {
+		err = errors.New("Address index disabled")
+		return
+	}
 	txids := make([]string, 0, 8)
 	lower, higher := uint32(opts.End), uint32(opts.Start)
 	for _, address := range addr {
@@ -378,6 +381,8 @@ func (s *SocketIoServer) getAddressHistory(addr []string, opts *addrOpts) (res r
 	if err != nil {
 		return
 	}
+	// the addresses of the transactions are in the format of the parser, the requested addresses may be in another format
+	addr = s.api.NormalizeAddresses(addr)
 	txids := txr.Result
 	res.Result.TotalCount = len(txids)
 	res.Result.Items = make([]addressHistoryItem, 0, 8)
@@ -520,8 +525,8 @@ func unmarshalEstimateSmartFee(params []byte) (blocks int, conservative bool, er
 }
 
 type resultEstimateSmartFee struct {
-	// for compatibility reasons use float64
-	Result float64 `json:"result"`
+	// for compatibility reasons the result is a json number, json.Number keeps the full precision of the amount
+	Result json.Number `json:"result"`
 }
 
 func (s *SocketIoServer) estimateSmartFee(blocks int, conservative bool) (res resultEstimateSmartFee, err error) {
@@ -529,7 +534,7 @@ func (s *SocketIoServer) estimateSmartFee(blocks int, conservative bool) (res re
 	if err != nil {
 		return
 	}
-	res.Result, err = strconv.ParseFloat(s.chainParser.AmountToDecimalString(&fee), 64)
+	res.Result = json.Number(s.chainParser.AmountToDecimalString(&fee))
 	return
 }
 
@@ -548,8 +553,8 @@ func unmarshalEstimateFee(params []byte) (blocks int, err error) {
 }
 
 type resultEstimateFee struct {
-	// for compatibility reasons use float64
-	Result float64 `json:"result"`
+	// for compatibility reasons the result is a json number, json.Number keeps the full precision of the amount
+	Result json.Number `json:"result"`
 }
 
 func (s *SocketIoServer) estimateFee(blocks int) (res resultEstimateFee, err error) {
@@ -557,7 +562,7 @@ func (s *SocketIoServer) estimateFee(blocks int) (res resultEstimateFee, err err
 	if err != nil {
 		return
 	}
-	res.Result, err = strconv.ParseFloat(s.chainParser.AmountToDecimalString(&fee), 64)
+	res.Result = json.Number(s.chainParser.AmountToDecimalString(&fee))
 	return
 }
 
@@ -720,6 +725,11 @@ func (s *SocketIoServer) OnNewBlockHash(hash string) {
 	glog.Info("broadcasting new block hash ", hash, " to ", c, " channels")
 }
 
+// IsWatchedAddrDesc returns true if there is a channel subscribed to bitcoind/addresstxid of the address
+func (s *SocketIoServer) IsWatchedAddrDesc(desc bchain.AddressDescriptor) bool {
+	return s.server.Amount("bitcoind/addresstxid-"+string(desc)) > 0
+}
+
 // OnNewTxAddr notifies users subscribed to bitcoind/addresstxid about new block
 func (s *SocketIoServer) OnNewTxAddr(txid string, desc bchain.AddressDescriptor) {
 	addr, searchable, err := s.chainParser.GetAddressesFromAddrDesc(desc)
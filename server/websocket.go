@@ -20,7 +20,9 @@ import (
 )
 
 const upgradeFailed = "Upgrade failed: "
+const tooManyConnections = "Too many connections"
 const outChannelSize = 500
+const maxTxConfirmationSubscriptions = 100
 const defaultTimeout = 60 * time.Second
 
 var (
@@ -49,25 +51,47 @@ type websocketChannel struct {
 	requestHeader http.Header
 	alive         bool
 	aliveLock     sync.Mutex
+	// address notifications waiting for the end of the batching window, all of them belong to the subscription addrBatchID
+	addrBatch     []interface{}
+	addrBatchID   string
+	addrBatchLock sync.Mutex
+	// number of the transaction confirmation subscriptions of the channel, guarded by txConfirmationSubscriptionsLock
+	txConfirmationSubscriptions int
 }
 
 // WebsocketServer is a handle to websocket server
 type WebsocketServer struct {
-	socket                    *websocket.Conn
-	upgrader                  *websocket.Upgrader
-	db                        *db.RocksDB
-	txCache                   *db.TxCache
-	chain                     bchain.BlockChain
-	chainParser               bchain.BlockChainParser
-	mempool                   bchain.Mempool
-	metrics                   *common.Metrics
-	is                        *common.InternalState
-	api                       *api.Worker
-	block0hash                string
-	newBlockSubscriptions     map[*websocketChannel]string
-	newBlockSubscriptionsLock sync.Mutex
-	addressSubscriptions      map[string]map[*websocketChannel]string
-	addressSubscriptionsLock  sync.Mutex
+	socket                          *websocket.Conn
+	upgrader                        *websocket.Upgrader
+	db                              *db.RocksDB
+	txCache                         *db.TxCache
+	chain                           bchain.BlockChain
+	chainParser                     bchain.BlockChainParser
+	mempool                         bchain.Mempool
+	metrics                         *common.Metrics
+	is                              *common.InternalState
+	api                             *api.Worker
+	block0hash                      string
+	newBlockSubscriptions           map[*websocketChannel]string
+	newBlockSubscriptionsLock       sync.Mutex
+	addressSubscriptions            map[string]map[*websocketChannel]string
+	addressSubscriptionsLock        sync.Mutex
+	txConfirmationSubscriptions     map[txConfirmationKey]map[*websocketChannel]*txConfirmationSubscription
+	txConfirmationSubscriptionsLock sync.Mutex
+	addressNotificationWindow       time.Duration
+	maxConnections                  int
+}
+
+// txConfirmationKey identifies the subscription to the confirmation of transaction txid at the depth target
+type txConfirmationKey struct {
+	txid   string
+	target uint32
+}
+
+// txConfirmationSubscription is a subscription of one channel, confirmed is set if the transaction was seen in a block
+type txConfirmationSubscription struct {
+	id        string
+	confirmed bool
 }
 
 // NewWebsocketServer creates new websocket interface to blockbook and returns its handle
@@ -86,21 +110,34 @@ func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.
 			WriteBufferSize: 1024 * 32,
 			CheckOrigin:     checkOrigin,
 		},
-		db:                    db,
-		txCache:               txCache,
-		chain:                 chain,
-		chainParser:           chain.GetChainParser(),
-		mempool:               mempool,
-		metrics:               metrics,
-		is:                    is,
-		api:                   api,
-		block0hash:            b0,
-		newBlockSubscriptions: make(map[*websocketChannel]string),
-		addressSubscriptions:  make(map[string]map[*websocketChannel]string),
+		db:                          db,
+		txCache:                     txCache,
+		chain:                       chain,
+		chainParser:                 chain.GetChainParser(),
+		mempool:                     mempool,
+		metrics:                     metrics,
+		is:                          is,
+		api:                         api,
+		block0hash:                  b0,
+		newBlockSubscriptions:       make(map[*websocketChannel]string),
+		addressSubscriptions:        make(map[string]map[*websocketChannel]string),
+		txConfirmationSubscriptions: make(map[txConfirmationKey]map[*websocketChannel]*txConfirmationSubscription),
 	}
 	return s, nil
 }
 
+// SetAddressNotificationWindow sets the window in which the address notifications to one channel are batched
+// to a single message, zero means that each notification is sent immediately
+func (s *WebsocketServer) SetAddressNotificationWindow(window time.Duration) {
+	s.addressNotificationWindow = window
+}
+
+// SetMaxConnections sets the maximum number of the open websocket connections, the further connections are closed
+// immediately after the upgrade with the reason tooManyConnections, zero means no limit
+func (s *WebsocketServer) SetMaxConnections(n int) {
+	s.maxConnections = n
+}
+
 // allow all origins
 func checkOrigin(r *http.Request) bool {
 	return true
@@ -117,6 +154,14 @@ func (s *WebsocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, upgradeFailed+err.Error(), 503)
 		return
 	}
+	// only the upgraded connections are counted, the limit is checked together with the increment
+	if !s.is.AddWebsocketConnectionWithLimit(s.maxConnections) {
+		glog.Warning("Client ", r.RemoteAddr, " rejected, ", tooManyConnections)
+		msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, tooManyConnections)
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		conn.Close()
+		return
+	}
 	c := &websocketChannel{
 		id:            atomic.AddUint64(&connectionCounter, 1),
 		conn:          conn,
@@ -156,6 +201,23 @@ func (c *websocketChannel) IsAlive() bool {
 	return c.alive
 }
 
+// sendUnderAliveLock sends the response to the channel holding the lock used by closeChannel, so that the response
+// cannot be sent to the closed out channel; it must not block under the lock, therefore the connection
+// is closed if the out channel is full and the input loop then closes the channel
+func (c *websocketChannel) sendUnderAliveLock(res *websocketRes) {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+	if !c.alive {
+		return
+	}
+	if len(c.out) < cap(c.out) {
+		c.out <- res
+	} else {
+		glog.Warning("Channel ", c.id, " overflow, closing")
+		c.conn.Close()
+	}
+}
+
 func (s *WebsocketServer) inputLoop(c *websocketChannel) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -214,8 +276,10 @@ func (s *WebsocketServer) onConnect(c *websocketChannel) {
 func (s *WebsocketServer) onDisconnect(c *websocketChannel) {
 	s.unsubscribeNewBlock(c)
 	s.unsubscribeAddresses(c)
+	s.unsubscribeTxConfirmations(c)
 	glog.Info("Client disconnected ", c.id, ", ", c.ip)
 	s.metrics.WebsocketClients.Dec()
+	s.is.AddWebsocketConnections(-1)
 }
 
 var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *websocketReq) (interface{}, error){
@@ -298,6 +362,20 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *webs
 	"unsubscribeAddresses": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
 		return s.unsubscribeAddresses(c)
 	},
+	"subscribeTxConfirmation": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		r := struct {
+			Txid          string `json:"txid"`
+			Confirmations uint32 `json:"confirmations"`
+		}{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.subscribeTxConfirmation(c, r.Txid, r.Confirmations, req)
+		}
+		return
+	},
+	"unsubscribeTxConfirmations": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		return s.unsubscribeTxConfirmations(c)
+	},
 }
 
 func (s *WebsocketServer) onRequest(c *websocketChannel, req *websocketReq) {
@@ -408,7 +486,12 @@ func (s *WebsocketServer) getAccountUtxo(descriptor string) (interface{}, error)
 }
 
 func (s *WebsocketServer) getTransaction(txid string) (interface{}, error) {
-	return s.api.GetTransaction(txid, false, false)
+	tx, err := s.api.GetTransaction(txid, false, false)
+	if err != nil {
+		return nil, err
+	}
+	s.api.SetTxIndexInBlock(tx, false)
+	return tx, nil
 }
 
 func (s *WebsocketServer) getTransactionSpecific(txid string) (interface{}, error) {
@@ -599,17 +682,164 @@ func (s *WebsocketServer) unsubscribeAddresses(c *websocketChannel) (res interfa
 	return &subscriptionResponse{false}, nil
 }
 
+type txConfirmationSubscriptionResponse struct {
+	Subscribed    bool   `json:"subscribed"`
+	Txid          string `json:"txid"`
+	Confirmations uint32 `json:"confirmations"`
+}
+
+// subscribeTxConfirmation subscribes the notification when the transaction txid reaches target confirmations,
+// the transaction must be in the mempool or confirmed; if it has already reached the target, it is not subscribed
+func (s *WebsocketServer) subscribeTxConfirmation(c *websocketChannel, txid string, target uint32, req *websocketReq) (res interface{}, err error) {
+	if target == 0 {
+		return nil, errors.New("Invalid confirmations")
+	}
+	status, err := s.api.HasTransaction(txid)
+	if err != nil {
+		return nil, err
+	}
+	if status.Status == api.TxStatusUnknown {
+		return nil, errors.New("Transaction not found")
+	}
+	if status.Confirmations >= target {
+		return &txConfirmationSubscriptionResponse{Subscribed: false, Txid: txid, Confirmations: status.Confirmations}, nil
+	}
+	s.txConfirmationSubscriptionsLock.Lock()
+	defer s.txConfirmationSubscriptionsLock.Unlock()
+	key := txConfirmationKey{txid: txid, target: target}
+	cs, ok := s.txConfirmationSubscriptions[key]
+	if _, found := cs[c]; !found {
+		if c.txConfirmationSubscriptions >= maxTxConfirmationSubscriptions {
+			return nil, errors.New("Too many transaction confirmation subscriptions")
+		}
+		c.txConfirmationSubscriptions++
+	}
+	if !ok {
+		cs = make(map[*websocketChannel]*txConfirmationSubscription)
+		s.txConfirmationSubscriptions[key] = cs
+	}
+	cs[c] = &txConfirmationSubscription{id: req.ID, confirmed: status.Status == api.TxStatusConfirmed}
+	return &txConfirmationSubscriptionResponse{Subscribed: true, Txid: txid, Confirmations: status.Confirmations}, nil
+}
+
+// unsubscribeTxConfirmations unsubscribes all transaction confirmation subscriptions by this channel
+func (s *WebsocketServer) unsubscribeTxConfirmations(c *websocketChannel) (res interface{}, err error) {
+	s.txConfirmationSubscriptionsLock.Lock()
+	defer s.txConfirmationSubscriptionsLock.Unlock()
+	for key, ts := range s.txConfirmationSubscriptions {
+		delete(ts, c)
+		if len(ts) == 0 {
+			delete(s.txConfirmationSubscriptions, key)
+		}
+	}
+	c.txConfirmationSubscriptions = 0
+	return &subscriptionResponse{false}, nil
+}
+
+type txConfirmationNotification struct {
+	Txid          string `json:"txid"`
+	Confirmations uint32 `json:"confirmations"`
+	Event         string `json:"event"`
+}
+
+// events of txConfirmationNotification
+const (
+	txConfirmationConfirmed = "confirmed"
+	txConfirmationReorged   = "reorged"
+	txConfirmationDropped   = "dropped"
+)
+
+// checkTxConfirmations notifies the subscribed clients about transactions which reached the target confirmations,
+// were removed from the chain by a reorg or dropped from the mempool, the notified subscriptions are removed;
+// the transactions are looked up and the notifications sent without holding the lock of the subscriptions
+func (s *WebsocketServer) checkTxConfirmations() {
+	s.txConfirmationSubscriptionsLock.Lock()
+	txids := make(map[string]*api.TxStatus, len(s.txConfirmationSubscriptions))
+	for key := range s.txConfirmationSubscriptions {
+		txids[key.txid] = nil
+	}
+	s.txConfirmationSubscriptionsLock.Unlock()
+	for txid := range txids {
+		status, err := s.api.HasTransaction(txid)
+		if err != nil {
+			glog.Error("HasTransaction error ", err, " for ", txid)
+			delete(txids, txid)
+			continue
+		}
+		txids[txid] = status
+	}
+	type notification struct {
+		c   *websocketChannel
+		res *websocketRes
+	}
+	var notifications []notification
+	s.txConfirmationSubscriptionsLock.Lock()
+	for key, ts := range s.txConfirmationSubscriptions {
+		// the subscriptions added after the snapshot are checked next time
+		status := txids[key.txid]
+		if status == nil {
+			continue
+		}
+		for c, sub := range ts {
+			var event string
+			switch {
+			case status.Confirmations >= key.target:
+				event = txConfirmationConfirmed
+			case status.Status == api.TxStatusConfirmed:
+				sub.confirmed = true
+				continue
+			case sub.confirmed:
+				event = txConfirmationReorged
+			case status.Status == api.TxStatusUnknown:
+				event = txConfirmationDropped
+			default:
+				continue
+			}
+			notifications = append(notifications, notification{c: c, res: &websocketRes{
+				ID: sub.id,
+				Data: &txConfirmationNotification{
+					Txid:          key.txid,
+					Confirmations: status.Confirmations,
+					Event:         event,
+				},
+			}})
+			delete(ts, c)
+			c.txConfirmationSubscriptions--
+			glog.Info("notifying tx ", key.txid, " ", event, " with ", status.Confirmations, " confirmations to channel ", c.id)
+		}
+		if len(ts) == 0 {
+			delete(s.txConfirmationSubscriptions, key)
+		}
+	}
+	s.txConfirmationSubscriptionsLock.Unlock()
+	for _, n := range notifications {
+		if n.c.IsAlive() {
+			n.c.out <- n.res
+		}
+	}
+}
+
 // OnNewBlock is a callback that broadcasts info about new block to subscribed clients
 func (s *WebsocketServer) OnNewBlock(hash string, height uint32) {
+	s.OnNewBlocks(height, hash, height)
+}
+
+// OnNewBlocks is a callback that broadcasts info about blocks fromHeight..height to subscribed clients,
+// the fromHeight field is sent only if the notification covers more than one block
+func (s *WebsocketServer) OnNewBlocks(fromHeight uint32, hash string, height uint32) {
 	s.newBlockSubscriptionsLock.Lock()
 	defer s.newBlockSubscriptionsLock.Unlock()
 	data := struct {
-		Height uint32 `json:"height"`
-		Hash   string `json:"hash"`
+		Height     uint32 `json:"height"`
+		Hash       string `json:"hash"`
+		FromHeight uint32 `json:"fromHeight,omitempty"`
 	}{
 		Height: height,
 		Hash:   hash,
 	}
+	if fromHeight < height {
+		data.FromHeight = fromHeight
+	}
 	for c, id := range s.newBlockSubscriptions {
 		if c.IsAlive() {
 			c.out <- &websocketRes{
@@ -618,7 +848,19 @@ func (s *WebsocketServer) OnNewBlock(hash string, height uint32) {
 			}
 		}
 	}
-	glog.Info("broadcasting new block ", height, " ", hash, " to ", len(s.newBlockSubscriptions), " channels")
+	if data.FromHeight > 0 {
+		glog.Info("broadcasting new blocks ", fromHeight, "-", height, " ", hash, " to ", len(s.newBlockSubscriptions), " channels")
+	} else {
+		glog.Info("broadcasting new block ", height, " ", hash, " to ", len(s.newBlockSubscriptions), " channels")
+	}
+	s.checkTxConfirmations()
+}
+
+// IsWatchedAddrDesc returns true if there is a subscription to the address
+func (s *WebsocketServer) IsWatchedAddrDesc(addrDesc bchain.AddressDescriptor) bool {
+	s.addressSubscriptionsLock.Lock()
+	defer s.addressSubscriptionsLock.Unlock()
+	return len(s.addressSubscriptions[string(addrDesc)]) > 0
 }
 
 // OnNewTxAddr is a callback that broadcasts info about a tx affecting subscribed address
@@ -652,15 +894,50 @@ func (s *WebsocketServer) OnNewTxAddr(tx *bchain.Tx, addrDesc bchain.AddressDesc
 			as, ok = s.addressSubscriptions[string(addrDesc)]
 			if ok {
 				for c, id := range as {
-					if c.IsAlive() {
-						c.out <- &websocketRes{
-							ID:   id,
-							Data: &data,
-						}
-					}
+					s.sendAddressNotification(c, id, &data)
 				}
 				glog.Info("broadcasting new tx ", tx.Txid, " for addr ", addr[0], " to ", len(as), " channels")
 			}
 		}
 	}
 }
+
+// sendAddressNotification sends the notification of the subscription id to the channel, with the batching window set
+// the notifications are collected and sent after the window elapses as a single message with the array of the notifications
+func (s *WebsocketServer) sendAddressNotification(c *websocketChannel, id string, data interface{}) {
+	if s.addressNotificationWindow <= 0 {
+		c.sendUnderAliveLock(&websocketRes{
+			ID:   id,
+			Data: data,
+		})
+		return
+	}
+	c.addrBatchLock.Lock()
+	defer c.addrBatchLock.Unlock()
+	// the batch of a replaced subscription is sent before the notifications of the new one are collected
+	if len(c.addrBatch) > 0 && c.addrBatchID != id {
+		c.flushAddressNotifications()
+	}
+	if len(c.addrBatch) == 0 {
+		time.AfterFunc(s.addressNotificationWindow, func() {
+			c.addrBatchLock.Lock()
+			defer c.addrBatchLock.Unlock()
+			c.flushAddressNotifications()
+		})
+	}
+	c.addrBatch = append(c.addrBatch, data)
+	c.addrBatchID = id
+}
+
+// flushAddressNotifications sends the pending address notifications, addrBatchLock must be held
+func (c *websocketChannel) flushAddressNotifications() {
+	if len(c.addrBatch) == 0 {
+		return
+	}
+	// the flush runs also from the timer after the window, possibly after the channel was closed
+	c.sendUnderAliveLock(&websocketRes{
+		ID:   c.addrBatchID,
+		Data: c.addrBatch,
+	})
+	c.addrBatch = nil
+}
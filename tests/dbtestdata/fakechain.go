@@ -8,6 +8,15 @@ import (
 	"math/big"
 )
 
+// TxKnownHex is a transaction reported by the fake backend as already known, its txid is TxKnownTxid
+const TxKnownHex = "01000000017f9a22c9cbf54bd902400df746f138f37bcf5b4d93eb755820e974ba43ed5f42040000006a4730440220037f4ed5427cde81d55b9b6a2fd08c8a25090c2c2fff3a75c1a57625ca8a7118022076c702fe55969fa08137f71afd4851c48e31082dd3c40c919c92cdbc826758d30121029f6da5623c9f9b68a9baf9c1bc7511df88fa34c6c2f71f7c62f2f03ff48dca80feffffff019c9700000000000017a9146144d57c8aff48492c9dfb914e120b20bad72d6f8773d00700"
+
+// TxKnownTxid is the txid of TxKnownHex
+const TxKnownTxid = "056e3d82e5ffd0e915fb9b62797d76263508c34fe3e5dbed30dd3e943930f204"
+
+// TxidMempoolLowFee is a mempool transaction with the fee rate 0.2 sat/B returned only by GetMempoolEntries
+const TxidMempoolLowFee = "e1bfa52f2fea98c3c2123c538fde2a668bbd353ca9a5c22bcb9c6f8372a2b1a8"
+
 type fakeBlockChain struct {
 	*bchain.BaseChain
 }
@@ -25,7 +34,7 @@ func (c *fakeBlockChain) Initialize() error {
 	return nil
 }
 
-func (c *fakeBlockChain) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc) error {
+func (c *fakeBlockChain) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, isWatchedAddrDesc bchain.IsWatchedAddrDescFunc) error {
 	return nil
 }
 
@@ -162,6 +171,41 @@ func (c *fakeBlockChain) GetTransactionForMempool(txid string) (v *bchain.Tx, er
 	return nil, errors.New("Not implemented")
 }
 
+// GetMempoolEntry returns the entry of TxidB2T2 as a prioritised transaction with an unconfirmed ancestor,
+// the other transactions are not in the mempool of the fake backend
+func (c *fakeBlockChain) GetMempoolEntry(txid string) (v *bchain.MempoolEntry, err error) {
+	if txid != TxidB2T2 {
+		return nil, errors.New("Transaction not in mempool")
+	}
+	v = &bchain.MempoolEntry{
+		Size:          240,
+		Vsize:         200,
+		Fee:           "0.00001",
+		ModifiedFee:   "0.00002",
+		AncestorCount: 2,
+		AncestorSize:  400,
+		AncestorFees:  3000,
+	}
+	v.FeeSat.SetInt64(1000)
+	v.ModifiedFeeSat.SetInt64(2000)
+	return v, nil
+}
+
+// GetMempoolEntries returns the entry of GetMempoolEntry for TxidB2T2 (fee rate 5 sat/B) and the entry for TxidMempoolLowFee
+func (c *fakeBlockChain) GetMempoolEntries() (v map[string]*bchain.MempoolEntry, err error) {
+	e, err := c.GetMempoolEntry(TxidB2T2)
+	if err != nil {
+		return nil, err
+	}
+	low := &bchain.MempoolEntry{
+		Size:  250,
+		Vsize: 250,
+		Fee:   "0.0000005",
+	}
+	low.FeeSat.SetInt64(50)
+	return map[string]*bchain.MempoolEntry{TxidB2T2: e, TxidMempoolLowFee: low}, nil
+}
+
 func (c *fakeBlockChain) EstimateSmartFee(blocks int, conservative bool) (v big.Int, err error) {
 	if conservative == false {
 		v.SetInt64(int64(blocks)*100 - 1)
@@ -180,6 +224,9 @@ func (c *fakeBlockChain) SendRawTransaction(tx string) (v string, err error) {
 	if tx == "123456" {
 		return "9876", nil
 	}
+	if tx == TxKnownHex {
+		return "", &bchain.RPCError{Code: -27, Message: "transaction already in block chain"}
+	}
 	return "", errors.New("Invalid data")
 }
 